@@ -1,19 +1,45 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"math"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/NethermindEth/cairo-vm-go/pkg/hintrunner"
 	runnerzero "github.com/NethermindEth/cairo-vm-go/pkg/runners/zero"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
 	"github.com/urfave/cli/v2"
 )
 
+// Steps whose trace is kept in memory before being written to the trace file
+const traceFlushSteps = 1 << 16
+
 func main() {
 	var proofmode bool
+	var profile bool
+	var readOnlyProgram bool
+	var printResources bool
+	var printOutput bool
+	var secureRun bool
+	var feltRadix int
 	var maxsteps uint64
+	var timeout time.Duration
+	var layoutName string
+	var hashFallback bool
+	var programLocation string
+	var jsonOutput bool
 	var traceLocation string
 	var memoryLocation string
+	var memoryJSONLocation string
+	var printMemory cli.StringSlice
 
 	app := &cli.App{
 		Name:                 "cairo-vm",
@@ -32,6 +58,46 @@ func main() {
 						Required:    false,
 						Destination: &proofmode,
 					},
+					&cli.BoolFlag{
+						Name:        "profile",
+						Usage:       "prints how many times each opcode, res logic and pc update was executed",
+						Required:    false,
+						Destination: &profile,
+					},
+					&cli.BoolFlag{
+						Name:        "readonly-program",
+						Usage:       "fails the run if the program segment is written after loading it",
+						Required:    false,
+						Destination: &readOnlyProgram,
+					},
+					&cli.BoolFlag{
+						Name:        "print-resources",
+						Usage:       "prints the steps, memory holes and builtin instances used by the run",
+						Required:    false,
+						Destination: &printResources,
+					},
+					&cli.BoolFlag{
+						Name:        "print-output",
+						Usage:       "prints the values written to the output builtin",
+						Required:    false,
+						Destination: &printOutput,
+					},
+					&cli.StringSliceFlag{
+						Name: "print-memory",
+						Usage: "prints the cells of a memory range after the run, e.g. 1:0-1:20 for the first 20 " +
+							"cells of the execution segment, the end being excluded, or 1:5 for a single cell. " +
+							"Can be repeated",
+						Required:    false,
+						Destination: &printMemory,
+					},
+					&cli.BoolFlag{
+						Name: "secure-run",
+						Usage: "verifies after the run that the program segment was not read past its end, " +
+							"that every builtin cell passes its builtin checks and that every address " +
+							"points inside an allocated segment. Defaults to true outside of proof mode",
+						Required:    false,
+						Destination: &secureRun,
+					},
 					&cli.Uint64Flag{
 						Name:        "maxsteps",
 						Usage:       "limits the execution steps to 'maxsteps'",
@@ -40,6 +106,37 @@ func main() {
 						Required:    false,
 						Destination: &maxsteps,
 					},
+					&cli.DurationFlag{
+						Name:        "timeout",
+						Usage:       "aborts the run if it takes longer than 'timeout', e.g. 30s",
+						Required:    false,
+						Destination: &timeout,
+					},
+					&cli.StringFlag{
+						Name:        "layout",
+						Usage:       "restricts the builtins to a layout: plain, small or all_cairo",
+						Required:    false,
+						Destination: &layoutName,
+					},
+					&cli.BoolFlag{
+						Name:        "hash-fallback",
+						Usage:       "computes the keccak and poseidon builtins the layout lacks through hints instead of failing",
+						Required:    false,
+						Destination: &hashFallback,
+					},
+					&cli.BoolFlag{
+						Name:        "json",
+						Usage:       "prints the steps, builtin usage, output, return values and error of the run as json",
+						Required:    false,
+						Destination: &jsonOutput,
+					},
+					&cli.IntFlag{
+						Name:        "felt-radix",
+						Usage:       "prints felts in base 10 or 16",
+						Value:       10,
+						Required:    false,
+						Destination: &feltRadix,
+					},
 					&cli.StringFlag{
 						Name:        "tracefile",
 						Usage:       "location to store the relocated trace",
@@ -52,14 +149,35 @@ func main() {
 						Required:    false,
 						Destination: &memoryLocation,
 					},
+					&cli.StringFlag{
+						Name:        "memoryjsonfile",
+						Usage:       "location to store the memory before relocation as json keyed by segment:offset, for debuggers",
+						Required:    false,
+						Destination: &memoryJSONLocation,
+					},
 				},
 				Action: func(ctx *cli.Context) error {
 					pathToFile := ctx.Args().Get(0)
 					if pathToFile == "" {
 						return fmt.Errorf("path to cairo file not set")
 					}
+					if err := memory.CheckFeltRadix(feltRadix); err != nil {
+						return err
+					}
+					// a mistyped range fails before running the program
+					memoryRanges := make([]memoryRange, len(printMemory.Value()))
+					for i, text := range printMemory.Value() {
+						memoryRange, err := parseMemoryRange(text)
+						if err != nil {
+							return err
+						}
+						memoryRanges[i] = memoryRange
+					}
 
-					fmt.Printf("Loading program at %s\n", pathToFile)
+					// progress messages would break the json document
+					if !jsonOutput {
+						fmt.Printf("Loading program at %s\n", pathToFile)
+					}
 					content, err := os.ReadFile(pathToFile)
 					if err != nil {
 						return fmt.Errorf("cannot load program: %w", err)
@@ -69,26 +187,100 @@ func main() {
 						return fmt.Errorf("cannot load program: %w", err)
 					}
 
-					fmt.Println("Running....")
-					runner, err := runnerzero.NewRunner(program, proofmode, maxsteps)
+					if !jsonOutput {
+						fmt.Println("Running....")
+					}
+					runner, err := createRunner(program, proofmode, maxsteps, layoutName, hashFallback)
 					if err != nil {
 						return fmt.Errorf("cannot create runner: %w", err)
 					}
 
-					if err := runner.Run(); err != nil {
-						return fmt.Errorf("runtime error: %w", err)
+					if err := runner.SetHintParser(hintrunner.StandardHintParser{}); err != nil {
+						return fmt.Errorf("cannot load program hints: %w", err)
+					}
+					if err := runner.SetFeltRadix(feltRadix); err != nil {
+						return err
+					}
+					if profile {
+						runner.EnableProfiling()
+					}
+					if readOnlyProgram {
+						runner.EnableReadOnlyProgram()
+					}
+					if timeout > 0 {
+						runner.SetTimeout(timeout)
+					}
+					if proofmode && traceLocation != "" {
+						traceFile, err := os.Create(traceLocation)
+						if err != nil {
+							return fmt.Errorf("cannot create relocated trace file: %w", err)
+						}
+						defer traceFile.Close()
+						runner.StreamTrace(traceFile, traceFlushSteps)
+					}
+
+					runErr := runner.Run()
+					if jsonOutput {
+						result, err := runner.RunResultJSON()
+						if err != nil {
+							return fmt.Errorf("cannot build run result: %w", err)
+						}
+						fmt.Println(string(result))
+					}
+					if runErr != nil {
+						return fmt.Errorf("runtime error: %w", runErr)
+					}
+					// like the python vm, proof mode programs are trusted by default
+					if !ctx.IsSet("secure-run") {
+						secureRun = !proofmode
+					}
+					if secureRun {
+						if err := runner.VerifySecure(); err != nil {
+							return fmt.Errorf("secure run verification failed: %w", err)
+						}
+					}
+
+					if profile {
+						printProfile(runner.ProfileStats())
+					}
+					if printResources {
+						resources, err := runner.ExecutionResources()
+						if err != nil {
+							return fmt.Errorf("cannot get execution resources: %w", err)
+						}
+						printExecutionResources(resources)
+					}
+					if printOutput {
+						output, err := runner.Output()
+						if err != nil {
+							return fmt.Errorf("cannot get program output: %w", err)
+						}
+						printProgramOutput(output, feltRadix)
+					}
+					for _, memoryRange := range memoryRanges {
+						values, err := runner.MemoryRange(&memoryRange.start, memoryRange.size)
+						if err != nil {
+							return fmt.Errorf("cannot read memory range %s: %w", memoryRange, err)
+						}
+						printMemoryRange(memoryRange, values, feltRadix)
+					}
+
+					if memoryJSONLocation != "" {
+						memoryJSON, err := runner.MemoryJSON()
+						if err != nil {
+							return fmt.Errorf("cannot encode memory as json: %w", err)
+						}
+						if err := os.WriteFile(memoryJSONLocation, memoryJSON, 0644); err != nil {
+							return fmt.Errorf("cannot write memory json: %w", err)
+						}
 					}
 
 					if proofmode {
-						trace, memory, err := runner.BuildProof()
+						// the trace is streamed to its file, if any, while running
+						_, memory, err := runner.BuildProof()
 						if err != nil {
 							return fmt.Errorf("cannot build proof: %w", err)
 						}
-						if traceLocation != "" {
-							if err := os.WriteFile(traceLocation, trace, 0644); err != nil {
-								return fmt.Errorf("cannot write relocated trace: %w", err)
-							}
-						}
 						if memoryLocation != "" {
 							if err := os.WriteFile(memoryLocation, memory, 0644); err != nil {
 								return fmt.Errorf("cannot write relocated memory: %w", err)
@@ -96,10 +288,77 @@ func main() {
 						}
 					}
 
-					fmt.Println("Success!")
+					if !jsonOutput {
+						fmt.Println("Success!")
+					}
 					return nil
 				},
 			},
+			{
+				Name:  "trace",
+				Usage: "prints a relocated trace file",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "program",
+						Usage:       "compiled program used to annotate each pc with its instruction",
+						Required:    false,
+						Destination: &programLocation,
+					},
+					&cli.BoolFlag{
+						Name:        "json",
+						Usage:       "prints the trace as json",
+						Required:    false,
+						Destination: &jsonOutput,
+					},
+				},
+				Action: func(ctx *cli.Context) error {
+					pathToFile := ctx.Args().Get(0)
+					if pathToFile == "" {
+						return fmt.Errorf("path to trace file not set")
+					}
+
+					content, err := os.ReadFile(pathToFile)
+					if err != nil {
+						return fmt.Errorf("cannot load trace: %w", err)
+					}
+					trace := runnerzero.DecodeTrace(content)
+
+					var program *runnerzero.Program
+					if programLocation != "" {
+						programContent, err := os.ReadFile(programLocation)
+						if err != nil {
+							return fmt.Errorf("cannot load program: %w", err)
+						}
+						program, err = runnerzero.LoadCairoZeroProgram(programContent)
+						if err != nil {
+							return fmt.Errorf("cannot load program: %w", err)
+						}
+					}
+
+					return printTrace(os.Stdout, trace, program, jsonOutput)
+				},
+			},
+			{
+				Name:  "disassemble",
+				Usage: "prints the instructions of a cairo zero compiled file",
+				Action: func(ctx *cli.Context) error {
+					pathToFile := ctx.Args().Get(0)
+					if pathToFile == "" {
+						return fmt.Errorf("path to cairo file not set")
+					}
+
+					content, err := os.ReadFile(pathToFile)
+					if err != nil {
+						return fmt.Errorf("cannot load program: %w", err)
+					}
+					program, err := runnerzero.LoadCairoZeroProgram(content)
+					if err != nil {
+						return fmt.Errorf("cannot load program: %w", err)
+					}
+
+					return printDisassembly(os.Stdout, program)
+				},
+			},
 		},
 	}
 
@@ -108,3 +367,212 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// Creates the runner, restricting its builtins to a layout if any is given
+func createRunner(
+	program *runnerzero.Program, proofmode bool, maxsteps uint64, layoutName string, hashFallback bool,
+) (*runnerzero.ZeroRunner, error) {
+	if layoutName == "" {
+		return runnerzero.NewRunner(program, proofmode, maxsteps)
+	}
+	layout, err := runnerzero.LayoutByName(layoutName)
+	if err != nil {
+		return nil, err
+	}
+	layout.HashHintFallback = hashFallback
+	return runnerzero.NewRunnerWithLayout(program, proofmode, maxsteps, layout)
+}
+
+func printProfile(stats map[string]uint64) {
+	keys := make([]string, 0, len(stats))
+	for key := range stats {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Println("Profile:")
+	for _, key := range keys {
+		fmt.Printf("  %-20s %d\n", key, stats[key])
+	}
+}
+
+func printExecutionResources(resources runnerzero.ExecutionResources) {
+	names := make([]string, 0, len(resources.BuiltinInstanceCounter))
+	for name := range resources.BuiltinInstanceCounter {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println("Execution resources:")
+	fmt.Printf("  %-20s %d\n", "n_steps", resources.NSteps)
+	fmt.Printf("  %-20s %d\n", "n_memory_holes", resources.NMemoryHoles)
+	for _, name := range names {
+		fmt.Printf("  %-20s %d\n", name, resources.BuiltinInstanceCounter[name])
+	}
+}
+
+// Prints each output cell on its own line, felts in radix. Cells the program
+// never wrote are shown as <missing>
+func printProgramOutput(output []memory.MemoryValue, radix int) {
+	fmt.Println("Program output:")
+	for i := range output {
+		if !output[i].Known() {
+			fmt.Println("  <missing>")
+			continue
+		}
+		fmt.Printf("  %s\n", output[i].Text(radix))
+	}
+}
+
+// A range of cells of a single segment, the end being excluded
+type memoryRange struct {
+	start memory.MemoryAddress
+	size  uint64
+}
+
+func (r memoryRange) String() string {
+	if r.size == 1 {
+		return r.start.String()
+	}
+	end := memory.MemoryAddress{SegmentIndex: r.start.SegmentIndex, Offset: r.start.Offset + r.size}
+	return fmt.Sprintf("%s-%s", r.start, end)
+}
+
+// Parses a range such as 1:0-1:20 or a single cell such as 1:5
+func parseMemoryRange(text string) (memoryRange, error) {
+	startText, endText, isRange := strings.Cut(text, "-")
+	start, err := parseMemoryAddress(startText)
+	if err != nil {
+		return memoryRange{}, err
+	}
+	if !isRange {
+		return memoryRange{start: start, size: 1}, nil
+	}
+
+	end, err := parseMemoryAddress(endText)
+	if err != nil {
+		return memoryRange{}, err
+	}
+	if end.SegmentIndex != start.SegmentIndex {
+		return memoryRange{}, fmt.Errorf("memory range %s spans several segments", text)
+	}
+	if end.Offset <= start.Offset {
+		return memoryRange{}, fmt.Errorf("memory range %s is empty", text)
+	}
+	return memoryRange{start: start, size: end.Offset - start.Offset}, nil
+}
+
+func parseMemoryAddress(text string) (memory.MemoryAddress, error) {
+	segmentText, offsetText, ok := strings.Cut(strings.TrimSpace(text), ":")
+	if !ok {
+		return memory.MemoryAddress{}, fmt.Errorf("invalid address %s, expected segment:offset", text)
+	}
+	segment, err := strconv.ParseUint(segmentText, 10, 64)
+	if err != nil {
+		return memory.MemoryAddress{}, fmt.Errorf("invalid segment of address %s: %w", text, err)
+	}
+	offset, err := strconv.ParseUint(offsetText, 10, 64)
+	if err != nil {
+		return memory.MemoryAddress{}, fmt.Errorf("invalid offset of address %s: %w", text, err)
+	}
+	return memory.MemoryAddress{SegmentIndex: segment, Offset: offset}, nil
+}
+
+// Prints each cell of the range on its own line, prefixed by its address and
+// with felts in radix. Unknown cells are shown as <missing>
+func printMemoryRange(r memoryRange, values []memory.MemoryValue, radix int) {
+	fmt.Printf("Memory %s:\n", r)
+	address := r.start
+	for i := range values {
+		if values[i].Known() {
+			fmt.Printf("  %s  %s\n", address, values[i].Text(radix))
+		} else {
+			fmt.Printf("  %s  <missing>\n", address)
+		}
+		address.Offset++
+	}
+}
+
+type traceRow struct {
+	Step        int    `json:"step"`
+	Pc          uint64 `json:"pc"`
+	Ap          uint64 `json:"ap"`
+	Fp          uint64 `json:"fp"`
+	Instruction string `json:"instruction,omitempty"`
+}
+
+// Prints each step of a relocated trace. If a program is given, each pc
+// is annotated with the instruction it points to
+func printTrace(w io.Writer, trace []vm.Trace, program *runnerzero.Program, asJson bool) error {
+	rows := make([]traceRow, len(trace))
+	for i := range trace {
+		rows[i] = traceRow{Step: i, Pc: trace[i].Pc, Ap: trace[i].Ap, Fp: trace[i].Fp}
+		if program != nil {
+			rows[i].Instruction = disassembleAt(program, trace[i].Pc)
+		}
+	}
+
+	if asJson {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rows)
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "step\tpc\tap\tfp\tinstruction")
+	for _, row := range rows {
+		fmt.Fprintf(table, "%d\t%d\t%d\t%d\t%s\n", row.Step, row.Pc, row.Ap, row.Fp, row.Instruction)
+	}
+	return table.Flush()
+}
+
+// Returns the instruction at a relocated pc. The program segment is relocated
+// right after the reserved address 0
+func disassembleAt(program *runnerzero.Program, pc uint64) string {
+	if pc == 0 || pc > uint64(len(program.Bytecode)) {
+		return "<outside of the program>"
+	}
+	instruction, err := vm.DecodeInstruction(program.Bytecode[pc-1])
+	if err != nil {
+		return fmt.Sprintf("<%s>", err)
+	}
+	return instruction.String()
+}
+
+// Prints the offset, word and decoded instruction of every instruction in
+// the program. Immediates are printed right below the instruction using them
+// and functions and labels are printed above the instruction they point to
+func printDisassembly(w io.Writer, program *runnerzero.Program) error {
+	names := make(map[uint64][]string)
+	for name, pc := range program.Entrypoints {
+		names[pc] = append(names[pc], name)
+	}
+	for name, pc := range program.Labels {
+		names[pc] = append(names[pc], name)
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "offset\tword\tinstruction")
+	for pc := 0; pc < len(program.Bytecode); {
+		sort.Strings(names[uint64(pc)])
+		for _, name := range names[uint64(pc)] {
+			fmt.Fprintf(table, "\t\t%s:\n", name)
+		}
+
+		word := program.Bytecode[pc]
+		instruction, err := vm.DecodeInstruction(word)
+		if err != nil {
+			// data that isn't an instruction, e.g. a constant after a return
+			fmt.Fprintf(table, "%d\t0x%s\t<%s>\n", pc, word.Text(16), err)
+			pc++
+			continue
+		}
+		fmt.Fprintf(table, "%d\t0x%s\t%s\n", pc, word.Text(16), instruction)
+		if instruction.Size() == 2 && pc+1 < len(program.Bytecode) {
+			immediate := program.Bytecode[pc+1]
+			fmt.Fprintf(table, "%d\t0x%s\t  immediate %s\n", pc+1, immediate.Text(16), immediate)
+		}
+		pc += int(instruction.Size())
+	}
+	return table.Flush()
+}
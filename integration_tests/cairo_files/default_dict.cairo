@@ -0,0 +1,27 @@
+%builtins output range_check
+
+from starkware.cairo.common.default_dict import default_dict_new, default_dict_finalize
+from starkware.cairo.common.dict import dict_read, dict_update, dict_write
+from starkware.cairo.common.dict_access import DictAccess
+
+// Writes, updates and reads a default dict, then squashes it
+func main{output_ptr: felt*, range_check_ptr}() {
+    alloc_locals;
+    let (local dict_start: DictAccess*) = default_dict_new(default_value=7);
+    let dict_end = dict_start;
+
+    dict_write{dict_ptr=dict_end}(key=1, new_value=10);
+    dict_write{dict_ptr=dict_end}(key=5, new_value=3);
+    dict_update{dict_ptr=dict_end}(key=1, prev_value=10, new_value=11);
+    let (value) = dict_read{dict_ptr=dict_end}(key=1);
+    let (missing) = dict_read{dict_ptr=dict_end}(key=100);
+
+    let (squashed_start, squashed_end) = default_dict_finalize(
+        dict_accesses_start=dict_start, dict_accesses_end=dict_end, default_value=7
+    );
+
+    assert [output_ptr] = value + missing;
+    assert [output_ptr + 1] = (squashed_end - squashed_start) / DictAccess.SIZE;
+    let output_ptr = output_ptr + 2;
+    return ();
+}
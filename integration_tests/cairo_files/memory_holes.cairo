@@ -0,0 +1,16 @@
+from starkware.cairo.common.alloc import alloc
+
+// Leaves unwritten cells in the execution segment and in a segment of its
+// own, both vms must relocate them as holes
+func main() {
+    alloc_locals;
+    let (array: felt*) = alloc();
+    assert array[0] = 1;
+    assert array[3] = 4;
+
+    local unwritten;
+    local written = 5;
+    ap += 2;
+    [ap] = written + array[3], ap++;
+    return ();
+}
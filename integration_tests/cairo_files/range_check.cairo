@@ -0,0 +1,21 @@
+%builtins output range_check
+
+// Range checks the squares of 0..n-1 and returns their sum
+func sum_squares{range_check_ptr}(i, n) -> (sum: felt) {
+    if (i == n) {
+        return (sum=0);
+    }
+
+    tempvar square = i * i;
+    assert [range_check_ptr] = square;
+    let range_check_ptr = range_check_ptr + 1;
+    let (sum) = sum_squares(i + 1, n);
+    return (sum=sum + square);
+}
+
+func main{output_ptr: felt*, range_check_ptr}() {
+    let (sum) = sum_squares(0, 100);
+    assert [output_ptr] = sum;
+    let output_ptr = output_ptr + 1;
+    return ();
+}
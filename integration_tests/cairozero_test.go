@@ -11,7 +11,6 @@ import (
 	"github.com/NethermindEth/cairo-vm-go/pkg/runners/zero"
 	"github.com/NethermindEth/cairo-vm-go/pkg/vm"
 	"github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
-	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -41,13 +40,19 @@ func TestCairoZeroFiles(t *testing.T) {
 			continue
 		}
 
-		pyTraceFile, pyMemoryFile, err := runPythonVm(compiledOutput)
+		layout, err := programLayout(path)
 		if err != nil {
 			t.Error(err)
 			continue
 		}
 
-		traceFile, memoryFile, err := runVm(compiledOutput)
+		pyTraceFile, pyMemoryFile, err := runPythonVm(compiledOutput, layout)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		traceFile, memoryFile, err := runVm(compiledOutput, layout)
 		if err != nil {
 			t.Error(err)
 			continue
@@ -65,13 +70,14 @@ func TestCairoZeroFiles(t *testing.T) {
 			continue
 		}
 
-		if !assert.Equal(t, pyTrace, trace) {
-			t.Logf("pytrace:\n%s\n", traceRepr(pyTrace))
-			t.Logf("trace:\n%s\n", traceRepr(trace))
+		for _, address := range holeDiffs(memory, pyMemory) {
+			t.Errorf("%s: memory holes differ from python vm: %s", path, address)
+		}
+		for _, diff := range zero.DiffTrace(trace, pyTrace) {
+			t.Errorf("%s: trace differs from python vm: %s", path, diff)
 		}
-		if !assert.Equal(t, pyMemory, memory) {
-			t.Logf("pymemory;\n%s\n", memoryRepr(pyMemory))
-			t.Logf("memory;\n%s\n", memoryRepr(memory))
+		for _, diff := range zero.DiffMemory(memory, pyMemory) {
+			t.Errorf("%s: memory differs from python vm: %s", path, diff)
 		}
 	}
 
@@ -113,14 +119,26 @@ func compileZeroCode(path string) (string, error) {
 	return compiledOutput, nil
 }
 
+// The default layout of both vms has no builtins, programs declaring any
+// run with a layout that has the ones the test programs use
+func programLayout(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(string(content), "%builtins") {
+		return "small", nil
+	}
+	return "", nil
+}
+
 // given a path to a compiled cairo zero file, execute it using the
 // python vm and returns the trace and memory files location
-func runPythonVm(path string) (string, string, error) {
+func runPythonVm(path string, layout string) (string, string, error) {
 	traceOutput := swapExtenstion(path, pyTraceSuffix)
 	memoryOutput := swapExtenstion(path, pyMemorySuffix)
 
-	cmd := exec.Command(
-		"cairo-run",
+	args := []string{
 		"--program",
 		path,
 		"--proof_mode",
@@ -128,7 +146,11 @@ func runPythonVm(path string) (string, string, error) {
 		traceOutput,
 		"--memory_file",
 		memoryOutput,
-	)
+	}
+	if layout != "" {
+		args = append(args, "--layout", layout)
+	}
+	cmd := exec.Command("cairo-run", args...)
 
 	res, err := cmd.CombinedOutput()
 	if err != nil {
@@ -142,20 +164,22 @@ func runPythonVm(path string) (string, string, error) {
 
 // given a path to a compiled cairo zero file, execute
 // it using our vm
-func runVm(path string) (string, string, error) {
+func runVm(path string, layout string) (string, string, error) {
 	traceOutput := swapExtenstion(path, traceSuffix)
 	memoryOutput := swapExtenstion(path, memorySuffix)
 
-	cmd := exec.Command(
-		"../bin/cairo-vm",
+	args := []string{
 		"run",
 		"--proofmode",
 		"--tracefile",
 		traceOutput,
 		"--memoryfile",
 		memoryOutput,
-		path,
-	)
+	}
+	if layout != "" {
+		args = append(args, "--layout", layout)
+	}
+	cmd := exec.Command("../bin/cairo-vm", append(args, path)...)
 
 	res, err := cmd.CombinedOutput()
 	if err != nil {
@@ -184,6 +208,22 @@ func decodeProof(traceLocation string, memoryLocation string) ([]vm.Trace, []*fp
 	return decodedTrace, decodedMemory, nil
 }
 
+// Returns the relocated addresses written by only one of the vms, which
+// places its memory holes differently than the other
+func holeDiffs(ours, theirs []*fp.Element) []string {
+	var diffs []string
+	for addr := 0; addr < len(ours) || addr < len(theirs); addr++ {
+		ourHole := addr >= len(ours) || ours[addr] == nil
+		theirHole := addr >= len(theirs) || theirs[addr] == nil
+		if ourHole && !theirHole {
+			diffs = append(diffs, fmt.Sprintf("address %d is only written by the python vm", addr))
+		} else if !ourHole && theirHole {
+			diffs = append(diffs, fmt.Sprintf("address %d is only written by our vm", addr))
+		}
+	}
+	return diffs
+}
+
 func clean(root string) {
 	err := filepath.Walk(
 		root,
@@ -221,20 +261,3 @@ func isGeneratedFile(path string) bool {
 		strings.HasSuffix(path, traceSuffix) ||
 		strings.HasSuffix(path, memorySuffix)
 }
-
-func traceRepr(trace []vm.Trace) string {
-	repr := make([]string, len(trace))
-	for i, ctx := range trace {
-		repr[i] = fmt.Sprintf("{pc: %d, ap: %d, fp: %d}", ctx.Pc, ctx.Ap, ctx.Fp)
-	}
-	return strings.Join(repr, ", ")
-}
-
-func memoryRepr(memory []*fp.Element) string {
-	repr := make([]string, len(memory))
-	for i, felt := range memory {
-		repr[i] = felt.Text(10)
-	}
-	return strings.Join(repr, ", ")
-
-}
@@ -0,0 +1,284 @@
+package hintrunner
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"unicode"
+
+	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+)
+
+// Assignment hints write an arithmetic expression to a cell, e.g.
+// memory[ap + 1] = ids.a * 2 + 1 or ids.x = ids.y - 3. They follow the grammar
+//
+//	assignment := target "=" expr
+//	target     := "memory[" ("ap" | "fp") [("+" | "-") int] "]" | "ids." name
+//	expr       := term {("+" | "-") term}
+//	term       := factor {"*" factor}
+//	factor     := int | "ids." name | "(" expr ")" | "-" factor
+//
+// where int is decimal or hexadecimal. Both felts and addresses can be
+// operands, with the same rules as the vm arithmetic
+type Assign struct {
+	dst   CellRefer
+	value assignExpr
+}
+
+func (hint Assign) String() string {
+	return "Assign"
+}
+
+func (hint Assign) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	value, err := hint.value.eval(vm)
+	if err != nil {
+		return err
+	}
+	dstAddr, err := hint.dst.Get(vm)
+	if err != nil {
+		return fmt.Errorf("get dst address %s: %w", hint.dst, err)
+	}
+	if err := vm.Memory.WriteToAddress(&dstAddr, &value); err != nil {
+		return fmt.Errorf("write to dst address %s: %w", dstAddr, err)
+	}
+	return nil
+}
+
+type assignExpr interface {
+	eval(vm *VM.VirtualMachine) (memory.MemoryValue, error)
+}
+
+type constantExpr struct {
+	value f.Element
+}
+
+func (expr constantExpr) eval(vm *VM.VirtualMachine) (memory.MemoryValue, error) {
+	return memory.MemoryValueFromFieldElement(&expr.value), nil
+}
+
+type referenceExpr struct {
+	name    string
+	operand ResOperander
+}
+
+func (expr referenceExpr) eval(vm *VM.VirtualMachine) (memory.MemoryValue, error) {
+	value, err := expr.operand.Resolve(vm)
+	if err != nil {
+		return memory.MemoryValue{}, fmt.Errorf("resolve ids.%s: %w", expr.name, err)
+	}
+	return value, nil
+}
+
+type binaryExpr struct {
+	operator string
+	lhs      assignExpr
+	rhs      assignExpr
+}
+
+func (expr binaryExpr) eval(vm *VM.VirtualMachine) (memory.MemoryValue, error) {
+	lhs, err := expr.lhs.eval(vm)
+	if err != nil {
+		return memory.MemoryValue{}, err
+	}
+	rhs, err := expr.rhs.eval(vm)
+	if err != nil {
+		return memory.MemoryValue{}, err
+	}
+
+	result := memory.EmptyMemoryValueAs(lhs.IsAddress() || rhs.IsAddress())
+	switch expr.operator {
+	case "+":
+		err = result.Add(&lhs, &rhs)
+	case "-":
+		err = result.Sub(&lhs, &rhs)
+	case "*":
+		err = result.Mul(&lhs, &rhs)
+	}
+	if err != nil {
+		return memory.MemoryValue{}, fmt.Errorf("%s %s %s: %w", &lhs, expr.operator, &rhs, err)
+	}
+	return result, nil
+}
+
+// Parses an assignment hint. False if the code isn't an assignment at all,
+// errors if it is one but falls outside of the grammar
+func (references HintReferences) assignment(code string) (Hinter, bool, error) {
+	target, value, ok := strings.Cut(code, "=")
+	if !ok || strings.Contains(code, "\n") || strings.Contains(value, "=") {
+		return nil, false, nil
+	}
+	target = strings.TrimSpace(target)
+	if !strings.HasPrefix(target, "memory[") && !strings.HasPrefix(target, "ids.") {
+		return nil, false, nil
+	}
+
+	dst, err := references.assignTarget(target)
+	if err != nil {
+		return nil, true, err
+	}
+	tokens, err := tokenizeExpr(value)
+	if err != nil {
+		return nil, true, err
+	}
+	parser := exprParser{tokens: tokens, references: references}
+	expr, err := parser.expr()
+	if err != nil {
+		return nil, true, err
+	}
+	if parser.pos < len(tokens) {
+		return nil, true, fmt.Errorf("unexpected %q", tokens[parser.pos])
+	}
+	return Assign{dst: dst, value: expr}, true, nil
+}
+
+func (references HintReferences) assignTarget(target string) (CellRefer, error) {
+	if name, ok := strings.CutPrefix(target, "ids."); ok {
+		return references.getCell(name)
+	}
+
+	tokens, err := tokenizeExpr(strings.TrimPrefix(target, "memory"))
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) != 3 && len(tokens) != 5 || tokens[0] != "[" || tokens[len(tokens)-1] != "]" {
+		return nil, fmt.Errorf("unsupported target %s", target)
+	}
+	offset := int64(0)
+	if len(tokens) == 5 {
+		value, ok := new(big.Int).SetString(tokens[3], 0)
+		if !ok || (tokens[2] != "+" && tokens[2] != "-") {
+			return nil, fmt.Errorf("unsupported target %s", target)
+		}
+		if tokens[2] == "-" {
+			value.Neg(value)
+		}
+		if !value.IsInt64() || value.Int64() < math.MinInt16 || value.Int64() > math.MaxInt16 {
+			return nil, fmt.Errorf("offset of target %s doesn't fit in 16 bits", target)
+		}
+		offset = value.Int64()
+	}
+
+	switch tokens[1] {
+	case "ap":
+		return ApCellRef(offset), nil
+	case "fp":
+		return FpCellRef(offset), nil
+	default:
+		return nil, fmt.Errorf("unsupported target %s", target)
+	}
+}
+
+// Splits an expression into numbers, names such as ids.a and single
+// character symbols
+func tokenizeExpr(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case strings.ContainsRune("+-*()[]", c):
+			tokens = append(tokens, string(c))
+			i++
+		case c == '_' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c):
+			start := i
+			for i < len(expr) {
+				c = rune(expr[i])
+				if c != '_' && c != '.' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+					break
+				}
+				i++
+			}
+			tokens = append(tokens, expr[start:i])
+		default:
+			return nil, fmt.Errorf("unexpected %q", c)
+		}
+	}
+	return tokens, nil
+}
+
+type exprParser struct {
+	tokens     []string
+	pos        int
+	references HintReferences
+}
+
+func (p *exprParser) next() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) expr() (assignExpr, error) {
+	lhs, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for operator := p.next(); operator == "+" || operator == "-"; operator = p.next() {
+		p.pos++
+		rhs, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		lhs = binaryExpr{operator: operator, lhs: lhs, rhs: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *exprParser) term() (assignExpr, error) {
+	lhs, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for p.next() == "*" {
+		p.pos++
+		rhs, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		lhs = binaryExpr{operator: "*", lhs: lhs, rhs: rhs}
+	}
+	return lhs, nil
+}
+
+func (p *exprParser) factor() (assignExpr, error) {
+	token := p.next()
+	p.pos++
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case token == "(":
+		expr, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return expr, nil
+	case token == "-":
+		operand, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return binaryExpr{operator: "-", lhs: constantExpr{}, rhs: operand}, nil
+	case strings.HasPrefix(token, "ids."):
+		name := strings.TrimPrefix(token, "ids.")
+		operand, err := p.references.get(name)
+		if err != nil {
+			return nil, err
+		}
+		return referenceExpr{name: name, operand: operand}, nil
+	}
+
+	value, ok := new(big.Int).SetString(token, 0)
+	if !ok {
+		return nil, fmt.Errorf("unexpected %q", token)
+	}
+	return constantExpr{value: *new(f.Element).SetBigInt(value)}, nil
+}
@@ -0,0 +1,80 @@
+package hintrunner
+
+import (
+	"testing"
+
+	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssign(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 4
+	vm.Context.Fp = 4
+	ctx := HintRunnerContext{}
+	parser := StandardHintParser{}
+
+	writeTo(vm, VM.ExecutionSegment, 3, memory.MemoryValueFromInt(20))
+	writeTo(vm, VM.ExecutionSegment, 2, memory.MemoryValueFromSegmentAndOffset(2, 5))
+	references := HintReferences{
+		"a":   Deref{FpCellRef(-1)},
+		"ptr": Deref{FpCellRef(-2)},
+		"x":   Deref{ApCellRef(2)},
+	}
+
+	hint, err := parser.Parse("memory[ap + 1] = ids.a * 2 + 1", references)
+	require.NoError(t, err)
+	require.NoError(t, hint.Execute(vm, &ctx))
+	assert.Equal(t, memory.MemoryValueFromInt(41), readFrom(vm, VM.ExecutionSegment, 5))
+
+	// the product binds tighter than the sum unless parenthesized
+	hint, err = parser.Parse("memory[fp - 4] = 2 + 3 * (ids.a - 0x10)", references)
+	require.NoError(t, err)
+	require.NoError(t, hint.Execute(vm, &ctx))
+	assert.Equal(t, memory.MemoryValueFromInt(14), readFrom(vm, VM.ExecutionSegment, 0))
+
+	hint, err = parser.Parse("memory[ap] = -ids.a", references)
+	require.NoError(t, err)
+	require.NoError(t, hint.Execute(vm, &ctx))
+	assert.Equal(t, memory.MemoryValueFromInt(-20), readFrom(vm, VM.ExecutionSegment, 4))
+
+	// addresses follow the vm arithmetic
+	hint, err = parser.Parse("ids.x = ids.ptr + 3 - 1", references)
+	require.NoError(t, err)
+	require.NoError(t, hint.Execute(vm, &ctx))
+	assert.Equal(t, memory.MemoryValueFromSegmentAndOffset(2, 7), readFrom(vm, VM.ExecutionSegment, 6))
+
+	hint, err = parser.Parse("memory[ap + 3] = ids.ptr * 2", references)
+	require.NoError(t, err)
+	require.ErrorContains(t, hint.Execute(vm, &ctx), "2:5 * 2: ")
+}
+
+func TestAssignParseErrors(t *testing.T) {
+	parser := StandardHintParser{}
+	references := HintReferences{"a": Deref{FpCellRef(-1)}, "b": Immediate{}}
+
+	for code, expected := range map[string]string{
+		"memory[ap] = ids.a / 2":    `unexpected '/'`,
+		"memory[ap] = (ids.a + 2":   "missing closing parenthesis",
+		"memory[ap] = ids.a +":      "unexpected end of expression",
+		"memory[ap] = ids.a ids.a":  `unexpected "ids.a"`,
+		"memory[ap] = ids.c":        "missing reference ids.c",
+		"memory[sp] = 1":            "unsupported target memory[sp]",
+		"memory[ap + 40000] = 1":    "offset of target memory[ap + 40000] doesn't fit in 16 bits",
+		"ids.b = ids.a":             "reference ids.b is not a cell",
+		"memory[ap] = f(ids.a)":     `unexpected "f"`,
+		"memory[ap + ids.a] = 1":    "unsupported target memory[ap + ids.a]",
+		"memory[ap] = 1 + (2 * 3))": `unexpected ")"`,
+	} {
+		_, err := parser.Parse(code, references)
+		require.EqualError(t, err, "unsupported assignment hint "+code+": "+expected, code)
+	}
+
+	// comparisons and code that isn't written to a cell aren't assignments
+	for _, code := range []string{"assert ids.a == 1", "x = ids.a", "memory[ap] = 1\nmemory[ap + 1] = 2"} {
+		_, err := parser.Parse(code, references)
+		require.EqualError(t, err, "unsupported hint: "+code, code)
+	}
+}
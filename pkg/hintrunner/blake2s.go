@@ -0,0 +1,163 @@
+package hintrunner
+
+import (
+	"fmt"
+	"math/bits"
+
+	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+)
+
+var blake2sIV = [8]uint32{
+	0x6A09E667, 0xBB67AE85, 0x3C6EF372, 0xA54FF53A,
+	0x510E527F, 0x9B05688C, 0x1F83D9AB, 0x5BE0CD19,
+}
+
+var blake2sSigma = [10][16]uint8{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+	{11, 8, 12, 0, 5, 2, 15, 13, 10, 14, 3, 6, 7, 1, 9, 4},
+	{7, 9, 3, 1, 13, 12, 11, 14, 2, 6, 5, 10, 4, 0, 15, 8},
+	{9, 0, 5, 7, 2, 4, 10, 15, 14, 1, 11, 12, 6, 8, 3, 13},
+	{2, 12, 6, 10, 0, 11, 8, 3, 4, 13, 7, 5, 15, 14, 1, 9},
+	{12, 5, 1, 15, 14, 13, 4, 10, 0, 7, 6, 3, 9, 2, 8, 11},
+	{13, 11, 7, 14, 12, 1, 3, 9, 5, 0, 15, 4, 8, 6, 2, 10},
+	{6, 15, 14, 9, 11, 3, 0, 8, 12, 2, 13, 7, 1, 4, 10, 5},
+	{10, 2, 8, 4, 7, 6, 1, 5, 15, 11, 9, 14, 3, 12, 13, 0},
+}
+
+// The blake2s compression function as specified by RFC 7693. h is the state,
+// t0 and t1 the low and high words of the bytes counter and f0 and f1 the
+// finalization flags
+func Blake2sCompress(h [8]uint32, message [16]uint32, t0, t1, f0, f1 uint32) [8]uint32 {
+	v := [16]uint32{}
+	copy(v[:8], h[:])
+	copy(v[8:], blake2sIV[:])
+	v[12] ^= t0
+	v[13] ^= t1
+	v[14] ^= f0
+	v[15] ^= f1
+
+	mix := func(a, b, c, d int, x, y uint32) {
+		v[a] = v[a] + v[b] + x
+		v[d] = bits.RotateLeft32(v[d]^v[a], -16)
+		v[c] = v[c] + v[d]
+		v[b] = bits.RotateLeft32(v[b]^v[c], -12)
+		v[a] = v[a] + v[b] + y
+		v[d] = bits.RotateLeft32(v[d]^v[a], -8)
+		v[c] = v[c] + v[d]
+		v[b] = bits.RotateLeft32(v[b]^v[c], -7)
+	}
+	for _, s := range blake2sSigma {
+		mix(0, 4, 8, 12, message[s[0]], message[s[1]])
+		mix(1, 5, 9, 13, message[s[2]], message[s[3]])
+		mix(2, 6, 10, 14, message[s[4]], message[s[5]])
+		mix(3, 7, 11, 15, message[s[6]], message[s[7]])
+		mix(0, 5, 10, 15, message[s[8]], message[s[9]])
+		mix(1, 6, 11, 12, message[s[10]], message[s[11]])
+		mix(2, 7, 8, 13, message[s[12]], message[s[13]])
+		mix(3, 4, 9, 14, message[s[14]], message[s[15]])
+	}
+
+	for i := range h {
+		h[i] ^= v[i] ^ v[i+8]
+	}
+	return h
+}
+
+// Computes the blake2s compression the cairo blake2s library then verifies.
+// The state, message, bytes counter and finalization flag precede the output
+// pointer, which the new state is written at
+type Blake2sCompute struct {
+	output ResOperander
+}
+
+func (hint Blake2sCompute) String() string {
+	return "Blake2sCompute"
+}
+
+func (hint Blake2sCompute) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	output, err := resolveAddress(vm, hint.output)
+	if err != nil {
+		return fmt.Errorf("resolve ids.output: %w", err)
+	}
+
+	// | state (8) | message (16) | t | f | output
+	input := memory.MemoryAddress{}
+	if err := input.Sub(&output, uint64(26)); err != nil {
+		return err
+	}
+	values, err := vm.Memory.GetRange(&input, 26)
+	if err != nil {
+		return err
+	}
+	words := make([]uint32, len(values))
+	for i := range values {
+		if words[i], err = uint32Value(&values[i]); err != nil {
+			return fmt.Errorf("blake2s input %d: %w", i, err)
+		}
+	}
+
+	h := [8]uint32(words[:8])
+	message := [16]uint32(words[8:24])
+	newState := Blake2sCompress(h, message, words[24], 0, words[25], 0)
+	return writeUint32s(vm, &output, newState[:])
+}
+
+// Fills the unused instances of the last blake2s batch with the compression
+// of an empty message, so the whole batch can be verified at once
+type FinalizeBlake2s struct {
+	blake2sPtrEnd    ResOperander
+	nPackedInstances uint64
+}
+
+func (hint FinalizeBlake2s) String() string {
+	return "FinalizeBlake2s"
+}
+
+func (hint FinalizeBlake2s) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	ptrEnd, err := resolveAddress(vm, hint.blake2sPtrEnd)
+	if err != nil {
+		return fmt.Errorf("resolve ids.blake2s_ptr_end: %w", err)
+	}
+	if hint.nPackedInstances == 0 || hint.nPackedInstances >= 20 {
+		return fmt.Errorf("%d packed instances is out of the range [1, 20)", hint.nPackedInstances)
+	}
+
+	modifiedIV := blake2sIV
+	modifiedIV[0] ^= 0x01010020
+	output := Blake2sCompress(modifiedIV, [16]uint32{}, 0, 0, 0xffffffff, 0)
+
+	// | state (8) | message (16) | t | f | output (8)
+	instance := make([]uint32, 0, 34)
+	instance = append(instance, modifiedIV[:]...)
+	instance = append(instance, make([]uint32, 16)...)
+	instance = append(instance, 0, 0xffffffff)
+	instance = append(instance, output[:]...)
+
+	padding := make([]uint32, 0, len(instance)*int(hint.nPackedInstances-1))
+	for i := uint64(1); i < hint.nPackedInstances; i++ {
+		padding = append(padding, instance...)
+	}
+	return writeUint32s(vm, &ptrEnd, padding)
+}
+
+func uint32Value(value *memory.MemoryValue) (uint32, error) {
+	word, err := value.Uint64()
+	if err != nil {
+		return 0, err
+	}
+	if word > 0xffffffff {
+		return 0, fmt.Errorf("%d does not fit in 32 bits", word)
+	}
+	return uint32(word), nil
+}
+
+func writeUint32s(vm *VM.VirtualMachine, address *memory.MemoryAddress, words []uint32) error {
+	values := make([]memory.MemoryValue, len(words))
+	for i := range words {
+		values[i] = memory.MemoryValueFromFieldElement(new(f.Element).SetUint64(uint64(words[i])))
+	}
+	return vm.Memory.WriteRange(address, values)
+}
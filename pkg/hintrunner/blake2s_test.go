@@ -0,0 +1,99 @@
+package hintrunner
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hashes a message of at most one block, as blake2s-256 without a key
+func blake2s256(t *testing.T, data []byte) string {
+	require.LessOrEqual(t, len(data), 64)
+	block := make([]byte, 64)
+	copy(block, data)
+	message := [16]uint32{}
+	for i := range message {
+		message[i] = binary.LittleEndian.Uint32(block[4*i:])
+	}
+
+	h := blake2sIV
+	h[0] ^= 0x01010020
+	h = Blake2sCompress(h, message, uint32(len(data)), 0, 0xffffffff, 0)
+
+	digest := make([]byte, 0, 32)
+	for i := range h {
+		digest = binary.LittleEndian.AppendUint32(digest, h[i])
+	}
+	return hex.EncodeToString(digest)
+}
+
+func TestBlake2sCompress(t *testing.T) {
+	// RFC 7693 appendix B
+	assert.Equal(t, "508c5e8c327c14e2e1a72ba34eeb452f37458b209ed63a294d999b4c86675982", blake2s256(t, []byte("abc")))
+	assert.Equal(t, "69217a3079908094e11121d042354a7c1f55b6482ca1a51e1b250dfd1ed0eef9", blake2s256(t, nil))
+}
+
+func TestBlake2sCompute(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+
+	h := blake2sIV
+	h[0] ^= 0x01010020
+	input := make([]memory.MemoryValue, 0, 26)
+	for i := range h {
+		input = append(input, memory.MemoryValueFromUint(h[i]))
+	}
+	// "abc" in the first message word
+	input = append(input, memory.MemoryValueFromUint(uint32(0x636261)))
+	for i := 1; i < 16; i++ {
+		input = append(input, memory.MemoryValueFromUint(uint32(0)))
+	}
+	input = append(input, memory.MemoryValueFromUint(uint32(3)), memory.MemoryValueFromUint(uint32(0xffffffff)))
+	require.NoError(t, vm.Memory.WriteRange(&memory.MemoryAddress{SegmentIndex: VM.ExecutionSegment, Offset: 1}, input))
+	writeTo(vm, VM.ExecutionSegment, 0, memory.MemoryValueFromSegmentAndOffset(VM.ExecutionSegment, 27))
+
+	require.NoError(t, Blake2sCompute{output: Deref{ApCellRef(0)}}.Execute(vm, nil))
+	// the first word of the blake2s-256 digest of "abc", little endian
+	assert.Equal(t, memory.MemoryValueFromUint(uint32(0x8c5e8c50)), readFrom(vm, VM.ExecutionSegment, 27))
+	assert.Equal(t, uint64(35), vm.Memory.Segments[VM.ExecutionSegment].Len())
+
+	// the words must fit in 32 bits
+	vm, _ = defaultVirtualMachine()
+	input[2] = memory.MemoryValueFromUint(uint64(1) << 32)
+	require.NoError(t, vm.Memory.WriteRange(&memory.MemoryAddress{SegmentIndex: VM.ExecutionSegment, Offset: 1}, input))
+	writeTo(vm, VM.ExecutionSegment, 0, memory.MemoryValueFromSegmentAndOffset(VM.ExecutionSegment, 27))
+	require.EqualError(
+		t, Blake2sCompute{output: Deref{ApCellRef(0)}}.Execute(vm, nil),
+		"blake2s input 2: 4294967296 does not fit in 32 bits",
+	)
+}
+
+func TestFinalizeBlake2s(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+	writeTo(vm, VM.ExecutionSegment, 0, memory.MemoryValueFromSegmentAndOffset(VM.ExecutionSegment, 1))
+
+	hint := FinalizeBlake2s{blake2sPtrEnd: Deref{ApCellRef(0)}, nPackedInstances: 3}
+	require.NoError(t, hint.Execute(vm, nil))
+	// two dummy instances of 34 cells
+	assert.Equal(t, uint64(1+2*34), vm.Memory.Segments[VM.ExecutionSegment].Len())
+
+	h := blake2sIV
+	h[0] ^= 0x01010020
+	output := Blake2sCompress(h, [16]uint32{}, 0, 0, 0xffffffff, 0)
+	for _, offset := range []uint64{1, 35} {
+		assert.Equal(t, memory.MemoryValueFromUint(h[0]), readFrom(vm, VM.ExecutionSegment, offset))
+		assert.Equal(t, memory.MemoryValueFromUint(uint32(0xffffffff)), readFrom(vm, VM.ExecutionSegment, offset+25))
+		assert.Equal(t, memory.MemoryValueFromUint(output[7]), readFrom(vm, VM.ExecutionSegment, offset+33))
+	}
+
+	hint.nPackedInstances = 20
+	require.EqualError(t, hint.Execute(vm, nil), "20 packed instances is out of the range [1, 20)")
+}
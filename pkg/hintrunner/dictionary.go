@@ -0,0 +1,291 @@
+package hintrunner
+
+import (
+	"fmt"
+	"sort"
+
+	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/builtins"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+)
+
+// Global context to keep track of different results across different
+// hints execution.
+type HintRunnerContext struct {
+	DictionaryManager         DictionaryManager
+	SquashedDictionaryManager SquashedDictionaryManager
+	// limits the array length the search hints accept, 0 means no limit.
+	// Equivalent to the `__find_element_max_size` scope variable, see
+	// HintRunner.SetFindElementMaxSize
+	FindElementMaxSize uint64
+	// the values dict_new starts the next cairo zero dictionary with, set by
+	// dict_squash. Equivalent to the `initial_dict` scope variable
+	InitialDict map[f.Element]memory.MemoryValue
+}
+
+// Used to keep track of all dictionaries data
+type Dictionary struct {
+	// The data contained on a dictionary
+	data map[f.Element]memory.MemoryValue
+	// Default value for key not present in the dictionary
+	defaultValue memory.MemoryValue
+	// Unique index assigned at the moment of creation
+	idx uint64
+	// Segment arena tracking the dictionary, nil if there is none
+	arena *builtins.SegmentArena
+	// where the next access of a cairo zero dictionary is, see GetTracker
+	currentPtr memory.MemoryAddress
+}
+
+// Gets the memory value at certain key, returns the default value
+// if the key has never been written
+func (d *Dictionary) At(key *f.Element) memory.MemoryValue {
+	if value, ok := d.data[*key]; ok {
+		return value
+	}
+	return d.defaultValue
+}
+
+// Given a key and a value, it sets the value at the given key
+func (d *Dictionary) Set(key *f.Element, value *memory.MemoryValue) {
+	d.data[*key] = *value
+}
+
+// Gets the value at a key of a cairo zero dictionary. Like the python dict
+// trackers, a missing key is an error unless the dictionary has a default
+// value, which is then stored at the key
+func (d *Dictionary) get(key *f.Element) (memory.MemoryValue, error) {
+	if value, ok := d.data[*key]; ok {
+		return value, nil
+	}
+	if !d.defaultValue.Known() {
+		return memory.MemoryValue{}, fmt.Errorf("key %s is not in the dictionary", key)
+	}
+	d.data[*key] = d.defaultValue
+	return d.defaultValue, nil
+}
+
+// Returns the index of the dictionary at the moment of creation
+func (d *Dictionary) Idx() uint64 {
+	return d.idx
+}
+
+// Used to manage dictionaries creation
+type DictionaryManager struct {
+	// a map that links a segment index to a dictionary
+	dictionaries map[uint64]*Dictionary
+}
+
+// Creates a new dictionary with a default value over a fresh segment and
+// returns the segment start address. If a segment arena is given, the new
+// segment is allocated and tracked through it
+func (dm *DictionaryManager) NewDictionary(
+	vm *VM.VirtualMachine, defaultValue *memory.MemoryValue, arena *builtins.SegmentArena,
+) memory.MemoryAddress {
+	if dm.dictionaries == nil {
+		dm.dictionaries = make(map[uint64]*Dictionary)
+	}
+
+	var idx uint64
+	var newDictAddr memory.MemoryAddress
+	if arena != nil {
+		idx, newDictAddr = arena.AllocateDict(vm.Memory)
+	} else {
+		idx = uint64(len(dm.dictionaries))
+		newDictAddr = memory.MemoryAddress{
+			SegmentIndex: uint64(vm.Memory.AllocateEmptySegment()),
+			Offset:       0,
+		}
+	}
+
+	dm.dictionaries[newDictAddr.SegmentIndex] = &Dictionary{
+		data:         make(map[f.Element]memory.MemoryValue),
+		defaultValue: *defaultValue,
+		idx:          idx,
+		arena:        arena,
+		currentPtr:   newDictAddr,
+	}
+	return newDictAddr
+}
+
+// Creates a cairo zero dictionary holding a copy of data over a fresh segment
+// and returns the segment start address. Missing keys read the default value,
+// or fail if it is unknown
+func (dm *DictionaryManager) NewDictionaryWithData(
+	vm *VM.VirtualMachine, data map[f.Element]memory.MemoryValue, defaultValue *memory.MemoryValue,
+) memory.MemoryAddress {
+	dictAddr := dm.NewDictionary(vm, defaultValue, nil)
+	dict := dm.dictionaries[dictAddr.SegmentIndex]
+	for key, value := range data {
+		dict.data[key] = value
+	}
+	return dictAddr
+}
+
+// Returns the cairo zero dictionary a pointer is at. The pointer must be where
+// the next access goes, since the accesses are checked when squashing
+func (dm *DictionaryManager) GetTracker(dictPtr *memory.MemoryAddress) (*Dictionary, error) {
+	dict, err := dm.GetDictionary(dictPtr)
+	if err != nil {
+		return nil, err
+	}
+	if !dict.currentPtr.Equal(dictPtr) {
+		return nil, fmt.Errorf("wrong dict pointer supplied, got %s, expected %s", dictPtr, &dict.currentPtr)
+	}
+	return dict, nil
+}
+
+// Given a memory address, it looks for the right dictionary using the segment index.
+// If no segment is associated with the given segment index, it errors
+func (dm *DictionaryManager) GetDictionary(dictAddr *memory.MemoryAddress) (*Dictionary, error) {
+	dict, ok := dm.dictionaries[dictAddr.SegmentIndex]
+	if !ok {
+		return nil, fmt.Errorf("no dictionary at address %s", dictAddr)
+	}
+	return dict, nil
+}
+
+// Given a dictionary address and a key it returns the value stored at that key
+func (dm *DictionaryManager) At(dictAddr *memory.MemoryAddress, key *f.Element) (memory.MemoryValue, error) {
+	dict, err := dm.GetDictionary(dictAddr)
+	if err != nil {
+		return memory.MemoryValue{}, err
+	}
+	return dict.At(key), nil
+}
+
+// Given a dictionary address, a key and a value it stores the value at that key
+func (dm *DictionaryManager) Set(dictAddr *memory.MemoryAddress, key *f.Element, value *memory.MemoryValue) error {
+	dict, err := dm.GetDictionary(dictAddr)
+	if err != nil {
+		return err
+	}
+	dict.Set(key, value)
+	return nil
+}
+
+// Records the end of the dictionary accesses, updating the size tracked by
+// the segment arena if any
+func (dm *DictionaryManager) trackEnd(dictEndAddr *memory.MemoryAddress) error {
+	dict, err := dm.GetDictionary(dictEndAddr)
+	if err != nil {
+		return err
+	}
+	if dict.arena == nil {
+		return nil
+	}
+	tracked, err := dict.arena.Dict(dict.idx)
+	if err != nil {
+		return err
+	}
+	if tracked.Size >= dictEndAddr.Offset {
+		return nil
+	}
+	return dict.arena.UpdateDictSize(dict.idx, dictEndAddr.Offset)
+}
+
+// Marks the dictionary as finalized in the segment arena if any
+func (dm *DictionaryManager) finalize(dictAddr *memory.MemoryAddress) error {
+	dict, err := dm.GetDictionary(dictAddr)
+	if err != nil {
+		return err
+	}
+	if dict.arena == nil {
+		return nil
+	}
+	return dict.arena.FinalizeDict(dict.idx)
+}
+
+// Each dictionary access is formed by three cells: key, previous value and new value
+const dictAccessSize = 3
+
+// SquashedDictionaryManager keeps the state required to squash a dictionary.
+// Keys and the access indices of each key are stored in descending order, so
+// popping from the end of each list produces the keys and accesses in the
+// ascending order the VM verifies
+type SquashedDictionaryManager struct {
+	// A map from each key to a list of indices where the key is present
+	// the list of indices should be sorted in descending order
+	KeyToIndices map[f.Element][]uint64
+	// A descending list of keys
+	Keys []f.Element
+	// the amount of accesses of each key, which popping indices doesn't change
+	accessCounts map[f.Element]uint64
+}
+
+// Initializes the squash data from the keys of every access of a dictionary.
+// The position of each key in the slice is its access index
+func (sdm *SquashedDictionaryManager) Initialize(keys []f.Element) {
+	sdm.KeyToIndices = make(map[f.Element][]uint64)
+	sdm.Keys = make([]f.Element, 0)
+	sdm.accessCounts = make(map[f.Element]uint64)
+	for i := range keys {
+		sdm.accessCounts[keys[i]]++
+		indices, ok := sdm.KeyToIndices[keys[i]]
+		if !ok {
+			sdm.Keys = append(sdm.Keys, keys[i])
+		}
+		sdm.KeyToIndices[keys[i]] = append(indices, uint64(i))
+	}
+
+	sort.Slice(sdm.Keys, func(i, j int) bool {
+		return sdm.Keys[i].Cmp(&sdm.Keys[j]) > 0
+	})
+	// indices were appended in ascending order
+	for _, indices := range sdm.KeyToIndices {
+		for i, j := 0, len(indices)-1; i < j; i, j = i+1, j-1 {
+			indices[i], indices[j] = indices[j], indices[i]
+		}
+	}
+}
+
+// Returns the key currently being squashed, that is, the smallest key left
+func (sdm *SquashedDictionaryManager) LastKey() (f.Element, error) {
+	if len(sdm.Keys) == 0 {
+		return f.Element{}, fmt.Errorf("no keys left")
+	}
+	return sdm.Keys[len(sdm.Keys)-1], nil
+}
+
+// Removes the key currently being squashed and returns it
+func (sdm *SquashedDictionaryManager) PopKey() (f.Element, error) {
+	key, err := sdm.LastKey()
+	if err != nil {
+		return key, err
+	}
+	sdm.Keys = sdm.Keys[:len(sdm.Keys)-1]
+	return key, nil
+}
+
+// Returns the access indices left of the key currently being squashed
+func (sdm *SquashedDictionaryManager) LastIndices() ([]uint64, error) {
+	key, err := sdm.LastKey()
+	if err != nil {
+		return nil, err
+	}
+	return sdm.KeyToIndices[key], nil
+}
+
+// Returns the smallest access index left of the key currently being squashed
+func (sdm *SquashedDictionaryManager) LastIndex() (uint64, error) {
+	indices, err := sdm.LastIndices()
+	if err != nil {
+		return 0, err
+	}
+	if len(indices) == 0 {
+		return 0, fmt.Errorf("no indices left")
+	}
+	return indices[len(indices)-1], nil
+}
+
+// Removes the smallest access index left of the current key and returns it
+func (sdm *SquashedDictionaryManager) PopIndex() (uint64, error) {
+	index, err := sdm.LastIndex()
+	if err != nil {
+		return 0, err
+	}
+	key := sdm.Keys[len(sdm.Keys)-1]
+	sdm.KeyToIndices[key] = sdm.KeyToIndices[key][:len(sdm.KeyToIndices[key])-1]
+	return index, nil
+}
@@ -1,9 +1,13 @@
 package hintrunner
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 
+	"github.com/NethermindEth/cairo-vm-go/pkg/safemath"
 	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/builtins"
 	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
 	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
 )
@@ -11,7 +15,7 @@ import (
 type Hinter interface {
 	fmt.Stringer
 
-	Execute(vm *VM.VirtualMachine) error
+	Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error
 }
 
 type AllocSegment struct {
@@ -22,7 +26,7 @@ func (hint AllocSegment) String() string {
 	return "AllocSegment"
 }
 
-func (hint AllocSegment) Execute(vm *VM.VirtualMachine) error {
+func (hint AllocSegment) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
 	segmentIndex := vm.Memory.AllocateEmptySegment()
 	memAddress := memory.MemoryValueFromSegmentAndOffset(segmentIndex, 0)
 
@@ -49,7 +53,7 @@ func (hint TestLessThan) String() string {
 	return "TestLessThan"
 }
 
-func (hint TestLessThan) Execute(vm *VM.VirtualMachine) error {
+func (hint TestLessThan) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
 	lhsVal, err := hint.lhs.Resolve(vm)
 	if err != nil {
 		return fmt.Errorf("resolve lhs operand %s: %w", hint.lhs, err)
@@ -88,3 +92,838 @@ func (hint TestLessThan) Execute(vm *VM.VirtualMachine) error {
 
 	return nil
 }
+
+type AllocFelt252Dict struct {
+	segmentArenaPtr ResOperander
+}
+
+func (hint AllocFelt252Dict) String() string {
+	return "AllocFelt252Dict"
+}
+
+func (hint AllocFelt252Dict) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	arenaPtr, err := resolveAddress(vm, hint.segmentArenaPtr)
+	if err != nil {
+		return fmt.Errorf("resolve segment arena pointer: %w", err)
+	}
+
+	// the last segment arena instance is right behind the pointer
+	infosAddr := memory.MemoryAddress{}
+	if err := infosAddr.Sub(&arenaPtr, uint64(3)); err != nil {
+		return err
+	}
+	infosValue, err := vm.Memory.ReadFromAddress(&infosAddr)
+	if err != nil {
+		return fmt.Errorf("read dict infos at %s: %w", infosAddr, err)
+	}
+	infosBase, err := infosValue.ToMemoryAddress()
+	if err != nil {
+		return err
+	}
+
+	nDictsAddr := memory.MemoryAddress{}
+	if err := nDictsAddr.Sub(&arenaPtr, uint64(2)); err != nil {
+		return err
+	}
+	nDictsValue, err := vm.Memory.ReadFromAddress(&nDictsAddr)
+	if err != nil {
+		return fmt.Errorf("read number of dicts at %s: %w", nDictsAddr, err)
+	}
+	nDicts, err := nDictsValue.Uint64()
+	if err != nil {
+		return err
+	}
+
+	var arena *builtins.SegmentArena
+	if arenaPtr.SegmentIndex < uint64(len(vm.Memory.Segments)) {
+		arena, _ = vm.Memory.Segments[arenaPtr.SegmentIndex].BuiltinRunner.(*builtins.SegmentArena)
+	}
+
+	defaultValue := memory.MemoryValueFromInt(0)
+	newDictAddr := ctx.DictionaryManager.NewDictionary(vm, &defaultValue, arena)
+
+	// each dict info is formed by three cells, the first one being the dict start
+	dictInfoAddr := memory.MemoryAddress{
+		SegmentIndex: infosBase.SegmentIndex,
+		Offset:       infosBase.Offset + nDicts*3,
+	}
+	mv := memory.MemoryValueFromMemoryAddress(&newDictAddr)
+	if err := vm.Memory.WriteToAddress(&dictInfoAddr, &mv); err != nil {
+		return fmt.Errorf("write to address %s: %w", dictInfoAddr, err)
+	}
+	return nil
+}
+
+type Felt252DictEntryInit struct {
+	dictPtr ResOperander
+	key     ResOperander
+}
+
+func (hint Felt252DictEntryInit) String() string {
+	return "Felt252DictEntryInit"
+}
+
+func (hint Felt252DictEntryInit) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	dictPtr, err := resolveAddress(vm, hint.dictPtr)
+	if err != nil {
+		return fmt.Errorf("resolve dictionary pointer: %w", err)
+	}
+
+	keyValue, err := hint.key.Resolve(vm)
+	if err != nil {
+		return fmt.Errorf("resolve key: %w", err)
+	}
+	key, err := keyValue.ToFieldElement()
+	if err != nil {
+		return err
+	}
+
+	prevValue, err := ctx.DictionaryManager.At(&dictPtr, key)
+	if err != nil {
+		return fmt.Errorf("get dictionary value: %w", err)
+	}
+
+	// entry layout is | key | previous value | new value |
+	prevValueAddr := memory.MemoryAddress{
+		SegmentIndex: dictPtr.SegmentIndex,
+		Offset:       dictPtr.Offset + 1,
+	}
+	if err := vm.Memory.WriteToAddress(&prevValueAddr, &prevValue); err != nil {
+		return fmt.Errorf("write to address %s: %w", prevValueAddr, err)
+	}
+	return nil
+}
+
+type Felt252DictEntryUpdate struct {
+	dictPtr ResOperander
+	value   ResOperander
+}
+
+func (hint Felt252DictEntryUpdate) String() string {
+	return "Felt252DictEntryUpdate"
+}
+
+func (hint Felt252DictEntryUpdate) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	// the pointer is right after the entry being updated
+	dictPtr, err := resolveAddress(vm, hint.dictPtr)
+	if err != nil {
+		return fmt.Errorf("resolve dictionary pointer: %w", err)
+	}
+
+	keyAddr := memory.MemoryAddress{}
+	if err := keyAddr.Sub(&dictPtr, uint64(3)); err != nil {
+		return err
+	}
+	keyValue, err := vm.Memory.ReadFromAddress(&keyAddr)
+	if err != nil {
+		return fmt.Errorf("read key at %s: %w", keyAddr, err)
+	}
+	key, err := keyValue.ToFieldElement()
+	if err != nil {
+		return err
+	}
+
+	value, err := hint.value.Resolve(vm)
+	if err != nil {
+		return fmt.Errorf("resolve value: %w", err)
+	}
+
+	if err := ctx.DictionaryManager.Set(&dictPtr, key, &value); err != nil {
+		return fmt.Errorf("set dictionary value: %w", err)
+	}
+	return ctx.DictionaryManager.trackEnd(&dictPtr)
+}
+
+type GetSegmentArenaIndex struct {
+	dictEndPtr ResOperander
+	dictIndex  CellRefer
+}
+
+func (hint GetSegmentArenaIndex) String() string {
+	return "GetSegmentArenaIndex"
+}
+
+func (hint GetSegmentArenaIndex) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	dictEndPtr, err := resolveAddress(vm, hint.dictEndPtr)
+	if err != nil {
+		return fmt.Errorf("resolve dictionary end pointer: %w", err)
+	}
+
+	dict, err := ctx.DictionaryManager.GetDictionary(&dictEndPtr)
+	if err != nil {
+		return fmt.Errorf("get dictionary: %w", err)
+	}
+	if err := ctx.DictionaryManager.finalize(&dictEndPtr); err != nil {
+		return err
+	}
+
+	dictIndexAddr, err := hint.dictIndex.Get(vm)
+	if err != nil {
+		return fmt.Errorf("get dict index address %s: %w", hint.dictIndex, err)
+	}
+	mv := memory.MemoryValueFromUint(dict.Idx())
+	if err := vm.Memory.WriteToAddress(&dictIndexAddr, &mv); err != nil {
+		return fmt.Errorf("write to address %s: %w", dictIndexAddr, err)
+	}
+	return nil
+}
+
+type InitSquashData struct {
+	dictAccesses ResOperander
+	ptrDiff      ResOperander
+	nAccesses    ResOperander
+	bigKeys      CellRefer
+	firstKey     CellRefer
+}
+
+func (hint InitSquashData) String() string {
+	return "InitSquashData"
+}
+
+func (hint InitSquashData) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	dictAccesses, err := resolveAddress(vm, hint.dictAccesses)
+	if err != nil {
+		return fmt.Errorf("resolve dictionary accesses: %w", err)
+	}
+
+	ptrDiffValue, err := hint.ptrDiff.Resolve(vm)
+	if err != nil {
+		return fmt.Errorf("resolve pointer difference: %w", err)
+	}
+	ptrDiff, err := ptrDiffValue.Uint64()
+	if err != nil {
+		return err
+	}
+	if ptrDiff%dictAccessSize != 0 {
+		return fmt.Errorf("accesses array size must be divisible by %d, got %d", dictAccessSize, ptrDiff)
+	}
+
+	nAccessesValue, err := hint.nAccesses.Resolve(vm)
+	if err != nil {
+		return fmt.Errorf("resolve number of accesses: %w", err)
+	}
+	nAccesses, err := nAccessesValue.Uint64()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]f.Element, nAccesses)
+	for i := uint64(0); i < nAccesses; i++ {
+		keyValue, err := vm.Memory.Read(dictAccesses.SegmentIndex, dictAccesses.Offset+i*dictAccessSize)
+		if err != nil {
+			return fmt.Errorf("read access %d key: %w", i, err)
+		}
+		key, err := keyValue.ToFieldElement()
+		if err != nil {
+			return err
+		}
+		keys[i] = *key
+	}
+	ctx.SquashedDictionaryManager.Initialize(keys)
+	if len(ctx.SquashedDictionaryManager.Keys) == 0 {
+		return fmt.Errorf("no accesses to squash")
+	}
+
+	// keys greater or equal than 2**128 cannot be range checked directly
+	rangeCheckBound := f.Element{}
+	rangeCheckBound.Exp(*new(f.Element).SetUint64(2), big.NewInt(128))
+	biggestKey := ctx.SquashedDictionaryManager.Keys[0]
+	bigKeys := memory.MemoryValueFromInt(0)
+	if biggestKey.Cmp(&rangeCheckBound) >= 0 {
+		bigKeys = memory.MemoryValueFromInt(1)
+	}
+	bigKeysAddr, err := hint.bigKeys.Get(vm)
+	if err != nil {
+		return fmt.Errorf("get big keys address %s: %w", hint.bigKeys, err)
+	}
+	if err := vm.Memory.WriteToAddress(&bigKeysAddr, &bigKeys); err != nil {
+		return fmt.Errorf("write to address %s: %w", bigKeysAddr, err)
+	}
+
+	firstKey, err := ctx.SquashedDictionaryManager.LastKey()
+	if err != nil {
+		return err
+	}
+	return writeFelt(vm, hint.firstKey, &firstKey)
+}
+
+type GetCurrentAccessIndex struct {
+	rangeCheckPtr ResOperander
+}
+
+func (hint GetCurrentAccessIndex) String() string {
+	return "GetCurrentAccessIndex"
+}
+
+func (hint GetCurrentAccessIndex) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	rangeCheckPtr, err := resolveAddress(vm, hint.rangeCheckPtr)
+	if err != nil {
+		return fmt.Errorf("resolve range check pointer: %w", err)
+	}
+
+	accessIndex, err := ctx.SquashedDictionaryManager.LastIndex()
+	if err != nil {
+		return err
+	}
+	mv := memory.MemoryValueFromUint(accessIndex)
+	if err := vm.Memory.WriteToAddress(&rangeCheckPtr, &mv); err != nil {
+		return fmt.Errorf("write to address %s: %w", rangeCheckPtr, err)
+	}
+	return nil
+}
+
+type ShouldSkipSquashLoop struct {
+	shouldSkipLoop CellRefer
+}
+
+func (hint ShouldSkipSquashLoop) String() string {
+	return "ShouldSkipSquashLoop"
+}
+
+func (hint ShouldSkipSquashLoop) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	indices, err := ctx.SquashedDictionaryManager.LastIndices()
+	if err != nil {
+		return err
+	}
+
+	shouldSkipLoop := f.Element{}
+	if len(indices) <= 1 {
+		shouldSkipLoop.SetOne()
+	}
+	return writeFelt(vm, hint.shouldSkipLoop, &shouldSkipLoop)
+}
+
+type GetCurrentAccessDelta struct {
+	indexDeltaMinusOne CellRefer
+}
+
+func (hint GetCurrentAccessDelta) String() string {
+	return "GetCurrentAccessDelta"
+}
+
+func (hint GetCurrentAccessDelta) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	prevAccessIndex, err := ctx.SquashedDictionaryManager.PopIndex()
+	if err != nil {
+		return err
+	}
+	currentAccessIndex, err := ctx.SquashedDictionaryManager.LastIndex()
+	if err != nil {
+		return err
+	}
+
+	// indices are popped in ascending order so the delta is always positive
+	indexDeltaMinusOne := new(f.Element).SetUint64(currentAccessIndex - prevAccessIndex - 1)
+	return writeFelt(vm, hint.indexDeltaMinusOne, indexDeltaMinusOne)
+}
+
+type ShouldContinueSquashLoop struct {
+	shouldContinue CellRefer
+}
+
+func (hint ShouldContinueSquashLoop) String() string {
+	return "ShouldContinueSquashLoop"
+}
+
+func (hint ShouldContinueSquashLoop) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	indices, err := ctx.SquashedDictionaryManager.LastIndices()
+	if err != nil {
+		return err
+	}
+
+	shouldContinue := f.Element{}
+	if len(indices) > 1 {
+		shouldContinue.SetOne()
+	}
+	return writeFelt(vm, hint.shouldContinue, &shouldContinue)
+}
+
+type GetNextDictKey struct {
+	nextKey CellRefer
+}
+
+func (hint GetNextDictKey) String() string {
+	return "GetNextDictKey"
+}
+
+func (hint GetNextDictKey) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	if _, err := ctx.SquashedDictionaryManager.PopKey(); err != nil {
+		return err
+	}
+	nextKey, err := ctx.SquashedDictionaryManager.LastKey()
+	if err != nil {
+		return err
+	}
+	return writeFelt(vm, hint.nextKey, &nextKey)
+}
+
+// Splits a felt into its 128 bits high and low parts
+type SplitFelt struct {
+	value ResOperander
+	high  CellRefer
+	low   CellRefer
+}
+
+func (hint SplitFelt) String() string {
+	return "SplitFelt"
+}
+
+func (hint SplitFelt) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	value, err := resolveFelt(vm, hint.value)
+	if err != nil {
+		return fmt.Errorf("resolve value: %w", err)
+	}
+	// felts are smaller than the modulus, so the high part always fits in 128 bits
+	return writeHighLow(vm, hint.high, hint.low, value.BigInt(new(big.Int)))
+}
+
+// Checks that a felt fits in 250 bits and splits it into its 128 bits high
+// and low parts, which are then range checked by the program
+type Assert250Bit struct {
+	value ResOperander
+	high  CellRefer
+	low   CellRefer
+}
+
+func (hint Assert250Bit) String() string {
+	return "Assert250Bit"
+}
+
+func (hint Assert250Bit) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	value, err := resolveFelt(vm, hint.value)
+	if err != nil {
+		return fmt.Errorf("resolve value: %w", err)
+	}
+	valueBig := value.BigInt(new(big.Int))
+	if valueBig.BitLen() > 250 {
+		return fmt.Errorf("%s is outside of the range [0, 2**250)", valueBig)
+	}
+	return writeHighLow(vm, hint.high, hint.low, valueBig)
+}
+
+// Checks that a felt is not zero, so the division that usually follows it
+// in the program doesn't fail without context
+type AssertNotZero struct {
+	value ResOperander
+}
+
+func (hint AssertNotZero) String() string {
+	return "AssertNotZero"
+}
+
+func (hint AssertNotZero) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	value, err := resolveFelt(vm, hint.value)
+	if err != nil {
+		return fmt.Errorf("resolve ids.value: %w", err)
+	}
+	if value.IsZero() {
+		return errors.New("assert_not_zero failed: ids.value = 0")
+	}
+	return nil
+}
+
+// Checks that two felts, or two addresses of the same segment, differ
+type AssertNotEqual struct {
+	lhs ResOperander
+	rhs ResOperander
+}
+
+func (hint AssertNotEqual) String() string {
+	return "AssertNotEqual"
+}
+
+func (hint AssertNotEqual) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	lhs, err := hint.lhs.Resolve(vm)
+	if err != nil {
+		return fmt.Errorf("resolve ids.a: %w", err)
+	}
+	rhs, err := hint.rhs.Resolve(vm)
+	if err != nil {
+		return fmt.Errorf("resolve ids.b: %w", err)
+	}
+
+	bothFelts := lhs.IsFelt() && rhs.IsFelt()
+	bothAddresses := lhs.IsAddress() && rhs.IsAddress()
+	if bothAddresses {
+		lhsAddr, _ := lhs.ToMemoryAddress()
+		rhsAddr, _ := rhs.ToMemoryAddress()
+		bothAddresses = lhsAddr.SegmentIndex == rhsAddr.SegmentIndex
+	}
+	if !bothFelts && !bothAddresses {
+		return fmt.Errorf(
+			"assert_not_equal failed: ids.a %s and ids.b %s are not comparable", &lhs, &rhs,
+		)
+	}
+	if lhs.Equal(&rhs) {
+		return fmt.Errorf("assert_not_equal failed: ids.a = ids.b = %s", &lhs)
+	}
+	return nil
+}
+
+// Divides a felt by a divisor small enough for the quotient to be range
+// checked, writing the quotient and remainder the program then asserts
+type UnsignedDivRem struct {
+	value ResOperander
+	div   ResOperander
+	q     CellRefer
+	r     CellRefer
+}
+
+func (hint UnsignedDivRem) String() string {
+	return "UnsignedDivRem"
+}
+
+func (hint UnsignedDivRem) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	value, err := resolveFelt(vm, hint.value)
+	if err != nil {
+		return fmt.Errorf("resolve ids.value: %w", err)
+	}
+	div, err := resolveDivisor(vm, hint.div)
+	if err != nil {
+		return err
+	}
+
+	q, r := new(big.Int).DivMod(value.BigInt(new(big.Int)), div, new(big.Int))
+	if err := writeFelt(vm, hint.q, new(f.Element).SetBigInt(q)); err != nil {
+		return err
+	}
+	return writeFelt(vm, hint.r, new(f.Element).SetBigInt(r))
+}
+
+// Divides a felt, read as a signed integer, by a positive divisor. The
+// quotient must lie in [-bound, bound) and is written biased by the bound so
+// the program can range check it
+type SignedDivRem struct {
+	value   ResOperander
+	div     ResOperander
+	bound   ResOperander
+	r       CellRefer
+	biasedQ CellRefer
+}
+
+func (hint SignedDivRem) String() string {
+	return "SignedDivRem"
+}
+
+func (hint SignedDivRem) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	value, err := resolveFelt(vm, hint.value)
+	if err != nil {
+		return fmt.Errorf("resolve ids.value: %w", err)
+	}
+	div, err := resolveDivisor(vm, hint.div)
+	if err != nil {
+		return err
+	}
+	boundFelt, err := resolveFelt(vm, hint.bound)
+	if err != nil {
+		return fmt.Errorf("resolve ids.bound: %w", err)
+	}
+	bound := boundFelt.BigInt(new(big.Int))
+	if bound.Cmp(new(big.Int).Rsh(rangeCheckBuiltinBound(), 1)) > 0 {
+		return fmt.Errorf("bound=%#x is out of the valid range", bound)
+	}
+
+	// like as_int, felts from PRIME // 2 on stand for negative integers
+	signedValue := value.BigInt(new(big.Int))
+	if signedValue.Cmp(new(big.Int).Rsh(f.Modulus(), 1)) >= 0 {
+		signedValue.Sub(signedValue, f.Modulus())
+	}
+	q, r := new(big.Int).DivMod(signedValue, div, new(big.Int))
+	if q.Cmp(new(big.Int).Neg(bound)) < 0 || q.Cmp(bound) >= 0 {
+		return fmt.Errorf(
+			"%s / %s = %s is out of the range [-%s, %s)", signedValue, div, q, bound, bound,
+		)
+	}
+
+	if err := writeFelt(vm, hint.r, new(f.Element).SetBigInt(r)); err != nil {
+		return err
+	}
+	return writeFelt(vm, hint.biasedQ, new(f.Element).SetBigInt(q.Add(q, bound)))
+}
+
+// resolves the divisor of the div_rem hints, which must be positive and at
+// most PRIME // 2**128 so the quotient fits in a range checked cell
+func resolveDivisor(vm *VM.VirtualMachine, operand ResOperander) (*big.Int, error) {
+	divFelt, err := resolveFelt(vm, operand)
+	if err != nil {
+		return nil, fmt.Errorf("resolve ids.div: %w", err)
+	}
+	div := divFelt.BigInt(new(big.Int))
+	maxDiv := new(big.Int).Div(f.Modulus(), rangeCheckBuiltinBound())
+	if div.Sign() == 0 || div.Cmp(maxDiv) > 0 {
+		return nil, fmt.Errorf("div=%#x is out of the valid range", div)
+	}
+	return div, nil
+}
+
+// the bound of the values the range check builtin accepts, 2**128
+func rangeCheckBuiltinBound() *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), 128)
+}
+
+// Writes the felts returned by a callback of the embedder starting at ap, the
+// same cells a `tempvar x = nondet %{ ... %}` hint fills. Lets external data
+// be fed into a run without interpreting the python code of the hint
+type Oracle struct {
+	fn func(vm *VM.VirtualMachine) ([]f.Element, error)
+}
+
+func (hint Oracle) String() string {
+	return "Oracle"
+}
+
+func (hint Oracle) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	values, err := hint.fn(vm)
+	if err != nil {
+		return fmt.Errorf("oracle: %w", err)
+	}
+	for i := range values {
+		if err := writeFelt(vm, ApCellRef(i), &values[i]); err != nil {
+			return fmt.Errorf("oracle value %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// writes the 128 bits high and low parts of a value
+func writeHighLow(vm *VM.VirtualMachine, high, low CellRefer, value *big.Int) error {
+	highBig, lowBig := new(big.Int).DivMod(value, new(big.Int).Lsh(big.NewInt(1), 128), new(big.Int))
+	if err := writeFelt(vm, high, new(f.Element).SetBigInt(highBig)); err != nil {
+		return err
+	}
+	return writeFelt(vm, low, new(f.Element).SetBigInt(lowBig))
+}
+
+// Finds the index of the element of an array whose first cell is the key.
+// Errors if the key is not found
+type FindElement struct {
+	arrayPtr ResOperander
+	elmSize  ResOperander
+	nElms    ResOperander
+	key      ResOperander
+	index    CellRefer
+}
+
+func (hint FindElement) String() string {
+	return "FindElement"
+}
+
+func (hint FindElement) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	array, err := resolveArray(vm, ctx, hint.arrayPtr, hint.elmSize, hint.nElms)
+	if err != nil {
+		return err
+	}
+	keyValue, err := hint.key.Resolve(vm)
+	if err != nil {
+		return fmt.Errorf("resolve key: %w", err)
+	}
+
+	for i := uint64(0); i < array.nElms; i++ {
+		elm, err := array.elementKey(vm, i)
+		if err != nil {
+			return err
+		}
+		if elm.Equal(&keyValue) {
+			return writeFelt(vm, hint.index, new(f.Element).SetUint64(i))
+		}
+	}
+	return fmt.Errorf("key %s was not found", &keyValue)
+}
+
+// Finds the index of the first element of a sorted array whose first cell is
+// greater or equal than the key, or the array length if there is none
+type SearchSortedLower struct {
+	arrayPtr ResOperander
+	elmSize  ResOperander
+	nElms    ResOperander
+	key      ResOperander
+	index    CellRefer
+}
+
+func (hint SearchSortedLower) String() string {
+	return "SearchSortedLower"
+}
+
+func (hint SearchSortedLower) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	array, err := resolveArray(vm, ctx, hint.arrayPtr, hint.elmSize, hint.nElms)
+	if err != nil {
+		return err
+	}
+	keyValue, err := hint.key.Resolve(vm)
+	if err != nil {
+		return fmt.Errorf("resolve key: %w", err)
+	}
+	key, err := keyValue.ToFieldElement()
+	if err != nil {
+		return err
+	}
+
+	for i := uint64(0); i < array.nElms; i++ {
+		elm, err := array.elementKey(vm, i)
+		if err != nil {
+			return err
+		}
+		elmFelt, err := elm.ToFieldElement()
+		if err != nil {
+			return err
+		}
+		if elmFelt.Cmp(key) >= 0 {
+			return writeFelt(vm, hint.index, new(f.Element).SetUint64(i))
+		}
+	}
+	return writeFelt(vm, hint.index, new(f.Element).SetUint64(array.nElms))
+}
+
+// Checks whether an element is part of a set stored between two pointers.
+// If it is, its index is written as well
+type SetAdd struct {
+	setPtr     ResOperander
+	setEndPtr  ResOperander
+	elmPtr     ResOperander
+	elmSize    ResOperander
+	index      CellRefer
+	isElmInSet CellRefer
+}
+
+func (hint SetAdd) String() string {
+	return "SetAdd"
+}
+
+func (hint SetAdd) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	setPtr, err := resolveAddress(vm, hint.setPtr)
+	if err != nil {
+		return fmt.Errorf("resolve set pointer: %w", err)
+	}
+	setEndPtr, err := resolveAddress(vm, hint.setEndPtr)
+	if err != nil {
+		return fmt.Errorf("resolve set end pointer: %w", err)
+	}
+	elmPtr, err := resolveAddress(vm, hint.elmPtr)
+	if err != nil {
+		return fmt.Errorf("resolve element pointer: %w", err)
+	}
+	elmSize, err := resolveUint64(vm, hint.elmSize)
+	if err != nil {
+		return fmt.Errorf("resolve element size: %w", err)
+	}
+	if elmSize == 0 {
+		return errors.New("element size must be positive")
+	}
+
+	setSize, err := setEndPtr.Distance(&setPtr)
+	if err != nil {
+		return fmt.Errorf("set end pointer must not precede the set pointer: %w", err)
+	}
+	elm, err := vm.Memory.GetRange(&elmPtr, elmSize)
+	if err != nil {
+		return err
+	}
+
+	for offset := uint64(0); offset < setSize; offset += elmSize {
+		candidatePtr := memory.MemoryAddress{SegmentIndex: setPtr.SegmentIndex, Offset: setPtr.Offset + offset}
+		candidate, err := vm.Memory.GetRange(&candidatePtr, elmSize)
+		if err != nil {
+			return err
+		}
+		if equalValues(candidate, elm) {
+			if err := writeFelt(vm, hint.index, new(f.Element).SetUint64(offset/elmSize)); err != nil {
+				return err
+			}
+			return writeFelt(vm, hint.isElmInSet, new(f.Element).SetOne())
+		}
+	}
+	return writeFelt(vm, hint.isElmInSet, new(f.Element))
+}
+
+// An array of elements of elmSize cells each, as used by the search hints
+type searchArray struct {
+	ptr     memory.MemoryAddress
+	elmSize uint64
+	nElms   uint64
+}
+
+func resolveArray(
+	vm *VM.VirtualMachine, ctx *HintRunnerContext, arrayPtr, elmSize, nElms ResOperander,
+) (searchArray, error) {
+	ptr, err := resolveAddress(vm, arrayPtr)
+	if err != nil {
+		return searchArray{}, fmt.Errorf("resolve array pointer: %w", err)
+	}
+	size, err := resolveUint64(vm, elmSize)
+	if err != nil {
+		return searchArray{}, fmt.Errorf("resolve element size: %w", err)
+	}
+	if size == 0 {
+		return searchArray{}, errors.New("element size must be positive")
+	}
+	n, err := resolveUint64(vm, nElms)
+	if err != nil {
+		return searchArray{}, fmt.Errorf("resolve number of elements: %w", err)
+	}
+	if ctx.FindElementMaxSize != 0 && n > ctx.FindElementMaxSize {
+		return searchArray{}, fmt.Errorf(
+			"search can only be used with at most %d elements, got %d", ctx.FindElementMaxSize, n,
+		)
+	}
+	return searchArray{ptr: ptr, elmSize: size, nElms: n}, nil
+}
+
+// reads the first cell of the i-th element
+func (array *searchArray) elementKey(vm *VM.VirtualMachine, i uint64) (memory.MemoryValue, error) {
+	offset, isOverflow := safemath.SafeMul(array.elmSize, i)
+	if isOverflow {
+		return memory.MemoryValue{}, fmt.Errorf("element %d is out of the addressable memory", i)
+	}
+	return vm.Memory.Read(array.ptr.SegmentIndex, array.ptr.Offset+offset)
+}
+
+func equalValues(lhs, rhs []memory.MemoryValue) bool {
+	for i := range lhs {
+		if !lhs[i].Equal(&rhs[i]) {
+			return false
+		}
+	}
+	return len(lhs) == len(rhs)
+}
+
+// resolves an operand that is expected to be an address
+func resolveAddress(vm *VM.VirtualMachine, operand ResOperander) (memory.MemoryAddress, error) {
+	value, err := operand.Resolve(vm)
+	if err != nil {
+		return memory.MemoryAddress{}, err
+	}
+	address, err := value.ToMemoryAddress()
+	if err != nil {
+		return memory.MemoryAddress{}, err
+	}
+	return *address, nil
+}
+
+// resolves an operand that is expected to be a felt
+func resolveFelt(vm *VM.VirtualMachine, operand ResOperander) (*f.Element, error) {
+	value, err := operand.Resolve(vm)
+	if err != nil {
+		return nil, err
+	}
+	return value.ToFieldElement()
+}
+
+// resolves an operand that is expected to be a felt fitting in 64 bits
+func resolveUint64(vm *VM.VirtualMachine, operand ResOperander) (uint64, error) {
+	value, err := operand.Resolve(vm)
+	if err != nil {
+		return 0, err
+	}
+	return value.Uint64()
+}
+
+func writeFelt(vm *VM.VirtualMachine, dst CellRefer, felt *f.Element) error {
+	dstAddr, err := dst.Get(vm)
+	if err != nil {
+		return fmt.Errorf("get dst address %s: %w", dst, err)
+	}
+	mv := memory.MemoryValueFromFieldElement(felt)
+	if err := vm.Memory.WriteToAddress(&dstAddr, &mv); err != nil {
+		return fmt.Errorf("write to dst address %s: %w", dstAddr, err)
+	}
+	return nil
+}
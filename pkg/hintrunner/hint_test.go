@@ -4,8 +4,11 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/NethermindEth/cairo-vm-go/pkg/assembler"
 	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/builtins"
 	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
 	"github.com/stretchr/testify/require"
 )
 
@@ -20,7 +23,7 @@ func TestAllocSegment(t *testing.T) {
 	alloc1 := AllocSegment{ap}
 	alloc2 := AllocSegment{fp}
 
-	err := alloc1.Execute(vm)
+	err := alloc1.Execute(vm, nil)
 	require.Nil(t, err)
 	require.Equal(t, 3, len(vm.Memory.Segments))
 	require.Equal(
@@ -29,7 +32,7 @@ func TestAllocSegment(t *testing.T) {
 		readFrom(vm, VM.ExecutionSegment, vm.Context.Ap+5),
 	)
 
-	err = alloc2.Execute(vm)
+	err = alloc2.Execute(vm, nil)
 	require.Nil(t, err)
 	require.Equal(t, 4, len(vm.Memory.Segments))
 	require.Equal(
@@ -59,7 +62,7 @@ func TestTestLessThanFalse(t *testing.T) {
 		rhs: rhs,
 	}
 
-	err := hint.Execute(vm)
+	err := hint.Execute(vm, nil)
 	require.Nil(t, err)
 	require.Equal(
 		t,
@@ -87,7 +90,7 @@ func TestTestLessThanTrue(t *testing.T) {
 		rhs: rhs,
 	}
 
-	err := hint.Execute(vm)
+	err := hint.Execute(vm, nil)
 	require.Nil(t, err)
 	require.Equal(
 		t,
@@ -95,3 +98,445 @@ func TestTestLessThanTrue(t *testing.T) {
 		readFrom(vm, VM.ExecutionSegment, 1),
 	)
 }
+
+func TestAllocFelt252Dict(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+
+	arena := &builtins.SegmentArena{}
+	arenaSegment := vm.Memory.AllocateBuiltinSegment("segment_arena", arena)
+	infosSegment := vm.Memory.AllocateEmptySegment()
+
+	// a single segment arena instance with no dicts allocated yet
+	writeTo(vm, uint64(arenaSegment), 0, memory.MemoryValueFromSegmentAndOffset(infosSegment, 0))
+	writeTo(vm, uint64(arenaSegment), 1, memory.MemoryValueFromInt(0))
+	writeTo(vm, uint64(arenaSegment), 2, memory.MemoryValueFromInt(0))
+	writeTo(vm, VM.ExecutionSegment, 0, memory.MemoryValueFromSegmentAndOffset(arenaSegment, 3))
+
+	ctx := HintRunnerContext{}
+	hint := AllocFelt252Dict{segmentArenaPtr: Deref{ApCellRef(0)}}
+
+	err := hint.Execute(vm, &ctx)
+	require.NoError(t, err)
+	require.Equal(t, 5, len(vm.Memory.Segments))
+	require.Equal(t, uint64(1), arena.DictCount())
+	require.Equal(
+		t,
+		memory.MemoryValueFromSegmentAndOffset(4, 0),
+		readFrom(vm, uint64(infosSegment), 0),
+	)
+
+	dict, err := ctx.DictionaryManager.GetDictionary(&memory.MemoryAddress{SegmentIndex: 4, Offset: 0})
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), dict.Idx())
+}
+
+func TestFelt252DictEntryInitAndUpdate(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+
+	ctx := HintRunnerContext{}
+	defaultValue := memory.MemoryValueFromInt(0)
+	dictAddr := ctx.DictionaryManager.NewDictionary(vm, &defaultValue, nil)
+	key := Immediate(*big.NewInt(5))
+
+	// first access reads the default value
+	writeTo(vm, VM.ExecutionSegment, 0, memory.MemoryValueFromSegmentAndOffset(dictAddr.SegmentIndex, 0))
+	writeTo(vm, dictAddr.SegmentIndex, 0, memory.MemoryValueFromInt(5))
+	initHint := Felt252DictEntryInit{dictPtr: Deref{ApCellRef(0)}, key: key}
+	require.NoError(t, initHint.Execute(vm, &ctx))
+	require.Equal(t, memory.MemoryValueFromInt(0), readFrom(vm, dictAddr.SegmentIndex, 1))
+
+	writeTo(vm, dictAddr.SegmentIndex, 2, memory.MemoryValueFromInt(7))
+	writeTo(vm, VM.ExecutionSegment, 1, memory.MemoryValueFromSegmentAndOffset(dictAddr.SegmentIndex, 3))
+	updateHint := Felt252DictEntryUpdate{dictPtr: Deref{ApCellRef(1)}, value: Immediate(*big.NewInt(7))}
+	require.NoError(t, updateHint.Execute(vm, &ctx))
+
+	// second access reads the previously written value
+	writeTo(vm, dictAddr.SegmentIndex, 3, memory.MemoryValueFromInt(5))
+	initHint = Felt252DictEntryInit{dictPtr: Deref{ApCellRef(1)}, key: key}
+	require.NoError(t, initHint.Execute(vm, &ctx))
+	require.Equal(t, memory.MemoryValueFromInt(7), readFrom(vm, dictAddr.SegmentIndex, 4))
+}
+
+func TestFelt252DictEntryInitUnknownDict(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	writeTo(vm, VM.ExecutionSegment, 0, memory.MemoryValueFromSegmentAndOffset(0, 0))
+
+	hint := Felt252DictEntryInit{dictPtr: Deref{ApCellRef(0)}, key: Immediate(*big.NewInt(1))}
+	err := hint.Execute(vm, &HintRunnerContext{})
+	require.ErrorContains(t, err, "no dictionary")
+}
+
+func TestGetSegmentArenaIndex(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+
+	ctx := HintRunnerContext{}
+	arena := &builtins.SegmentArena{}
+	defaultValue := memory.MemoryValueFromInt(0)
+	ctx.DictionaryManager.NewDictionary(vm, &defaultValue, arena)
+	dictAddr := ctx.DictionaryManager.NewDictionary(vm, &defaultValue, arena)
+
+	writeTo(vm, VM.ExecutionSegment, 0, memory.MemoryValueFromSegmentAndOffset(dictAddr.SegmentIndex, 6))
+	hint := GetSegmentArenaIndex{dictEndPtr: Deref{ApCellRef(0)}, dictIndex: ApCellRef(1)}
+	require.NoError(t, hint.Execute(vm, &ctx))
+	require.Equal(t, memory.MemoryValueFromInt(1), readFrom(vm, VM.ExecutionSegment, 1))
+
+	tracked, err := arena.Dict(1)
+	require.NoError(t, err)
+	require.True(t, tracked.Finalized)
+}
+
+func TestSquashDict(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+	ctx := HintRunnerContext{}
+
+	// accesses with keys 3, 1, 3 and 3
+	accesses := vm.Memory.AllocateEmptySegment()
+	for i, key := range []int{3, 1, 3, 3} {
+		writeTo(vm, uint64(accesses), uint64(i*3), memory.MemoryValueFromInt(key))
+	}
+	rangeCheck := vm.Memory.AllocateEmptySegment()
+	writeTo(vm, VM.ExecutionSegment, 0, memory.MemoryValueFromSegmentAndOffset(accesses, 0))
+	writeTo(vm, VM.ExecutionSegment, 1, memory.MemoryValueFromSegmentAndOffset(rangeCheck, 0))
+	rangeCheckPtr := Deref{ApCellRef(1)}
+
+	initHint := InitSquashData{
+		dictAccesses: Deref{ApCellRef(0)},
+		ptrDiff:      Immediate(*big.NewInt(12)),
+		nAccesses:    Immediate(*big.NewInt(4)),
+		bigKeys:      ApCellRef(2),
+		firstKey:     ApCellRef(3),
+	}
+	require.NoError(t, initHint.Execute(vm, &ctx))
+	require.Equal(t, memory.MemoryValueFromInt(0), readFrom(vm, VM.ExecutionSegment, 2))
+	require.Equal(t, memory.MemoryValueFromInt(1), readFrom(vm, VM.ExecutionSegment, 3))
+
+	// key 1 has a single access
+	require.NoError(t, GetCurrentAccessIndex{rangeCheckPtr}.Execute(vm, &ctx))
+	require.Equal(t, memory.MemoryValueFromInt(1), readFrom(vm, uint64(rangeCheck), 0))
+	require.NoError(t, ShouldSkipSquashLoop{ApCellRef(4)}.Execute(vm, &ctx))
+	require.Equal(t, memory.MemoryValueFromInt(1), readFrom(vm, VM.ExecutionSegment, 4))
+
+	require.NoError(t, GetNextDictKey{ApCellRef(5)}.Execute(vm, &ctx))
+	require.Equal(t, memory.MemoryValueFromInt(3), readFrom(vm, VM.ExecutionSegment, 5))
+
+	// key 3 is accessed at indices 0, 2 and 3
+	writeTo(vm, VM.ExecutionSegment, 6, memory.MemoryValueFromSegmentAndOffset(rangeCheck, 1))
+	require.NoError(t, GetCurrentAccessIndex{Deref{ApCellRef(6)}}.Execute(vm, &ctx))
+	require.Equal(t, memory.MemoryValueFromInt(0), readFrom(vm, uint64(rangeCheck), 1))
+	require.NoError(t, ShouldSkipSquashLoop{ApCellRef(7)}.Execute(vm, &ctx))
+	require.Equal(t, memory.MemoryValueFromInt(0), readFrom(vm, VM.ExecutionSegment, 7))
+
+	require.NoError(t, GetCurrentAccessDelta{ApCellRef(8)}.Execute(vm, &ctx))
+	require.Equal(t, memory.MemoryValueFromInt(1), readFrom(vm, VM.ExecutionSegment, 8))
+	require.NoError(t, ShouldContinueSquashLoop{ApCellRef(9)}.Execute(vm, &ctx))
+	require.Equal(t, memory.MemoryValueFromInt(1), readFrom(vm, VM.ExecutionSegment, 9))
+
+	require.NoError(t, GetCurrentAccessDelta{ApCellRef(10)}.Execute(vm, &ctx))
+	require.Equal(t, memory.MemoryValueFromInt(0), readFrom(vm, VM.ExecutionSegment, 10))
+	require.NoError(t, ShouldContinueSquashLoop{ApCellRef(11)}.Execute(vm, &ctx))
+	require.Equal(t, memory.MemoryValueFromInt(0), readFrom(vm, VM.ExecutionSegment, 11))
+
+	// no keys are left after key 3
+	require.Error(t, GetNextDictKey{ApCellRef(12)}.Execute(vm, &ctx))
+}
+
+func TestInitSquashDataBigKeys(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+	ctx := HintRunnerContext{}
+
+	accesses := vm.Memory.AllocateEmptySegment()
+	bigKey := new(f.Element).SetBigInt(new(big.Int).Lsh(big.NewInt(1), 128))
+	writeTo(vm, uint64(accesses), 0, memory.MemoryValueFromFieldElement(bigKey))
+	writeTo(vm, VM.ExecutionSegment, 0, memory.MemoryValueFromSegmentAndOffset(accesses, 0))
+
+	hint := InitSquashData{
+		dictAccesses: Deref{ApCellRef(0)},
+		ptrDiff:      Immediate(*big.NewInt(3)),
+		nAccesses:    Immediate(*big.NewInt(1)),
+		bigKeys:      ApCellRef(1),
+		firstKey:     ApCellRef(2),
+	}
+	require.NoError(t, hint.Execute(vm, &ctx))
+	require.Equal(t, memory.MemoryValueFromInt(1), readFrom(vm, VM.ExecutionSegment, 1))
+	require.Equal(t, memory.MemoryValueFromFieldElement(bigKey), readFrom(vm, VM.ExecutionSegment, 2))
+
+	hint.ptrDiff = Immediate(*big.NewInt(4))
+	require.ErrorContains(t, hint.Execute(vm, &ctx), "divisible by 3")
+}
+
+func TestSearchHints(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+
+	// three elements of two cells each, keyed by 3, 7 and 9
+	arraySegment := uint64(vm.Memory.AllocateEmptySegment())
+	for i, value := range []int{3, 30, 7, 70, 9, 90} {
+		writeTo(vm, arraySegment, uint64(i), memory.MemoryValueFromInt(value))
+	}
+	writeTo(vm, VM.ExecutionSegment, 0, memory.MemoryValueFromSegmentAndOffset(arraySegment, 0))
+
+	ctx := HintRunnerContext{}
+	arrayPtr := Deref{ApCellRef(0)}
+	elmSize := Immediate(*big.NewInt(2))
+	nElms := Immediate(*big.NewInt(3))
+	key := func(k int64) Immediate { return Immediate(*big.NewInt(k)) }
+
+	find := FindElement{arrayPtr: arrayPtr, elmSize: elmSize, nElms: nElms, key: key(7), index: ApCellRef(1)}
+	require.NoError(t, find.Execute(vm, &ctx))
+	require.Equal(t, memory.MemoryValueFromInt(1), readFrom(vm, VM.ExecutionSegment, 1))
+
+	find.key = key(8)
+	require.ErrorContains(t, find.Execute(vm, &ctx), "key 8 was not found")
+
+	for i, tc := range []struct {
+		key   int64
+		index int
+	}{{3, 0}, {8, 2}, {10, 3}} {
+		search := SearchSortedLower{
+			arrayPtr: arrayPtr, elmSize: elmSize, nElms: nElms, key: key(tc.key), index: ApCellRef(2 + i),
+		}
+		require.NoError(t, search.Execute(vm, &ctx))
+		require.Equal(t, memory.MemoryValueFromInt(tc.index), readFrom(vm, VM.ExecutionSegment, uint64(2+i)))
+	}
+
+	ctx.FindElementMaxSize = 2
+	find.key = key(3)
+	require.ErrorContains(t, find.Execute(vm, &ctx), "at most 2 elements, got 3")
+}
+
+func TestSetAdd(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+
+	// a set of two elements, (1, 2) and (3, 4), followed by the searched elements
+	setSegment := uint64(vm.Memory.AllocateEmptySegment())
+	for i, value := range []int{1, 2, 3, 4, 3, 4, 5, 6} {
+		writeTo(vm, setSegment, uint64(i), memory.MemoryValueFromInt(value))
+	}
+	writeTo(vm, VM.ExecutionSegment, 0, memory.MemoryValueFromSegmentAndOffset(setSegment, 0))
+	writeTo(vm, VM.ExecutionSegment, 1, memory.MemoryValueFromSegmentAndOffset(setSegment, 4))
+
+	hint := SetAdd{
+		setPtr:     Deref{ApCellRef(0)},
+		setEndPtr:  Deref{ApCellRef(1)},
+		elmPtr:     Deref{ApCellRef(1)},
+		elmSize:    Immediate(*big.NewInt(2)),
+		index:      ApCellRef(2),
+		isElmInSet: ApCellRef(3),
+	}
+	require.NoError(t, hint.Execute(vm, nil))
+	require.Equal(t, memory.MemoryValueFromInt(1), readFrom(vm, VM.ExecutionSegment, 2))
+	require.Equal(t, memory.MemoryValueFromInt(1), readFrom(vm, VM.ExecutionSegment, 3))
+
+	writeTo(vm, VM.ExecutionSegment, 4, memory.MemoryValueFromSegmentAndOffset(setSegment, 6))
+	hint.elmPtr = Deref{ApCellRef(4)}
+	hint.index = ApCellRef(5)
+	hint.isElmInSet = ApCellRef(6)
+	require.NoError(t, hint.Execute(vm, nil))
+	require.Equal(t, memory.MemoryValueFromInt(0), readFrom(vm, VM.ExecutionSegment, 6))
+	index := vm.Memory.Segments[VM.ExecutionSegment].Peek(5)
+	require.False(t, index.Known())
+
+	hint.setPtr, hint.setEndPtr = hint.setEndPtr, hint.setPtr
+	require.ErrorContains(t, hint.Execute(vm, nil), "set end pointer must not precede the set pointer")
+}
+
+func TestSplitFelt(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+
+	value := new(big.Int).Lsh(big.NewInt(1), 130)
+	value.Add(value, big.NewInt(5))
+	hint := SplitFelt{value: Immediate(*value), high: ApCellRef(0), low: ApCellRef(1)}
+	require.NoError(t, hint.Execute(vm, nil))
+	require.Equal(t, memory.MemoryValueFromInt(4), readFrom(vm, VM.ExecutionSegment, 0))
+	require.Equal(t, memory.MemoryValueFromInt(5), readFrom(vm, VM.ExecutionSegment, 1))
+
+	// the biggest felt splits into the high and low parts of the modulus minus one
+	maxHigh, ok := new(big.Int).SetString("10633823966279327296825105735305134080", 10)
+	require.True(t, ok)
+	hint = SplitFelt{value: Immediate(*big.NewInt(-1)), high: ApCellRef(2), low: ApCellRef(3)}
+	require.NoError(t, hint.Execute(vm, nil))
+	require.Equal(t, memory.MemoryValueFromFieldElement(new(f.Element).SetBigInt(maxHigh)), readFrom(vm, VM.ExecutionSegment, 2))
+	require.Equal(t, memory.MemoryValueFromInt(0), readFrom(vm, VM.ExecutionSegment, 3))
+}
+
+func TestAssert250Bit(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+
+	maxValue := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 250), big.NewInt(1))
+	hint := Assert250Bit{value: Immediate(*maxValue), high: ApCellRef(0), low: ApCellRef(1)}
+	require.NoError(t, hint.Execute(vm, nil))
+
+	maxHigh := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 122), big.NewInt(1))
+	maxLow := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+	require.Equal(t, memory.MemoryValueFromFieldElement(new(f.Element).SetBigInt(maxHigh)), readFrom(vm, VM.ExecutionSegment, 0))
+	require.Equal(t, memory.MemoryValueFromFieldElement(new(f.Element).SetBigInt(maxLow)), readFrom(vm, VM.ExecutionSegment, 1))
+
+	hint.value = Immediate(*new(big.Int).Lsh(big.NewInt(1), 250))
+	require.ErrorContains(t, hint.Execute(vm, nil), "is outside of the range [0, 2**250)")
+
+	hint.value = Immediate(*big.NewInt(-1))
+	require.ErrorContains(t, hint.Execute(vm, nil), "is outside of the range [0, 2**250)")
+}
+
+func TestAssertNotZero(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+	writeTo(vm, VM.ExecutionSegment, 0, memory.MemoryValueFromInt(-3))
+	writeTo(vm, VM.ExecutionSegment, 1, memory.MemoryValueFromInt(0))
+	writeTo(vm, VM.ExecutionSegment, 2, memory.MemoryValueFromSegmentAndOffset(1, 0))
+
+	require.NoError(t, AssertNotZero{value: Deref{ApCellRef(0)}}.Execute(vm, nil))
+	require.EqualError(
+		t, AssertNotZero{value: Deref{ApCellRef(1)}}.Execute(vm, nil),
+		"assert_not_zero failed: ids.value = 0",
+	)
+	require.ErrorContains(t, AssertNotZero{value: Deref{ApCellRef(2)}}.Execute(vm, nil), "resolve ids.value")
+}
+
+func TestAssertNotEqual(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+	for i, value := range []memory.MemoryValue{
+		memory.MemoryValueFromInt(4),
+		memory.MemoryValueFromInt(5),
+		memory.MemoryValueFromSegmentAndOffset(1, 3),
+		memory.MemoryValueFromSegmentAndOffset(1, 4),
+		memory.MemoryValueFromSegmentAndOffset(2, 4),
+	} {
+		writeTo(vm, VM.ExecutionSegment, uint64(i), value)
+	}
+
+	assertNotEqual := func(lhs, rhs ApCellRef) error {
+		return AssertNotEqual{lhs: Deref{lhs}, rhs: Deref{rhs}}.Execute(vm, nil)
+	}
+	require.NoError(t, assertNotEqual(0, 1))
+	require.NoError(t, assertNotEqual(2, 3))
+	require.EqualError(t, assertNotEqual(1, 1), "assert_not_equal failed: ids.a = ids.b = 5")
+	require.EqualError(t, assertNotEqual(3, 3), "assert_not_equal failed: ids.a = ids.b = 1:4")
+	require.EqualError(
+		t, assertNotEqual(3, 4),
+		"assert_not_equal failed: ids.a 1:4 and ids.b 2:4 are not comparable",
+	)
+	require.EqualError(
+		t, assertNotEqual(0, 2),
+		"assert_not_equal failed: ids.a 4 and ids.b 1:3 are not comparable",
+	)
+}
+
+func TestUnsignedDivRem(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+	// q and r are written through the range check pointer
+	writeTo(vm, VM.ExecutionSegment, 0, memory.MemoryValueFromSegmentAndOffset(VM.ExecutionSegment, 5))
+
+	hint := UnsignedDivRem{
+		value: Immediate(*big.NewInt(17)),
+		div:   Immediate(*big.NewInt(5)),
+		q:     PtrCellRef{ptr: ApCellRef(0), offset: 1},
+		r:     PtrCellRef{ptr: ApCellRef(0)},
+	}
+	require.NoError(t, hint.Execute(vm, nil))
+	require.Equal(t, memory.MemoryValueFromInt(3), readFrom(vm, VM.ExecutionSegment, 6))
+	require.Equal(t, memory.MemoryValueFromInt(2), readFrom(vm, VM.ExecutionSegment, 5))
+
+	// PRIME // 2**128 is the biggest divisor allowed
+	maxDiv := new(big.Int).Rsh(f.Modulus(), 128)
+	hint = UnsignedDivRem{value: Immediate(*big.NewInt(1)), div: Immediate(*maxDiv), q: ApCellRef(1), r: ApCellRef(2)}
+	require.NoError(t, hint.Execute(vm, nil))
+
+	hint.div = Immediate(*new(big.Int).Add(maxDiv, big.NewInt(1)))
+	require.ErrorContains(t, hint.Execute(vm, nil), "is out of the valid range")
+
+	hint.div = Immediate(*big.NewInt(0))
+	require.EqualError(t, hint.Execute(vm, nil), "div=0x0 is out of the valid range")
+}
+
+func TestSignedDivRem(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+
+	// -7 = -4 * 2 + 1, the quotient is rounded down
+	hint := SignedDivRem{
+		value:   Immediate(*big.NewInt(-7)),
+		div:     Immediate(*big.NewInt(2)),
+		bound:   Immediate(*big.NewInt(4)),
+		r:       ApCellRef(0),
+		biasedQ: ApCellRef(1),
+	}
+	require.NoError(t, hint.Execute(vm, nil))
+	require.Equal(t, memory.MemoryValueFromInt(1), readFrom(vm, VM.ExecutionSegment, 0))
+	require.Equal(t, memory.MemoryValueFromInt(0), readFrom(vm, VM.ExecutionSegment, 1))
+
+	hint.value = Immediate(*big.NewInt(7))
+	hint.r, hint.biasedQ = ApCellRef(2), ApCellRef(3)
+	require.NoError(t, hint.Execute(vm, nil))
+	require.Equal(t, memory.MemoryValueFromInt(1), readFrom(vm, VM.ExecutionSegment, 2))
+	require.Equal(t, memory.MemoryValueFromInt(7), readFrom(vm, VM.ExecutionSegment, 3))
+
+	hint.value = Immediate(*big.NewInt(8))
+	require.EqualError(t, hint.Execute(vm, nil), "8 / 2 = 4 is out of the range [-4, 4)")
+
+	hint.value = Immediate(*big.NewInt(-9))
+	require.EqualError(t, hint.Execute(vm, nil), "-9 / 2 = -5 is out of the range [-4, 4)")
+
+	// PRIME // 2 is the first negative value
+	half := new(big.Int).Rsh(f.Modulus(), 1)
+	hint.value = Immediate(*half)
+	require.ErrorContains(t, hint.Execute(vm, nil), new(big.Int).Sub(half, f.Modulus()).String()+" / 2")
+
+	hint.bound = Immediate(*new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), 127), big.NewInt(1)))
+	require.ErrorContains(t, hint.Execute(vm, nil), "bound=0x80000000000000000000000000000001 is out of the valid range")
+
+	hint.div = Immediate(*big.NewInt(0))
+	require.EqualError(t, hint.Execute(vm, nil), "div=0x0 is out of the valid range")
+}
+
+func TestAssertNotZeroBeforeDivision(t *testing.T) {
+	bytecode, err := assembler.CasmToBytecode(`
+        [ap] = 0, ap++;
+        [ap] = 6, ap++;
+        [ap - 1] = [ap] * [ap - 2], ap++;
+    `)
+	require.NoError(t, err)
+	manager := memory.CreateMemoryManager()
+	_, err = manager.Memory.AllocateSegment(bytecode)
+	require.NoError(t, err)
+	manager.Memory.AllocateEmptySegment()
+	vm, err := VM.NewVirtualMachine(VM.Context{Fp: 1}, manager.Memory, VM.VirtualMachineConfig{})
+	require.NoError(t, err)
+
+	// the divisor is checked right before the division, which deduces [ap] from
+	// [ap - 1] = [ap] * [ap - 2]
+	hr := NewHintRunner(map[uint64][]Hinter{
+		4: {AssertNotZero{value: Deref{ApCellRef(-2)}}},
+	})
+	for step := 0; step < 3; step++ {
+		if err = hr.RunHint(vm); err != nil {
+			break
+		}
+		require.NoError(t, vm.RunStep(&hr))
+	}
+	require.EqualError(t, err, "execute hint AssertNotZero: assert_not_zero failed: ids.value = 0")
+	require.Equal(t, uint64(2), vm.Step)
+}
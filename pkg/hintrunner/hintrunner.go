@@ -4,27 +4,53 @@ import (
 	"fmt"
 
 	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
 )
 
-// todo: Can two or more hints be assigned to a specific PC?
 type HintRunner struct {
-	// A mapping from program counter to hint implementation
-	hints map[uint64]Hinter
+	// Execution context required by certain hints such as dictionaries
+	context HintRunnerContext
+	// A mapping from program counter to the hints run there, in the order
+	// they are declared
+	hints map[uint64][]Hinter
 }
 
-func NewHintRunner(hints map[uint64]Hinter) HintRunner {
-	return HintRunner{hints}
+func NewHintRunner(hints map[uint64][]Hinter) HintRunner {
+	return HintRunner{
+		context: HintRunnerContext{
+			DictionaryManager:         DictionaryManager{},
+			SquashedDictionaryManager: SquashedDictionaryManager{},
+		},
+		hints: hints,
+	}
+}
+
+// Limits the array length the search hints accept, like the
+// __find_element_max_size scope variable. 0 means no limit
+func (hr *HintRunner) SetFindElementMaxSize(maxSize uint64) {
+	hr.context.FindElementMaxSize = maxSize
 }
 
-func (hr HintRunner) RunHint(vm *VM.VirtualMachine) error {
-	hint := hr.hints[vm.Context.Pc.Offset]
-	if hint == nil {
-		return nil
+// Registers a callback whose felts are written starting at ap when the vm
+// reaches pc, see Oracle. Errors if there is already a hint at pc
+func (hr *HintRunner) RegisterOracle(pc uint64, fn func(vm *VM.VirtualMachine) ([]f.Element, error)) error {
+	if hints := hr.hints[pc]; len(hints) > 0 {
+		return fmt.Errorf("pc %d already has hint %s", pc, hints[0])
 	}
+	if hr.hints == nil {
+		hr.hints = make(map[uint64][]Hinter)
+	}
+	hr.hints[pc] = []Hinter{Oracle{fn: fn}}
+	return nil
+}
 
-	err := hint.Execute(vm)
-	if err != nil {
-		return fmt.Errorf("execute hint %s: %v", hint, err)
+// Runs the hints at pc in order, stopping at the first one that fails
+func (hr *HintRunner) RunHint(vm *VM.VirtualMachine) error {
+	for _, hint := range hr.hints[vm.Context.Pc.Offset] {
+		err := hint.Execute(vm, &hr.context)
+		if err != nil {
+			return fmt.Errorf("execute hint %s: %v", hint, err)
+		}
 	}
 	return nil
 }
@@ -1,10 +1,13 @@
 package hintrunner
 
 import (
+	"errors"
 	"testing"
 
+	"github.com/NethermindEth/cairo-vm-go/pkg/assembler"
 	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
 	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
 	"github.com/stretchr/testify/require"
 )
 
@@ -15,8 +18,8 @@ func TestExistingHint(t *testing.T) {
 	var ap ApCellRef = 5
 	allocHint := AllocSegment{ap}
 
-	hr := NewHintRunner(map[uint64]Hinter{
-		10: allocHint,
+	hr := NewHintRunner(map[uint64][]Hinter{
+		10: {allocHint},
 	})
 
 	vm.Context.Pc = memory.MemoryAddress{
@@ -39,8 +42,8 @@ func TestNoHint(t *testing.T) {
 	var ap ApCellRef = 5
 	allocHint := AllocSegment{ap}
 
-	hr := NewHintRunner(map[uint64]Hinter{
-		10: allocHint,
+	hr := NewHintRunner(map[uint64][]Hinter{
+		10: {allocHint},
 	})
 
 	vm.Context.Pc = memory.MemoryAddress{
@@ -51,3 +54,72 @@ func TestNoHint(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, 2, len(vm.Memory.Segments))
 }
+
+// Logs its name when executed, failing if it is empty
+type loggingHint struct {
+	name string
+	log  *[]string
+}
+
+func (hint loggingHint) String() string {
+	return "LoggingHint"
+}
+
+func (hint loggingHint) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	if hint.name == "" {
+		return errors.New("no name")
+	}
+	*hint.log = append(*hint.log, hint.name)
+	return nil
+}
+
+func TestHintsAtSamePc(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Pc = memory.MemoryAddress{SegmentIndex: 0, Offset: 10}
+
+	var log []string
+	hr := NewHintRunner(map[uint64][]Hinter{
+		10: {loggingHint{"first", &log}, loggingHint{"second", &log}},
+	})
+	require.NoError(t, hr.RunHint(vm))
+	require.Equal(t, []string{"first", "second"}, log)
+
+	// the hints after a failing one don't run
+	log = nil
+	hr = NewHintRunner(map[uint64][]Hinter{
+		10: {loggingHint{"first", &log}, loggingHint{"", &log}, loggingHint{"third", &log}},
+	})
+	require.EqualError(t, hr.RunHint(vm), "execute hint LoggingHint: no name")
+	require.Equal(t, []string{"first"}, log)
+}
+
+func TestRegisterOracle(t *testing.T) {
+	bytecode, err := assembler.CasmToBytecode(`
+        [ap + 2] = [ap] + [ap + 1];
+    `)
+	require.NoError(t, err)
+	manager := memory.CreateMemoryManager()
+	_, err = manager.Memory.AllocateSegment(bytecode)
+	require.NoError(t, err)
+	manager.Memory.AllocateEmptySegment()
+	vm, err := VM.NewVirtualMachine(VM.Context{}, manager.Memory, VM.VirtualMachineConfig{})
+	require.NoError(t, err)
+
+	// the oracle provides both operands of the addition
+	hr := NewHintRunner(nil)
+	require.NoError(t, hr.RegisterOracle(0, func(vm *VM.VirtualMachine) ([]f.Element, error) {
+		return []f.Element{f.NewElement(20), f.NewElement(22)}, nil
+	}))
+	require.NoError(t, hr.RunHint(vm))
+	require.NoError(t, vm.RunStep(&hr))
+	require.Equal(t, memory.MemoryValueFromInt(42), readFrom(vm, VM.ExecutionSegment, 2))
+
+	err = hr.RegisterOracle(0, nil)
+	require.EqualError(t, err, "pc 0 already has hint Oracle")
+
+	require.NoError(t, hr.RegisterOracle(1, func(vm *VM.VirtualMachine) ([]f.Element, error) {
+		return nil, errors.New("no input left")
+	}))
+	vm.Context.Pc.Offset = 1
+	require.EqualError(t, hr.RunHint(vm), "execute hint Oracle: oracle: no input left")
+}
@@ -47,6 +47,64 @@ func (fp FpCellRef) Get(vm *VM.VirtualMachine) (memory.MemoryAddress, error) {
 	return memory.MemoryAddress{SegmentIndex: VM.ExecutionSegment, Offset: res}, nil
 }
 
+// The cell at some offset from the address another cell holds, as hints
+// writing through a pointer, e.g. to [range_check_ptr], refer to
+type PtrCellRef struct {
+	ptr    CellRefer
+	offset int16
+}
+
+func (ref PtrCellRef) String() string {
+	return fmt.Sprintf("PtrCellRef(%s, %d)", ref.ptr, ref.offset)
+}
+
+func (ref PtrCellRef) Get(vm *VM.VirtualMachine) (memory.MemoryAddress, error) {
+	ptrAddr, err := ref.ptr.Get(vm)
+	if err != nil {
+		return memory.MemoryAddress{}, fmt.Errorf("get pointer cell %s: %w", ref.ptr, err)
+	}
+	ptrValue, err := vm.Memory.ReadFromAddress(&ptrAddr)
+	if err != nil {
+		return memory.MemoryAddress{}, fmt.Errorf("read pointer at %s: %w", ptrAddr, err)
+	}
+	address, err := ptrValue.ToMemoryAddress()
+	if err != nil {
+		return memory.MemoryAddress{}, err
+	}
+
+	offset, overflow := safemath.SafeOffset(address.Offset, ref.offset)
+	if overflow {
+		return memory.MemoryAddress{}, safemath.NewSafeOffsetError(address.Offset, ref.offset)
+	}
+	return memory.MemoryAddress{SegmentIndex: address.SegmentIndex, Offset: offset}, nil
+}
+
+// Returns the cell at some offset from another one, e.g. a member of a struct
+// living in the stack
+func offsetCell(cell CellRefer, offset int16) (CellRefer, error) {
+	switch cell := cell.(type) {
+	case ApCellRef:
+		shifted, err := shiftCellOffset(int16(cell), offset)
+		return ApCellRef(shifted), err
+	case FpCellRef:
+		shifted, err := shiftCellOffset(int16(cell), offset)
+		return FpCellRef(shifted), err
+	case PtrCellRef:
+		shifted, err := shiftCellOffset(cell.offset, offset)
+		return PtrCellRef{ptr: cell.ptr, offset: shifted}, err
+	default:
+		return nil, fmt.Errorf("cannot offset cell %s", cell)
+	}
+}
+
+func shiftCellOffset(base, offset int16) (int16, error) {
+	shifted := int(base) + int(offset)
+	if shifted < -1<<15 || shifted >= 1<<15 {
+		return 0, fmt.Errorf("cell offset %d out of range", shifted)
+	}
+	return int16(shifted), nil
+}
+
 //
 // All ResOperand definitions
 
@@ -77,6 +135,10 @@ type DoubleDeref struct {
 	offset int16
 }
 
+func (dderef DoubleDeref) String() string {
+	return "DoubleDeref"
+}
+
 func (dderef DoubleDeref) Resolve(vm *VM.VirtualMachine) (memory.MemoryValue, error) {
 	lhsAddr, err := dderef.deref.Get(vm)
 	if err != nil {
@@ -110,6 +172,24 @@ func (dderef DoubleDeref) Resolve(vm *VM.VirtualMachine) (memory.MemoryValue, er
 	return value, nil
 }
 
+// The address of a cell rather than its value, which is what references to
+// a struct in the stack, e.g. cast(ap, LoopTemps*), hold
+type CellAddress struct {
+	cell CellRefer
+}
+
+func (address CellAddress) String() string {
+	return "CellAddress"
+}
+
+func (address CellAddress) Resolve(vm *VM.VirtualMachine) (memory.MemoryValue, error) {
+	cellAddr, err := address.cell.Get(vm)
+	if err != nil {
+		return memory.MemoryValue{}, fmt.Errorf("get cell: %w", err)
+	}
+	return memory.MemoryValueFromMemoryAddress(&cellAddr), nil
+}
+
 type Immediate big.Int
 
 func (imm Immediate) String() string {
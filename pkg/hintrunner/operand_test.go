@@ -40,6 +40,21 @@ func TestGetFp(t *testing.T) {
 	require.Equal(t, memory.MemoryValueFromInt(11), value)
 }
 
+func TestGetPtrCell(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Fp = 3
+	writeTo(vm, VM.ExecutionSegment, 1, memory.MemoryValueFromSegmentAndOffset(VM.ExecutionSegment, 10))
+
+	ref := PtrCellRef{ptr: FpCellRef(-2), offset: 1}
+	address, err := ref.Get(vm)
+	require.NoError(t, err)
+	require.Equal(t, memory.MemoryAddress{SegmentIndex: VM.ExecutionSegment, Offset: 11}, address)
+
+	writeTo(vm, VM.ExecutionSegment, 2, memory.MemoryValueFromInt(10))
+	_, err = PtrCellRef{ptr: FpCellRef(-1)}.Get(vm)
+	require.Error(t, err)
+}
+
 func TestResolveDeref(t *testing.T) {
 	vm, _ := defaultVirtualMachine()
 	vm.Context.Ap = 5
@@ -0,0 +1,668 @@
+package hintrunner
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The operands a hint accesses through ids, keyed by their name, e.g.
+// "value" for ids.value. They are resolved at the pc of the hint, constants
+// such as ids.N_PACKED_INSTANCES are Immediates
+type HintReferences map[string]ResOperander
+
+// Translates the python code of a cairo zero hint into the Hinter that runs
+// it. Embedders with custom hints implement it, usually falling back to
+// StandardHintParser for the hints of the standard library
+type HintParser interface {
+	Parse(code string, references HintReferences) (Hinter, error)
+}
+
+// Parses the hints of the cairo zero standard library the hint runner
+// implements, errors on any other hint
+type StandardHintParser struct{}
+
+const (
+	allocSegmentCode = "memory[ap] = segments.add()"
+
+	assertNotZeroCode = `from starkware.cairo.common.math_utils import assert_integer
+assert_integer(ids.value)
+assert ids.value % PRIME != 0, f'assert_not_zero failed: ids.value = {ids.value}.'`
+
+	assertNotEqualCode = `from starkware.cairo.lang.vm.relocatable import RelocatableValue
+both_ints = isinstance(ids.a, int) and isinstance(ids.b, int)
+both_relocatable = (
+    isinstance(ids.a, RelocatableValue) and isinstance(ids.b, RelocatableValue) and
+    ids.a.segment_index == ids.b.segment_index)
+assert both_ints or both_relocatable, \
+    f'assert_not_equal failed: non-comparable values: {ids.a}, {ids.b}.'
+assert (ids.a - ids.b) % PRIME != 0, f'assert_not_equal failed: {ids.a} = {ids.b}.'`
+
+	unsignedDivRemCode = `from starkware.cairo.common.math_utils import assert_integer
+assert_integer(ids.div)
+assert 0 < ids.div <= PRIME // range_check_builtin.bound, \
+    f'div={hex(ids.div)} is out of the valid range.'
+ids.q, ids.r = divmod(ids.value, ids.div)`
+
+	signedDivRemCode = `from starkware.cairo.common.math_utils import as_int, assert_integer
+
+assert_integer(ids.div)
+assert 0 < ids.div <= PRIME // range_check_builtin.bound, \
+    f'div={hex(ids.div)} is out of the valid range.'
+
+assert_integer(ids.bound)
+assert ids.bound <= range_check_builtin.bound // 2, \
+    f'bound={hex(ids.bound)} is out of the valid range.'
+
+int_value = as_int(ids.value, PRIME)
+q, ids.r = divmod(int_value, ids.div)
+
+assert -ids.bound <= q < ids.bound, \
+    f'{int_value} / {ids.div} = {q} is out of the range [{-ids.bound}, {ids.bound}).'
+
+ids.biased_q = q + ids.bound`
+
+	blake2sComputeCode = `from starkware.cairo.common.cairo_blake2s.blake2s_utils import compute_blake2s_func
+compute_blake2s_func(segments=segments, output_ptr=ids.output)`
+
+	finalizeBlake2sCode = `# Add dummy pairs of input and output.
+from starkware.cairo.common.cairo_blake2s.blake2s_utils import IV, blake2s_compress
+
+_n_packed_instances = int(ids.N_PACKED_INSTANCES)
+assert 0 <= _n_packed_instances < 20
+_blake2s_input_chunk_size_felts = int(ids.INPUT_BLOCK_FELTS)
+assert 0 <= _blake2s_input_chunk_size_felts < 100
+
+message = [0] * _blake2s_input_chunk_size_felts
+modified_iv = [IV[0] ^ 0x01010020] + IV[1:]
+output = blake2s_compress(
+    message=message,
+    h=modified_iv,
+    t0=0,
+    t1=0,
+    f0=0xffffffff,
+    f1=0,
+)
+padding = (modified_iv + message + [0, 0xffffffff] + output) * (_n_packed_instances - 1)
+segments.write_arg(ids.blake2s_ptr_end, padding)`
+
+	dictNewCode = `if '__dict_manager' not in globals():
+    from starkware.cairo.common.dict import DictManager
+    __dict_manager = DictManager()
+
+memory[ap] = __dict_manager.new_dict(segments, initial_dict)
+del initial_dict`
+
+	defaultDictNewCode = `if '__dict_manager' not in globals():
+    from starkware.cairo.common.dict import DictManager
+    __dict_manager = DictManager()
+
+memory[ap] = __dict_manager.new_default_dict(segments, ids.default_value)`
+
+	dictReadCode = `dict_tracker = __dict_manager.get_tracker(ids.dict_ptr)
+dict_tracker.current_ptr += ids.DictAccess.SIZE
+ids.value = dict_tracker.data[ids.key]`
+
+	dictWriteCode = `dict_tracker = __dict_manager.get_tracker(ids.dict_ptr)
+dict_tracker.current_ptr += ids.DictAccess.SIZE
+ids.dict_ptr.prev_value = dict_tracker.data[ids.key]
+dict_tracker.data[ids.key] = ids.new_value`
+
+	dictUpdateCode = `# Verify dict pointer and prev value.
+dict_tracker = __dict_manager.get_tracker(ids.dict_ptr)
+current_value = dict_tracker.data[ids.key]
+assert current_value == ids.prev_value, \
+    f'Wrong previous value in dict. Got {ids.prev_value}, expected {current_value}.'
+
+# Update value.
+dict_tracker.data[ids.key] = ids.new_value
+dict_tracker.current_ptr += ids.DictAccess.SIZE`
+
+	dictSquashCopyDictCode = `# Prepare arguments for dict_new. In particular, the same dictionary values should be copied
+# to the new (squashed) dictionary.
+vm_enter_scope({
+    # Make __dict_manager accessible.
+    '__dict_manager': __dict_manager,
+    # Create a copy of the dict, in case it changes in the future.
+    'initial_dict': dict(__dict_manager.get_dict(ids.dict_accesses_end)),
+})`
+
+	dictSquashUpdatePtrCode = `# Update the DictTracker's current_ptr to point to the end of the squashed dict.
+__dict_manager.get_tracker(ids.squashed_dict_start).current_ptr = \
+    ids.squashed_dict_end.address_`
+
+	vmExitScopeCode = "vm_exit_scope()"
+
+	squashDictCode = `dict_access_size = ids.DictAccess.SIZE
+address = ids.dict_accesses.address_
+assert ids.ptr_diff % dict_access_size == 0, \
+    'Accesses array size must be divisible by DictAccess.SIZE'
+n_accesses = ids.n_accesses
+if '__squash_dict_max_size' in globals():
+    assert n_accesses <= __squash_dict_max_size, \
+        f'squash_dict() can only be used with n_accesses<={__squash_dict_max_size}. ' \
+        f'Got: n_accesses={n_accesses}.'
+# A map from key to the list of indices accessing it.
+access_indices = {}
+for i in range(n_accesses):
+    key = memory[address + dict_access_size * i]
+    access_indices.setdefault(key, []).append(i)
+# Descending list of keys.
+keys = sorted(access_indices.keys(), reverse=True)
+# Are the keys used bigger than range_check bound.
+ids.big_keys = 1 if keys[0] >= range_check_builtin.bound else 0
+ids.first_key = key = keys.pop()`
+
+	squashDictInnerFirstIterationCode = `current_access_indices = sorted(access_indices[key])[::-1]
+current_access_index = current_access_indices.pop()
+memory[ids.range_check_ptr] = current_access_index`
+
+	squashDictInnerSkipLoopCode = "ids.should_skip_loop = 0 if current_access_indices else 1"
+
+	squashDictInnerCheckAccessIndexCode = `new_access_index = current_access_indices.pop()
+ids.loop_temps.index_delta_minus1 = new_access_index - current_access_index - 1
+current_access_index = new_access_index`
+
+	squashDictInnerContinueLoopCode = "ids.loop_temps.should_continue = 1 if current_access_indices else 0"
+
+	squashDictInnerLenAssertCode = "assert len(current_access_indices) == 0"
+
+	squashDictInnerUsedAccessesAssertCode = "assert ids.n_used_accesses == len(access_indices[key])"
+
+	squashDictInnerAssertLenKeysCode = "assert len(keys) == 0"
+
+	squashDictInnerNextKeyCode = `assert len(keys) > 0, 'No keys left but remaining_accesses > 0.'
+ids.next_key = key = keys.pop()`
+
+	findElementCode = `array_ptr = ids.array_ptr
+elm_size = ids.elm_size
+assert isinstance(elm_size, int) and elm_size > 0, \
+    f'Invalid value for elm_size. Got: {elm_size}.'
+key = ids.key
+
+if '__find_element_index' in globals():
+    ids.index = __find_element_index
+    found_key = memory[array_ptr + elm_size * __find_element_index]
+    assert found_key == key, \
+        f'Invalid index found in __find_element_index. index: {__find_element_index}, ' \
+        f'expected key {key}, found key: {found_key}.'
+    # Delete __find_element_index to make sure it's not used for the next calls.
+    del __find_element_index
+else:
+    n_elms = ids.n_elms
+    assert isinstance(n_elms, int) and n_elms >= 0, \
+        f'Invalid value for n_elms. Got: {n_elms}.'
+    if '__find_element_max_size' in globals():
+        assert n_elms <= __find_element_max_size, \
+            f'find_element() can only be used with n_elms<={__find_element_max_size}. ' \
+            f'Got: n_elms={n_elms}.'
+
+    for i in range(n_elms):
+        if memory[array_ptr + elm_size * i] == key:
+            ids.index = i
+            break
+    else:
+        raise ValueError(f'Key {key} was not found.')`
+
+	searchSortedLowerCode = `array_ptr = ids.array_ptr
+elm_size = ids.elm_size
+assert isinstance(elm_size, int) and elm_size > 0, \
+    f'Invalid value for elm_size. Got: {elm_size}.'
+
+n_elms = ids.n_elms
+assert isinstance(n_elms, int) and n_elms >= 0, \
+    f'Invalid value for n_elms. Got: {n_elms}.'
+if '__find_element_max_size' in globals():
+    assert n_elms <= __find_element_max_size, \
+        f'find_element() can only be used with n_elms<={__find_element_max_size}. ' \
+        f'Got: n_elms={n_elms}.'
+
+for i in range(n_elms):
+    if memory[array_ptr + elm_size * i] >= ids.key:
+        ids.index = i
+        break
+else:
+    ids.index = n_elms`
+
+	// offsets of the members of squash_dict_inner.LoopTemps the hints write
+	loopTempsIndexDeltaMinusOne = 0
+	loopTempsShouldContinue     = 3
+)
+
+// Code of the standard library hints StandardHintParser implements. It is
+// not the Starknet hint whitelist, only the library hints this vm runs, so a
+// program limited to them can still be one Starknet rejects. Assignment hints
+// are arbitrary code and are left out
+var SupportedLibraryHints = []string{
+	allocSegmentCode,
+	assertNotZeroCode,
+	assertNotEqualCode,
+	unsignedDivRemCode,
+	signedDivRemCode,
+	blake2sComputeCode,
+	finalizeBlake2sCode,
+	dictNewCode,
+	defaultDictNewCode,
+	dictReadCode,
+	dictWriteCode,
+	dictUpdateCode,
+	dictSquashCopyDictCode,
+	dictSquashUpdatePtrCode,
+	vmExitScopeCode,
+	squashDictCode,
+	squashDictInnerFirstIterationCode,
+	squashDictInnerSkipLoopCode,
+	squashDictInnerCheckAccessIndexCode,
+	squashDictInnerContinueLoopCode,
+	squashDictInnerLenAssertCode,
+	squashDictInnerUsedAccessesAssertCode,
+	squashDictInnerAssertLenKeysCode,
+	squashDictInnerNextKeyCode,
+	findElementCode,
+	searchSortedLowerCode,
+}
+
+func (StandardHintParser) Parse(code string, references HintReferences) (Hinter, error) {
+	switch strings.TrimSpace(code) {
+	case allocSegmentCode:
+		return AllocSegment{dst: ApCellRef(0)}, nil
+	case assertNotZeroCode:
+		value, err := references.get("value")
+		if err != nil {
+			return nil, err
+		}
+		return AssertNotZero{value: value}, nil
+	case assertNotEqualCode:
+		lhs, err := references.get("a")
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := references.get("b")
+		if err != nil {
+			return nil, err
+		}
+		return AssertNotEqual{lhs: lhs, rhs: rhs}, nil
+	case unsignedDivRemCode:
+		return references.unsignedDivRem()
+	case signedDivRemCode:
+		return references.signedDivRem()
+	case blake2sComputeCode:
+		output, err := references.get("output")
+		if err != nil {
+			return nil, err
+		}
+		return Blake2sCompute{output: output}, nil
+	case finalizeBlake2sCode:
+		ptrEnd, err := references.get("blake2s_ptr_end")
+		if err != nil {
+			return nil, err
+		}
+		nPackedInstances, err := references.getConstant("N_PACKED_INSTANCES")
+		if err != nil {
+			return nil, err
+		}
+		return FinalizeBlake2s{blake2sPtrEnd: ptrEnd, nPackedInstances: nPackedInstances}, nil
+	case dictNewCode:
+		return DictNew{dst: ApCellRef(0)}, nil
+	case defaultDictNewCode:
+		defaultValue, err := references.get("default_value")
+		if err != nil {
+			return nil, err
+		}
+		return DefaultDictNew{defaultValue: defaultValue, dst: ApCellRef(0)}, nil
+	case dictReadCode, dictWriteCode, dictUpdateCode:
+		return references.dictAccess(strings.TrimSpace(code))
+	case dictSquashCopyDictCode:
+		dictAccessesEnd, err := references.get("dict_accesses_end")
+		if err != nil {
+			return nil, err
+		}
+		return DictSquashCopyDict{dictAccessesEnd: dictAccessesEnd}, nil
+	case dictSquashUpdatePtrCode:
+		start, err := references.get("squashed_dict_start")
+		if err != nil {
+			return nil, err
+		}
+		end, err := references.get("squashed_dict_end")
+		if err != nil {
+			return nil, err
+		}
+		return DictSquashUpdatePtr{squashedDictStart: start, squashedDictEnd: end}, nil
+	case vmExitScopeCode:
+		return ExitScope{}, nil
+	case squashDictCode:
+		return references.squashDict()
+	case squashDictInnerFirstIterationCode:
+		rangeCheckPtr, err := references.get("range_check_ptr")
+		if err != nil {
+			return nil, err
+		}
+		return GetCurrentAccessIndex{rangeCheckPtr: rangeCheckPtr}, nil
+	case squashDictInnerSkipLoopCode:
+		shouldSkipLoop, err := references.getCell("should_skip_loop")
+		if err != nil {
+			return nil, err
+		}
+		return ShouldSkipSquashLoop{shouldSkipLoop: shouldSkipLoop}, nil
+	case squashDictInnerCheckAccessIndexCode:
+		indexDeltaMinusOne, err := references.getMemberCell("loop_temps", loopTempsIndexDeltaMinusOne)
+		if err != nil {
+			return nil, err
+		}
+		return GetCurrentAccessDelta{indexDeltaMinusOne: indexDeltaMinusOne}, nil
+	case squashDictInnerContinueLoopCode:
+		shouldContinue, err := references.getMemberCell("loop_temps", loopTempsShouldContinue)
+		if err != nil {
+			return nil, err
+		}
+		return ShouldContinueSquashLoop{shouldContinue: shouldContinue}, nil
+	case squashDictInnerLenAssertCode:
+		return SquashDictInnerLenAssert{}, nil
+	case squashDictInnerUsedAccessesAssertCode:
+		nUsedAccesses, err := references.get("n_used_accesses")
+		if err != nil {
+			return nil, err
+		}
+		return SquashDictInnerUsedAccessesAssert{nUsedAccesses: nUsedAccesses}, nil
+	case squashDictInnerAssertLenKeysCode:
+		return SquashDictInnerAssertLenKeys{}, nil
+	case squashDictInnerNextKeyCode:
+		nextKey, err := references.getCell("next_key")
+		if err != nil {
+			return nil, err
+		}
+		return GetNextDictKey{nextKey: nextKey}, nil
+	case findElementCode:
+		return references.search(false)
+	case searchSortedLowerCode:
+		return references.search(true)
+	default:
+		hint, ok, err := references.assignment(strings.TrimSpace(code))
+		if !ok {
+			return nil, fmt.Errorf("unsupported hint: %s", code)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unsupported assignment hint %s: %w", strings.TrimSpace(code), err)
+		}
+		return hint, nil
+	}
+}
+
+func (references HintReferences) get(name string) (ResOperander, error) {
+	operand, ok := references[name]
+	if !ok {
+		return nil, fmt.Errorf("missing reference ids.%s", name)
+	}
+	return operand, nil
+}
+
+// the value of a constant the hint reads, such as ids.N_PACKED_INSTANCES
+func (references HintReferences) getConstant(name string) (uint64, error) {
+	operand, err := references.get(name)
+	if err != nil {
+		return 0, err
+	}
+	constant, ok := operand.(Immediate)
+	if !ok {
+		return 0, fmt.Errorf("reference ids.%s is not a constant", name)
+	}
+	value := big.Int(constant)
+	if !value.IsUint64() {
+		return 0, fmt.Errorf("constant ids.%s=%s doesn't fit in 64 bits", name, &value)
+	}
+	return value.Uint64(), nil
+}
+
+// find_element or search_sorted_lower, the __find_element_index shortcut of
+// find_element is not supported
+func (references HintReferences) search(sorted bool) (Hinter, error) {
+	arrayPtr, err := references.get("array_ptr")
+	if err != nil {
+		return nil, err
+	}
+	elmSize, err := references.get("elm_size")
+	if err != nil {
+		return nil, err
+	}
+	nElms, err := references.get("n_elms")
+	if err != nil {
+		return nil, err
+	}
+	key, err := references.get("key")
+	if err != nil {
+		return nil, err
+	}
+	index, err := references.getCell("index")
+	if err != nil {
+		return nil, err
+	}
+	if sorted {
+		return SearchSortedLower{arrayPtr: arrayPtr, elmSize: elmSize, nElms: nElms, key: key, index: index}, nil
+	}
+	return FindElement{arrayPtr: arrayPtr, elmSize: elmSize, nElms: nElms, key: key, index: index}, nil
+}
+
+func (references HintReferences) unsignedDivRem() (Hinter, error) {
+	value, err := references.get("value")
+	if err != nil {
+		return nil, err
+	}
+	div, err := references.get("div")
+	if err != nil {
+		return nil, err
+	}
+	q, err := references.getCell("q")
+	if err != nil {
+		return nil, err
+	}
+	r, err := references.getCell("r")
+	if err != nil {
+		return nil, err
+	}
+	return UnsignedDivRem{value: value, div: div, q: q, r: r}, nil
+}
+
+func (references HintReferences) signedDivRem() (Hinter, error) {
+	value, err := references.get("value")
+	if err != nil {
+		return nil, err
+	}
+	div, err := references.get("div")
+	if err != nil {
+		return nil, err
+	}
+	bound, err := references.get("bound")
+	if err != nil {
+		return nil, err
+	}
+	r, err := references.getCell("r")
+	if err != nil {
+		return nil, err
+	}
+	biasedQ, err := references.getCell("biased_q")
+	if err != nil {
+		return nil, err
+	}
+	return SignedDivRem{value: value, div: div, bound: bound, r: r, biasedQ: biasedQ}, nil
+}
+
+// dict_read, dict_write and dict_update access the dictionary at ids.dict_ptr
+func (references HintReferences) dictAccess(code string) (Hinter, error) {
+	dictPtr, err := references.get("dict_ptr")
+	if err != nil {
+		return nil, err
+	}
+	key, err := references.get("key")
+	if err != nil {
+		return nil, err
+	}
+	if code == dictReadCode {
+		value, err := references.getCell("value")
+		if err != nil {
+			return nil, err
+		}
+		return DictRead{dictPtr: dictPtr, key: key, value: value}, nil
+	}
+
+	newValue, err := references.get("new_value")
+	if err != nil {
+		return nil, err
+	}
+	if code == dictWriteCode {
+		return DictWrite{dictPtr: dictPtr, key: key, newValue: newValue}, nil
+	}
+	prevValue, err := references.get("prev_value")
+	if err != nil {
+		return nil, err
+	}
+	return DictUpdate{dictPtr: dictPtr, key: key, prevValue: prevValue, newValue: newValue}, nil
+}
+
+func (references HintReferences) squashDict() (Hinter, error) {
+	dictAccesses, err := references.get("dict_accesses")
+	if err != nil {
+		return nil, err
+	}
+	ptrDiff, err := references.get("ptr_diff")
+	if err != nil {
+		return nil, err
+	}
+	nAccesses, err := references.get("n_accesses")
+	if err != nil {
+		return nil, err
+	}
+	bigKeys, err := references.getCell("big_keys")
+	if err != nil {
+		return nil, err
+	}
+	firstKey, err := references.getCell("first_key")
+	if err != nil {
+		return nil, err
+	}
+	return InitSquashData{
+		dictAccesses: dictAccesses,
+		ptrDiff:      ptrDiff,
+		nAccesses:    nAccesses,
+		bigKeys:      bigKeys,
+		firstKey:     firstKey,
+	}, nil
+}
+
+// the cell of a struct member, for references to a struct in the stack or
+// to a pointer to one
+func (references HintReferences) getMemberCell(name string, offset int16) (CellRefer, error) {
+	operand, err := references.get(name)
+	if err != nil {
+		return nil, err
+	}
+	switch operand := operand.(type) {
+	case CellAddress:
+		cell, err := offsetCell(operand.cell, offset)
+		if err != nil {
+			return nil, fmt.Errorf("reference ids.%s: %w", name, err)
+		}
+		return cell, nil
+	case Deref:
+		return PtrCellRef{ptr: operand.deref, offset: offset}, nil
+	default:
+		return nil, fmt.Errorf("reference ids.%s is not a struct", name)
+	}
+}
+
+// the cell a reference the hint writes to lives in
+func (references HintReferences) getCell(name string) (CellRefer, error) {
+	operand, err := references.get(name)
+	if err != nil {
+		return nil, err
+	}
+	switch operand := operand.(type) {
+	case Deref:
+		return operand.deref, nil
+	case DoubleDeref:
+		return PtrCellRef{ptr: operand.deref, offset: operand.offset}, nil
+	default:
+		return nil, fmt.Errorf("reference ids.%s is not a cell", name)
+	}
+}
+
+// The value of a reference without its outer brackets, e.g. cast(fp + (-3),
+// felt) or cast([ap + (-1)] + 2, felt*)
+var referencePattern = regexp.MustCompile(
+	`^(?:cast\()?(\[)?(ap|fp)(?: \+ \(?(-?\d+)\)?)?(\])?(?: \+ \(?(-?\d+)\)?)?(?:, [^()]+\))?$`,
+)
+
+// Position of ap relative to the start of a function, as tracked by the
+// compiler. Offsets are only comparable within the same group
+type ApTracking struct {
+	Group  int
+	Offset int
+}
+
+// Parses the value of a reference defined at some ap tracking into the operand
+// holding it when used at another one, e.g. by a hint. Ap based references
+// are shifted by how much ap advanced in between. Only references to a cell,
+// to the cell a pointer in a cell points to, or to the address of a cell or
+// the one a pointer holds, e.g. cast(ap, T*) for a struct in the stack, are
+// supported
+func ParseReference(value string, definedAt ApTracking, usedAt ApTracking) (ResOperander, error) {
+	// a reference wrapped in brackets is the cell at the address inside
+	body := value
+	deref := strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]")
+	if deref {
+		body = value[1 : len(value)-1]
+	}
+	match := referencePattern.FindStringSubmatch(body)
+	// the inner brackets go in pairs
+	if match == nil || (match[1] == "") != (match[4] == "") {
+		return nil, fmt.Errorf("unsupported reference %s", value)
+	}
+	offset, err := referenceOffset(match[3])
+	if err != nil {
+		return nil, fmt.Errorf("reference %s: %w", value, err)
+	}
+
+	var cell CellRefer = FpCellRef(offset)
+	if match[2] == "ap" {
+		if definedAt.Group != usedAt.Group {
+			return nil, fmt.Errorf("reference %s was revoked", value)
+		}
+		offset -= usedAt.Offset - definedAt.Offset
+		if offset < -1<<15 || offset >= 1<<15 {
+			return nil, fmt.Errorf("reference %s: ap offset %d out of range", value, offset)
+		}
+		cell = ApCellRef(offset)
+	}
+
+	innerOffset, err := referenceOffset(match[5])
+	if err != nil {
+		return nil, fmt.Errorf("reference %s: %w", value, err)
+	}
+	innerDeref := match[1] != ""
+	switch {
+	case deref && innerDeref:
+		return DoubleDeref{deref: cell, offset: int16(innerOffset)}, nil
+	case match[5] != "" && !innerDeref:
+		return nil, fmt.Errorf("unsupported reference %s", value)
+	case deref:
+		return Deref{deref: cell}, nil
+	case !innerDeref:
+		return CellAddress{cell: cell}, nil
+	case innerOffset == 0:
+		return Deref{deref: cell}, nil
+	default:
+		return BinaryOp{operator: Add, lhs: cell, rhs: Immediate(*big.NewInt(int64(innerOffset)))}, nil
+	}
+}
+
+func referenceOffset(offset string) (int, error) {
+	if offset == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseInt(offset, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("offset %s out of range", offset)
+	}
+	return int(value), nil
+}
@@ -0,0 +1,178 @@
+package hintrunner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReference(t *testing.T) {
+	group := ApTracking{Group: 1, Offset: 2}
+
+	operand, err := ParseReference("[cast(fp + (-3), felt)]", group, group)
+	require.NoError(t, err)
+	assert.Equal(t, Deref{FpCellRef(-3)}, operand)
+
+	operand, err = ParseReference("[fp]", group, group)
+	require.NoError(t, err)
+	assert.Equal(t, Deref{FpCellRef(0)}, operand)
+
+	// ap advanced by 3 since the reference was defined
+	operand, err = ParseReference("[cast(ap + (-1), felt*)]", group, ApTracking{Group: 1, Offset: 5})
+	require.NoError(t, err)
+	assert.Equal(t, Deref{ApCellRef(-4)}, operand)
+
+	operand, err = ParseReference("[cast([ap + (-1)] + 2, felt*)]", group, group)
+	require.NoError(t, err)
+	assert.Equal(t, DoubleDeref{ApCellRef(-1), 2}, operand)
+
+	_, err = ParseReference("[cast(ap + (-1), felt)]", group, ApTracking{Group: 2})
+	require.EqualError(t, err, "reference [cast(ap + (-1), felt)] was revoked")
+
+	// references without outer brackets are addresses
+	operand, err = ParseReference("cast(ap, LoopTemps*)", group, ApTracking{Group: 1, Offset: 4})
+	require.NoError(t, err)
+	assert.Equal(t, CellAddress{ApCellRef(-2)}, operand)
+
+	operand, err = ParseReference("cast([fp + (-3)], DictAccess*)", group, group)
+	require.NoError(t, err)
+	assert.Equal(t, Deref{FpCellRef(-3)}, operand)
+
+	operand, err = ParseReference("cast([fp + (-3)] + 3, DictAccess*)", group, group)
+	require.NoError(t, err)
+	assert.Equal(t, BinaryOp{Add, FpCellRef(-3), Immediate(*big.NewInt(3))}, operand)
+
+	for _, value := range []string{
+		"cast(fp + (-3) + 2, felt*)",
+		"[cast([fp + (-3)], felt*)",
+		"[cast(fp + (-3) + 2, felt*)]",
+		"[cast(fp + (-40000), felt)]",
+	} {
+		_, err = ParseReference(value, group, group)
+		require.Error(t, err, value)
+	}
+}
+
+func TestStandardHintParser(t *testing.T) {
+	parser := StandardHintParser{}
+
+	hint, err := parser.Parse("memory[ap] = segments.add()", nil)
+	require.NoError(t, err)
+	assert.Equal(t, AllocSegment{ApCellRef(0)}, hint)
+
+	references := HintReferences{"value": Deref{FpCellRef(-3)}}
+	hint, err = parser.Parse(assertNotZeroCode+"\n", references)
+	require.NoError(t, err)
+	assert.Equal(t, AssertNotZero{Deref{FpCellRef(-3)}}, hint)
+
+	_, err = parser.Parse(assertNotEqualCode, references)
+	require.EqualError(t, err, "missing reference ids.a")
+
+	// unsigned_div_rem writes q and r through the range check pointer
+	references = HintReferences{
+		"value": Deref{FpCellRef(-4)},
+		"div":   Deref{FpCellRef(-3)},
+		"q":     DoubleDeref{FpCellRef(-5), 1},
+		"r":     DoubleDeref{FpCellRef(-5), 0},
+	}
+	hint, err = parser.Parse(unsignedDivRemCode, references)
+	require.NoError(t, err)
+	assert.Equal(t, UnsignedDivRem{
+		value: Deref{FpCellRef(-4)},
+		div:   Deref{FpCellRef(-3)},
+		q:     PtrCellRef{FpCellRef(-5), 1},
+		r:     PtrCellRef{FpCellRef(-5), 0},
+	}, hint)
+
+	_, err = parser.Parse(signedDivRemCode, references)
+	require.EqualError(t, err, "missing reference ids.bound")
+
+	references["q"] = Immediate{}
+	_, err = parser.Parse(unsignedDivRemCode, references)
+	require.EqualError(t, err, "reference ids.q is not a cell")
+
+	references = HintReferences{"blake2s_ptr_end": Deref{FpCellRef(-3)}}
+	_, err = parser.Parse(finalizeBlake2sCode, references)
+	require.EqualError(t, err, "missing reference ids.N_PACKED_INSTANCES")
+
+	references["N_PACKED_INSTANCES"] = Deref{FpCellRef(-4)}
+	_, err = parser.Parse(finalizeBlake2sCode, references)
+	require.EqualError(t, err, "reference ids.N_PACKED_INSTANCES is not a constant")
+
+	references["N_PACKED_INSTANCES"] = immediate(7)
+	hint, err = parser.Parse(finalizeBlake2sCode, references)
+	require.NoError(t, err)
+	assert.Equal(t, FinalizeBlake2s{Deref{FpCellRef(-3)}, 7}, hint)
+
+	_, err = parser.Parse(blake2sComputeCode, references)
+	require.EqualError(t, err, "missing reference ids.output")
+
+	references = HintReferences{
+		"dict_ptr":  Deref{FpCellRef(-5)},
+		"key":       Deref{FpCellRef(-4)},
+		"new_value": Deref{FpCellRef(-3)},
+	}
+	hint, err = parser.Parse(dictWriteCode, references)
+	require.NoError(t, err)
+	assert.Equal(t, DictWrite{Deref{FpCellRef(-5)}, Deref{FpCellRef(-4)}, Deref{FpCellRef(-3)}}, hint)
+
+	_, err = parser.Parse(dictUpdateCode, references)
+	require.EqualError(t, err, "missing reference ids.prev_value")
+
+	hint, err = parser.Parse(dictNewCode, nil)
+	require.NoError(t, err)
+	assert.Equal(t, DictNew{ApCellRef(0)}, hint)
+
+	// the loop temps are a struct at the top of the stack
+	references = HintReferences{"loop_temps": CellAddress{ApCellRef(0)}}
+	hint, err = parser.Parse(squashDictInnerContinueLoopCode, references)
+	require.NoError(t, err)
+	assert.Equal(t, ShouldContinueSquashLoop{ApCellRef(3)}, hint)
+
+	references = HintReferences{"loop_temps": Deref{FpCellRef(1)}}
+	hint, err = parser.Parse(squashDictInnerCheckAccessIndexCode, references)
+	require.NoError(t, err)
+	assert.Equal(t, GetCurrentAccessDelta{PtrCellRef{FpCellRef(1), 0}}, hint)
+
+	references = HintReferences{"loop_temps": Immediate{}}
+	_, err = parser.Parse(squashDictInnerContinueLoopCode, references)
+	require.EqualError(t, err, "reference ids.loop_temps is not a struct")
+
+	references = HintReferences{
+		"array_ptr": Deref{FpCellRef(-6)},
+		"elm_size":  Deref{FpCellRef(-5)},
+		"n_elms":    Deref{FpCellRef(-4)},
+		"key":       Deref{FpCellRef(-3)},
+		"index":     Deref{ApCellRef(0)},
+	}
+	hint, err = parser.Parse(findElementCode, references)
+	require.NoError(t, err)
+	assert.Equal(t, FindElement{
+		Deref{FpCellRef(-6)}, Deref{FpCellRef(-5)}, Deref{FpCellRef(-4)}, Deref{FpCellRef(-3)}, ApCellRef(0),
+	}, hint)
+	hint, err = parser.Parse(searchSortedLowerCode, references)
+	require.NoError(t, err)
+	assert.Equal(t, SearchSortedLower{
+		Deref{FpCellRef(-6)}, Deref{FpCellRef(-5)}, Deref{FpCellRef(-4)}, Deref{FpCellRef(-3)}, ApCellRef(0),
+	}, hint)
+
+	references["index"] = Immediate{}
+	_, err = parser.Parse(searchSortedLowerCode, references)
+	require.EqualError(t, err, "reference ids.index is not a cell")
+
+	_, err = parser.Parse("print(ids.value)", references)
+	require.EqualError(t, err, "unsupported hint: print(ids.value)")
+}
+
+func TestSupportedLibraryHints(t *testing.T) {
+	// every listed hint is one the parser implements, even if its
+	// references are missing
+	for _, code := range SupportedLibraryHints {
+		_, err := StandardHintParser{}.Parse(code, nil)
+		if err != nil {
+			require.NotContains(t, err.Error(), "unsupported", code)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package hintrunner
+
+import (
+	"fmt"
+
+	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+)
+
+// The hints of the cairo zero dictionaries, whose accesses are DictAccess
+// structs of a key, a previous value and a new value. Squashing them reuses
+// the squash hints of the cairo 1 dictionaries
+
+// Creates a dictionary holding the initial values dict_squash left and
+// writes its start at dst
+type DictNew struct {
+	dst CellRefer
+}
+
+func (hint DictNew) String() string {
+	return "DictNew"
+}
+
+func (hint DictNew) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	if ctx.InitialDict == nil {
+		return fmt.Errorf("initial_dict is not in scope")
+	}
+	dictAddr := ctx.DictionaryManager.NewDictionaryWithData(vm, ctx.InitialDict, &memory.MemoryValue{})
+	ctx.InitialDict = nil
+	return writeAddress(vm, hint.dst, &dictAddr)
+}
+
+// Creates an empty dictionary whose missing keys hold a default value and
+// writes its start at dst
+type DefaultDictNew struct {
+	defaultValue ResOperander
+	dst          CellRefer
+}
+
+func (hint DefaultDictNew) String() string {
+	return "DefaultDictNew"
+}
+
+func (hint DefaultDictNew) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	defaultValue, err := hint.defaultValue.Resolve(vm)
+	if err != nil {
+		return fmt.Errorf("resolve ids.default_value: %w", err)
+	}
+	dictAddr := ctx.DictionaryManager.NewDictionaryWithData(vm, nil, &defaultValue)
+	return writeAddress(vm, hint.dst, &dictAddr)
+}
+
+type DictRead struct {
+	dictPtr ResOperander
+	key     ResOperander
+	value   CellRefer
+}
+
+func (hint DictRead) String() string {
+	return "DictRead"
+}
+
+func (hint DictRead) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	dict, key, err := dictAccess(vm, ctx, hint.dictPtr, hint.key)
+	if err != nil {
+		return err
+	}
+	value, err := dict.get(key)
+	if err != nil {
+		return err
+	}
+	dict.currentPtr.Offset += dictAccessSize
+
+	valueAddr, err := hint.value.Get(vm)
+	if err != nil {
+		return fmt.Errorf("get value address %s: %w", hint.value, err)
+	}
+	return vm.Memory.WriteToAddress(&valueAddr, &value)
+}
+
+// Writes the previous value of the key in the access and sets the new one
+type DictWrite struct {
+	dictPtr  ResOperander
+	key      ResOperander
+	newValue ResOperander
+}
+
+func (hint DictWrite) String() string {
+	return "DictWrite"
+}
+
+func (hint DictWrite) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	dict, key, err := dictAccess(vm, ctx, hint.dictPtr, hint.key)
+	if err != nil {
+		return err
+	}
+	newValue, err := hint.newValue.Resolve(vm)
+	if err != nil {
+		return fmt.Errorf("resolve ids.new_value: %w", err)
+	}
+	prevValue, err := dict.get(key)
+	if err != nil {
+		return err
+	}
+
+	// the access being written is at the current pointer
+	prevValueAddr := dict.currentPtr
+	prevValueAddr.Offset++
+	if err := vm.Memory.WriteToAddress(&prevValueAddr, &prevValue); err != nil {
+		return fmt.Errorf("write to address %s: %w", prevValueAddr, err)
+	}
+	dict.Set(key, &newValue)
+	dict.currentPtr.Offset += dictAccessSize
+	return nil
+}
+
+// Sets the new value of the key after checking the previous value the
+// program claims is the one the dictionary holds
+type DictUpdate struct {
+	dictPtr   ResOperander
+	key       ResOperander
+	prevValue ResOperander
+	newValue  ResOperander
+}
+
+func (hint DictUpdate) String() string {
+	return "DictUpdate"
+}
+
+func (hint DictUpdate) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	dict, key, err := dictAccess(vm, ctx, hint.dictPtr, hint.key)
+	if err != nil {
+		return err
+	}
+	prevValue, err := hint.prevValue.Resolve(vm)
+	if err != nil {
+		return fmt.Errorf("resolve ids.prev_value: %w", err)
+	}
+	newValue, err := hint.newValue.Resolve(vm)
+	if err != nil {
+		return fmt.Errorf("resolve ids.new_value: %w", err)
+	}
+
+	currentValue, err := dict.get(key)
+	if err != nil {
+		return err
+	}
+	if !currentValue.Equal(&prevValue) {
+		return fmt.Errorf("wrong previous value in dict, got %s, expected %s", prevValue, currentValue)
+	}
+	dict.Set(key, &newValue)
+	dict.currentPtr.Offset += dictAccessSize
+	return nil
+}
+
+// Copies the values of the dictionary being squashed so dict_new starts the
+// squashed dictionary with them
+type DictSquashCopyDict struct {
+	dictAccessesEnd ResOperander
+}
+
+func (hint DictSquashCopyDict) String() string {
+	return "DictSquashCopyDict"
+}
+
+func (hint DictSquashCopyDict) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	dictAccessesEnd, err := resolveAddress(vm, hint.dictAccessesEnd)
+	if err != nil {
+		return fmt.Errorf("resolve ids.dict_accesses_end: %w", err)
+	}
+	dict, err := ctx.DictionaryManager.GetTracker(&dictAccessesEnd)
+	if err != nil {
+		return err
+	}
+
+	ctx.InitialDict = make(map[f.Element]memory.MemoryValue, len(dict.data))
+	for key, value := range dict.data {
+		ctx.InitialDict[key] = value
+	}
+	return nil
+}
+
+// Moves the pointer of the squashed dictionary past the accesses squash_dict
+// wrote, so the dictionary can be used again
+type DictSquashUpdatePtr struct {
+	squashedDictStart ResOperander
+	squashedDictEnd   ResOperander
+}
+
+func (hint DictSquashUpdatePtr) String() string {
+	return "DictSquashUpdatePtr"
+}
+
+func (hint DictSquashUpdatePtr) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	start, err := resolveAddress(vm, hint.squashedDictStart)
+	if err != nil {
+		return fmt.Errorf("resolve ids.squashed_dict_start: %w", err)
+	}
+	end, err := resolveAddress(vm, hint.squashedDictEnd)
+	if err != nil {
+		return fmt.Errorf("resolve ids.squashed_dict_end: %w", err)
+	}
+	dict, err := ctx.DictionaryManager.GetTracker(&start)
+	if err != nil {
+		return err
+	}
+	dict.currentPtr = end
+	return nil
+}
+
+// Leaves the scope dict_squash enters. The hint runner context has no
+// scopes, the initial_dict variable is already consumed by dict_new
+type ExitScope struct{}
+
+func (hint ExitScope) String() string {
+	return "ExitScope"
+}
+
+func (hint ExitScope) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	return nil
+}
+
+// Checks every access of the key being squashed was visited, that is, only
+// the index of the last one is left
+type SquashDictInnerLenAssert struct{}
+
+func (hint SquashDictInnerLenAssert) String() string {
+	return "SquashDictInnerLenAssert"
+}
+
+func (hint SquashDictInnerLenAssert) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	indices, err := ctx.SquashedDictionaryManager.LastIndices()
+	if err != nil {
+		return err
+	}
+	if len(indices) > 1 {
+		return fmt.Errorf("%d accesses of the key are left", len(indices)-1)
+	}
+	return nil
+}
+
+// Checks the program used as many accesses of the key being squashed as
+// there are
+type SquashDictInnerUsedAccessesAssert struct {
+	nUsedAccesses ResOperander
+}
+
+func (hint SquashDictInnerUsedAccessesAssert) String() string {
+	return "SquashDictInnerUsedAccessesAssert"
+}
+
+func (hint SquashDictInnerUsedAccessesAssert) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	nUsedAccesses, err := resolveUint64(vm, hint.nUsedAccesses)
+	if err != nil {
+		return fmt.Errorf("resolve ids.n_used_accesses: %w", err)
+	}
+	key, err := ctx.SquashedDictionaryManager.LastKey()
+	if err != nil {
+		return err
+	}
+	if accesses := ctx.SquashedDictionaryManager.accessCounts[key]; nUsedAccesses != accesses {
+		return fmt.Errorf("key %s has %d accesses but %d were used", &key, accesses, nUsedAccesses)
+	}
+	return nil
+}
+
+// Checks the key being squashed is the last one
+type SquashDictInnerAssertLenKeys struct{}
+
+func (hint SquashDictInnerAssertLenKeys) String() string {
+	return "SquashDictInnerAssertLenKeys"
+}
+
+func (hint SquashDictInnerAssertLenKeys) Execute(vm *VM.VirtualMachine, ctx *HintRunnerContext) error {
+	if keys := len(ctx.SquashedDictionaryManager.Keys); keys > 1 {
+		return fmt.Errorf("%d keys are left to squash", keys-1)
+	}
+	return nil
+}
+
+// Returns the dictionary a cairo zero dictionary access is made to and the
+// key accessed
+func dictAccess(
+	vm *VM.VirtualMachine, ctx *HintRunnerContext, dictPtr ResOperander, key ResOperander,
+) (*Dictionary, *f.Element, error) {
+	ptr, err := resolveAddress(vm, dictPtr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolve ids.dict_ptr: %w", err)
+	}
+	dict, err := ctx.DictionaryManager.GetTracker(&ptr)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyFelt, err := resolveFelt(vm, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolve ids.key: %w", err)
+	}
+	return dict, keyFelt, nil
+}
+
+func writeAddress(vm *VM.VirtualMachine, dst CellRefer, address *memory.MemoryAddress) error {
+	dstAddr, err := dst.Get(vm)
+	if err != nil {
+		return fmt.Errorf("get dst address %s: %w", dst, err)
+	}
+	mv := memory.MemoryValueFromMemoryAddress(address)
+	if err := vm.Memory.WriteToAddress(&dstAddr, &mv); err != nil {
+		return fmt.Errorf("write to dst address %s: %w", dstAddr, err)
+	}
+	return nil
+}
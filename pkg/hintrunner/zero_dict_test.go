@@ -0,0 +1,101 @@
+package hintrunner
+
+import (
+	"math/big"
+	"testing"
+
+	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func immediate(value int64) Immediate {
+	return Immediate(*big.NewInt(value))
+}
+
+func TestDefaultDict(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+	ctx := HintRunnerContext{}
+
+	writeTo(vm, VM.ExecutionSegment, 1, memory.MemoryValueFromInt(7))
+	require.NoError(t, DefaultDictNew{defaultValue: Deref{FpCellRef(1)}, dst: ApCellRef(0)}.Execute(vm, &ctx))
+	dictStart := memory.MemoryValueFromSegmentAndOffset(2, 0)
+	assert.Equal(t, dictStart, readFrom(vm, VM.ExecutionSegment, 0))
+
+	// the previous value of a new key is the default one
+	write := DictWrite{dictPtr: Deref{FpCellRef(0)}, key: immediate(1), newValue: immediate(10)}
+	require.NoError(t, write.Execute(vm, &ctx))
+	assert.Equal(t, memory.MemoryValueFromInt(7), readFrom(vm, 2, 1))
+
+	// the next access must be right after
+	require.EqualError(t, write.Execute(vm, &ctx), "wrong dict pointer supplied, got 2:0, expected 2:3")
+	writeTo(vm, VM.ExecutionSegment, 2, memory.MemoryValueFromSegmentAndOffset(2, 3))
+
+	update := DictUpdate{
+		dictPtr: Deref{FpCellRef(2)}, key: immediate(1), prevValue: immediate(9), newValue: immediate(11),
+	}
+	require.EqualError(t, update.Execute(vm, &ctx), "wrong previous value in dict, got 9, expected 10")
+	update.prevValue = immediate(10)
+	require.NoError(t, update.Execute(vm, &ctx))
+
+	writeTo(vm, VM.ExecutionSegment, 3, memory.MemoryValueFromSegmentAndOffset(2, 6))
+	read := DictRead{dictPtr: Deref{FpCellRef(3)}, key: immediate(1), value: FpCellRef(4)}
+	require.NoError(t, read.Execute(vm, &ctx))
+	assert.Equal(t, memory.MemoryValueFromInt(11), readFrom(vm, VM.ExecutionSegment, 4))
+
+	writeTo(vm, VM.ExecutionSegment, 5, memory.MemoryValueFromSegmentAndOffset(2, 9))
+	read = DictRead{dictPtr: Deref{FpCellRef(5)}, key: immediate(5), value: FpCellRef(6)}
+	require.NoError(t, read.Execute(vm, &ctx))
+	assert.Equal(t, memory.MemoryValueFromInt(7), readFrom(vm, VM.ExecutionSegment, 6))
+
+	// squashing copies the values into a dictionary without default value
+	writeTo(vm, VM.ExecutionSegment, 7, memory.MemoryValueFromSegmentAndOffset(2, 12))
+	require.NoError(t, DictSquashCopyDict{dictAccessesEnd: Deref{FpCellRef(7)}}.Execute(vm, &ctx))
+	assert.Equal(t, map[f.Element]memory.MemoryValue{
+		f.NewElement(1): memory.MemoryValueFromInt(11),
+		f.NewElement(5): memory.MemoryValueFromInt(7),
+	}, ctx.InitialDict)
+
+	vm.Context.Ap = 8
+	require.NoError(t, DictNew{dst: ApCellRef(0)}.Execute(vm, &ctx))
+	assert.Nil(t, ctx.InitialDict)
+	require.EqualError(t, DictNew{dst: ApCellRef(0)}.Execute(vm, &ctx), "initial_dict is not in scope")
+	assert.Equal(t, memory.MemoryValueFromSegmentAndOffset(3, 0), readFrom(vm, VM.ExecutionSegment, 8))
+
+	writeTo(vm, VM.ExecutionSegment, 9, memory.MemoryValueFromSegmentAndOffset(3, 6))
+	updatePtr := DictSquashUpdatePtr{squashedDictStart: Deref{FpCellRef(8)}, squashedDictEnd: Deref{FpCellRef(9)}}
+	require.NoError(t, updatePtr.Execute(vm, &ctx))
+
+	read = DictRead{dictPtr: Deref{FpCellRef(9)}, key: immediate(9), value: FpCellRef(10)}
+	require.EqualError(t, read.Execute(vm, &ctx), "key 9 is not in the dictionary")
+	read.key = immediate(5)
+	require.NoError(t, read.Execute(vm, &ctx))
+	assert.Equal(t, memory.MemoryValueFromInt(7), readFrom(vm, VM.ExecutionSegment, 10))
+}
+
+func TestSquashDictInnerAsserts(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 0
+	vm.Context.Fp = 0
+	ctx := HintRunnerContext{}
+	ctx.SquashedDictionaryManager.Initialize([]f.Element{f.NewElement(3), f.NewElement(1), f.NewElement(3)})
+
+	require.EqualError(t, SquashDictInnerAssertLenKeys{}.Execute(vm, &ctx), "1 keys are left to squash")
+	require.NoError(t, SquashDictInnerLenAssert{}.Execute(vm, &ctx))
+	used := SquashDictInnerUsedAccessesAssert{nUsedAccesses: immediate(2)}
+	require.EqualError(t, used.Execute(vm, &ctx), "key 1 has 1 accesses but 2 were used")
+
+	// key 3 has two accesses
+	_, err := ctx.SquashedDictionaryManager.PopKey()
+	require.NoError(t, err)
+	require.EqualError(t, SquashDictInnerLenAssert{}.Execute(vm, &ctx), "1 accesses of the key are left")
+	_, err = ctx.SquashedDictionaryManager.PopIndex()
+	require.NoError(t, err)
+	require.NoError(t, SquashDictInnerLenAssert{}.Execute(vm, &ctx))
+	require.NoError(t, used.Execute(vm, &ctx))
+	require.NoError(t, SquashDictInnerAssertLenKeys{}.Execute(vm, &ctx))
+}
@@ -1,9 +1,14 @@
 package zero
 
 import (
+	"bytes"
 	"encoding/json"
-	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
 )
 
 type FlowTrackingData struct {
@@ -71,7 +76,7 @@ type ZeroProgram struct {
 	Data             []string                 `json:"data"`
 	Builtins         []starknetParser.Builtin `json:"builtins"`
 	Hints            map[string][]Hint        `json:"hints"`
-	CompilerVersion  string                   `json:"version"`
+	CompilerVersion  string                   `json:"compiler_version"`
 	MainScope        string                   `json:"main_scope"`
 	Identifiers      map[string]any           `json:"identifiers"`
 	ReferenceManager ReferenceManager         `json:"reference_manager"`
@@ -103,7 +108,50 @@ func ZeroProgramFromFile(filepath string) (zero *ZeroProgram, err error) {
 	return ZeroProgramFromJSON(content)
 }
 
+// Numbers inside untyped fields such as the identifiers are kept as
+// json.Number, since constants don't fit in a float64. Errors if the program
+// was compiled by an unsupported compiler, see CheckCompilerVersion
 func ZeroProgramFromJSON(content json.RawMessage) (*ZeroProgram, error) {
 	var zero ZeroProgram
-	return &zero, json.Unmarshal(content, &zero)
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&zero); err != nil {
+		return nil, err
+	}
+	if err := CheckCompilerVersion(zero.CompilerVersion); err != nil {
+		return nil, err
+	}
+	return &zero, nil
+}
+
+// Cairo zero programs are compiled by the 0.x releases of cairo-lang. Newer
+// major versions compile cairo 1 into a different format
+const supportedCompilerMajor = 0
+
+// Checks the compiler_version field of a program. Compilers older than 0.10
+// don't write it, so programs without a version are accepted. Programs of
+// every 0.x release are decoded the same way
+func CheckCompilerVersion(version string) error {
+	if version == "" {
+		return nil
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 {
+		return fmt.Errorf("invalid compiler version %q", version)
+	}
+	numbers := make([]uint64, len(parts))
+	for i, part := range parts {
+		number, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid compiler version %q", version)
+		}
+		numbers[i] = number
+	}
+	if numbers[0] != supportedCompilerMajor {
+		return fmt.Errorf(
+			"unsupported compiler version %s: only cairo zero programs compiled by cairo-lang %d.x are supported",
+			version, supportedCompilerMajor,
+		)
+	}
+	return nil
 }
@@ -1,6 +1,8 @@
 package zero
 
 import (
+	"encoding/json"
+	"fmt"
 	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
 	"testing"
 
@@ -139,7 +141,7 @@ func TestHints(t *testing.T) {
 func TestCompilerVersion(t *testing.T) {
 	content := []byte(`
         {
-            "prime": "0x05"
+            "compiler_version": "0.12.2"
         }
     `)
 	zeroProgram, err := ZeroProgramFromJSON(content)
@@ -148,12 +150,74 @@ func TestCompilerVersion(t *testing.T) {
 	require.Equal(
 		t,
 		&ZeroProgram{
-			Prime: "0x05",
+			CompilerVersion: "0.12.2",
 		},
 		zeroProgram,
 	)
 }
 
+func TestCompilerVersionLayouts(t *testing.T) {
+	// written by compilers older than 0.10, which have no version
+	legacy := []byte(`
+        {
+            "prime": "0x800000000000011000000000000000000000000000000000000000000000001",
+            "data": ["0x480680017fff8000", "0x2", "0x208b7fff7fff7ffe"],
+            "builtins": [],
+            "hints": {},
+            "main_scope": "__main__",
+            "identifiers": {
+                "__main__.main": {"decorators": [], "pc": 0, "type": "function"}
+            },
+            "reference_manager": {"references": []},
+            "attributes": [],
+            "debug_info": null
+        }
+    `)
+	current := []byte(`
+        {
+            "prime": "0x800000000000011000000000000000000000000000000000000000000000001",
+            "compiler_version": "0.12.2",
+            "data": ["0x480680017fff8000", "0x2", "0x208b7fff7fff7ffe"],
+            "builtins": [],
+            "hints": {},
+            "main_scope": "__main__",
+            "identifiers": {
+                "__main__.main": {"decorators": [], "pc": 0, "type": "function"}
+            },
+            "reference_manager": {"references": []},
+            "attributes": [],
+            "debug_info": null
+        }
+    `)
+
+	legacyProgram, err := ZeroProgramFromJSON(legacy)
+	require.NoError(t, err)
+	currentProgram, err := ZeroProgramFromJSON(current)
+	require.NoError(t, err)
+
+	require.Equal(t, "0.12.2", currentProgram.CompilerVersion)
+	currentProgram.CompilerVersion = ""
+	require.Equal(t, legacyProgram, currentProgram)
+}
+
+func TestUnsupportedCompilerVersion(t *testing.T) {
+	_, err := ZeroProgramFromJSON([]byte(`{"compiler_version": "2.1.0"}`))
+	require.EqualError(
+		t, err,
+		"unsupported compiler version 2.1.0: only cairo zero programs compiled by cairo-lang 0.x are supported",
+	)
+
+	_, err = ZeroProgramFromJSON([]byte(`{"compiler_version": "0.11.0.2"}`))
+	require.NoError(t, err)
+
+	require.NoError(t, CheckCompilerVersion(""))
+	for _, version := range []string{"0", "0.x", "v0.12.0"} {
+		require.EqualError(
+			t, CheckCompilerVersion(version), fmt.Sprintf("invalid compiler version %q", version),
+		)
+	}
+}
+
 func TestMainScope(t *testing.T) {
 	content := []byte(`
         {
@@ -223,7 +287,7 @@ func TestIdentifiers(t *testing.T) {
 			Identifiers: map[string]any{
 				"__main__.fib": map[string]any{
 					"decorators": make([]any, 0),
-					"pc":         float64(9),
+					"pc":         json.Number("9"),
 					"type":       "function",
 				},
 				"__main__.BitwiseBuiltin": map[string]any{
@@ -235,10 +299,10 @@ func TestIdentifiers(t *testing.T) {
 					"members": map[string]any{
 						"array": map[string]any{
 							"cairo_type": "felt*",
-							"offset":     float64(0),
+							"offset":     json.Number("0"),
 						},
 					},
-					"size": float64(1),
+					"size": json.Number("1"),
 					"type": "struct",
 				},
 				"__main__.fill_array.__temp18": map[string]any{
@@ -247,10 +311,10 @@ func TestIdentifiers(t *testing.T) {
 					"references": []any{
 						map[string]any{
 							"ap_tracking_data": map[string]any{
-								"group":  float64(26),
-								"offset": float64(1),
+								"group":  json.Number("26"),
+								"offset": json.Number("1"),
 							},
-							"pc":    float64(312),
+							"pc":    json.Number("312"),
 							"value": "[cast(ap + (-1), felt*)]",
 						},
 					},
@@ -0,0 +1,143 @@
+package cairo1
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NethermindEth/cairo-vm-go/pkg/safemath"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+)
+
+const (
+	// discriminants of the variants of the PanicResult enum
+	panicResultOk  = 0
+	panicResultErr = 1
+	// the Err variant holds the panic reason as an Array<felt252>, i.e. the
+	// pointers to its start and end
+	panicReasonSize = 2
+)
+
+// The PanicResult enum a Cairo 1 main returns. It is laid out as its
+// discriminant followed by the payload of its variant, padded on the left to
+// the size of the biggest one. The Ok variant holds the values main returns,
+// the Err variant the span of felts main panicked with
+type PanicResult struct {
+	memory *memory.Memory
+	// ap once main returned, right past the enum
+	ap memory.MemoryAddress
+	// cells taken by the values main returns
+	valuesSize uint64
+}
+
+// Decodes the PanicResult main returned before ap, valuesSize being the
+// amount of cells its return type takes
+func NewPanicResult(mem *memory.Memory, ap memory.MemoryAddress, valuesSize uint64) *PanicResult {
+	return &PanicResult{memory: mem, ap: ap, valuesSize: valuesSize}
+}
+
+// Returns the values main returned. Errors with a PanicError holding the
+// panic reason if main panicked
+func (result *PanicResult) GetReturnValues() ([]f.Element, error) {
+	enumSize := 1 + safemath.Max(result.valuesSize, panicReasonSize)
+	if enumSize > result.ap.Offset {
+		return nil, fmt.Errorf("the %d cells panic result doesn't fit below ap %s", enumSize, result.ap)
+	}
+	start := result.ap.Offset - enumSize
+
+	discriminant, err := result.felt(start)
+	if err != nil {
+		return nil, fmt.Errorf("panic result discriminant: %w", err)
+	}
+	switch {
+	case discriminant.IsUint64() && discriminant.Uint64() == panicResultOk:
+		values := make([]f.Element, result.valuesSize)
+		for i := range values {
+			value, err := result.felt(result.ap.Offset - result.valuesSize + uint64(i))
+			if err != nil {
+				return nil, fmt.Errorf("return value %d: %w", i, err)
+			}
+			values[i] = *value
+		}
+		return values, nil
+	case discriminant.IsUint64() && discriminant.Uint64() == panicResultErr:
+		reason, err := result.panicReason()
+		if err != nil {
+			return nil, err
+		}
+		return nil, &PanicError{Reason: reason}
+	default:
+		return nil, fmt.Errorf("invalid panic result discriminant %s", discriminant.Text(10))
+	}
+}
+
+// Reads the felts of the Array<felt252> held by the Err variant
+func (result *PanicResult) panicReason() ([]f.Element, error) {
+	pointers := make([]*memory.MemoryAddress, panicReasonSize)
+	for i := range pointers {
+		value, err := result.cell(result.ap.Offset - panicReasonSize + uint64(i))
+		if err != nil {
+			return nil, fmt.Errorf("panic reason: %w", err)
+		}
+		pointers[i], err = value.ToMemoryAddress()
+		if err != nil {
+			return nil, fmt.Errorf("panic reason: %w", err)
+		}
+	}
+	start, end := pointers[0], pointers[1]
+	if start.SegmentIndex != end.SegmentIndex || start.Offset > end.Offset {
+		return nil, fmt.Errorf("invalid panic reason span [%s, %s)", start, end)
+	}
+
+	reason := make([]f.Element, end.Offset-start.Offset)
+	for i := range reason {
+		address := memory.MemoryAddress{SegmentIndex: start.SegmentIndex, Offset: start.Offset + uint64(i)}
+		value, err := result.memory.PeekFromAddress(&address)
+		if err != nil {
+			return nil, fmt.Errorf("panic reason: %w", err)
+		}
+		if !value.Known() {
+			return nil, fmt.Errorf("panic reason: cell %s is unknown", address)
+		}
+		felt, err := value.ToFieldElement()
+		if err != nil {
+			return nil, fmt.Errorf("panic reason: %w", err)
+		}
+		reason[i] = *felt
+	}
+	return reason, nil
+}
+
+// Reads a known cell of the ap segment
+func (result *PanicResult) cell(offset uint64) (memory.MemoryValue, error) {
+	value, err := result.memory.Peek(result.ap.SegmentIndex, offset)
+	if err != nil {
+		return memory.MemoryValue{}, err
+	}
+	if !value.Known() {
+		address := memory.MemoryAddress{SegmentIndex: result.ap.SegmentIndex, Offset: offset}
+		return memory.MemoryValue{}, fmt.Errorf("cell %s is unknown", address)
+	}
+	return value, nil
+}
+
+func (result *PanicResult) felt(offset uint64) (*f.Element, error) {
+	value, err := result.cell(offset)
+	if err != nil {
+		return nil, err
+	}
+	return value.ToFieldElement()
+}
+
+// The felts a Cairo 1 main panicked with, usually short strings
+type PanicError struct {
+	Reason []f.Element
+}
+
+func (e *PanicError) Error() string {
+	reason := make([]string, len(e.Reason))
+	for i := range e.Reason {
+		reason[i] = memory.FeltString(&e.Reason[i], 16)
+	}
+	return fmt.Sprintf("panicked with [%s]", strings.Join(reason, ", "))
+}
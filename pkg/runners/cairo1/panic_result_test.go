@@ -0,0 +1,136 @@
+package cairo1
+
+import (
+	"testing"
+
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Writes the cells of the stack at the start of segment 1 and returns the ap
+// right past them. The panic reason, if any, lives in segment 2
+func panicResultMemory(t *testing.T, stack ...memory.MemoryValue) (*memory.Memory, memory.MemoryAddress) {
+	t.Helper()
+	mem := memory.InitializeEmptyMemory()
+	for i := 0; i < 3; i++ {
+		mem.AllocateEmptySegment()
+	}
+	for i := range stack {
+		if stack[i].Known() {
+			require.NoError(t, mem.Write(1, uint64(i), &stack[i]))
+		}
+	}
+	return mem, memory.MemoryAddress{SegmentIndex: 1, Offset: uint64(len(stack))}
+}
+
+func felts(values ...uint64) []f.Element {
+	elements := make([]f.Element, len(values))
+	for i := range values {
+		elements[i].SetUint64(values[i])
+	}
+	return elements
+}
+
+func TestPanicResultOk(t *testing.T) {
+	// a single value is padded to the size of the panic reason
+	mem, ap := panicResultMemory(
+		t, memory.MemoryValueFromInt(5), memory.MemoryValueFromInt(0),
+		memory.MemoryValueFromInt(0), memory.MemoryValueFromInt(7),
+	)
+	values, err := NewPanicResult(mem, ap, 1).GetReturnValues()
+	require.NoError(t, err)
+	assert.Equal(t, felts(7), values)
+
+	mem, ap = panicResultMemory(
+		t, memory.MemoryValueFromInt(0), memory.MemoryValueFromInt(1),
+		memory.MemoryValueFromInt(2), memory.MemoryValueFromInt(3),
+	)
+	values, err = NewPanicResult(mem, ap, 3).GetReturnValues()
+	require.NoError(t, err)
+	assert.Equal(t, felts(1, 2, 3), values)
+
+	// main returns nothing
+	mem, ap = panicResultMemory(
+		t, memory.MemoryValueFromInt(0), memory.MemoryValueFromInt(0), memory.MemoryValueFromInt(0),
+	)
+	values, err = NewPanicResult(mem, ap, 0).GetReturnValues()
+	require.NoError(t, err)
+	assert.Empty(t, values)
+}
+
+func TestPanicResultErr(t *testing.T) {
+	mem, ap := panicResultMemory(
+		t, memory.MemoryValueFromInt(1),
+		memory.MemoryValueFromSegmentAndOffset(2, 0), memory.MemoryValueFromSegmentAndOffset(2, 2),
+	)
+	reason := []memory.MemoryValue{memory.MemoryValueFromInt(0x6f6f7073), memory.MemoryValueFromInt(42)}
+	require.NoError(t, mem.Write(2, 0, &reason[0]))
+	require.NoError(t, mem.Write(2, 1, &reason[1]))
+
+	_, err := NewPanicResult(mem, ap, 1).GetReturnValues()
+	var panicErr *PanicError
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, felts(0x6f6f7073, 42), panicErr.Reason)
+	assert.EqualError(t, err, "panicked with [0x6f6f7073, 0x2a]")
+
+	// the padding is on the left of the panic reason
+	mem, ap = panicResultMemory(
+		t, memory.MemoryValueFromInt(1), memory.MemoryValueFromInt(0),
+		memory.MemoryValueFromSegmentAndOffset(2, 0), memory.MemoryValueFromSegmentAndOffset(2, 0),
+	)
+	_, err = NewPanicResult(mem, ap, 3).GetReturnValues()
+	require.ErrorAs(t, err, &panicErr)
+	assert.Empty(t, panicErr.Reason)
+}
+
+func TestPanicResultInvalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		stack []memory.MemoryValue
+		err   string
+	}{
+		"too short": {
+			stack: []memory.MemoryValue{memory.MemoryValueFromInt(0), memory.MemoryValueFromInt(0)},
+			err:   "the 3 cells panic result doesn't fit below ap 1:2",
+		},
+		"invalid discriminant": {
+			stack: []memory.MemoryValue{
+				memory.MemoryValueFromInt(2), memory.MemoryValueFromInt(0), memory.MemoryValueFromInt(0),
+			},
+			err: "invalid panic result discriminant 2",
+		},
+		"unknown value": {
+			stack: []memory.MemoryValue{
+				memory.MemoryValueFromInt(0), memory.MemoryValueFromInt(0), {},
+			},
+			err: "return value 0: cell 1:2 is unknown",
+		},
+		"felt panic reason": {
+			stack: []memory.MemoryValue{
+				memory.MemoryValueFromInt(1), memory.MemoryValueFromInt(0), memory.MemoryValueFromInt(0),
+			},
+			err: "panic reason: ",
+		},
+		"reversed panic reason": {
+			stack: []memory.MemoryValue{
+				memory.MemoryValueFromInt(1),
+				memory.MemoryValueFromSegmentAndOffset(2, 1), memory.MemoryValueFromSegmentAndOffset(2, 0),
+			},
+			err: "invalid panic reason span [2:1, 2:0)",
+		},
+		"unknown panic reason": {
+			stack: []memory.MemoryValue{
+				memory.MemoryValueFromInt(1),
+				memory.MemoryValueFromSegmentAndOffset(2, 0), memory.MemoryValueFromSegmentAndOffset(2, 1),
+			},
+			err: "panic reason: cell 2:0 is unknown",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mem, ap := panicResultMemory(t, tc.stack...)
+			_, err := NewPanicResult(mem, ap, 1).GetReturnValues()
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
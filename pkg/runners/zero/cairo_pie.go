@@ -0,0 +1,227 @@
+package zero
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
+	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+)
+
+const cairoPieVersion = "1.1"
+
+type pieSegmentInfo struct {
+	Index uint64 `json:"index"`
+	Size  uint64 `json:"size"`
+}
+
+type pieProgram struct {
+	Prime    string   `json:"prime"`
+	Data     []string `json:"data"`
+	Builtins []string `json:"builtins"`
+	Main     uint64   `json:"main"`
+}
+
+type pieMetadata struct {
+	Program          pieProgram                `json:"program"`
+	ProgramSegment   pieSegmentInfo            `json:"program_segment"`
+	ExecutionSegment pieSegmentInfo            `json:"execution_segment"`
+	RetFpSegment     pieSegmentInfo            `json:"ret_fp_segment"`
+	RetPcSegment     pieSegmentInfo            `json:"ret_pc_segment"`
+	BuiltinSegments  map[string]pieSegmentInfo `json:"builtin_segments"`
+	ExtraSegments    []pieSegmentInfo          `json:"extra_segments"`
+}
+
+// The execution resources of the PIE key their builtins by runner name,
+// e.g. output_builtin, unlike the metadata
+type pieExecutionResources struct {
+	NSteps                 uint64            `json:"n_steps"`
+	BuiltinInstanceCounter map[string]uint64 `json:"builtin_instance_counter"`
+	NMemoryHoles           uint64            `json:"n_memory_holes"`
+}
+
+// Implemented by builtin runners holding data the Cairo PIE keeps, e.g. the
+// hashes a pedersen runner verified. The value is encoded as json the way
+// the reference toolchain does
+type PieAdditionalDataProvider interface {
+	PieAdditionalData() any
+}
+
+// Builds the zip based Cairo PIE (Program Independent Execution) artifact of a
+// finished run, as produced by the reference toolchain. The program must have
+// been run from its main entrypoint outside of proof mode
+func (runner *ZeroRunner) BuildCairoPie() ([]byte, error) {
+	if runner.proofmode {
+		return nil, errors.New("cairo pie cannot be built in proof mode")
+	}
+	if runner.retPcSegment == 0 {
+		return nil, errors.New("cairo pie requires running the main entrypoint first")
+	}
+	// a pie declares a builtin segment for each builtin of the program
+	if len(runner.hintedBuiltins) > 0 {
+		return nil, fmt.Errorf("cairo pie cannot hold the %s builtin computed through hints", runner.hintedBuiltins[0])
+	}
+
+	metadata, err := runner.pieMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := runner.ExecutionResources()
+	if err != nil {
+		return nil, err
+	}
+	pieResources := pieExecutionResources{
+		NSteps:                 resources.NSteps,
+		BuiltinInstanceCounter: make(map[string]uint64, len(resources.BuiltinInstanceCounter)),
+		NMemoryHoles:           resources.NMemoryHoles,
+	}
+	for name, count := range resources.BuiltinInstanceCounter {
+		pieResources.BuiltinInstanceCounter[pieBuiltinName(name)] = count
+	}
+
+	additionalData, err := runner.pieAdditionalData()
+	if err != nil {
+		return nil, err
+	}
+	version := map[string]string{"cairo_pie": cairoPieVersion}
+
+	files := []struct {
+		name    string
+		content any
+	}{
+		{"metadata.json", metadata},
+		{"additional_data.json", additionalData},
+		{"execution_resources.json", pieResources},
+		{"version.json", version},
+	}
+
+	buffer := new(bytes.Buffer)
+	archive := zip.NewWriter(buffer)
+	if err := writeZipFile(archive, "memory.bin", EncodeRelocatableMemory(runner.memory())); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		content, err := json.Marshal(file.content)
+		if err != nil {
+			return nil, fmt.Errorf("encoding %s: %w", file.name, err)
+		}
+		if err := writeZipFile(archive, file.name, content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func (runner *ZeroRunner) pieMetadata() (*pieMetadata, error) {
+	segments := runner.segments()
+	segmentInfo := func(index uint64) pieSegmentInfo {
+		return pieSegmentInfo{Index: index, Size: segments[index].Len()}
+	}
+
+	data := make([]string, segments[VM.ProgramSegment].Len())
+	for i := range data {
+		value, err := segments[VM.ProgramSegment].Read(uint64(i))
+		if err != nil {
+			return nil, fmt.Errorf("reading program: %w", err)
+		}
+		felt, err := value.ToFieldElement()
+		if err != nil {
+			return nil, fmt.Errorf("reading program: %w", err)
+		}
+		data[i] = "0x" + felt.Text(16)
+	}
+
+	mainPc, ok := runner.program.Entrypoints["main"]
+	if !ok {
+		return nil, errors.New("unknown entrypoint: main")
+	}
+
+	builtinNames := runner.program.Builtins()
+	builtinSegments := make(map[string]pieSegmentInfo, len(builtinNames))
+	for _, name := range builtinNames {
+		index, err := runner.builtinSegmentIndex(name)
+		if err != nil {
+			return nil, err
+		}
+		builtinSegments[name] = segmentInfo(uint64(index))
+	}
+
+	extraSegments := make([]pieSegmentInfo, 0)
+	// builtin segments are allocated right after the execution segment
+	firstExtra := uint64(VM.ExecutionSegment + 1 + len(runner.program.builtins))
+	for i := firstExtra; i < uint64(len(segments)); i++ {
+		if i == runner.retFpSegment || i == runner.retPcSegment {
+			continue
+		}
+		extraSegments = append(extraSegments, segmentInfo(i))
+	}
+
+	return &pieMetadata{
+		Program: pieProgram{
+			Prime:    "0x" + f.Modulus().Text(16),
+			Data:     data,
+			Builtins: builtinNames,
+			Main:     mainPc,
+		},
+		ProgramSegment:   segmentInfo(VM.ProgramSegment),
+		ExecutionSegment: segmentInfo(VM.ExecutionSegment),
+		RetFpSegment:     segmentInfo(runner.retFpSegment),
+		RetPcSegment:     segmentInfo(runner.retPcSegment),
+		BuiltinSegments:  builtinSegments,
+		ExtraSegments:    extraSegments,
+	}, nil
+}
+
+// Returns the additional data of every builtin of the program, keyed by
+// runner name. Without a PieAdditionalDataProvider, the output has no pages
+// nor attributes, pedersen and ecdsa verified nothing and the other builtins
+// have none, as null
+func (runner *ZeroRunner) pieAdditionalData() (map[string]any, error) {
+	additionalData := make(map[string]any, len(runner.program.builtins))
+	for _, builtin := range runner.program.builtins {
+		index, err := runner.builtinSegmentIndex(builtin.String())
+		if err != nil {
+			return nil, err
+		}
+		name := pieBuiltinName(builtin.String())
+		if provider, ok := runner.segments()[index].BuiltinRunner.(PieAdditionalDataProvider); ok {
+			additionalData[name] = provider.PieAdditionalData()
+			continue
+		}
+		switch builtin {
+		case starknetParser.Output:
+			additionalData[name] = map[string]any{"pages": map[string]any{}, "attributes": map[string]any{}}
+		case starknetParser.Pedersen, starknetParser.ECDSA:
+			additionalData[name] = []any{}
+		default:
+			additionalData[name] = nil
+		}
+	}
+	return additionalData, nil
+}
+
+// the name of the builtin runner in the reference toolchain, e.g.
+// range_check_builtin
+func pieBuiltinName(name string) string {
+	return name + "_builtin"
+}
+
+func writeZipFile(archive *zip.Writer, name string, content []byte) error {
+	writer, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", name, err)
+	}
+	if _, err := writer.Write(content); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package zero
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/builtins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCairoPie(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = 2, ap++;
+        [ap] = 3, ap++;
+        ret;
+    `)
+	program.builtins = []starknetParser.Builtin{starknetParser.RangeCheck}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+
+	_, err = runner.BuildCairoPie()
+	require.ErrorContains(t, err, "running the main entrypoint first")
+
+	require.NoError(t, runner.Run())
+	pie, err := runner.BuildCairoPie()
+	require.NoError(t, err)
+
+	files := readZip(t, pie)
+	assert.Len(t, files, 5)
+
+	var metadata pieMetadata
+	require.NoError(t, json.Unmarshal(files["metadata.json"], &metadata))
+	assert.Equal(t, pieMetadata{
+		Program: pieProgram{
+			Prime:    "0x800000000000011000000000000000000000000000000000000000000000001",
+			Data:     []string{"0x480680017fff8000", "0x2", "0x480680017fff8000", "0x3", "0x208b7fff7fff7ffe"},
+			Builtins: []string{"range_check"},
+			Main:     0,
+		},
+		ProgramSegment:   pieSegmentInfo{Index: 0, Size: 5},
+		ExecutionSegment: pieSegmentInfo{Index: 1, Size: 5},
+		RetFpSegment:     pieSegmentInfo{Index: 3, Size: 0},
+		RetPcSegment:     pieSegmentInfo{Index: 4, Size: 0},
+		BuiltinSegments:  map[string]pieSegmentInfo{"range_check": {Index: 2, Size: 0}},
+		ExtraSegments:    []pieSegmentInfo{},
+	}, metadata)
+
+	var resources pieExecutionResources
+	require.NoError(t, json.Unmarshal(files["execution_resources.json"], &resources))
+	assert.Equal(t, uint64(3), resources.NSteps)
+	assert.Equal(t, uint64(0), resources.NMemoryHoles)
+	assert.Equal(t, map[string]uint64{"range_check_builtin": 0}, resources.BuiltinInstanceCounter)
+
+	assert.JSONEq(t, `{"cairo_pie": "1.1"}`, string(files["version.json"]))
+	assert.JSONEq(t, `{"range_check_builtin": null}`, string(files["additional_data.json"]))
+
+	// 5 program cells and 5 execution cells
+	memoryBin := files["memory.bin"]
+	require.Len(t, memoryBin, 10*(addrSize+feltSize))
+
+	// the execution cell after the range check base is the return fp,
+	// which points to segment 3
+	entry := memoryBin[6*(addrSize+feltSize):]
+	assert.Equal(t, uint64(1)<<63|uint64(1)<<47|1, binary.LittleEndian.Uint64(entry[:addrSize]))
+	expectedValue := make([]byte, feltSize)
+	expectedValue[feltSize-1] = 0x80
+	expectedValue[5] = 3 << 7 & 0xff
+	expectedValue[6] = 3 >> 1
+	assert.Equal(t, expectedValue, entry[addrSize:addrSize+feltSize])
+}
+
+// The builtin files of a PIE with output and range check, laid out as the
+// reference toolchain writes them
+func TestBuildCairoPieBuiltinFiles(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = 7, ap++;
+        [ap - 1] = [[fp - 4]];
+        ret;
+    `)
+	program.builtins = []starknetParser.Builtin{starknetParser.Output, starknetParser.RangeCheck}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.Run())
+	pie, err := runner.BuildCairoPie()
+	require.NoError(t, err)
+
+	files := readZip(t, pie)
+	for _, name := range []string{"additional_data.json", "execution_resources.json"} {
+		expected, err := os.ReadFile(filepath.Join("testdata", "cairo_pie", name))
+		require.NoError(t, err)
+		assert.JSONEq(t, string(expected), string(files[name]), name)
+	}
+
+	// builtin runners can provide their own data
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.WithBuiltin("output", &pagedOutput{}))
+	require.NoError(t, runner.Run())
+	pie, err = runner.BuildCairoPie()
+	require.NoError(t, err)
+	assert.JSONEq(
+		t,
+		`{"output_builtin": {"pages": {"1": [0, 1]}, "attributes": {}}, "range_check_builtin": null}`,
+		string(readZip(t, pie)["additional_data.json"]),
+	)
+}
+
+// An output runner with a single page holding the whole output
+type pagedOutput struct {
+	builtins.Output
+}
+
+func (o *pagedOutput) PieAdditionalData() any {
+	return map[string]any{"pages": map[string][]int{"1": {0, 1}}, "attributes": map[string]any{}}
+}
+
+func TestBuildCairoPieProofMode(t *testing.T) {
+	program := createDefaultProgram(`ret;`)
+	program.Labels = map[string]uint64{"__start__": 0, "__end__": 0}
+	runner, err := NewRunner(program, true, math.MaxUint64)
+	require.NoError(t, err)
+
+	_, err = runner.BuildCairoPie()
+	require.ErrorContains(t, err, "proof mode")
+}
+
+func readZip(t *testing.T, content []byte) map[string][]byte {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+
+	files := make(map[string][]byte)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		files[file.Name] = data
+	}
+	return files
+}
@@ -0,0 +1,111 @@
+package zero
+
+import (
+	"fmt"
+
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+)
+
+// A step where two traces disagree. Either context is nil when its
+// trace is shorter than the step
+type TraceDiff struct {
+	Step   uint64
+	Ours   *vm.Trace
+	Theirs *vm.Trace
+}
+
+func (diff TraceDiff) String() string {
+	switch {
+	case diff.Ours == nil:
+		return fmt.Sprintf("step %d missing in ours, theirs %s", diff.Step, traceRepr(diff.Theirs))
+	case diff.Theirs == nil:
+		return fmt.Sprintf("step %d missing in theirs, ours %s", diff.Step, traceRepr(diff.Ours))
+	case diff.Ours.Pc != diff.Theirs.Pc:
+		return fmt.Sprintf("step %d pc mismatch %d vs %d", diff.Step, diff.Ours.Pc, diff.Theirs.Pc)
+	case diff.Ours.Ap != diff.Theirs.Ap:
+		return fmt.Sprintf("step %d ap mismatch %d vs %d", diff.Step, diff.Ours.Ap, diff.Theirs.Ap)
+	default:
+		return fmt.Sprintf("step %d fp mismatch %d vs %d", diff.Step, diff.Ours.Fp, diff.Theirs.Fp)
+	}
+}
+
+// A relocated address where two memories disagree. A nil value means
+// the address is unknown in that memory
+type MemoryDiff struct {
+	Address uint64
+	Ours    *f.Element
+	Theirs  *f.Element
+}
+
+// Formats the diff with decimal felts
+func (diff MemoryDiff) String() string {
+	return diff.Text(10)
+}
+
+// Formats the diff with felts in radix, see memory.FeltString
+func (diff MemoryDiff) Text(radix int) string {
+	return fmt.Sprintf(
+		"address %d mismatch %s vs %s",
+		diff.Address, feltRepr(diff.Ours, radix), feltRepr(diff.Theirs, radix),
+	)
+}
+
+// Compares two relocated traces and returns the first step where they differ.
+// Only the first step is reported since any divergence makes every following
+// step differ as well. Returns nil if both traces are equal
+func DiffTrace(ours, theirs []vm.Trace) []TraceDiff {
+	for step := 0; step < len(ours) || step < len(theirs); step++ {
+		diff := TraceDiff{Step: uint64(step)}
+		if step < len(ours) {
+			diff.Ours = &ours[step]
+		}
+		if step < len(theirs) {
+			diff.Theirs = &theirs[step]
+		}
+		if diff.Ours == nil || diff.Theirs == nil || *diff.Ours != *diff.Theirs {
+			return []TraceDiff{diff}
+		}
+	}
+	return nil
+}
+
+// Compares two relocated memories and returns every address where they
+// differ, in ascending order. Returns nil if both memories are equal
+func DiffMemory(ours, theirs []*f.Element) []MemoryDiff {
+	var diffs []MemoryDiff
+	for addr := 0; addr < len(ours) || addr < len(theirs); addr++ {
+		var ourValue, theirValue *f.Element
+		if addr < len(ours) {
+			ourValue = ours[addr]
+		}
+		if addr < len(theirs) {
+			theirValue = theirs[addr]
+		}
+
+		if ourValue == nil && theirValue == nil {
+			continue
+		}
+		if ourValue != nil && theirValue != nil && ourValue.Equal(theirValue) {
+			continue
+		}
+		diffs = append(diffs, MemoryDiff{
+			Address: uint64(addr),
+			Ours:    ourValue,
+			Theirs:  theirValue,
+		})
+	}
+	return diffs
+}
+
+func traceRepr(trace *vm.Trace) string {
+	return fmt.Sprintf("{pc: %d, ap: %d, fp: %d}", trace.Pc, trace.Ap, trace.Fp)
+}
+
+func feltRepr(felt *f.Element, radix int) string {
+	if felt == nil {
+		return "unknown"
+	}
+	return memory.FeltString(felt, radix)
+}
@@ -0,0 +1,56 @@
+package zero
+
+import (
+	"testing"
+
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffTraceEqual(t *testing.T) {
+	trace := []vm.Trace{{Pc: 1, Ap: 2, Fp: 2}, {Pc: 2, Ap: 3, Fp: 2}}
+	assert.Nil(t, DiffTrace(trace, trace))
+}
+
+func TestDiffTraceFirstMismatch(t *testing.T) {
+	ours := []vm.Trace{{Pc: 1, Ap: 2, Fp: 2}, {Pc: 10, Ap: 3, Fp: 2}, {Pc: 12, Ap: 4, Fp: 2}}
+	theirs := []vm.Trace{{Pc: 1, Ap: 2, Fp: 2}, {Pc: 11, Ap: 3, Fp: 2}, {Pc: 13, Ap: 4, Fp: 2}}
+
+	diffs := DiffTrace(ours, theirs)
+	require.Len(t, diffs, 1)
+	assert.Equal(t, TraceDiff{Step: 1, Ours: &ours[1], Theirs: &theirs[1]}, diffs[0])
+	assert.Equal(t, "step 1 pc mismatch 10 vs 11", diffs[0].String())
+}
+
+func TestDiffTraceDifferentLength(t *testing.T) {
+	ours := []vm.Trace{{Pc: 1, Ap: 2, Fp: 2}}
+	theirs := []vm.Trace{{Pc: 1, Ap: 2, Fp: 2}, {Pc: 3, Ap: 3, Fp: 2}}
+
+	diffs := DiffTrace(ours, theirs)
+	require.Len(t, diffs, 1)
+	assert.Equal(t, uint64(1), diffs[0].Step)
+	assert.Nil(t, diffs[0].Ours)
+	assert.Equal(t, "step 1 missing in ours, theirs {pc: 3, ap: 3, fp: 2}", diffs[0].String())
+}
+
+func TestDiffMemory(t *testing.T) {
+	one := new(f.Element).SetUint64(1)
+	two := new(f.Element).SetUint64(2)
+	three := new(f.Element).SetUint64(3)
+
+	ours := []*f.Element{nil, one, two, nil}
+	theirs := []*f.Element{nil, one, three, nil, three}
+
+	diffs := DiffMemory(ours, theirs)
+	assert.Equal(t, []MemoryDiff{
+		{Address: 2, Ours: two, Theirs: three},
+		{Address: 4, Ours: nil, Theirs: three},
+	}, diffs)
+	assert.Equal(t, "address 4 mismatch unknown vs 3", diffs[1].String())
+
+	assert.Equal(t, "address 2 mismatch 0x2 vs 0x3", diffs[0].Text(16))
+
+	assert.Nil(t, DiffMemory(ours, ours))
+}
@@ -0,0 +1,121 @@
+package zero
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/NethermindEth/cairo-vm-go/pkg/hintrunner"
+)
+
+// Translates the hints of the program with parser, so they run before the
+// instruction at their pc. Without a parser the hints of the program are
+// ignored. Must be called before running
+func (runner *ZeroRunner) SetHintParser(parser hintrunner.HintParser) error {
+	pcs := make([]uint64, 0, len(runner.program.Hints))
+	for pc := range runner.program.Hints {
+		pcs = append(pcs, pc)
+	}
+	// report the first hint that fails in the program
+	sort.Slice(pcs, func(i, j int) bool { return pcs[i] < pcs[j] })
+
+	hints := make(map[uint64][]hintrunner.Hinter, len(pcs))
+	for _, pc := range pcs {
+		// the hints of a pc run in the order the program declares them
+		for i := range runner.program.Hints[pc] {
+			pcHint := &runner.program.Hints[pc][i]
+			if runner.allowedHints != nil && !runner.allowedHints[strings.TrimSpace(pcHint.Code)] {
+				return fmt.Errorf("hint at pc %d is not allowed: %s", pc, pcHint.Code)
+			}
+			hint, err := parser.Parse(pcHint.Code, runner.hintReferences(pcHint))
+			if err != nil {
+				return fmt.Errorf("hint at pc %d: %w", pc, err)
+			}
+			hints[pc] = append(hints[pc], hint)
+		}
+	}
+
+	hintRunner, err := runner.newHintRunner(hints)
+	if err != nil {
+		return err
+	}
+	runner.hints = hints
+	runner.hintrunner = hintRunner
+	return nil
+}
+
+// Restricts the hints SetHintParser accepts to the ones whose code is in
+// allowedHints, e.g. hintrunner.SupportedLibraryHints, so untrusted programs
+// can't run arbitrary hints. A nil list accepts any hint again. Must be called
+// before SetHintParser
+func (runner *ZeroRunner) SetAllowedHints(allowedHints []string) {
+	if allowedHints == nil {
+		runner.allowedHints = nil
+		return
+	}
+	runner.allowedHints = make(map[string]bool, len(allowedHints))
+	for _, code := range allowedHints {
+		runner.allowedHints[strings.TrimSpace(code)] = true
+	}
+}
+
+// Builds a hint runner for the program hints, with the oracles registered on
+// the ZeroRunner. Errors if a program hint shares the pc of an oracle
+func (runner *ZeroRunner) newHintRunner(programHints map[uint64][]hintrunner.Hinter) (hintrunner.HintRunner, error) {
+	hints := make(map[uint64][]hintrunner.Hinter, len(programHints))
+	for pc, pcHints := range programHints {
+		hints[pc] = append([]hintrunner.Hinter(nil), pcHints...)
+	}
+	hintRunner := hintrunner.NewHintRunner(hints)
+	hintRunner.SetFindElementMaxSize(runner.findElementMaxSize)
+	for pc, fn := range runner.oracles {
+		if err := hintRunner.RegisterOracle(pc, fn); err != nil {
+			return hintrunner.HintRunner{}, fmt.Errorf("oracle at pc %d: %w", pc, err)
+		}
+	}
+	return hintRunner, nil
+}
+
+// Resolves the ids a hint can access, as seen from its pc. Every reference
+// in scope is listed by the compiler, so the ones that can't be resolved,
+// including ids past the end of a truncated reference manager, are left out
+// and only fail the hints that use them
+func (runner *ZeroRunner) hintReferences(hint *Hint) hintrunner.HintReferences {
+	usedAt := hintrunner.ApTracking{Group: hint.ApTrackingGroup, Offset: hint.ApTrackingOffset}
+	references := make(hintrunner.HintReferences, len(hint.ReferenceIds))
+	for fullName, index := range hint.ReferenceIds {
+		if index >= uint64(len(runner.program.References)) {
+			continue
+		}
+		reference := runner.program.References[index]
+		definedAt := hintrunner.ApTracking{
+			Group: reference.ApTrackingGroup, Offset: reference.ApTrackingOffset,
+		}
+		operand, err := hintrunner.ParseReference(reference.Value, definedAt, usedAt)
+		if err != nil {
+			continue
+		}
+		name := fullName[strings.LastIndex(fullName, ".")+1:]
+		references[name] = operand
+	}
+
+	// constants aren't references, they are looked up from the scopes of
+	// the hint, e.g. N_PACKED_INSTANCES of the blake2s library
+	for _, match := range hintIdsPattern.FindAllStringSubmatch(hint.Code, -1) {
+		name := match[1]
+		if _, ok := references[name]; ok {
+			continue
+		}
+		identifier, err := runner.program.ResolveIdentifier(name, hint.AccessibleScopes)
+		if err != nil || identifier.Type != "const" {
+			continue
+		}
+		references[name] = hintrunner.Immediate(*identifier.Value.BigInt(new(big.Int)))
+	}
+	return references
+}
+
+// The names a hint accesses through ids, e.g. value for ids.value
+var hintIdsPattern = regexp.MustCompile(`\bids\.([A-Za-z_][A-Za-z0-9_]*)`)
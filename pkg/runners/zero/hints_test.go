@@ -0,0 +1,322 @@
+package zero
+
+import (
+	"math"
+	"testing"
+
+	"github.com/NethermindEth/cairo-vm-go/pkg/hintrunner"
+	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Writes 42 at ap, standing for a hint only an embedder knows
+type answerHint struct{}
+
+func (answerHint) String() string {
+	return "Answer"
+}
+
+func (answerHint) Execute(vm *VM.VirtualMachine, ctx *hintrunner.HintRunnerContext) error {
+	answer := memory.MemoryValueFromInt(42)
+	return vm.Memory.Write(VM.ExecutionSegment, vm.Context.Ap, &answer)
+}
+
+type answerParser struct {
+	hintrunner.StandardHintParser
+}
+
+func (parser answerParser) Parse(code string, references hintrunner.HintReferences) (hintrunner.Hinter, error) {
+	if code == "memory[ap] = compute_answer()" {
+		return answerHint{}, nil
+	}
+	return parser.StandardHintParser.Parse(code, references)
+}
+
+const findElementCode = `array_ptr = ids.array_ptr
+elm_size = ids.elm_size
+assert isinstance(elm_size, int) and elm_size > 0, \
+    f'Invalid value for elm_size. Got: {elm_size}.'
+key = ids.key
+
+if '__find_element_index' in globals():
+    ids.index = __find_element_index
+    found_key = memory[array_ptr + elm_size * __find_element_index]
+    assert found_key == key, \
+        f'Invalid index found in __find_element_index. index: {__find_element_index}, ' \
+        f'expected key {key}, found key: {found_key}.'
+    # Delete __find_element_index to make sure it's not used for the next calls.
+    del __find_element_index
+else:
+    n_elms = ids.n_elms
+    assert isinstance(n_elms, int) and n_elms >= 0, \
+        f'Invalid value for n_elms. Got: {n_elms}.'
+    if '__find_element_max_size' in globals():
+        assert n_elms <= __find_element_max_size, \
+            f'find_element() can only be used with n_elms<={__find_element_max_size}. ' \
+            f'Got: n_elms={n_elms}.'
+
+    for i in range(n_elms):
+        if memory[array_ptr + elm_size * i] == key:
+            ids.index = i
+            break
+    else:
+        raise ValueError(f'Key {key} was not found.')`
+
+const assertNotZeroCode = `from starkware.cairo.common.math_utils import assert_integer
+assert_integer(ids.value)
+assert ids.value % PRIME != 0, f'assert_not_zero failed: ids.value = {ids.value}.'`
+
+func TestSetHintParser(t *testing.T) {
+	program := createDefaultProgram(`
+        ap += 1;
+        ap += 1;
+        ret;
+    `)
+	program.Hints = map[uint64][]Hint{
+		0: {{Code: "memory[ap] = segments.add()"}},
+		2: {{Code: "memory[ap] = compute_answer()"}},
+	}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.EqualError(
+		t, runner.SetHintParser(hintrunner.StandardHintParser{}),
+		`hint at pc 2: unsupported assignment hint memory[ap] = compute_answer(): unexpected "compute_answer"`,
+	)
+	require.NoError(t, runner.SetHintParser(answerParser{}))
+	require.NoError(t, runner.Run())
+
+	allocated, err := runner.memory().Read(VM.ExecutionSegment, 2)
+	require.NoError(t, err)
+	lastSegment := len(runner.segments()) - 1
+	assert.Equal(t, memory.MemoryValueFromSegmentAndOffset(lastSegment, 0), allocated)
+	answer, err := runner.memory().Read(VM.ExecutionSegment, 3)
+	require.NoError(t, err)
+	assert.Equal(t, memory.MemoryValueFromInt(42), answer)
+}
+
+func TestSetHintParserReferences(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = 0, ap++;
+        ret;
+    `)
+	program.References = []Reference{{ApTrackingOffset: 1, Value: "[cast(ap + (-1), felt)]"}}
+	program.Hints = map[uint64][]Hint{
+		2: {{
+			Code:             assertNotZeroCode,
+			ApTrackingOffset: 1,
+			ReferenceIds:     map[string]uint64{"__main__.main.value": 0},
+		}},
+	}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.SetHintParser(hintrunner.StandardHintParser{}))
+	require.EqualError(
+		t, runner.Run(),
+		"pc 0:2 step 1: execute hint AssertNotZero: assert_not_zero failed: ids.value = 0",
+	)
+
+	// references the vm can't resolve are only an error for the hints using them
+	program.References[0].Value = "[cast(ap + (-1) + 2, felt)]"
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.EqualError(
+		t, runner.SetHintParser(hintrunner.StandardHintParser{}),
+		"hint at pc 2: missing reference ids.value",
+	)
+
+	// as are ids past the end of a truncated reference manager
+	program.References = nil
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.EqualError(
+		t, runner.SetHintParser(hintrunner.StandardHintParser{}),
+		"hint at pc 2: missing reference ids.value",
+	)
+}
+
+func TestSetHintParserConstants(t *testing.T) {
+	program := createDefaultProgram(`
+        ap += 1;
+        ret;
+    `)
+	size := f.NewElement(3)
+	program.Identifiers = map[string]Identifier{
+		"__main__.SIZE":      {Type: "const", Value: &size},
+		"__main__.main":      {Type: "function"},
+		"__main__.main.SIZE": {Type: "alias", Destination: "__main__.SIZE"},
+	}
+	program.Hints = map[uint64][]Hint{
+		0: {{Code: "memory[ap] = ids.SIZE * 2", AccessibleScopes: []string{"__main__", "__main__.main"}}},
+	}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.SetHintParser(hintrunner.StandardHintParser{}))
+	require.NoError(t, runner.Run())
+	value, err := runner.memory().Read(VM.ExecutionSegment, 2)
+	require.NoError(t, err)
+	assert.Equal(t, memory.MemoryValueFromInt(6), value)
+
+	// only constants are looked up
+	program.Hints[0][0].Code = "memory[ap] = ids.main"
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.EqualError(
+		t, runner.SetHintParser(hintrunner.StandardHintParser{}),
+		"hint at pc 0: unsupported assignment hint memory[ap] = ids.main: missing reference ids.main",
+	)
+}
+
+func TestSetAllowedHints(t *testing.T) {
+	program := createDefaultProgram(`
+        ap += 1;
+        ap += 1;
+        ret;
+    `)
+	program.Hints = map[uint64][]Hint{
+		0: {{Code: "memory[ap] = segments.add()"}},
+		2: {{Code: "memory[ap] = 42"}},
+	}
+
+	// assignments are arbitrary code, they aren't library hints
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	runner.SetAllowedHints(hintrunner.SupportedLibraryHints)
+	require.EqualError(
+		t, runner.SetHintParser(hintrunner.StandardHintParser{}),
+		"hint at pc 2 is not allowed: memory[ap] = 42",
+	)
+
+	delete(program.Hints, 2)
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	runner.SetAllowedHints(hintrunner.SupportedLibraryHints)
+	require.NoError(t, runner.SetHintParser(hintrunner.StandardHintParser{}))
+	require.NoError(t, runner.Run())
+
+	runner.SetAllowedHints([]string{})
+	require.EqualError(
+		t, runner.SetHintParser(hintrunner.StandardHintParser{}),
+		"hint at pc 0 is not allowed: memory[ap] = segments.add()",
+	)
+	runner.SetAllowedHints(nil)
+	require.NoError(t, runner.SetHintParser(hintrunner.StandardHintParser{}))
+}
+
+func TestFindElementMaxSize(t *testing.T) {
+	// the array, its element size, its length and the key are stored from fp
+	program := createDefaultProgram(`
+        [ap] = 5, ap++;
+        [ap] = 7, ap++;
+        [ap] = 9, ap++;
+        [ap] = 1, ap++;
+        [ap] = 3, ap++;
+        [ap] = 9, ap++;
+        ap += 1;
+        ret;
+    `)
+	program.References = []Reference{
+		{Value: "cast(fp, felt*)"},
+		{Value: "[cast(fp + 3, felt)]"},
+		{Value: "[cast(fp + 4, felt)]"},
+		{Value: "[cast(fp + 5, felt)]"},
+		{Value: "[cast(fp + 6, felt)]"},
+	}
+	program.Hints = map[uint64][]Hint{
+		12: {{
+			Code: findElementCode,
+			ReferenceIds: map[string]uint64{
+				"__main__.main.array_ptr": 0,
+				"__main__.main.elm_size":  1,
+				"__main__.main.n_elms":    2,
+				"__main__.main.key":       3,
+				"__main__.main.index":     4,
+			},
+		}},
+	}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.SetHintParser(hintrunner.StandardHintParser{}))
+	require.NoError(t, runner.Run())
+	index, err := runner.memory().Read(VM.ExecutionSegment, 8)
+	require.NoError(t, err)
+	assert.Equal(t, memory.MemoryValueFromInt(2), index)
+
+	// the limit holds whether it is set before or after parsing the hints
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	runner.SetFindElementMaxSize(2)
+	require.NoError(t, runner.SetHintParser(hintrunner.StandardHintParser{}))
+	require.ErrorContains(t, runner.Run(), "search can only be used with at most 2 elements, got 3")
+
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.SetHintParser(hintrunner.StandardHintParser{}))
+	runner.SetFindElementMaxSize(2)
+	require.ErrorContains(t, runner.Run(), "search can only be used with at most 2 elements, got 3")
+}
+
+func TestSetHintParserHintsAtSamePc(t *testing.T) {
+	program := createDefaultProgram(`
+        ap += 1;
+        ret;
+    `)
+	program.References = []Reference{{Value: "[cast(ap, felt)]"}}
+	answer := Hint{Code: "memory[ap] = compute_answer()"}
+	assertNotZero := Hint{
+		Code:         assertNotZeroCode,
+		ReferenceIds: map[string]uint64{"__main__.main.value": 0},
+	}
+
+	// the value is only known if the answer runs first
+	program.Hints = map[uint64][]Hint{0: {answer, assertNotZero}}
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.SetHintParser(answerParser{}))
+	require.NoError(t, runner.Run())
+
+	program.Hints = map[uint64][]Hint{0: {assertNotZero, answer}}
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.SetHintParser(answerParser{}))
+	require.EqualError(
+		t, runner.Run(),
+		"pc 0:0 step 0: execute hint AssertNotZero: assert_not_zero failed: ids.value = 0",
+	)
+}
+
+func TestRegisterOracle(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap + 1] = [ap] + 1, ap++;
+        ret;
+    `)
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.RegisterOracle(0, func(vm *VM.VirtualMachine) ([]f.Element, error) {
+		return []f.Element{*new(f.Element).SetUint64(41)}, nil
+	}))
+
+	// the oracle is kept when the program hints are parsed and on reset
+	require.NoError(t, runner.SetHintParser(hintrunner.StandardHintParser{}))
+	for i := 0; i < 2; i++ {
+		require.NoError(t, runner.Run())
+		value, err := runner.memory().Read(VM.ExecutionSegment, 3)
+		require.NoError(t, err)
+		assert.Equal(t, memory.MemoryValueFromInt(42), value)
+		require.NoError(t, runner.Reset())
+	}
+
+	// a program hint can't share its pc
+	program.Hints = map[uint64][]Hint{0: {{Code: "memory[ap] = 5"}}}
+	require.EqualError(
+		t, runner.SetHintParser(hintrunner.StandardHintParser{}),
+		"oracle at pc 0: pc 0 already has hint Assign",
+	)
+	require.EqualError(t, runner.RegisterOracle(0, nil), "pc 0 already has hint Oracle")
+}
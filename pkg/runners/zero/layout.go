@@ -0,0 +1,126 @@
+package zero
+
+import (
+	"fmt"
+
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/builtins"
+)
+
+// A builtin available in a layout together with its ratio, i.e. the amount
+// of steps per builtin instance. Builtins without instances have ratio 0
+type LayoutBuiltin struct {
+	Builtin starknetParser.Builtin
+	Ratio   uint64
+}
+
+// Layouts decide which builtins a program is allowed to use. Builtins are
+// listed in the canonical order their segments are allocated
+type Layout struct {
+	Name     string
+	Builtins []LayoutBuiltin
+	// when set, a keccak or poseidon builtin the program declares but the
+	// layout lacks is computed by the vm, like the hints of the cairo hash
+	// libraries do, instead of failing. No builtin segment is allocated for
+	// it: main gets a plain segment instead, which the prover doesn't see
+	HashHintFallback bool
+}
+
+// The builtins whose hashes can be computed without the layout having them
+var hashHintFallbackBuiltins = map[starknetParser.Builtin]bool{
+	starknetParser.Keccak:   true,
+	starknetParser.Poseidon: true,
+}
+
+var PlainLayout = Layout{
+	Name: "plain",
+}
+
+var SmallLayout = Layout{
+	Name: "small",
+	Builtins: []LayoutBuiltin{
+		{starknetParser.Output, 0},
+		{starknetParser.Pedersen, 8},
+		{starknetParser.RangeCheck, 8},
+		{starknetParser.ECDSA, 512},
+	},
+}
+
+var AllCairoLayout = Layout{
+	Name: "all_cairo",
+	Builtins: []LayoutBuiltin{
+		{starknetParser.Output, 0},
+		{starknetParser.Pedersen, 256},
+		{starknetParser.RangeCheck, 8},
+		{starknetParser.ECDSA, 2048},
+		{starknetParser.Bitwise, 16},
+		{starknetParser.ECOP, 1024},
+		{starknetParser.Keccak, 2048},
+		{starknetParser.Poseidon, 256},
+	},
+}
+
+// Returns the layout with the given name
+func LayoutByName(name string) (Layout, error) {
+	for _, layout := range []Layout{PlainLayout, SmallLayout, AllCairoLayout} {
+		if layout.Name == name {
+			return layout, nil
+		}
+	}
+	return Layout{}, fmt.Errorf("unknown layout: %s", name)
+}
+
+// Returns the ratio of a builtin in the layout, 0 if it has none or it's not
+// part of the layout
+func (layout *Layout) ratio(builtin starknetParser.Builtin) uint64 {
+	for _, layoutBuiltin := range layout.Builtins {
+		if layoutBuiltin.Builtin == builtin {
+			return layoutBuiltin.Ratio
+		}
+	}
+	return 0
+}
+
+// Splits the given builtins into the ones of the layout, sorted in its
+// canonical order, and the ones computed through the hash hint fallback.
+// Errors if any of them is neither part of the layout nor can fall back
+func (layout *Layout) canonicalOrder(
+	programBuiltins []starknetParser.Builtin,
+) (ordered []starknetParser.Builtin, hinted []starknetParser.Builtin, err error) {
+	declared := make(map[starknetParser.Builtin]bool, len(programBuiltins))
+	for _, builtin := range programBuiltins {
+		declared[builtin] = true
+	}
+
+	ordered = make([]starknetParser.Builtin, 0, len(programBuiltins))
+	for _, layoutBuiltin := range layout.Builtins {
+		if declared[layoutBuiltin.Builtin] {
+			ordered = append(ordered, layoutBuiltin.Builtin)
+			delete(declared, layoutBuiltin.Builtin)
+		}
+	}
+
+	for _, builtin := range programBuiltins {
+		if !declared[builtin] {
+			continue
+		}
+		if !hashHintFallbackBuiltins[builtin] {
+			return nil, nil, fmt.Errorf("builtin %s is not available in layout %s", builtin, layout.Name)
+		}
+		if !layout.HashHintFallback {
+			return nil, nil, fmt.Errorf(
+				"builtin %s is not available in layout %s and the hash hint fallback is disabled",
+				builtin, layout.Name,
+			)
+		}
+		if _, err := builtins.Runner(builtin); err != nil {
+			return nil, nil, fmt.Errorf(
+				"builtin %s is not available in layout %s and can't fall back to hints: %w",
+				builtin, layout.Name, err,
+			)
+		}
+		hinted = append(hinted, builtin)
+		delete(declared, builtin)
+	}
+	return ordered, hinted, nil
+}
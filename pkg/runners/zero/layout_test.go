@@ -0,0 +1,170 @@
+package zero
+
+import (
+	"math"
+	"testing"
+
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayoutByName(t *testing.T) {
+	for _, name := range []string{"plain", "small", "all_cairo"} {
+		layout, err := LayoutByName(name)
+		require.NoError(t, err)
+		assert.Equal(t, name, layout.Name)
+	}
+
+	_, err := LayoutByName("dynamic")
+	require.ErrorContains(t, err, "unknown layout: dynamic")
+}
+
+func TestNewRunnerWithLayoutCanonicalOrder(t *testing.T) {
+	program := createDefaultProgram(`ret;`)
+	program.builtins = []starknetParser.Builtin{starknetParser.Keccak, starknetParser.RangeCheck}
+
+	runner, err := NewRunnerWithLayout(program, false, math.MaxUint64, AllCairoLayout)
+	require.NoError(t, err)
+
+	require.Len(t, runner.segments(), 4)
+	assert.Equal(t, "range_check", runner.segments()[2].Name)
+	assert.Equal(t, "keccak", runner.segments()[3].Name)
+	require.NoError(t, runner.Run())
+}
+
+func TestNewRunnerWithLayoutMissingBuiltin(t *testing.T) {
+	program := createDefaultProgram(`ret;`)
+	program.builtins = []starknetParser.Builtin{starknetParser.RangeCheck}
+
+	_, err := NewRunnerWithLayout(program, false, math.MaxUint64, PlainLayout)
+	require.ErrorContains(t, err, "builtin range_check is not available in layout plain")
+
+	program.builtins = []starknetParser.Builtin{starknetParser.RangeCheck, starknetParser.Keccak}
+	_, err = NewRunnerWithLayout(program, false, math.MaxUint64, SmallLayout)
+	require.ErrorContains(t, err, "builtin keccak is not available in layout small")
+
+	program.builtins = nil
+	runner, err := NewRunnerWithLayout(program, false, math.MaxUint64, PlainLayout)
+	require.NoError(t, err)
+	assert.Len(t, runner.segments(), 2)
+}
+
+func TestNewRunnerWithLayoutHashHintFallback(t *testing.T) {
+	program := createDefaultProgram(`ret;`)
+	program.builtins = []starknetParser.Builtin{starknetParser.Keccak, starknetParser.RangeCheck}
+
+	_, err := NewRunnerWithLayout(program, false, math.MaxUint64, SmallLayout)
+	require.EqualError(
+		t, err,
+		"runner error: builtin keccak is not available in layout small and the hash hint fallback is disabled",
+	)
+
+	// keccak gets a plain segment after the builtin ones instead of a builtin one
+	layout := SmallLayout
+	layout.HashHintFallback = true
+	runner, err := NewRunnerWithLayout(program, false, math.MaxUint64, layout)
+	require.NoError(t, err)
+	require.Len(t, runner.segments(), 4)
+	assert.Equal(t, "range_check", runner.segments()[2].Name)
+	assert.Equal(t, "", runner.segments()[3].Name)
+	assert.Equal(t, "keccak hints", runner.segments()[3].BuiltinRunner.String())
+	// its cells are still deduced like the builtin ones
+	deducer, ok := runner.segments()[3].BuiltinRunner.(memory.BuiltinDeducer)
+	require.True(t, ok)
+	assert.False(t, deducer.Deduces(0))
+	assert.True(t, deducer.Deduces(8))
+	_, ok = runner.memory().FindSegmentByName("keccak")
+	assert.False(t, ok)
+
+	stack, err := runner.builtinsStack()
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]memory.MemoryValue{
+			memory.MemoryValueFromSegmentAndOffset(3, 0),
+			memory.MemoryValueFromSegmentAndOffset(2, 0),
+		},
+		stack,
+	)
+	require.NoError(t, runner.Run())
+	resources, err := runner.ExecutionResources()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]uint64{"range_check": 0}, resources.BuiltinInstanceCounter)
+	_, err = runner.BuildCairoPie()
+	require.EqualError(t, err, "cairo pie cannot hold the keccak builtin computed through hints")
+
+	// the prover only knows the layout builtins
+	_, err = NewRunnerWithLayout(program, true, math.MaxUint64, layout)
+	require.EqualError(
+		t, err,
+		"runner error: builtin keccak is not available in layout small and can't fall back to hints in proof mode",
+	)
+
+	// the vm can't compute poseidon hashes yet
+	program.builtins = []starknetParser.Builtin{starknetParser.Poseidon}
+	_, err = NewRunnerWithLayout(program, false, math.MaxUint64, layout)
+	require.EqualError(
+		t, err,
+		"runner error: builtin poseidon is not available in layout small and can't fall back to hints: "+
+			"unsupported builtin: poseidon",
+	)
+
+	// only hashes can fall back
+	program.builtins = []starknetParser.Builtin{starknetParser.Bitwise}
+	_, err = NewRunnerWithLayout(program, false, math.MaxUint64, layout)
+	require.EqualError(t, err, "runner error: builtin bitwise is not available in layout small")
+}
+
+func TestVerifyBuiltinRatios(t *testing.T) {
+	// runs for 8 steps
+	program := createDefaultProgram(`
+        [ap] = 1, ap++;
+        [ap] = 1, ap++;
+        [ap] = 1, ap++;
+        [ap] = 1, ap++;
+        [ap] = 1, ap++;
+        [ap] = 1, ap++;
+        [ap] = 1, ap++;
+        ret;
+    `)
+	program.builtins = []starknetParser.Builtin{starknetParser.RangeCheck}
+	value := memory.MemoryValueFromInt(7)
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.Run())
+	require.EqualError(t, runner.VerifyBuiltinRatios(), "builtin ratios require a runner created with a layout")
+
+	runner, err = NewRunnerWithLayout(program, false, math.MaxUint64, SmallLayout)
+	require.NoError(t, err)
+	require.ErrorContains(t, runner.VerifyBuiltinRatios(), "running the program first")
+	runner.EnableAccessLog()
+	require.NoError(t, runner.Run())
+	require.Equal(t, uint64(8), runner.steps())
+	// a single range check instance is allowed every 8 steps
+	require.NoError(t, runner.memory().Write(2, 0, &value))
+	require.NoError(t, runner.VerifyBuiltinRatios())
+	require.NoError(t, runner.memory().Write(2, 1, &value))
+	require.EqualError(
+		t,
+		runner.VerifyBuiltinRatios(),
+		"builtin range_check uses 2 instances but 8 steps at ratio 8 only allow 1, "+
+			"its last cell was first accessed at step 8",
+	)
+
+	// the access log is kept after a reset
+	require.NoError(t, runner.Reset())
+	require.NoError(t, runner.memory().Write(2, 0, &value))
+	step, ok := runner.segments()[2].FirstAccess(0)
+	require.True(t, ok)
+	assert.Equal(t, uint64(0), step)
+
+	// a run whose steps don't fill a whole range check instance
+	program.Bytecode = program.Bytecode[2:]
+	runner, err = NewRunnerWithLayout(program, false, math.MaxUint64, SmallLayout)
+	require.NoError(t, err)
+	require.NoError(t, runner.Run())
+	require.EqualError(t, runner.VerifyBuiltinRatios(), "7 steps are not a multiple of the range_check ratio 8")
+}
@@ -0,0 +1,47 @@
+package zero
+
+import (
+	"time"
+
+	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+)
+
+// Receives usage metrics of the runs, so services embedding the vm can export
+// them, e.g. as Prometheus counters, without the vm depending on any metrics
+// library. Its methods are called by Run once it finishes, failed or not, from
+// the goroutine running the program
+type MetricsSink interface {
+	// Steps executed by the run
+	IncSteps(steps uint64)
+	// Wall clock time spent by the run
+	ObserveRunDuration(duration time.Duration)
+	// Instances used by each builtin of the program, only called for builtins
+	// that used at least one instance
+	IncBuiltinInstances(builtin string, instances uint64)
+}
+
+type noopMetricsSink struct{}
+
+func (noopMetricsSink) IncSteps(uint64)                    {}
+func (noopMetricsSink) ObserveRunDuration(time.Duration)   {}
+func (noopMetricsSink) IncBuiltinInstances(string, uint64) {}
+
+// Sets where the metrics of each run are reported, nil restores the default
+// sink which discards them. Must be called before running
+func (runner *ZeroRunner) SetMetricsSink(sink MetricsSink) {
+	if sink == nil {
+		sink = noopMetricsSink{}
+	}
+	runner.metrics = sink
+}
+
+func (runner *ZeroRunner) reportMetrics(start time.Time) {
+	runner.metrics.ObserveRunDuration(time.Since(start))
+	runner.metrics.IncSteps(runner.steps())
+	for i, builtin := range runner.builtins {
+		segment := runner.segments()[VM.ExecutionSegment+1+i]
+		if instances := segment.BuiltinRunner.InstancesUsed(segment); instances > 0 {
+			runner.metrics.IncBuiltinInstances(builtin.String(), instances)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package zero
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	steps     uint64
+	runs      int
+	instances map[string]uint64
+}
+
+func (sink *recordingSink) IncSteps(steps uint64) {
+	sink.steps += steps
+}
+
+func (sink *recordingSink) ObserveRunDuration(time.Duration) {
+	sink.runs++
+}
+
+func (sink *recordingSink) IncBuiltinInstances(builtin string, instances uint64) {
+	sink.instances[builtin] += instances
+}
+
+func TestMetricsSink(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = [fp - 4] + 1, ap++;
+        [ap] = [fp - 3], ap++;
+        ret;
+    `)
+	program.builtins = []starknetParser.Builtin{starknetParser.RangeCheck, starknetParser.Output}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	sink := &recordingSink{instances: make(map[string]uint64)}
+	runner.SetMetricsSink(sink)
+
+	// the range check instance main increments its pointer past
+	value := memory.MemoryValueFromInt(5)
+	require.NoError(t, runner.memory().Write(2, 0, &value))
+	require.NoError(t, runner.Run())
+
+	assert.Equal(t, uint64(3), sink.steps)
+	assert.Equal(t, 1, sink.runs)
+	// the output builtin wasn't used
+	assert.Equal(t, map[string]uint64{"range_check": 1}, sink.instances)
+
+	// failed runs are reported as well
+	runner, err = NewRunner(program, false, 1)
+	require.NoError(t, err)
+	runner.SetMetricsSink(sink)
+	require.ErrorContains(t, runner.Run(), "max step limit exceeded")
+	assert.Equal(t, 2, sink.runs)
+	assert.Equal(t, uint64(4), sink.steps)
+}
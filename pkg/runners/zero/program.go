@@ -1,10 +1,16 @@
 package zero
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
 
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
 	"github.com/NethermindEth/cairo-vm-go/pkg/parsers/zero"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
 	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
 )
 
@@ -15,42 +21,238 @@ type Program struct {
 	Entrypoints map[string]uint64
 	// it stores the start and end label pcs
 	Labels map[string]uint64
+	// the builtins the program requires, in the order they were declared
+	builtins []starknetParser.Builtin
+	// the undecoded bytecode words, set only when the program is loaded lazily
+	rawBytecode []string
+	// amount of cells returned by each function whose return type size is known
+	returnSizes map[string]uint64
+	// the scope of the main module, usually "__main__"
+	MainScope string
+	// every identifier declared by the program keyed by its full name
+	Identifiers map[string]Identifier
+	// the references of the reference manager, hints refer to them by index
+	References []Reference
+	// the hints of the program keyed by the pc they run at
+	Hints map[uint64][]Hint
+}
+
+// The python code run before the instruction at some pc
+type Hint struct {
+	Code             string
+	AccessibleScopes []string
+	ApTrackingGroup  int
+	ApTrackingOffset int
+	// index in References of each id the hint can access, keyed by full name
+	ReferenceIds map[string]uint64
+}
+
+// A named value, usually relative to ap or fp such as `[cast(fp + (-3), felt*)]`.
+// References using ap are only valid within their ap tracking group
+type Reference struct {
+	Pc               uint64
+	ApTrackingGroup  int
+	ApTrackingOffset int
+	Value            string
+}
+
+// An identifier declared by the program
+type Identifier struct {
+	// e.g. "function", "label", "const", "reference", "alias" or "struct"
+	Type string
+	// pc of functions and labels
+	Pc uint64
+	// value of constants
+	Value *f.Element
+	// full name of the identifier an alias stands for
+	Destination string
+	// type of references and type definitions
+	CairoType string
+	// every definition of a reference in the order they appear
+	References []Reference
+}
+
+// Returns the definition of a reference that is active at pc, which is the
+// last one defined at or before it
+func (identifier *Identifier) ReferenceAt(pc uint64) (Reference, bool) {
+	var active Reference
+	found := false
+	for _, reference := range identifier.References {
+		if reference.Pc <= pc && (!found || reference.Pc >= active.Pc) {
+			active = reference
+			found = true
+		}
+	}
+	return active, found
+}
+
+// Returns the identifier with the given full name, following aliases
+func (program *Program) Identifier(fullName string) (Identifier, error) {
+	name := fullName
+	// an alias chain can't be longer than the amount of identifiers
+	for i := 0; i <= len(program.Identifiers); i++ {
+		identifier, ok := program.Identifiers[name]
+		if !ok {
+			return Identifier{}, fmt.Errorf("unknown identifier %s", name)
+		}
+		if identifier.Type != "alias" {
+			return identifier, nil
+		}
+		name = identifier.Destination
+	}
+	return Identifier{}, fmt.Errorf("identifier %s is part of an alias cycle", fullName)
+}
+
+// Resolves a name as seen from code with the given accessible scopes, e.g. the
+// ones of a hint. Inner scopes, which come last, shadow the outer ones
+func (program *Program) ResolveIdentifier(name string, accessibleScopes []string) (Identifier, error) {
+	for i := len(accessibleScopes) - 1; i >= 0; i-- {
+		fullName := accessibleScopes[i] + "." + name
+		if _, ok := program.Identifiers[fullName]; ok {
+			return program.Identifier(fullName)
+		}
+	}
+	return Identifier{}, fmt.Errorf("unknown identifier %s in scopes %v", name, accessibleScopes)
+}
+
+// Returns the value of a constant. Constants may be used directly as
+// immediates, in which case the compiler already reduced them modulo the prime
+func (program *Program) Constant(fullName string) (*f.Element, error) {
+	identifier, err := program.Identifier(fullName)
+	if err != nil {
+		return nil, err
+	}
+	if identifier.Type != "const" {
+		return nil, fmt.Errorf("identifier %s is a %s, not a const", fullName, identifier.Type)
+	}
+	return identifier.Value, nil
+}
+
+// Returns the names of the builtins the program requires, in the order they
+// were declared
+func (program *Program) Builtins() []string {
+	names := make([]string, len(program.builtins))
+	for i, builtin := range program.builtins {
+		names[i] = builtin.String()
+	}
+	return names
+}
+
+// Checks the program has the __start__ and __end__ labels a proof mode run
+// starts and stops at, which only programs compiled with `--proof_mode` have
+func (program *Program) ValidateForProofMode() error {
+	if _, ok := program.Labels["__start__"]; !ok {
+		return errors.New("start label not found. Try compiling with `--proof_mode`")
+	}
+	if _, ok := program.Labels["__end__"]; !ok {
+		return errors.New("end label not found. Try compiling with `--proof_mode`")
+	}
+	return nil
 }
 
 func LoadCairoZeroProgram(content []byte) (*Program, error) {
-	cairoZeroJson, err := zero.ZeroProgramFromJSON(content)
+	program, cairoZeroJson, err := loadCairoZeroProgram(content)
 	if err != nil {
 		return nil, err
 	}
 
-	// bytecode
-	bytecode := make([]*f.Element, len(cairoZeroJson.Data))
-	for i := range cairoZeroJson.Data {
-		felt, err := new(f.Element).SetString(cairoZeroJson.Data[i])
-		if err != nil {
-			return nil, fmt.Errorf(
-				"cannot read bytecode %s at position %d: %w",
-				cairoZeroJson.Data[i], i, err,
-			)
+	// programs repeat the same instructions and small immediates all the time,
+	// so every distinct word is decoded once and its felt shared
+	decoded := make(map[string]*f.Element)
+	program.Bytecode = make([]*f.Element, len(cairoZeroJson.Data))
+	for i, word := range cairoZeroJson.Data {
+		felt, ok := decoded[word]
+		if !ok {
+			felt, err = memory.FeltFromWord(word)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"cannot read bytecode %s at position %d: %w", word, i, err,
+				)
+			}
+			decoded[word] = felt
 		}
-		bytecode[i] = felt
+		program.Bytecode[i] = felt
 	}
+	return program, nil
+}
 
-	entrypoints, err := extractEntrypoints(cairoZeroJson)
+// Loads a program without decoding its bytecode. Each instruction word is
+// decoded the first time it is accessed during execution, which avoids
+// decoding unreachable bytecode of large programs
+func LoadCairoZeroProgramLazy(content []byte) (*Program, error) {
+	program, cairoZeroJson, err := loadCairoZeroProgram(content)
 	if err != nil {
 		return nil, err
 	}
+	program.rawBytecode = cairoZeroJson.Data
+	return program, nil
+}
+
+// Decodes everything in the compiled program but its bytecode, which each
+// loader sets its own way
+func loadCairoZeroProgram(content []byte) (*Program, *zero.ZeroProgram, error) {
+	cairoZeroJson, err := zero.ZeroProgramFromJSON(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkPrime(cairoZeroJson.Prime); err != nil {
+		return nil, nil, err
+	}
+
+	entrypoints, err := extractEntrypoints(cairoZeroJson)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	labels, err := extractLabels(cairoZeroJson)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	returnSizes, err := extractReturnSizes(cairoZeroJson)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	identifiers, err := extractIdentifiers(cairoZeroJson)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hints, err := extractHints(cairoZeroJson)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	return &Program{
-		Bytecode:    bytecode,
 		Entrypoints: entrypoints,
 		Labels:      labels,
-	}, nil
+		builtins:    cairoZeroJson.Builtins,
+		returnSizes: returnSizes,
+		MainScope:   cairoZeroJson.MainScope,
+		Identifiers: identifiers,
+		References:  convertReferences(cairoZeroJson.ReferenceManager.References),
+		Hints:       hints,
+	}, cairoZeroJson, nil
+}
+
+// Checks that the program was compiled for the field the vm computes in,
+// otherwise its arithmetic would be meaningless. Hand written programs
+// without a prime are accepted
+func checkPrime(prime string) error {
+	if prime == "" {
+		return nil
+	}
+	value, ok := new(big.Int).SetString(prime, 0)
+	if !ok {
+		return fmt.Errorf("invalid program prime %q", prime)
+	}
+	if value.Cmp(f.Modulus()) != 0 {
+		return fmt.Errorf(
+			"program prime %#x doesn't match the field modulus %#x of the vm", value, f.Modulus(),
+		)
+	}
+	return nil
 }
 
 func extractEntrypoints(json *zero.ZeroProgram) (map[string]uint64, error) {
@@ -59,12 +261,12 @@ func extractEntrypoints(json *zero.ZeroProgram) (map[string]uint64, error) {
 		json,
 		func(key string, typex string, value map[string]any) error {
 			if typex == "function" {
-				pc, ok := value["pc"].(float64)
+				pc, ok := jsonUint(value["pc"])
 				if !ok {
 					return fmt.Errorf("%s: unknown entrypoint pc", key)
 				}
 				name := key[len(json.MainScope)+1:]
-				result[name] = uint64(pc)
+				result[name] = pc
 			}
 			return nil
 		},
@@ -82,12 +284,12 @@ func extractLabels(json *zero.ZeroProgram) (map[string]uint64, error) {
 		json,
 		func(key string, typex string, value map[string]any) error {
 			if typex == "label" {
-				pc, ok := value["pc"].(float64)
+				pc, ok := jsonUint(value["pc"])
 				if !ok {
 					return fmt.Errorf("%s: unknown entrypoint pc", key)
 				}
 				name := key[len(json.MainScope)+1:]
-				labels[name] = uint64(pc)
+				labels[name] = pc
 			}
 			return nil
 		},
@@ -99,6 +301,250 @@ func extractLabels(json *zero.ZeroProgram) (map[string]uint64, error) {
 	return labels, nil
 }
 
+// Extracts the size of the return type of every function. Older compilers
+// describe it as a struct with a size while newer ones give its cairo type.
+// Functions returning named structs are skipped since their size is unknown
+func extractReturnSizes(json *zero.ZeroProgram) (map[string]uint64, error) {
+	sizes := make(map[string]uint64)
+	err := scanIdentifiers(
+		json,
+		func(key string, typex string, value map[string]any) error {
+			name, ok := strings.CutSuffix(key, ".Return")
+			if !ok || !strings.HasPrefix(name, json.MainScope+".") {
+				return nil
+			}
+			name = name[len(json.MainScope)+1:]
+
+			switch typex {
+			case "struct":
+				size, ok := jsonUint(value["size"])
+				if !ok {
+					return fmt.Errorf("%s: unknown return size", key)
+				}
+				sizes[name] = size
+			case "type_definition":
+				cairoType, ok := value["cairo_type"].(string)
+				if !ok {
+					return fmt.Errorf("%s: unknown return type", key)
+				}
+				if size, ok := cairoTypeSize(cairoType); ok {
+					sizes[name] = size
+				}
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("extracting return sizes: %w", err)
+	}
+	return sizes, nil
+}
+
+// Extracts every identifier keeping the properties needed to resolve them
+func extractIdentifiers(cairoZeroJson *zero.ZeroProgram) (map[string]Identifier, error) {
+	identifiers := make(map[string]Identifier, len(cairoZeroJson.Identifiers))
+	err := scanIdentifiers(
+		cairoZeroJson,
+		func(key string, typex string, value map[string]any) error {
+			identifier := Identifier{Type: typex}
+			switch typex {
+			case "function", "label":
+				pc, ok := jsonUint(value["pc"])
+				if !ok {
+					return fmt.Errorf("%s: unknown pc", key)
+				}
+				identifier.Pc = pc
+			case "const":
+				number, ok := value["value"].(json.Number)
+				if !ok {
+					return fmt.Errorf("%s: unknown const value", key)
+				}
+				// the value can be negative or bigger than the prime
+				felt, err := new(f.Element).SetString(number.String())
+				if err != nil {
+					return fmt.Errorf("%s: invalid const value %s: %w", key, number, err)
+				}
+				identifier.Value = felt
+			case "alias":
+				destination, ok := value["destination"].(string)
+				if !ok {
+					return fmt.Errorf("%s: unknown alias destination", key)
+				}
+				identifier.Destination = destination
+			case "reference":
+				references, err := parseIdentifierReferences(value["references"])
+				if err != nil {
+					return fmt.Errorf("%s: %w", key, err)
+				}
+				identifier.References = references
+			}
+			if cairoType, ok := value["cairo_type"].(string); ok {
+				identifier.CairoType = cairoType
+			}
+			identifiers[key] = identifier
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("extracting identifiers: %w", err)
+	}
+	return identifiers, nil
+}
+
+func parseIdentifierReferences(value any) ([]Reference, error) {
+	rawReferences, ok := value.([]any)
+	if !ok {
+		return nil, errors.New("unknown references")
+	}
+	references := make([]Reference, len(rawReferences))
+	for i, rawReference := range rawReferences {
+		properties, ok := rawReference.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("reference %d is not an object", i)
+		}
+		pc, okPc := jsonUint(properties["pc"])
+		referenceValue, okValue := properties["value"].(string)
+		apTracking, okTracking := properties["ap_tracking_data"].(map[string]any)
+		if !okPc || !okValue || !okTracking {
+			return nil, fmt.Errorf("reference %d is missing its pc, value or ap tracking", i)
+		}
+		group, okGroup := jsonInt(apTracking["group"])
+		offset, okOffset := jsonInt(apTracking["offset"])
+		if !okGroup || !okOffset {
+			return nil, fmt.Errorf("reference %d has an invalid ap tracking", i)
+		}
+		references[i] = Reference{
+			Pc:               pc,
+			ApTrackingGroup:  group,
+			ApTrackingOffset: offset,
+			Value:            referenceValue,
+		}
+	}
+	return references, nil
+}
+
+func convertReferences(references []zero.Reference) []Reference {
+	converted := make([]Reference, len(references))
+	for i, reference := range references {
+		converted[i] = Reference{
+			Pc:               reference.Pc,
+			ApTrackingGroup:  reference.ApTrackingData.Group,
+			ApTrackingOffset: reference.ApTrackingData.Offset,
+			Value:            reference.Value,
+		}
+	}
+	return converted
+}
+
+// Programs without hints have a nil map
+func extractHints(json *zero.ZeroProgram) (map[uint64][]Hint, error) {
+	if len(json.Hints) == 0 {
+		return nil, nil
+	}
+	hints := make(map[uint64][]Hint, len(json.Hints))
+	for key, pcHints := range json.Hints {
+		pc, err := strconv.ParseUint(key, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("extracting hints: invalid pc %s", key)
+		}
+		converted := make([]Hint, len(pcHints))
+		for i, hint := range pcHints {
+			for name, index := range hint.FlowTrackingData.ReferenceIds {
+				if index >= uint64(len(json.ReferenceManager.References)) {
+					return nil, fmt.Errorf(
+						"extracting hints: pc %d: reference %s has unknown index %d", pc, name, index,
+					)
+				}
+			}
+			converted[i] = Hint{
+				Code:             hint.Code,
+				AccessibleScopes: hint.AccessibleScopes,
+				ApTrackingGroup:  hint.FlowTrackingData.ApTracking.Group,
+				ApTrackingOffset: hint.FlowTrackingData.ApTracking.Offset,
+				ReferenceIds:     hint.FlowTrackingData.ReferenceIds,
+			}
+		}
+		hints[pc] = converted
+	}
+	return hints, nil
+}
+
+// Identifiers are decoded without a schema, so their numbers are json.Number
+func jsonUint(value any) (uint64, bool) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	res, err := strconv.ParseUint(number.String(), 10, 64)
+	return res, err == nil
+}
+
+func jsonInt(value any) (int, bool) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	res, err := strconv.Atoi(number.String())
+	return res, err == nil
+}
+
+// Returns how many cells a cairo type such as `felt`, `felt*` or
+// `(a: felt, b: (felt, felt))` takes. Named structs are not supported
+func cairoTypeSize(cairoType string) (uint64, bool) {
+	cairoType = strings.TrimSpace(cairoType)
+	if cairoType == "felt" || strings.HasSuffix(cairoType, "*") {
+		return 1, true
+	}
+	if !strings.HasPrefix(cairoType, "(") || !strings.HasSuffix(cairoType, ")") {
+		return 0, false
+	}
+
+	members := cairoType[1 : len(cairoType)-1]
+	if strings.TrimSpace(members) == "" {
+		return 0, true
+	}
+
+	var size uint64
+	depth := 0
+	start := 0
+	// members are split on the commas outside of nested tuples
+	for i := 0; i <= len(members); i++ {
+		if i < len(members) {
+			switch members[i] {
+			case '(':
+				depth++
+				continue
+			case ')':
+				depth--
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+
+		member := members[start:i]
+		start = i + 1
+		// named members are written as `name: type`
+		if colon := strings.Index(member, ":"); colon >= 0 && !strings.Contains(member[:colon], "(") {
+			member = member[colon+1:]
+		}
+		if strings.TrimSpace(member) == "" {
+			// tuples may end with a trailing comma
+			continue
+		}
+		memberSize, ok := cairoTypeSize(member)
+		if !ok {
+			return 0, false
+		}
+		size += memberSize
+	}
+	return size, true
+}
+
 func scanIdentifiers(
 	json *zero.ZeroProgram,
 	f func(key string, typex string, value map[string]any) error,
@@ -1,9 +1,15 @@
 package zero
 
 import (
+	"math"
+	"strings"
+	"testing"
+
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
+	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
 	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
 	"github.com/stretchr/testify/require"
-	"testing"
 )
 
 func TestLoadCairoZeroProgram(t *testing.T) {
@@ -15,6 +21,7 @@ func TestLoadCairoZeroProgram(t *testing.T) {
                 "0x0000003",
                 "0x0000004"
             ],
+            "builtins": ["range_check", "keccak"],
             "main_scope": "__main__",
             "identifiers": {
                 "__main__.main": {
@@ -53,8 +60,347 @@ func TestLoadCairoZeroProgram(t *testing.T) {
 			"main": 0,
 			"fib":  4,
 		},
-		Labels: map[string]uint64{},
+		Labels:      map[string]uint64{},
+		builtins:    []starknetParser.Builtin{starknetParser.RangeCheck, starknetParser.Keccak},
+		returnSizes: map[string]uint64{},
+		MainScope:   "__main__",
+		Identifiers: map[string]Identifier{
+			"__main__.main": {Type: "function", Pc: 0},
+			"__main__.fib":  {Type: "function", Pc: 4},
+		},
+		References: []Reference{},
 	},
 		program,
 	)
+	require.Equal(t, []string{"range_check", "keccak"}, program.Builtins())
+}
+
+func TestLoadCairoZeroProgramSharesRepeatedWords(t *testing.T) {
+	content := []byte(`
+        {
+            "data": ["0x480680017fff8000", "0x1", "0x480680017fff8000", "0x2"],
+            "builtins": [],
+            "main_scope": "__main__",
+            "identifiers": {"__main__.main": {"pc": 0, "type": "function"}},
+            "hints": {},
+            "reference_manager": {"references": []},
+            "attributes": []
+        }
+    `)
+
+	program, err := LoadCairoZeroProgram(content)
+	require.NoError(t, err)
+	require.Same(t, program.Bytecode[0], program.Bytecode[2])
+	require.Equal(t, f.NewElement(1), *program.Bytecode[1])
+	require.Equal(t, f.NewElement(2), *program.Bytecode[3])
+}
+
+func TestLoadCairoZeroProgramLazy(t *testing.T) {
+	content := []byte(`
+        {
+            "data": [
+                "0x0000001",
+                "not a felt"
+            ],
+            "builtins": [],
+            "main_scope": "__main__",
+            "identifiers": {
+                "__main__.main": {
+                    "decorators": [],
+                    "pc": 0,
+                    "type": "function"
+                }
+            }
+        }
+    `)
+
+	// invalid bytecode is not detected until it is accessed
+	program, err := LoadCairoZeroProgramLazy(content)
+	require.NoError(t, err)
+
+	require.Equal(t, &Program{
+		Entrypoints: map[string]uint64{
+			"main": 0,
+		},
+		Labels:      map[string]uint64{},
+		builtins:    []starknetParser.Builtin{},
+		rawBytecode: []string{"0x0000001", "not a felt"},
+		returnSizes: map[string]uint64{},
+		MainScope:   "__main__",
+		Identifiers: map[string]Identifier{
+			"__main__.main": {Type: "function", Pc: 0},
+		},
+		References: []Reference{},
+	},
+		program,
+	)
+
+	_, err = LoadCairoZeroProgram(content)
+	require.Error(t, err)
+}
+
+func TestLoadCairoZeroProgramHints(t *testing.T) {
+	content := []byte(`
+        {
+            "data": ["0x208b7fff7fff7ffe"],
+            "main_scope": "__main__",
+            "hints": {
+                "0": [
+                    {
+                        "accessible_scopes": ["__main__", "__main__.main"],
+                        "code": "memory[ap] = segments.add()",
+                        "flow_tracking_data": {
+                            "ap_tracking": {"group": 1, "offset": 2},
+                            "reference_ids": {"__main__.main.x": 0}
+                        }
+                    }
+                ]
+            },
+            "reference_manager": {
+                "references": [
+                    {"ap_tracking_data": {"group": 1, "offset": 0}, "pc": 0, "value": "[cast(fp + (-3), felt)]"}
+                ]
+            }
+        }
+    `)
+	program, err := LoadCairoZeroProgram(content)
+	require.NoError(t, err)
+	require.Equal(t, map[uint64][]Hint{
+		0: {{
+			Code:             "memory[ap] = segments.add()",
+			AccessibleScopes: []string{"__main__", "__main__.main"},
+			ApTrackingGroup:  1,
+			ApTrackingOffset: 2,
+			ReferenceIds:     map[string]uint64{"__main__.main.x": 0},
+		}},
+	}, program.Hints)
+
+	// the reference manager has a single reference
+	content = []byte(strings.Replace(string(content), `"__main__.main.x": 0`, `"__main__.main.x": 1`, 1))
+	_, err = LoadCairoZeroProgram(content)
+	require.EqualError(t, err, "extracting hints: pc 0: reference __main__.main.x has unknown index 1")
+}
+
+func TestLoadCairoZeroProgramModulusWord(t *testing.T) {
+	content := []byte(`
+        {
+            "data": [
+                "0x800000000000011000000000000000000000000000000000000000000000000",
+                "0x800000000000011000000000000000000000000000000000000000000000001"
+            ],
+            "builtins": [],
+            "main_scope": "__main__",
+            "identifiers": {"__main__.main": {"pc": 0, "type": "function"}},
+            "hints": {},
+            "reference_manager": {"references": []},
+            "attributes": []
+        }
+    `)
+
+	// P - 1 is the biggest felt, P itself would be reduced to 0
+	_, err := LoadCairoZeroProgram(content)
+	require.ErrorContains(t, err, "at position 1: word 0x800000000000011000000000000000000000000000000000000000000000001 is outside of the field [0, P)")
+
+	program, err := LoadCairoZeroProgramLazy(content)
+	require.NoError(t, err)
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	value, err := runner.memory().Read(VM.ProgramSegment, 0)
+	require.NoError(t, err)
+	require.Equal(t, memory.MemoryValueFromInt(-1), value)
+	_, err = runner.memory().Read(VM.ProgramSegment, 1)
+	require.ErrorContains(t, err, "at offset 1: word 0x800000000000011000000000000000000000000000000000000000000000001 is outside of the field")
+}
+
+func BenchmarkLoadCairoZeroProgram(b *testing.B) {
+	// a large program made of small immediates and repeated instructions
+	words := []string{
+		`"0x480680017fff8000"`, `"0x1"`, `"0x48307fff7ffe8000"`, `"0x40780017fff7fff"`,
+		`"0x0"`, `"0x482680017ffd8000"`, `"0x2a"`, `"0x208b7fff7fff7ffe"`,
+	}
+	data := make([]string, 0, 100_000)
+	for len(data) < cap(data) {
+		data = append(data, words...)
+	}
+	content := []byte(`{
+        "data": [` + strings.Join(data, ",") + `],
+        "builtins": [],
+        "main_scope": "__main__",
+        "identifiers": {"__main__.main": {"pc": 0, "type": "function"}},
+        "hints": {},
+        "reference_manager": {"references": []},
+        "attributes": []
+    }`)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		program, err := LoadCairoZeroProgram(content)
+		if err != nil {
+			b.Fatal(err)
+		}
+		runner, err := NewRunner(program, false, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = runner
+	}
+}
+
+func TestLoadCairoZeroProgramReturnSizes(t *testing.T) {
+	content := []byte(`
+        {
+            "data": [],
+            "builtins": [],
+            "main_scope": "__main__",
+            "identifiers": {
+                "__main__.main": {"pc": 0, "type": "function"},
+                "__main__.main.Return": {"cairo_type": "(res: felt, ptr: felt*)", "type": "type_definition"},
+                "__main__.fib.Return": {"size": 1, "type": "struct", "members": {}},
+                "__main__.point.Return": {"cairo_type": "__main__.Point", "type": "type_definition"},
+                "starkware.cairo.common.alloc.alloc.Return": {"cairo_type": "(ptr: felt*)", "type": "type_definition"}
+            }
+        }
+    `)
+
+	program, err := LoadCairoZeroProgram(content)
+	require.NoError(t, err)
+	require.Equal(t, map[string]uint64{"main": 2, "fib": 1}, program.returnSizes)
+}
+
+func TestCairoTypeSize(t *testing.T) {
+	testCases := []struct {
+		cairoType string
+		size      uint64
+		known     bool
+	}{
+		{"()", 0, true},
+		{"felt", 1, true},
+		{"felt**", 1, true},
+		{"(felt,)", 1, true},
+		{"(res: felt)", 1, true},
+		{"(a: felt, b: (felt, felt), c: __main__.Point*)", 4, true},
+		{"(a: (x: felt, y: felt), b: felt)", 3, true},
+		{"__main__.Point", 0, false},
+		{"(a: felt, b: __main__.Point)", 0, false},
+	}
+
+	for _, tc := range testCases {
+		size, known := cairoTypeSize(tc.cairoType)
+		require.Equal(t, tc.known, known, tc.cairoType)
+		require.Equal(t, tc.size, size, tc.cairoType)
+	}
+}
+
+func TestLoadCairoZeroProgramIdentifiers(t *testing.T) {
+	content := []byte(`
+        {
+            "data": [],
+            "builtins": [],
+            "main_scope": "__main__",
+            "identifiers": {
+                "__main__.main": {"pc": 0, "type": "function"},
+                "__main__.main.loop": {"pc": 6, "type": "label"},
+                "__main__.SIZE": {"type": "const", "value": 3618502788666131213697322783095070105623107215331596699973092056135872020482},
+                "__main__.MINUS_ONE": {"type": "const", "value": -1},
+                "__main__.ONE": {"destination": "__main__.ALIAS", "type": "alias"},
+                "__main__.ALIAS": {"destination": "__main__.SIZE", "type": "alias"},
+                "__main__.main.x": {
+                    "cairo_type": "felt",
+                    "full_name": "__main__.main.x",
+                    "references": [
+                        {"ap_tracking_data": {"group": 1, "offset": 0}, "pc": 2, "value": "[cast(fp + (-3), felt*)]"},
+                        {"ap_tracking_data": {"group": 1, "offset": 2}, "pc": 8, "value": "[cast(ap + (-1), felt*)]"}
+                    ],
+                    "type": "reference"
+                }
+            },
+            "reference_manager": {
+                "references": [
+                    {"ap_tracking_data": {"group": 1, "offset": 0}, "pc": 2, "value": "[cast(fp + (-3), felt*)]"}
+                ]
+            }
+        }
+    `)
+
+	program, err := LoadCairoZeroProgram(content)
+	require.NoError(t, err)
+	require.Equal(t, "__main__", program.MainScope)
+	require.Equal(t, []Reference{
+		{Pc: 2, ApTrackingGroup: 1, ApTrackingOffset: 0, Value: "[cast(fp + (-3), felt*)]"},
+	}, program.References)
+	require.Equal(t, map[string]uint64{"main.loop": 6}, program.Labels)
+
+	// constants are reduced modulo the prime, without losing precision
+	size, err := program.Constant("__main__.SIZE")
+	require.NoError(t, err)
+	require.Equal(t, f.NewElement(1), *size)
+	minusOne, err := program.Constant("__main__.MINUS_ONE")
+	require.NoError(t, err)
+	require.Equal(t, *new(f.Element).SetInt64(-1), *minusOne)
+	aliased, err := program.Constant("__main__.ONE")
+	require.NoError(t, err)
+	require.Equal(t, size, aliased)
+	_, err = program.Constant("__main__.main")
+	require.EqualError(t, err, "identifier __main__.main is a function, not a const")
+
+	x, err := program.ResolveIdentifier("x", []string{"__main__", "__main__.main"})
+	require.NoError(t, err)
+	require.Equal(t, "felt", x.CairoType)
+	_, ok := x.ReferenceAt(1)
+	require.False(t, ok)
+	reference, ok := x.ReferenceAt(7)
+	require.True(t, ok)
+	require.Equal(t, "[cast(fp + (-3), felt*)]", reference.Value)
+	reference, ok = x.ReferenceAt(8)
+	require.True(t, ok)
+	require.Equal(t, "[cast(ap + (-1), felt*)]", reference.Value)
+
+	// inner scopes are looked up first
+	one, err := program.ResolveIdentifier("ONE", []string{"__main__"})
+	require.NoError(t, err)
+	require.Equal(t, "const", one.Type)
+	_, err = program.ResolveIdentifier("x", []string{"__main__"})
+	require.EqualError(t, err, "unknown identifier x in scopes [__main__]")
+}
+
+func TestProgramIdentifierAliasCycle(t *testing.T) {
+	program := Program{Identifiers: map[string]Identifier{
+		"a": {Type: "alias", Destination: "b"},
+		"b": {Type: "alias", Destination: "a"},
+	}}
+	_, err := program.Identifier("a")
+	require.EqualError(t, err, "identifier a is part of an alias cycle")
+}
+
+func TestLoadCairoZeroProgramPrime(t *testing.T) {
+	load := func(prime string) error {
+		content := []byte(`
+        {
+            "data": ["0x1"],
+            "prime": "` + prime + `",
+            "builtins": [],
+            "main_scope": "__main__",
+            "identifiers": {"__main__.main": {"pc": 0, "type": "function"}},
+            "hints": {},
+            "reference_manager": {"references": []},
+            "attributes": []
+        }
+    `)
+		_, err := LoadCairoZeroProgram(content)
+		if err != nil {
+			return err
+		}
+		_, err = LoadCairoZeroProgramLazy(content)
+		return err
+	}
+
+	require.NoError(t, load(""))
+	require.NoError(t, load("0x800000000000011000000000000000000000000000000000000000000000001"))
+	require.EqualError(
+		t, load("0xbf"),
+		"program prime 0xbf doesn't match the field modulus "+
+			"0x800000000000011000000000000000000000000000000000000000000000001 of the vm",
+	)
+	require.EqualError(t, load("prime"), `invalid program prime "prime"`)
 }
@@ -0,0 +1,104 @@
+package zero
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+)
+
+// relocatable values are serialized as 2**(8 * n_bytes - 1) + segment * 2**47 + offset
+const relocatableOffsetBits = 47
+
+// Encodes the memory before relocation as consecutive (address, value) pairs,
+// 8 bytes for the address and 32 bytes for the value, both little endian.
+// Addresses, both the cell ones and the values, keep their segment and offset.
+// This is the format of the memory stored in a Cairo PIE
+func EncodeRelocatableMemory(mem *memory.Memory) []byte {
+	content := make([]byte, 0)
+	for i, segment := range mem.Segments {
+		for j := uint64(0); j < segment.Len(); j++ {
+			cell := segment.Data[j]
+			if !cell.Known() {
+				continue
+			}
+
+			address := uint64(1)<<63 | uint64(i)<<relocatableOffsetBits | j
+			content = binary.LittleEndian.AppendUint64(content, address)
+
+			var value [feltSize]byte
+			if cell.IsAddress() {
+				addr, _ := cell.ToMemoryAddress()
+				encoded := new(big.Int).Lsh(big.NewInt(1), 8*feltSize-1)
+				encoded.Add(encoded, new(big.Int).Lsh(new(big.Int).SetUint64(addr.SegmentIndex), relocatableOffsetBits))
+				encoded.Add(encoded, new(big.Int).SetUint64(addr.Offset))
+				// big.Int bytes are big endian
+				encoded.FillBytes(value[:])
+				reverseBytes(value[:])
+			} else {
+				felt, _ := cell.ToFieldElement()
+				f.LittleEndian.PutElement(&value, *felt)
+			}
+			content = append(content, value[:]...)
+		}
+	}
+	return content
+}
+
+// Decodes a memory encoded with EncodeRelocatableMemory. Segments without
+// any known cell are allocated empty
+func DecodeRelocatableMemory(content []byte) (*memory.Memory, error) {
+	if len(content)%(addrSize+feltSize) != 0 {
+		return nil, fmt.Errorf(
+			"content length %d is not a multiple of %d", len(content), addrSize+feltSize,
+		)
+	}
+
+	offsetMask := uint64(1)<<relocatableOffsetBits - 1
+	mem := memory.InitializeEmptyMemory()
+	for i := 0; i < len(content); i += addrSize + feltSize {
+		address := binary.LittleEndian.Uint64(content[i : i+addrSize])
+		if address>>63 == 0 {
+			return nil, fmt.Errorf("address %#x at byte %d is not relocatable", address, i)
+		}
+		segmentIndex := (address &^ (1 << 63)) >> relocatableOffsetBits
+		offset := address & offsetMask
+
+		var value [feltSize]byte
+		copy(value[:], content[i+addrSize:i+addrSize+feltSize])
+		var cell memory.MemoryValue
+		if value[feltSize-1]&0x80 != 0 {
+			value[feltSize-1] &^= 0x80
+			reverseBytes(value[:])
+			encoded := new(big.Int).SetBytes(value[:])
+			if encoded.BitLen() > 64 {
+				return nil, fmt.Errorf("relocatable value at byte %d does not fit in 64 bits", i)
+			}
+			cell = memory.MemoryValueFromSegmentAndOffset(
+				encoded.Uint64()>>relocatableOffsetBits, encoded.Uint64()&offsetMask,
+			)
+		} else {
+			felt, err := f.LittleEndian.Element(&value)
+			if err != nil {
+				return nil, fmt.Errorf("felt at byte %d: %w", i, err)
+			}
+			cell = memory.MemoryValueFromFieldElement(&felt)
+		}
+
+		for uint64(len(mem.Segments)) <= segmentIndex {
+			mem.AllocateEmptySegment()
+		}
+		if err := mem.Write(segmentIndex, offset, &cell); err != nil {
+			return nil, err
+		}
+	}
+	return mem, nil
+}
+
+func reverseBytes(bytes []byte) {
+	for l, r := 0, len(bytes)-1; l < r; l, r = l+1, r-1 {
+		bytes[l], bytes[r] = bytes[r], bytes[l]
+	}
+}
@@ -0,0 +1,46 @@
+package zero
+
+import (
+	"testing"
+
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelocatableMemoryRoundTrip(t *testing.T) {
+	mem := memory.InitializeEmptyMemory()
+	mem.AllocateEmptySegment()
+	mem.AllocateEmptySegment()
+	mem.AllocateEmptySegment()
+
+	cells := []struct {
+		segment uint64
+		offset  uint64
+		value   memory.MemoryValue
+	}{
+		{0, 0, memory.MemoryValueFromInt(1)},
+		{0, 1, memory.MemoryValueFromInt(-1)},
+		{0, 4, memory.MemoryValueFromSegmentAndOffset(2, 3)},
+		{2, 0, memory.MemoryValueFromSegmentAndOffset(0, 1<<40)},
+		{2, 2, memory.MemoryValueFromInt(1 << 62)},
+	}
+	for _, cell := range cells {
+		require.NoError(t, mem.Write(cell.segment, cell.offset, &cell.value))
+	}
+
+	content := EncodeRelocatableMemory(mem)
+	require.Len(t, content, len(cells)*(addrSize+feltSize))
+
+	decoded, err := DecodeRelocatableMemory(content)
+	require.NoError(t, err)
+	assert.Equal(t, mem.String(), decoded.String())
+}
+
+func TestDecodeRelocatableMemoryInvalid(t *testing.T) {
+	_, err := DecodeRelocatableMemory(make([]byte, addrSize+feltSize-1))
+	require.ErrorContains(t, err, "is not a multiple of 40")
+
+	_, err = DecodeRelocatableMemory(make([]byte, addrSize+feltSize))
+	require.ErrorContains(t, err, "is not relocatable")
+}
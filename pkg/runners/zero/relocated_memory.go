@@ -0,0 +1,52 @@
+package zero
+
+import (
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+)
+
+// A read only view of a relocated memory. Cells are handed out as copies, so
+// the same memory can be shared by several verification passes without any
+// of them changing what the others see
+type RelocatedMemory struct {
+	cells []*f.Element
+}
+
+// Copies the cells, later changes to them don't affect the memory
+func NewRelocatedMemory(cells []*f.Element) RelocatedMemory {
+	copied := make([]*f.Element, len(cells))
+	for i, cell := range cells {
+		if cell != nil {
+			felt := *cell
+			copied[i] = &felt
+		}
+	}
+	return RelocatedMemory{cells: copied}
+}
+
+// Decodes a memory encoded with EncodeMemory
+func DecodeRelocatedMemory(content []byte) RelocatedMemory {
+	// the decoded cells are not shared with anyone
+	return RelocatedMemory{cells: DecodeMemory(content)}
+}
+
+// Returns the value of a cell and whether it is known
+func (memory RelocatedMemory) Get(index uint64) (f.Element, bool) {
+	if index >= uint64(len(memory.cells)) || memory.cells[index] == nil {
+		return f.Element{}, false
+	}
+	return *memory.cells[index], true
+}
+
+// Returns the amount of cells, up to the last known one
+func (memory RelocatedMemory) Len() uint64 {
+	return uint64(len(memory.cells))
+}
+
+// Returns a copy of the cells, unknown cells are nil
+func (memory RelocatedMemory) Cells() []*f.Element {
+	return NewRelocatedMemory(memory.cells).cells
+}
+
+func (memory RelocatedMemory) Encode() []byte {
+	return EncodeMemory(memory.cells)
+}
@@ -0,0 +1,35 @@
+package zero
+
+import (
+	"testing"
+
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelocatedMemory(t *testing.T) {
+	cells := []*f.Element{nil, new(f.Element).SetUint64(7), nil, new(f.Element).SetUint64(9)}
+	memory := NewRelocatedMemory(cells)
+
+	// neither the cells it was built from nor the returned values alias it
+	cells[1].SetUint64(8)
+	value, ok := memory.Get(1)
+	require.True(t, ok)
+	assert.Equal(t, *new(f.Element).SetUint64(7), value)
+	value.SetUint64(10)
+	memory.Cells()[3].SetUint64(11)
+	value, _ = memory.Get(1)
+	assert.Equal(t, *new(f.Element).SetUint64(7), value)
+	value, _ = memory.Get(3)
+	assert.Equal(t, *new(f.Element).SetUint64(9), value)
+
+	_, ok = memory.Get(2)
+	assert.False(t, ok)
+	_, ok = memory.Get(4)
+	assert.False(t, ok)
+	assert.Equal(t, uint64(4), memory.Len())
+
+	decoded := DecodeRelocatedMemory(memory.Encode())
+	assert.Equal(t, memory, decoded)
+}
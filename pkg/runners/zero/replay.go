@@ -0,0 +1,108 @@
+package zero
+
+import (
+	"fmt"
+
+	"github.com/NethermindEth/cairo-vm-go/pkg/hintrunner"
+	"github.com/NethermindEth/cairo-vm-go/pkg/safemath"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+)
+
+// Runs the program in proof mode again, with its hints, and checks that it
+// reproduces a previously captured relocated trace and memory, such as the
+// ones written by BuildProof. Errors at the first step whose context differs
+// from the trace, or at the first cell where the final memories differ.
+// Runs reading past the end of the program are not supported
+func Replay(program *Program, trace []vm.Trace, relocatedMemory []*f.Element) error {
+	runner, err := NewRunner(program, true, uint64(len(trace)))
+	if err != nil {
+		return err
+	}
+	if err := runner.SetHintParser(hintrunner.StandardHintParser{}); err != nil {
+		return err
+	}
+	if _, err := runner.InitializeMainEntrypoint(); err != nil {
+		return fmt.Errorf("initializing main entry point: %w", err)
+	}
+	runner.runFinished = true
+
+	// the execution segment is relocated right after the program, as long as
+	// the run doesn't read past its end
+	executionOffset := runner.programSize() + 1
+	for step := range trace {
+		if size := runner.segments()[vm.ProgramSegment].Len(); size > runner.programSize() {
+			return fmt.Errorf(
+				"replay step %d: the program segment grew to %d cells past the %d words program",
+				step, size, runner.programSize(),
+			)
+		}
+		ours, err := runner.vm.Context.Relocate(executionOffset)
+		if err != nil {
+			return fmt.Errorf("replay step %d: %w", step, err)
+		}
+		if ours != trace[step] {
+			diff := TraceDiff{Step: uint64(step), Ours: &ours, Theirs: &trace[step]}
+			return fmt.Errorf("replay diverged: %s", diff)
+		}
+		if err := runner.runStep(); err != nil {
+			return fmt.Errorf("replay step %d: %w", step, err)
+		}
+	}
+
+	oursRelocated, err := runner.relocatedMemory()
+	if err != nil {
+		return err
+	}
+	return diffReplayMemory(
+		oursRelocated.cells, relocatedMemory, runner.memoryManager.SegmentOffsets(),
+	)
+}
+
+// Splits both memories into the segments of the replayed run and returns an
+// error pointing at the first cell where they disagree
+func diffReplayMemory(ours, theirs []*f.Element, segmentOffsets []uint64) error {
+	ourMemory, err := memory.BuildMemoryFromRelocated(ours, segmentOffsets)
+	if err != nil {
+		return fmt.Errorf("replay memory: %w", err)
+	}
+	theirMemory, err := memory.BuildMemoryFromRelocated(theirs, segmentOffsets)
+	if err != nil {
+		return fmt.Errorf("replay memory: %w", err)
+	}
+
+	for i := range theirMemory.Segments {
+		ourSegment, theirSegment := ourMemory.Segments[i], theirMemory.Segments[i]
+		// trailing unknown cells are not encoded, so either last segment can be shorter
+		length := safemath.Max(ourSegment.Len(), theirSegment.Len())
+		for offset := uint64(0); offset < length; offset++ {
+			ourValue := segmentFelt(ourSegment, offset)
+			theirValue := segmentFelt(theirSegment, offset)
+			if ourValue == nil && theirValue == nil {
+				continue
+			}
+			if ourValue != nil && theirValue != nil && ourValue.Equal(theirValue) {
+				continue
+			}
+			address := memory.MemoryAddress{SegmentIndex: uint64(i), Offset: offset}
+			return fmt.Errorf(
+				"replay diverged: memory at %s (relocated %d) mismatch %s vs %s",
+				address, segmentOffsets[i]+offset, feltRepr(ourValue, 10), feltRepr(theirValue, 10),
+			)
+		}
+	}
+	return nil
+}
+
+// Returns the felt of a rebuilt cell, nil when it is unknown
+func segmentFelt(segment *memory.Segment, offset uint64) *f.Element {
+	if offset >= segment.Len() || !segment.Data[offset].Known() {
+		return nil
+	}
+	felt, err := segment.Data[offset].ToFieldElement()
+	if err != nil {
+		return nil
+	}
+	return felt
+}
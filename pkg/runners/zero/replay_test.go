@@ -0,0 +1,102 @@
+package zero
+
+import (
+	"math"
+	"testing"
+
+	"github.com/NethermindEth/cairo-vm-go/pkg/hintrunner"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+	"github.com/stretchr/testify/require"
+)
+
+func replayProgram(t *testing.T) (*Program, []vm.Trace, []*f.Element) {
+	program := createDefaultProgram(`
+        [ap] = 2, ap++;
+        [ap] = 3, ap++;
+        [ap] = [ap - 1] + [ap - 2], ap++;
+        jmp rel 0;
+    `)
+	trace, memory := captureProofModeRun(t, program)
+	return program, trace, memory
+}
+
+// Runs a program ending with jmp rel 0 in proof mode, with its hints
+func captureProofModeRun(t *testing.T, program *Program) ([]vm.Trace, []*f.Element) {
+	// properties required by proofmode
+	program.Labels = map[string]uint64{
+		"__start__": 0,
+		"__end__":   uint64(len(program.Bytecode) - 2),
+	}
+
+	runner, err := NewRunner(program, true, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.SetHintParser(hintrunner.StandardHintParser{}))
+	require.NoError(t, runner.Run())
+	trace, memory, err := runner.BuildProof()
+	require.NoError(t, err)
+	return DecodeTrace(trace), DecodeMemory(memory)
+}
+
+func TestReplay(t *testing.T) {
+	program, trace, memory := replayProgram(t)
+	require.NoError(t, Replay(program, trace, memory))
+}
+
+func TestReplayWithHints(t *testing.T) {
+	// the second instruction can only run once the hint wrote the cell it adds to
+	program := createDefaultProgram(`
+        ap += 1;
+        [ap] = [ap - 1] + 2, ap++;
+        jmp rel 0;
+    `)
+	program.Hints = map[uint64][]Hint{0: {{Code: "memory[ap] = 5"}}}
+	trace, memory := captureProofModeRun(t, program)
+	require.NoError(t, Replay(program, trace, memory))
+}
+
+func TestReplayTraceDivergence(t *testing.T) {
+	program, trace, memory := replayProgram(t)
+	trace[2].Ap++
+
+	err := Replay(program, trace, memory)
+	require.EqualError(t, err, "replay diverged: step 2 ap mismatch 12 vs 13")
+}
+
+func TestReplayMemoryDivergence(t *testing.T) {
+	program, trace, memory := replayProgram(t)
+	// the sum written by the third instruction
+	sum := trace[2].Ap
+	cells := append([]*f.Element{}, memory...)
+	cells[sum] = new(f.Element).SetUint64(6)
+
+	err := Replay(program, trace, cells)
+	require.EqualError(
+		t, err, "replay diverged: memory at 1:4 (relocated 12) mismatch 5 vs 6",
+	)
+	// the memory the cells were copied from is unchanged
+	require.NoError(t, Replay(program, trace, memory))
+
+	// a cell that the run never wrote
+	cells = append(memory[:len(memory):len(memory)], new(f.Element).SetUint64(1))
+	err = Replay(program, trace, cells)
+	require.ErrorContains(t, err, "mismatch unknown vs 1")
+}
+
+func TestReplayProgramSegmentGrowth(t *testing.T) {
+	// the dummy fp of proof mode points past the end of the program segment
+	program := createDefaultProgram(`
+        [ap] = 7, ap++;
+        [ap - 1] = [[fp - 2]];
+        jmp rel 0;
+    `)
+	trace, memory := captureProofModeRun(t, program)
+	// relocated as if the execution segment still followed the program
+	for i := range trace {
+		trace[i].Ap -= 3
+		trace[i].Fp -= 3
+	}
+
+	err := Replay(program, trace, memory)
+	require.EqualError(t, err, "replay step 2: the program segment grew to 8 cells past the 5 words program")
+}
@@ -0,0 +1,72 @@
+package zero
+
+import (
+	"encoding/json"
+	"errors"
+
+	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+)
+
+// Everything a run produced, as a single document for programmatic consumers
+type RunResult struct {
+	Success bool `json:"success"`
+	// why the run failed, empty if it succeeded
+	Error string `json:"error,omitempty"`
+	Steps uint64 `json:"steps"`
+	// amount of instances used by each builtin, keyed by the builtin name
+	BuiltinInstanceCounter map[string]uint64 `json:"builtin_instance_counter"`
+	// values of the output builtin segment, null for the cells never written.
+	// Omitted if the program doesn't use the output builtin
+	Output []*string `json:"output,omitempty"`
+	// omitted in proof mode or if main didn't return
+	ReturnValues []string `json:"return_values,omitempty"`
+}
+
+// Returns the result of the last run, failed or not. Felts are formatted in
+// the radix set by SetFeltRadix
+func (runner *ZeroRunner) RunResult() (RunResult, error) {
+	if !runner.runFinished {
+		return RunResult{}, errors.New("run result requires running the program first")
+	}
+
+	result := RunResult{
+		Success:                runner.runErr == nil,
+		Steps:                  runner.steps(),
+		BuiltinInstanceCounter: make(map[string]uint64, len(runner.builtins)),
+	}
+	if runner.runErr != nil {
+		result.Error = runner.runErr.Error()
+	}
+	for i, builtin := range runner.builtins {
+		segment := runner.segments()[VM.ExecutionSegment+1+i]
+		result.BuiltinInstanceCounter[builtin.String()] = segment.BuiltinRunner.InstancesUsed(segment)
+	}
+
+	// both are only available once the program went far enough
+	if output, err := runner.Output(); err == nil {
+		result.Output = make([]*string, len(output))
+		for i := range output {
+			if output[i].Known() {
+				value := output[i].Text(runner.feltRadix)
+				result.Output[i] = &value
+			}
+		}
+	}
+	if values, err := runner.MainReturnValues(); err == nil {
+		result.ReturnValues = make([]string, len(values))
+		for i := range values {
+			result.ReturnValues[i] = memory.FeltString(&values[i], runner.feltRadix)
+		}
+	}
+	return result, nil
+}
+
+// Returns the result of the last run encoded as JSON, see RunResult
+func (runner *ZeroRunner) RunResultJSON() ([]byte, error) {
+	result, err := runner.RunResult()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
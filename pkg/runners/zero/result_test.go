@@ -0,0 +1,71 @@
+package zero
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunResultJSON(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = 7, ap++;
+        [ap - 1] = [[fp - 3]];
+        [ap] = [fp - 3] + 1, ap++;
+        [ap] = 9, ap++;
+        ret;
+    `)
+	program.builtins = []starknetParser.Builtin{starknetParser.Output}
+	program.returnSizes = map[string]uint64{"main": 1}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	_, err = runner.RunResultJSON()
+	require.EqualError(t, err, "run result requires running the program first")
+
+	require.NoError(t, runner.Run())
+	result, err := runner.RunResultJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+        "success": true,
+        "steps": 5,
+        "builtin_instance_counter": {"output": 1},
+        "output": ["7"],
+        "return_values": ["9"]
+    }`, string(result))
+
+	require.NoError(t, runner.SetFeltRadix(16))
+	resultHex, err := runner.RunResult()
+	require.NoError(t, err)
+	assert.Equal(t, "0x7", *resultHex.Output[0])
+	assert.Equal(t, []string{"0x9"}, resultHex.ReturnValues)
+	require.EqualError(t, runner.SetFeltRadix(8), "unsupported felt radix 8, expected 10 or 16")
+
+	// a failed run reports why and what it got to do
+	program = createDefaultProgram(`
+        [ap] = 1, ap++;
+        [ap - 1] = 2;
+        ret;
+    `)
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	runErr := runner.Run()
+	require.Error(t, runErr)
+	result, err = runner.RunResultJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+        "success": false,
+        "error": `+jsonString(t, runErr.Error())+`,
+        "steps": 1,
+        "builtin_instance_counter": {}
+    }`, string(result))
+}
+
+func jsonString(t *testing.T, value string) string {
+	encoded, err := json.Marshal(value)
+	require.NoError(t, err)
+	return string(encoded)
+}
@@ -0,0 +1,73 @@
+package zero
+
+import (
+	"errors"
+	"fmt"
+
+	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+)
+
+// Checks that a finished run of an untrusted program is sound and can be
+// proven, like the python vm does for secure runs:
+//   - no cell past the program bytecode was accessed in the program segment
+//   - every known cell of a builtin segment passes the builtin checks, which
+//     also verifies the values the builtins inferred
+//   - every address stored in memory points into an allocated segment, at
+//     most one cell past its end
+func (runner *ZeroRunner) VerifySecure() error {
+	if !runner.runFinished {
+		return errors.New("secure run verification requires running the program first")
+	}
+
+	programSize := runner.programSize()
+	if size := runner.segments()[VM.ProgramSegment].Len(); size > programSize {
+		return fmt.Errorf(
+			"out of bounds access to the program segment: cell %d of a %d words program",
+			size-1, programSize,
+		)
+	}
+
+	for i, builtin := range runner.builtins {
+		index := VM.ExecutionSegment + 1 + i
+		segment := runner.segments()[index]
+		for offset := uint64(0); offset < segment.Len(); offset++ {
+			cell := segment.Peek(offset)
+			if !cell.Known() {
+				continue
+			}
+			if err := segment.BuiltinRunner.CheckWrite(segment, offset, &cell); err != nil {
+				return fmt.Errorf("builtin %s cell %d:%d: %w", builtin, index, offset, err)
+			}
+		}
+	}
+
+	for i, segment := range runner.segments() {
+		for offset := uint64(0); offset < segment.Len(); offset++ {
+			cell := segment.Peek(offset)
+			if !cell.IsAddress() {
+				continue
+			}
+			// the proof mode dummy fp points past the program on purpose
+			if runner.proofmode && i == VM.ExecutionSegment && offset == 0 {
+				continue
+			}
+			address, _ := cell.ToMemoryAddress()
+			if err := runner.checkAddressInBounds(address); err != nil {
+				return fmt.Errorf("cell %d:%d: %w", i, offset, err)
+			}
+		}
+	}
+	return nil
+}
+
+// End pointers of a segment are one past its last cell, so they are allowed
+func (runner *ZeroRunner) checkAddressInBounds(address *memory.MemoryAddress) error {
+	if address.SegmentIndex >= uint64(len(runner.segments())) {
+		return fmt.Errorf("address %s points to an unallocated segment", address)
+	}
+	if size := runner.segments()[address.SegmentIndex].Len(); address.Offset > size {
+		return fmt.Errorf("address %s is past the end of its segment of size %d", address, size)
+	}
+	return nil
+}
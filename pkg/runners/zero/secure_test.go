@@ -0,0 +1,94 @@
+package zero
+
+import (
+	"math"
+	"testing"
+
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
+	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func secureRunner(t *testing.T) *ZeroRunner {
+	program := createDefaultProgram(`
+        [ap] = [fp - 3] + 1, ap++;
+        ret;
+    `)
+	program.builtins = []starknetParser.Builtin{starknetParser.RangeCheck}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.Run())
+
+	// the instance returned by main
+	value := memory.MemoryValueFromInt(5)
+	require.NoError(t, runner.memory().Write(2, 0, &value))
+	return runner
+}
+
+func TestVerifySecure(t *testing.T) {
+	runner := secureRunner(t)
+	require.NoError(t, runner.VerifySecure())
+
+	// the dummy fp of proof mode points past the program
+	program := createDefaultProgram(`
+        [ap] = 2, ap++;
+        jmp rel 0;
+    `)
+	program.Labels = map[string]uint64{"__start__": 0, "__end__": 2}
+	runner, err := NewRunner(program, true, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.Run())
+	require.NoError(t, runner.VerifySecure())
+
+	program = createDefaultProgram("ret;")
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.EqualError(
+		t, runner.VerifySecure(), "secure run verification requires running the program first",
+	)
+}
+
+func TestVerifySecureProgramOutOfBounds(t *testing.T) {
+	runner := secureRunner(t)
+	size := runner.segments()[VM.ProgramSegment].Len()
+	_, err := runner.memory().Read(VM.ProgramSegment, size+1)
+	require.NoError(t, err)
+
+	require.EqualError(
+		t, runner.VerifySecure(),
+		"out of bounds access to the program segment: cell 4 of a 3 words program",
+	)
+}
+
+func TestVerifySecureBuiltinCell(t *testing.T) {
+	runner := secureRunner(t)
+	// skips the range check so only the final verification catches it
+	value := memory.MemoryValueFromInt(-1)
+	require.NoError(t, runner.segments()[2].WriteInferred(1, &value))
+
+	require.ErrorContains(t, runner.VerifySecure(), "builtin range_check cell 2:1:")
+}
+
+func TestVerifySecureAddressOutOfBounds(t *testing.T) {
+	runner := secureRunner(t)
+	executionEnd := runner.segments()[VM.ExecutionSegment].Len()
+
+	// a pointer to the end of a segment is allowed
+	end := memory.MemoryValueFromSegmentAndOffset(VM.ExecutionSegment, executionEnd)
+	require.NoError(t, runner.memory().Write(VM.ExecutionSegment, executionEnd, &end))
+	require.NoError(t, runner.VerifySecure())
+
+	past := memory.MemoryValueFromSegmentAndOffset(VM.ProgramSegment, 10)
+	require.NoError(t, runner.memory().Write(VM.ExecutionSegment, executionEnd+1, &past))
+	require.EqualError(
+		t, runner.VerifySecure(),
+		"cell 1:5: address 0:10 is past the end of its segment of size 3",
+	)
+
+	runner = secureRunner(t)
+	unallocated := memory.MemoryValueFromSegmentAndOffset(42, 0)
+	require.NoError(t, runner.memory().Write(VM.ExecutionSegment, executionEnd, &unallocated))
+	require.ErrorContains(t, runner.VerifySecure(), "address 42:0 points to an unallocated segment")
+}
@@ -4,15 +4,27 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
+	"strconv"
+	"time"
 
 	"github.com/NethermindEth/cairo-vm-go/pkg/hintrunner"
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
 	"github.com/NethermindEth/cairo-vm-go/pkg/safemath"
 	"github.com/NethermindEth/cairo-vm-go/pkg/vm"
 	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/builtins"
 	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
 	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
 )
 
+// Steps run between each check of the run deadline
+const timeoutCheckInterval = 1 << 10
+
+// Upper bound of the initial execution segment capacity, so huge programs
+// don't reserve a huge amount of memory up front
+const maxExecutionSegmentCapacity = 1 << 20
+
 type ZeroRunner struct {
 	memoryManager *memory.MemoryManager
 	// core components
@@ -22,44 +34,325 @@ type ZeroRunner struct {
 	// config
 	proofmode bool
 	maxsteps  uint64
+	// when set, writing into the program segment after loading it fails
+	readOnlyProgram bool
+	// when set, identical instruction words are decoded only once
+	bytecodeCache bool
+	// wall clock limit of a run, 0 means no limit
+	timeout time.Duration
+	// when the run must stop, set once it starts if there is a timeout
+	deadline time.Time
+	// the builtins of the program in the order their segments are allocated
+	builtins []starknetParser.Builtin
+	// the builtins of the program computed through the hash hint fallback,
+	// their plain segments are allocated after the builtin ones in this order
+	hintedBuiltins []starknetParser.Builtin
+	// builtin runners used instead of the default ones, see WithBuiltin
+	builtinRunners map[starknetParser.Builtin]memory.BuiltinRunner
+	// layout the runner was created with, nil if none
+	layout *Layout
+	// when set, the step at which each builtin cell is first accessed is recorded
+	accessLog bool
+	// when set, the relocated trace is written there every traceFlushSteps steps
+	// instead of being kept in memory until BuildProof
+	traceWriter     io.Writer
+	traceFlushSteps int
+	// where the metrics of each run are reported, see MetricsSink
+	metrics MetricsSink
+	// the program hints translated by SetHintParser, keyed by pc
+	hints map[uint64][]hintrunner.Hinter
+	// callbacks registered with RegisterOracle, keyed by pc
+	oracles map[uint64]func(vm *VM.VirtualMachine) ([]f.Element, error)
+	// array length the search hints accept, 0 means no limit
+	findElementMaxSize uint64
+	// radix the felts of RunResult are printed in, decimal unless it is 16
+	feltRadix int
+	// code of the hints SetHintParser accepts, nil means any hint
+	allowedHints map[string]bool
 	// auxiliar
 	runFinished bool
+	// what the last run failed with, see RunResult
+	runErr error
+	// offsets of the execution segment cells that are public, i.e. the stack
+	// main starts with in proof mode
+	executionPublicMemory []uint64
+	// segments holding the return fp and return pc of the main entrypoint,
+	// only allocated when not running in proof mode
+	retFpSegment uint64
+	retPcSegment uint64
 }
 
-// Creates a new Runner of a Cairo Zero program
+// Creates a new Runner of a Cairo Zero program. In proof mode the program
+// must have been compiled for it, see Program.ValidateForProofMode
 func NewRunner(program *Program, proofmode bool, maxsteps uint64) (*ZeroRunner, error) {
-	memoryManager := memory.CreateMemoryManager()
-	_, err := memoryManager.Memory.AllocateSegment(program.Bytecode) // ProgramSegment
+	return newRunner(program, proofmode, maxsteps, program.builtins, nil, nil)
+}
+
+// Creates a new Runner of a Cairo Zero program restricted to the builtins of
+// a layout. Errors if the program declares a builtin the layout doesn't have,
+// unless it falls back to hints, see Layout.HashHintFallback. Builtin segments
+// are allocated in the layout canonical order
+func NewRunnerWithLayout(program *Program, proofmode bool, maxsteps uint64, layout Layout) (*ZeroRunner, error) {
+	builtins, hintedBuiltins, err := layout.canonicalOrder(program.builtins)
 	if err != nil {
+		return nil, fmt.Errorf("runner error: %w", err)
+	}
+	// the prover only knows the builtins of the layout
+	if proofmode && len(hintedBuiltins) > 0 {
+		return nil, fmt.Errorf(
+			"runner error: builtin %s is not available in layout %s and can't fall back to hints in proof mode",
+			hintedBuiltins[0], layout.Name,
+		)
+	}
+	return newRunner(program, proofmode, maxsteps, builtins, hintedBuiltins, &layout)
+}
+
+func newRunner(
+	program *Program,
+	proofmode bool,
+	maxsteps uint64,
+	programBuiltins []starknetParser.Builtin,
+	hintedBuiltins []starknetParser.Builtin,
+	layout *Layout,
+) (*ZeroRunner, error) {
+	if proofmode {
+		if err := program.ValidateForProofMode(); err != nil {
+			return nil, fmt.Errorf("runner error: %w", err)
+		}
+	}
+	runner := &ZeroRunner{
+		program:        program,
+		builtins:       programBuiltins,
+		hintedBuiltins: hintedBuiltins,
+		layout:         layout,
+		proofmode:      proofmode,
+		maxsteps:       maxsteps,
+		metrics:        noopMetricsSink{},
+	}
+	if err := runner.initialize(); err != nil {
 		return nil, err
 	}
-	memoryManager.Memory.AllocateEmptySegment() // ExecutionSegment
+	return runner, nil
+}
+
+// Allocates fresh memory, builtin runners, vm and hint runner for a run,
+// discarding any state left by a previous one
+func (runner *ZeroRunner) initialize() error {
+	memoryManager := memory.CreateMemoryManager()
+	// ProgramSegment
+	programSegment := 0
+	if runner.program.rawBytecode != nil {
+		programSegment = memoryManager.Memory.AllocateLazySegment(runner.program.rawBytecode)
+	} else {
+		var err error
+		programSegment, err = memoryManager.Memory.AllocateSegment(runner.program.Bytecode)
+		if err != nil {
+			return err
+		}
+	}
+	memoryManager.Memory.Segments[programSegment].Name = VM.ProgramSegmentName
+	memoryManager.Memory.Segments[programSegment].ReadOnly = runner.readOnlyProgram
+	executionSegment := memoryManager.AllocateEmptySegmentWithCapacity(runner.executionSegmentCapacity())
+	memoryManager.Memory.Segments[executionSegment].Name = VM.ExecutionSegmentName
+	if err := VM.CheckFixedSegments(memoryManager.Memory); err != nil {
+		return fmt.Errorf("runner error: %w", err)
+	}
+
+	// builtin segments are allocated right after in the given order
+	for _, builtin := range runner.builtins {
+		memoryManager.Memory.AllocateBuiltinSegment(builtin.String(), runner.builtinRunner(builtin))
+	}
+	// followed by the unnamed ones of the hinted builtins
+	for _, builtin := range runner.hintedBuiltins {
+		index := memoryManager.Memory.AllocateEmptySegment()
+		memoryManager.Memory.Segments[index].BuiltinRunner = &hashHint{runner.builtinRunner(builtin)}
+	}
 
 	// initialize vm
-	vm, err := VM.NewVirtualMachine(vm.Context{}, memoryManager.Memory, vm.VirtualMachineConfig{ProofMode: proofmode})
+	vm, err := VM.NewVirtualMachine(
+		vm.Context{},
+		memoryManager.Memory,
+		vm.VirtualMachineConfig{ProofMode: runner.proofmode, CacheByBytecode: runner.bytecodeCache},
+	)
 	if err != nil {
-		return nil, fmt.Errorf("runner error: %w", err)
+		return fmt.Errorf("runner error: %w", err)
+	}
+	if runner.accessLog {
+		for i := range runner.builtins {
+			memoryManager.Memory.Segments[executionSegment+1+i].RecordAccesses(&vm.Step)
+		}
 	}
 
-	// todo(rodro): given the program get the appropiate hints
-	hintrunner := hintrunner.NewHintRunner(make(map[uint64]hintrunner.Hinter))
+	if runner.traceWriter != nil {
+		vm.SetTraceFlusher(runner.traceFlushSteps, runner.writeTrace)
+	}
 
-	return &ZeroRunner{
-		memoryManager: memoryManager,
-		program:       program,
-		vm:            vm,
-		hintrunner:    hintrunner,
-		proofmode:     proofmode,
-		maxsteps:      maxsteps,
-	}, nil
+	runner.memoryManager = memoryManager
+	runner.vm = vm
+	hintRunner, err := runner.newHintRunner(runner.hints)
+	if err != nil {
+		return err
+	}
+	runner.hintrunner = hintRunner
+	runner.runFinished = false
+	runner.runErr = nil
+	runner.deadline = time.Time{}
+	runner.retFpSegment = 0
+	runner.retPcSegment = 0
+	runner.executionPublicMemory = nil
+	return nil
+}
+
+// Returns the runner registered for a builtin with WithBuiltin, its default
+// runner otherwise. A registered runner holding state, see
+// memory.BuiltinCloner, is cloned so every run starts from its registered
+// state. A builtin without any runner gets one that fails as soon as it is
+// used, so a runner can still be registered after creating the ZeroRunner
+func (runner *ZeroRunner) builtinRunner(builtin starknetParser.Builtin) memory.BuiltinRunner {
+	if builtinRunner, ok := runner.builtinRunners[builtin]; ok {
+		if cloner, ok := builtinRunner.(memory.BuiltinCloner); ok {
+			return cloner.Clone()
+		}
+		return builtinRunner
+	}
+	builtinRunner, err := builtins.Runner(builtin)
+	if err != nil {
+		return &unsupportedBuiltin{builtin: builtin, err: err}
+	}
+	return builtinRunner
+}
+
+// Makes the segment of a builtin use builtinRunner instead of the default
+// one, e.g. to supply a faster implementation or one of a builtin the vm
+// doesn't implement. The runner must be named after the builtin and is kept
+// across Reset: a stateless one is shared by every run, a memory.BuiltinCloner
+// is cloned for each. Registering a builtin the program doesn't use does
+// nothing. Must be called before running
+func (runner *ZeroRunner) WithBuiltin(name string, builtinRunner memory.BuiltinRunner) error {
+	var builtin starknetParser.Builtin
+	if err := builtin.UnmarshalJSON([]byte(strconv.Quote(name))); err != nil {
+		return fmt.Errorf("cannot register builtin runner: %w", err)
+	}
+	if builtinRunner.String() != name {
+		return fmt.Errorf("cannot register the %s builtin runner for builtin %s", builtinRunner, name)
+	}
+
+	if runner.builtinRunners == nil {
+		runner.builtinRunners = make(map[starknetParser.Builtin]memory.BuiltinRunner)
+	}
+	runner.builtinRunners[builtin] = builtinRunner
+	for i := range runner.builtins {
+		if runner.builtins[i] == builtin {
+			runner.segments()[VM.ExecutionSegment+1+i].BuiltinRunner = runner.builtinRunner(builtin)
+		}
+	}
+	if index, ok := runner.hintedBuiltinSegment(builtin); ok {
+		runner.segments()[index].BuiltinRunner = &hashHint{runner.builtinRunner(builtin)}
+	}
+	return nil
+}
+
+// Computes the hashes of a builtin the layout lacks in a plain segment, see
+// Layout.HashHintFallback. Only the cells are computed: the segment has no
+// name, instances, padding nor public memory, as the prover doesn't see it
+type hashHint struct {
+	memory.BuiltinRunner
+}
+
+func (h *hashHint) InstancesUsed(segment *memory.Segment) uint64 {
+	return 0
+}
+
+func (h *hashHint) Deduces(offset uint64) bool {
+	deducer, ok := h.BuiltinRunner.(memory.BuiltinDeducer)
+	return ok && deducer.Deduces(offset)
+}
+
+func (h *hashHint) String() string {
+	return h.BuiltinRunner.String() + " hints"
+}
+
+// Runs the segment of a builtin without implementation, failing on any use
+type unsupportedBuiltin struct {
+	builtin starknetParser.Builtin
+	err     error
+}
+
+func (b *unsupportedBuiltin) CheckWrite(segment *memory.Segment, offset uint64, value *memory.MemoryValue) error {
+	return b.err
+}
+
+func (b *unsupportedBuiltin) InferValue(segment *memory.Segment, offset uint64) error {
+	return b.err
+}
+
+func (b *unsupportedBuiltin) InstancesUsed(segment *memory.Segment) uint64 {
+	return 0
+}
+
+func (b *unsupportedBuiltin) String() string {
+	return b.builtin.String()
+}
+
+// Programs with more bytecode tend to run for longer, so the execution segment
+// is sized after the program. It is never bigger than what maxsteps allows,
+// since ap advances at most two cells per step
+func (runner *ZeroRunner) executionSegmentCapacity() int {
+	capacity := uint64(safemath.Max(len(runner.program.Bytecode), len(runner.program.rawBytecode)))
+	capacity = safemath.Max(capacity, memory.DefaultSegmentCapacity)
+	capacity = safemath.Min(capacity, maxExecutionSegmentCapacity)
+
+	stepsBound, overflow := safemath.SafeMul(runner.maxsteps, 2)
+	if !overflow {
+		// the builtin bases, return fp and return pc are written before running
+		stepsBound, overflow = safemath.SafeAdd(stepsBound, uint64(len(runner.builtins))+2)
+	}
+	if !overflow {
+		capacity = safemath.Min(capacity, stepsBound)
+	}
+	return int(capacity)
+}
+
+// Discards the state of the previous run so the runner can run the program
+// again with the same configuration. Profiling stays enabled if it was
+func (runner *ZeroRunner) Reset() error {
+	profiling := runner.vm.ProfileStats() != nil
+	tracing := runner.vm.RawTrace() != nil
+	if err := runner.initialize(); err != nil {
+		return err
+	}
+	if profiling {
+		runner.vm.EnableProfiling()
+	}
+	if tracing {
+		runner.vm.EnableTracing()
+	}
+	return nil
 }
 
 // todo(rodro): should we add support for running any function?
 func (runner *ZeroRunner) Run() error {
 	if runner.runFinished {
-		return errors.New("cannot re-run using the same runner")
+		return errors.New("cannot re-run using the same runner, call Reset first")
+	}
+	// a failed run leaves the memory dirty as well
+	runner.runFinished = true
+	defer runner.reportMetrics(time.Now())
+
+	runner.runErr = runner.run()
+	runner.compactSegments()
+	return runner.runErr
+}
+
+// Releases the spare capacity segments grew while running, so it isn't held
+// while relocating and encoding the memory
+func (runner *ZeroRunner) compactSegments() {
+	for _, segment := range runner.segments() {
+		segment.Compact()
 	}
+}
 
+func (runner *ZeroRunner) run() error {
 	end, err := runner.InitializeMainEntrypoint()
 	if err != nil {
 		return fmt.Errorf("initializing main entry point: %w", err)
@@ -71,29 +364,89 @@ func (runner *ZeroRunner) Run() error {
 	}
 
 	if runner.proofmode {
-		// proof mode require an extra instruction run
-		if err := runner.RunFor(1); err != nil {
+		if err := runner.padTrace(); err != nil {
 			return err
 		}
+		return runner.finalizeBuiltins()
+	}
+	return nil
+}
 
-		// proof mode also requires that the trace is a power of two
-		pow2Steps := safemath.NextPowerOfTwo(runner.vm.Step)
-		if err := runner.RunFor(pow2Steps); err != nil {
+// Runs the end loop until the steps are a power of two, as proof mode requires.
+// Like the python vm, one extra step always runs first, so the last pc
+// executed is __end__ even when the steps already are a power of two
+func (runner *ZeroRunner) padTrace() error {
+	extraStep := runner.vm.Step + 1
+	pow2Steps, isOverflow := safemath.NextPowerOfTwo(extraStep)
+	if isOverflow {
+		return fmt.Errorf("proof-mode padding of %d steps overflows", extraStep)
+	}
+	if pow2Steps > runner.maxsteps {
+		return fmt.Errorf(
+			"proof-mode padding to %d steps exceeds maxsteps %d; increase maxsteps",
+			pow2Steps,
+			runner.maxsteps,
+		)
+	}
+
+	// RunFor counts the steps in total
+	if err := runner.RunFor(extraStep); err != nil {
+		return err
+	}
+	return runner.RunFor(pow2Steps)
+}
+
+// Checks the stop pointer main returns for each builtin, the cells right
+// below the final ap in the order the program declares its builtins. Each
+// must point right past the cells its builtin used, and is written if the
+// program left it unknown. Like the builtin bases, the stop pointers are
+// public so the prover can bound every builtin segment
+func (runner *ZeroRunner) finalizeBuiltins() error {
+	ap := runner.vm.Context.Ap
+	count := uint64(len(runner.program.builtins))
+	if count > ap {
+		return fmt.Errorf("%d builtin stop pointers don't fit below ap %d", count, ap)
+	}
+	executionSegment := runner.segments()[VM.ExecutionSegment]
+	for i, builtin := range runner.program.builtins {
+		index, err := runner.builtinSegmentIndex(builtin.String())
+		if err != nil {
 			return err
 		}
+		expected := memory.MemoryValueFromSegmentAndOffset(uint64(index), runner.segments()[index].Len())
+
+		offset := ap - count + uint64(i)
+		stopPtr := executionSegment.Peek(offset)
+		if !stopPtr.Known() {
+			if err := executionSegment.Write(offset, &expected); err != nil {
+				return fmt.Errorf("builtin %s stop pointer: %w", builtin, err)
+			}
+		} else if !stopPtr.Equal(&expected) {
+			return fmt.Errorf(
+				"invalid stop pointer for builtin %s at %d:%d: expected %s, found %s",
+				builtin, VM.ExecutionSegment, offset, expected, stopPtr,
+			)
+		}
+		// main may return its builtin base untouched in the very same cell
+		public := runner.executionPublicMemory
+		if len(public) == 0 || public[len(public)-1] < offset {
+			runner.executionPublicMemory = append(public, offset)
+		}
 	}
 	return nil
 }
 
 func (runner *ZeroRunner) InitializeMainEntrypoint() (memory.MemoryAddress, error) {
 	if runner.proofmode {
-		startPc, ok := runner.program.Labels["__start__"]
-		if !ok {
-			return memory.UnknownValue, errors.New("start label not found. Try compiling with `--proof_mode`")
+		if err := runner.program.ValidateForProofMode(); err != nil {
+			return memory.UnknownValue, err
 		}
-		endPc, ok := runner.program.Labels["__end__"]
-		if !ok {
-			return memory.UnknownValue, errors.New("end label not found. Try compiling with `--proof_mode`")
+		startPc := runner.program.Labels["__start__"]
+		endPc := runner.program.Labels["__end__"]
+
+		stack, err := runner.builtinsStack()
+		if err != nil {
+			return memory.UnknownValue, err
 		}
 
 		offset := runner.segments()[VM.ExecutionSegment].Len()
@@ -102,21 +455,21 @@ func (runner *ZeroRunner) InitializeMainEntrypoint() (memory.MemoryAddress, erro
 			VM.ProgramSegment,
 			runner.segments()[VM.ProgramSegment].Len()+offset+2,
 		)
-		// set dummy fp value
-		err := runner.memory().Write(
-			VM.ExecutionSegment,
-			offset,
-			&dummyFPValue,
+		dummyPCValue := memory.MemoryValueFromUint[uint64](0)
+		// the builtin base pointers follow the dummy fp and pc values, where
+		// ap and fp start
+		initialStack := append([]memory.MemoryValue{dummyFPValue, dummyPCValue}, stack...)
+		err = runner.memory().WriteRange(
+			&memory.MemoryAddress{SegmentIndex: VM.ExecutionSegment, Offset: offset}, initialStack,
 		)
 		if err != nil {
 			return memory.UnknownValue, err
 		}
 
-		dummyPCValue := memory.MemoryValueFromUint[uint64](0)
-		// set dummy pc value
-		err = runner.memory().Write(VM.ExecutionSegment, offset+1, &dummyPCValue)
-		if err != nil {
-			return memory.UnknownValue, err
+		// the dummy values and builtin bases are public
+		runner.executionPublicMemory = make([]uint64, 2+len(stack))
+		for i := range runner.executionPublicMemory {
+			runner.executionPublicMemory[i] = offset + uint64(i)
 		}
 
 		runner.vm.Context.Pc = memory.MemoryAddress{SegmentIndex: VM.ProgramSegment, Offset: startPc}
@@ -125,33 +478,39 @@ func (runner *ZeroRunner) InitializeMainEntrypoint() (memory.MemoryAddress, erro
 		return memory.MemoryAddress{SegmentIndex: VM.ProgramSegment, Offset: endPc}, nil
 	}
 
-	returnFp := memory.MemoryValueFromSegmentAndOffset(
-		runner.memory().AllocateEmptySegment(),
-		0,
-	)
-	return runner.InitializeEntrypoint("main", nil, &returnFp)
+	stack, err := runner.builtinsStack()
+	if err != nil {
+		return memory.UnknownValue, err
+	}
+
+	retFpSegment := runner.memory().AllocateEmptySegment()
+	returnFp := memory.MemoryValueFromSegmentAndOffset(retFpSegment, 0)
+	end, err := runner.InitializeEntrypoint("main", stack, &returnFp)
+	if err != nil {
+		return memory.UnknownValue, err
+	}
+	runner.retFpSegment = uint64(retFpSegment)
+	runner.retPcSegment = end.SegmentIndex
+	return end, nil
 }
 
 func (runner *ZeroRunner) InitializeEntrypoint(
-	funcName string, arguments []*f.Element, returnFp *memory.MemoryValue,
+	funcName string, arguments []memory.MemoryValue, returnFp *memory.MemoryValue,
 ) (memory.MemoryAddress, error) {
 	segmentIndex := runner.memory().AllocateEmptySegment()
 	end := memory.MemoryAddress{SegmentIndex: uint64(segmentIndex), Offset: 0}
 	// write arguments
-	for i := range arguments {
-		v := memory.MemoryValueFromFieldElement(arguments[i])
-		err := runner.memory().Write(VM.ExecutionSegment, uint64(i), &v)
-		if err != nil {
-			return memory.UnknownValue, err
-		}
-	}
-	offset := runner.segments()[VM.ExecutionSegment].Len()
-	err := runner.memory().Write(VM.ExecutionSegment, offset, returnFp)
+	err := runner.memory().WriteRange(
+		&memory.MemoryAddress{SegmentIndex: VM.ExecutionSegment, Offset: 0}, arguments,
+	)
 	if err != nil {
 		return memory.UnknownValue, err
 	}
-	endMV := memory.MemoryValueFromMemoryAddress(&end)
-	err = runner.memory().Write(VM.ExecutionSegment, offset+1, &endMV)
+	offset := runner.segments()[VM.ExecutionSegment].Len()
+	err = runner.memory().WriteRange(
+		&memory.MemoryAddress{SegmentIndex: VM.ExecutionSegment, Offset: offset},
+		[]memory.MemoryValue{*returnFp, memory.MemoryValueFromMemoryAddress(&end)},
+	)
 	if err != nil {
 		return memory.UnknownValue, err
 	}
@@ -168,8 +527,84 @@ func (runner *ZeroRunner) InitializeEntrypoint(
 	return end, nil
 }
 
+// Returns the base address of each builtin segment in the order the program
+// declares its builtins. They are the first arguments received by main.
+// Errors if a builtin is declared twice or its segment isn't where the
+// allocation order puts it, since the program would then use the wrong one
+func (runner *ZeroRunner) builtinsStack() ([]memory.MemoryValue, error) {
+	stack := make([]memory.MemoryValue, len(runner.program.builtins))
+	declared := make(map[starknetParser.Builtin]bool, len(runner.program.builtins))
+	for i, builtin := range runner.program.builtins {
+		if declared[builtin] {
+			return nil, fmt.Errorf("builtin %s is declared twice", builtin)
+		}
+		declared[builtin] = true
+
+		if index, ok := runner.hintedBuiltinSegment(builtin); ok {
+			stack[i] = memory.MemoryValueFromSegmentAndOffset(index, 0)
+			continue
+		}
+		index, err := runner.builtinSegmentIndex(builtin.String())
+		if err != nil {
+			return nil, err
+		}
+		if err := runner.checkBuiltinSegment(builtin, index); err != nil {
+			return nil, err
+		}
+		stack[i] = memory.MemoryValueFromSegmentAndOffset(index, 0)
+	}
+	return stack, nil
+}
+
+// Returns the index of the plain segment allocated for a builtin computed
+// through the hash hint fallback, if it is one
+func (runner *ZeroRunner) hintedBuiltinSegment(builtin starknetParser.Builtin) (int, bool) {
+	for i := range runner.hintedBuiltins {
+		if runner.hintedBuiltins[i] == builtin {
+			return VM.ExecutionSegment + 1 + len(runner.builtins) + i, true
+		}
+	}
+	return 0, false
+}
+
+// Checks that the segment of a builtin is the one allocated for it, right
+// after the execution segment at its position in runner.builtins, which is
+// how every builtin segment is found once running
+func (runner *ZeroRunner) checkBuiltinSegment(builtin starknetParser.Builtin, index int) error {
+	expected := -1
+	for i := range runner.builtins {
+		if runner.builtins[i] == builtin {
+			expected = VM.ExecutionSegment + 1 + i
+			break
+		}
+	}
+	if expected < 0 {
+		return fmt.Errorf("builtin %s has no allocated segment", builtin)
+	}
+	if index != expected {
+		return fmt.Errorf(
+			"builtin %s segment was allocated at index %d instead of %d", builtin, index, expected,
+		)
+	}
+	if unsupported, ok := runner.segments()[index].BuiltinRunner.(*unsupportedBuiltin); ok {
+		return unsupported.err
+	}
+	if name := runner.segments()[index].BuiltinRunner.String(); name != builtin.String() {
+		return fmt.Errorf("builtin %s segment %d is run by the %s builtin", builtin, index, name)
+	}
+	return nil
+}
+
 func (runner *ZeroRunner) RunUntilPc(pc *memory.MemoryAddress) error {
+	runner.startClock()
 	for !runner.vm.Context.Pc.Equal(pc) {
+		returned, err := runner.checkMainReturn()
+		if err != nil {
+			return err
+		}
+		if returned {
+			return fmt.Errorf("pc %s step %d: main returned before reaching pc %s", runner.pc(), runner.steps(), pc)
+		}
 		if runner.steps() >= runner.maxsteps {
 			return fmt.Errorf(
 				"pc %s step %d: max step limit exceeded (%d)",
@@ -178,17 +613,28 @@ func (runner *ZeroRunner) RunUntilPc(pc *memory.MemoryAddress) error {
 				runner.maxsteps,
 			)
 		}
+		if err := runner.checkTimeout(); err != nil {
+			return err
+		}
 
-		err := runner.vm.RunStep(nil)
-		if err != nil {
-			return fmt.Errorf("pc %s step %d: %w", runner.pc(), runner.steps(), err)
+		if err := runner.runStep(); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// Runs until the vm executed steps steps in total, or until main returns
 func (runner *ZeroRunner) RunFor(steps uint64) error {
+	runner.startClock()
 	for runner.steps() < steps {
+		returned, err := runner.checkMainReturn()
+		if err != nil {
+			return err
+		}
+		if returned {
+			return nil
+		}
 		if runner.steps() >= runner.maxsteps {
 			return fmt.Errorf(
 				"pc %s step %d: max step limit exceeded (%d)",
@@ -197,27 +643,460 @@ func (runner *ZeroRunner) RunFor(steps uint64) error {
 				runner.maxsteps,
 			)
 		}
+		if err := runner.checkTimeout(); err != nil {
+			return err
+		}
 
-		err := runner.vm.RunStep(nil)
-		if err != nil {
-			return fmt.Errorf(
-				"pc %s step %d: %w",
-				runner.pc().String(),
-				runner.steps(),
-				err,
-			)
+		if err := runner.runStep(); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// Resumes execution from a captured context, e.g. one saved by a debugger
+// along with a memory snapshot, and runs until pc reaches untilPc. The memory
+// must already hold the state the context was captured in. Errors if the
+// context pc is outside of the allocated segments or of the program
+func (runner *ZeroRunner) RunFromContext(ctx VM.Context, untilPc memory.MemoryAddress) error {
+	if ctx.Pc.SegmentIndex >= uint64(len(runner.segments())) {
+		return fmt.Errorf("context pc %s is in an unallocated segment", ctx.Pc)
+	}
+	if ctx.Pc.SegmentIndex == VM.ProgramSegment && ctx.Pc.Offset >= runner.programSize() {
+		return fmt.Errorf(
+			"context pc %s is past the end of the %d words program", ctx.Pc, runner.programSize(),
+		)
+	}
+	// the memory no longer matches a fresh run
+	runner.runFinished = true
+	runner.vm.Context = ctx
+	return runner.RunUntilPc(&untilPc)
+}
+
+// Runs the hint at pc, if any, and then a single step. Errors if the step
+// made pc jump out of the program
+func (runner *ZeroRunner) runStep() error {
+	if err := runner.hintrunner.RunHint(runner.vm); err != nil {
+		return fmt.Errorf("pc %s step %d: %w", runner.pc(), runner.steps(), err)
+	}
+	source := runner.pc()
+	if err := runner.vm.RunStep(nil); err != nil {
+		return err
+	}
+	return runner.checkPcJump(&source)
+}
+
+// Errors if pc left the program segment, or landed on its last word with an
+// instruction whose immediate would be past its end. Otherwise the next step
+// would fail reading an unrelated cell, or silently read past the program.
+// Pcs in other allocated segments, like the end pc outside of proof mode, are
+// allowed
+func (runner *ZeroRunner) checkPcJump(source *memory.MemoryAddress) error {
+	pc := runner.pc()
+	if pc.SegmentIndex >= uint64(len(runner.segments())) {
+		return fmt.Errorf(
+			"pc %s step %d: pc jumped to unallocated segment at %s", source, runner.steps()-1, pc,
+		)
+	}
+	if pc.SegmentIndex != vm.ProgramSegment {
+		return nil
+	}
+
+	programSize := runner.programSize()
+	if pc.Offset >= programSize {
+		return fmt.Errorf(
+			"pc %s step %d: pc jumped out of program segment to %s", source, runner.steps()-1, pc,
+		)
+	}
+	if pc.Offset < programSize-1 {
+		return nil
+	}
+	// only the last word needs decoding to know its size
+	word, err := runner.memory().Read(vm.ProgramSegment, pc.Offset)
+	if err != nil {
+		return fmt.Errorf("pc %s step %d: pc jumped to %s: %w", source, runner.steps()-1, pc, err)
+	}
+	bytecode, err := word.ToFieldElement()
+	if err != nil {
+		return fmt.Errorf("pc %s step %d: pc jumped to %s: %w", source, runner.steps()-1, pc, err)
+	}
+	instruction, err := vm.DecodeInstruction(bytecode)
+	if err == nil && instruction.Size() > 1 {
+		return fmt.Errorf(
+			"pc %s step %d: pc jumped to %s whose immediate is out of program segment",
+			source, runner.steps()-1, pc,
+		)
+	}
+	return nil
+}
+
+// the number of words of the program bytecode
+func (runner *ZeroRunner) programSize() uint64 {
+	return uint64(len(runner.program.Bytecode) + len(runner.program.rawBytecode))
+}
+
+// Returns the encoded relocated trace and memory. If the trace is streamed,
+// see StreamTrace, its remaining entries are written to the stream instead
+// and the returned trace is nil
 func (runner *ZeroRunner) BuildProof() ([]byte, []byte, error) {
-	relocatedTrace, err := runner.vm.ExecutionTrace()
+	var encodedTrace []byte
+	if runner.traceWriter != nil {
+		// reading past the end of the program grows its segment, the entries
+		// already streamed were relocated with the execution base of the
+		// program alone
+		if size := runner.segments()[VM.ProgramSegment].Len(); size > runner.programSize() {
+			return nil, nil, fmt.Errorf(
+				"the program segment grew to %d cells while the trace was streamed for a %d words program",
+				size, runner.programSize(),
+			)
+		}
+		if err := runner.vm.FlushTrace(); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		relocatedTrace, err := runner.vm.ExecutionTrace()
+		if err != nil {
+			return nil, nil, err
+		}
+		encodedTrace = EncodeTrace(relocatedTrace)
+	}
+
+	relocatedMemory, err := runner.relocatedMemory()
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return EncodeTrace(relocatedTrace), EncodeMemory(runner.memoryManager.RelocateMemory()), nil
+	return encodedTrace, relocatedMemory.Encode(), nil
+}
+
+// Finalizes the segments and relocates the memory
+func (runner *ZeroRunner) relocatedMemory() (RelocatedMemory, error) {
+	runner.finalizeSegments()
+	cells, err := runner.memoryManager.RelocateMemory()
+	if err != nil {
+		return RelocatedMemory{}, err
+	}
+	// the relocated cells are not shared with anyone
+	return RelocatedMemory{cells: cells}, nil
+}
+
+// Returns the memory before relocation as the json object debuggers load,
+// see memory.EncodeMemoryRelocatableJSON
+func (runner *ZeroRunner) MemoryJSON() ([]byte, error) {
+	return memory.EncodeMemoryRelocatableJSON(runner.memory())
+}
+
+// Returns size cells starting at an address, for inspecting the memory. The
+// cells are peeked, unknown ones are left as such and the segment doesn't
+// grow, so the memory is the same afterwards even if it was relocated
+func (runner *ZeroRunner) MemoryRange(start *memory.MemoryAddress, size uint64) ([]memory.MemoryValue, error) {
+	if start.SegmentIndex >= uint64(len(runner.segments())) {
+		return nil, fmt.Errorf("unallocated segment at index %d", start.SegmentIndex)
+	}
+	segment := runner.segments()[start.SegmentIndex]
+	values := make([]memory.MemoryValue, size)
+	for i := range values {
+		// peeking past the end of the segment would grow it
+		if offset := start.Offset + uint64(i); offset < segment.Len() {
+			values[i] = segment.Peek(offset)
+		}
+	}
+	return values, nil
+}
+
+// Returns the relocated cells the prover receives as public memory: the
+// whole program, the initial stack of main in proof mode and the cells of
+// builtins such as output. Finalizes the segments like BuildProof does
+func (runner *ZeroRunner) PublicMemory() ([]memory.PublicMemoryCell, error) {
+	runner.finalizeSegments()
+	return runner.memoryManager.PublicMemory()
+}
+
+// Writes the relocated trace to w in the EncodeTrace format every flushSteps
+// steps, so proof mode runs of millions of steps don't hold their whole trace
+// in memory. Shorter runs keep it in memory until BuildProof writes it.
+// Must be called before running
+func (runner *ZeroRunner) StreamTrace(w io.Writer, flushSteps int) {
+	runner.traceWriter = w
+	runner.traceFlushSteps = flushSteps
+	runner.vm.SetTraceFlusher(flushSteps, runner.writeTrace)
+}
+
+// Relocates and writes trace entries to the trace stream. The execution
+// segment is relocated right after the program, whose size is known before
+// running. BuildProof fails if the program segment grew past it
+func (runner *ZeroRunner) writeTrace(trace []VM.Context) error {
+	executionOffset := runner.programSize() + 1
+	relocatedTrace := make([]VM.Trace, len(trace))
+	for i := range trace {
+		var err error
+		relocatedTrace[i], err = trace[i].Relocate(executionOffset)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := runner.traceWriter.Write(EncodeTrace(relocatedTrace))
+	return err
+}
+
+// Limits the array length find_element and search_sorted_lower accept, like
+// the __find_element_max_size scope variable. 0, the default, means no limit
+func (runner *ZeroRunner) SetFindElementMaxSize(maxSize uint64) {
+	runner.findElementMaxSize = maxSize
+	runner.hintrunner.SetFindElementMaxSize(maxSize)
+}
+
+// Sets the radix the felts of RunResult are printed in, either 10, the
+// default, or 16
+func (runner *ZeroRunner) SetFeltRadix(radix int) error {
+	if err := memory.CheckFeltRadix(radix); err != nil {
+		return err
+	}
+	runner.feltRadix = radix
+	return nil
+}
+
+// Registers a callback whose felts are written starting at ap when the vm
+// reaches pc, see hintrunner.Oracle. It is kept across SetHintParser and
+// Reset. Errors if there is already a hint at pc
+func (runner *ZeroRunner) RegisterOracle(pc uint64, fn func(vm *VM.VirtualMachine) ([]f.Element, error)) error {
+	if err := runner.hintrunner.RegisterOracle(pc, fn); err != nil {
+		return err
+	}
+	if runner.oracles == nil {
+		runner.oracles = make(map[uint64]func(vm *VM.VirtualMachine) ([]f.Element, error))
+	}
+	runner.oracles[pc] = fn
+	return nil
+}
+
+// Enables counting the executed opcodes, res logics and pc updates.
+// Must be called before running
+func (runner *ZeroRunner) EnableProfiling() {
+	runner.vm.EnableProfiling()
+}
+
+// Limits how long a run can take. The clock starts with the first step and
+// is checked between steps, so a single step that never returns, e.g. a
+// hanging hint, is not interrupted. Must be called before running
+func (runner *ZeroRunner) SetTimeout(timeout time.Duration) {
+	runner.timeout = timeout
+}
+
+// Records the context of every step outside of proof mode as well.
+// Must be called before running
+func (runner *ZeroRunner) EnableTracing() {
+	runner.vm.EnableTracing()
+}
+
+// Returns the unrelocated context of every step executed so far, see
+// VirtualMachine.RawTrace. Nil if tracing is disabled outside of proof mode
+func (runner *ZeroRunner) RawTrace() []VM.Context {
+	return runner.vm.RawTrace()
+}
+
+// Makes any write into the program segment fail, reporting the offset written.
+// Correct programs never write there, so it helps catching faulty bytecode.
+// Must be called before running
+func (runner *ZeroRunner) EnableReadOnlyProgram() {
+	runner.readOnlyProgram = true
+	runner.segments()[VM.ProgramSegment].ReadOnly = true
+}
+
+// Records the step at which each builtin cell is first accessed, so
+// VerifyBuiltinRatios can tell when a builtin ran out of instances.
+// Must be called before running
+func (runner *ZeroRunner) EnableAccessLog() {
+	runner.accessLog = true
+	for i := range runner.builtins {
+		runner.segments()[VM.ExecutionSegment+1+i].RecordAccesses(&runner.vm.Step)
+	}
+}
+
+// Caches decoded instructions by their bytecode word as well as by pc, so
+// identical words, e.g. in unrolled loops, are decoded once.
+// Must be called before running
+func (runner *ZeroRunner) EnableBytecodeCache() {
+	runner.bytecodeCache = true
+	runner.vm.EnableBytecodeCache()
+}
+
+// Returns how many times each opcode, res logic and pc update was executed,
+// or nil if profiling wasn't enabled
+func (runner *ZeroRunner) ProfileStats() map[string]uint64 {
+	return runner.vm.ProfileStats()
+}
+
+// Returns the values returned by main once it has returned to the synthetic
+// end set up by InitializeMainEntrypoint. They are the cells right below the
+// final ap, after the implicit builtin pointers. Not available in proof mode
+func (runner *ZeroRunner) MainReturnValues() ([]f.Element, error) {
+	if runner.proofmode {
+		return nil, errors.New("main return values are not available in proof mode")
+	}
+	if !runner.mainReturned() {
+		return nil, errors.New("main return values require running main until it returns")
+	}
+	size, ok := runner.program.returnSizes["main"]
+	if !ok {
+		return nil, errors.New("unknown return size of main")
+	}
+
+	ap := runner.vm.Context.Ap
+	if size > ap {
+		return nil, fmt.Errorf("main returns %d values but ap is %d", size, ap)
+	}
+	values := make([]f.Element, size)
+	for i := range values {
+		value, err := runner.memory().Read(VM.ExecutionSegment, ap-size+uint64(i))
+		if err != nil {
+			return nil, err
+		}
+		felt, err := value.ToFieldElement()
+		if err != nil {
+			return nil, fmt.Errorf("main return value %d: %w", i, err)
+		}
+		values[i] = *felt
+	}
+	return values, nil
+}
+
+// Returns the cells of the output builtin segment, unknown ones included.
+// Errors if the program doesn't use the output builtin
+func (runner *ZeroRunner) Output() ([]memory.MemoryValue, error) {
+	index, ok := runner.memory().FindSegmentByName(starknetParser.Output.String())
+	if !ok {
+		return nil, errors.New("the program doesn't use the output builtin")
+	}
+	segment := runner.segments()[index]
+	output := make([]memory.MemoryValue, segment.Len())
+	for i := range output {
+		output[i] = segment.Peek(uint64(i))
+	}
+	return output, nil
+}
+
+// Returns whether main has returned, which happens when the pc reaches the
+// segment holding its return pc
+func (runner *ZeroRunner) mainReturned() bool {
+	return runner.retPcSegment != 0 && runner.pc().SegmentIndex == runner.retPcSegment
+}
+
+// Returns whether main has returned. The segment of its return pc holds no
+// instruction, so a ret landing anywhere in it but at its start is an error
+// instead of something to decode
+func (runner *ZeroRunner) checkMainReturn() (bool, error) {
+	if !runner.mainReturned() {
+		return false, nil
+	}
+	if runner.pc().Offset != 0 {
+		return false, fmt.Errorf(
+			"pc %s step %d: main returned past its end %d:0",
+			runner.pc(), runner.steps(), runner.retPcSegment,
+		)
+	}
+	return true, nil
+}
+
+// Resources consumed by a run
+type ExecutionResources struct {
+	NSteps       uint64 `json:"n_steps"`
+	NMemoryHoles uint64 `json:"n_memory_holes"`
+	// amount of instances used by each builtin, keyed by the builtin name
+	BuiltinInstanceCounter map[string]uint64 `json:"builtin_instance_counter"`
+}
+
+// Returns the steps executed, the memory holes left and the instances used
+// by each builtin of the program
+func (runner *ZeroRunner) ExecutionResources() (ExecutionResources, error) {
+	if runner.steps() == 0 {
+		return ExecutionResources{}, errors.New("execution resources require running the program first")
+	}
+
+	// builtins computed through hints have no instances
+	counter := make(map[string]uint64, len(runner.builtins))
+	for i := range runner.builtins {
+		segment := runner.segments()[VM.ExecutionSegment+1+i]
+		counter[segment.BuiltinRunner.String()] = segment.BuiltinRunner.InstancesUsed(segment)
+	}
+
+	return ExecutionResources{
+		NSteps:                 runner.steps(),
+		NMemoryHoles:           runner.memoryHoles(),
+		BuiltinInstanceCounter: counter,
+	}, nil
+}
+
+// Checks that the builtins weren't used more than the layout ratios allow.
+// The prover gives each builtin one instance every ratio steps, so the steps
+// must be a multiple of the ratio and cover all the instances used. Requires
+// a runner created with a layout
+func (runner *ZeroRunner) VerifyBuiltinRatios() error {
+	if runner.layout == nil {
+		return errors.New("builtin ratios require a runner created with a layout")
+	}
+	if runner.steps() == 0 {
+		return errors.New("builtin ratios require running the program first")
+	}
+
+	steps := runner.steps()
+	for i, builtin := range runner.builtins {
+		ratio := runner.layout.ratio(builtin)
+		if ratio == 0 {
+			continue
+		}
+		if steps%ratio != 0 {
+			return fmt.Errorf("%d steps are not a multiple of the %s ratio %d", steps, builtin, ratio)
+		}
+
+		segment := runner.segments()[VM.ExecutionSegment+1+i]
+		used := segment.BuiltinRunner.InstancesUsed(segment)
+		if allowed := steps / ratio; used > allowed {
+			err := fmt.Errorf(
+				"builtin %s uses %d instances but %d steps at ratio %d only allow %d",
+				builtin, used, steps, ratio, allowed,
+			)
+			if step, ok := segment.FirstAccess(segment.Len() - 1); ok {
+				err = fmt.Errorf("%w, its last cell was first accessed at step %d", err, step)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Locks the size of every segment before relocating them, padding builtin
+// segments to whole instances so the prover sees the expected layout
+func (runner *ZeroRunner) finalizeSegments() {
+	programSegment := runner.segments()[VM.ProgramSegment]
+	programPublicMemory := make([]uint64, programSegment.Len())
+	for i := range programPublicMemory {
+		programPublicMemory[i] = uint64(i)
+	}
+	programSegment.SetPublicMemory(programPublicMemory)
+	runner.segments()[VM.ExecutionSegment].SetPublicMemory(runner.executionPublicMemory)
+
+	for _, segment := range runner.segments() {
+		segment.Finalize()
+	}
+}
+
+// Counts the cells of every non builtin segment that are left out of the
+// relocated memory, see Segment.Accessed
+func (runner *ZeroRunner) memoryHoles() uint64 {
+	var holes uint64
+	for i, segment := range runner.segments() {
+		isBuiltin := i > VM.ExecutionSegment && i <= VM.ExecutionSegment+len(runner.builtins)
+		if isBuiltin {
+			continue
+		}
+		for j := uint64(0); j < segment.Len(); j++ {
+			if !segment.Accessed(j) {
+				holes++
+			}
+		}
+	}
+	return holes
 }
 
 func (runner *ZeroRunner) memory() *memory.Memory {
@@ -228,6 +1107,32 @@ func (runner *ZeroRunner) segments() []*memory.Segment {
 	return runner.memoryManager.Memory.Segments
 }
 
+func (runner *ZeroRunner) builtinSegmentIndex(name string) (int, error) {
+	index, ok := runner.memory().FindSegmentByName(name)
+	if !ok {
+		return 0, fmt.Errorf("segment of builtin %s not found", name)
+	}
+	return index, nil
+}
+
+func (runner *ZeroRunner) startClock() {
+	if runner.timeout > 0 && runner.deadline.IsZero() {
+		runner.deadline = time.Now().Add(runner.timeout)
+	}
+}
+
+// Errors if the run went past its deadline. The clock is only read every
+// timeoutCheckInterval steps to keep the overhead out of the step loop
+func (runner *ZeroRunner) checkTimeout() error {
+	if runner.deadline.IsZero() || runner.steps()%timeoutCheckInterval != 0 {
+		return nil
+	}
+	if time.Now().After(runner.deadline) {
+		return fmt.Errorf("pc %s step %d: run timed out after %s", runner.pc(), runner.steps(), runner.timeout)
+	}
+	return nil
+}
+
 func (runner *ZeroRunner) pc() memory.MemoryAddress {
 	return runner.vm.Context.Pc
 }
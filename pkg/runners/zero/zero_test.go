@@ -1,13 +1,20 @@
 package zero
 
 import (
+	"bytes"
 	"encoding/binary"
+	"fmt"
 	"math"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/NethermindEth/cairo-vm-go/pkg/assembler"
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
 	"github.com/NethermindEth/cairo-vm-go/pkg/vm"
 	VM "github.com/NethermindEth/cairo-vm-go/pkg/vm"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/builtins"
 	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
 	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
 	"github.com/stretchr/testify/assert"
@@ -50,7 +57,7 @@ func TestSimpleProgram(t *testing.T) {
 			3,
 			4,
 			4,
-		),
+		).WithName(VM.ExecutionSegmentName),
 		trimmedSegment(executionSegment),
 	)
 
@@ -94,7 +101,7 @@ func TestStepLimitExceeded(t *testing.T) {
 			2,
 			3,
 			5,
-		),
+		).WithName(VM.ExecutionSegmentName),
 		trimmedSegment(executionSegment),
 	)
 
@@ -149,7 +156,7 @@ func TestStepLimitExceededProofMode(t *testing.T) {
 				7,
 				11,
 				13,
-			),
+			).WithName(VM.ExecutionSegmentName),
 			trimmedSegment(executionSegment),
 		)
 
@@ -164,6 +171,874 @@ func TestStepLimitExceededProofMode(t *testing.T) {
 	}
 }
 
+func TestProofModeMissingLabels(t *testing.T) {
+	program := createDefaultProgram(`jmp rel 0;`)
+
+	program.Labels = map[string]uint64{"__end__": 0}
+	_, err := NewRunner(program, true, math.MaxUint64)
+	require.EqualError(t, err, "runner error: start label not found. Try compiling with `--proof_mode`")
+
+	program.Labels = map[string]uint64{"__start__": 0}
+	_, err = NewRunnerWithLayout(program, true, math.MaxUint64, PlainLayout)
+	require.EqualError(t, err, "runner error: end label not found. Try compiling with `--proof_mode`")
+
+	// the labels are only needed in proof mode
+	_, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+
+	program.Labels["__end__"] = 0
+	require.NoError(t, program.ValidateForProofMode())
+}
+
+func TestProofModePaddingExceedsMaxSteps(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = 2, ap++;
+        [ap] = 3, ap++;
+        [ap] = 5, ap++;
+        jmp rel 0;
+    `)
+	// properties required by proofmode
+	program.Labels = map[string]uint64{
+		"__start__": 0,
+		"__end__":   6,
+	}
+
+	// the end is reached after 3 steps, padding requires 4
+	runner, err := NewRunner(program, true, 3)
+	require.NoError(t, err)
+
+	err = runner.Run()
+	require.ErrorContains(t, err, "proof-mode padding to 4 steps exceeds maxsteps 3")
+	assert.Equal(t, uint64(3), runner.steps())
+
+	runner, err = NewRunner(program, true, 4)
+	require.NoError(t, err)
+
+	err = runner.Run()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), runner.steps())
+}
+
+func TestProofModeExtraStepWhenPowerOfTwo(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = 2, ap++;
+        [ap] = 3, ap++;
+        [ap] = 5, ap++;
+        [ap] = 7, ap++;
+        jmp rel 0;
+    `)
+	// properties required by proofmode
+	program.Labels = map[string]uint64{
+		"__start__": 0,
+		"__end__":   8,
+	}
+
+	// the end is reached after 4 steps, already a power of two, and only the
+	// extra step makes padding require 8. The error is returned before
+	// running any padding step
+	for _, maxsteps := range []uint64{4, 5, 7} {
+		runner, err := NewRunner(program, true, maxsteps)
+		require.NoError(t, err)
+		require.EqualError(
+			t, runner.Run(),
+			fmt.Sprintf("proof-mode padding to 8 steps exceeds maxsteps %d; increase maxsteps", maxsteps),
+		)
+		assert.Equal(t, uint64(4), runner.steps())
+	}
+
+	runner, err := NewRunner(program, true, 8)
+	require.NoError(t, err)
+	runner.EnableTracing()
+	require.NoError(t, runner.Run())
+	assert.Equal(t, uint64(8), runner.steps())
+	// the extra step runs the end loop once, then padding runs it 3 times
+	for step := 4; step < 8; step++ {
+		assert.Equal(t, memory.MemoryAddress{SegmentIndex: 0, Offset: 8}, runner.RawTrace()[step].Pc)
+	}
+}
+
+func TestBuiltinSegmentsAllocation(t *testing.T) {
+	program := createDefaultProgram(`ret;`)
+	program.builtins = []starknetParser.Builtin{starknetParser.RangeCheck, starknetParser.Keccak}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+
+	require.Len(t, runner.segments(), 4)
+	assert.Equal(t, &builtins.RangeCheck{}, runner.segments()[2].BuiltinRunner)
+	assert.Equal(t, &builtins.Keccak{}, runner.segments()[3].BuiltinRunner)
+
+	names := make([]string, len(runner.segments()))
+	for i, segment := range runner.segments() {
+		names[i] = segment.Name
+	}
+	assert.Equal(t, []string{"program", "execution", "range_check", "keccak"}, names)
+
+	// a runner can still be registered for a builtin without implementation,
+	// so using it only fails once running
+	program.builtins = []starknetParser.Builtin{starknetParser.Pedersen}
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.EqualError(t, runner.Run(), "initializing main entry point: unsupported builtin: pedersen")
+}
+
+// Counts the writes of a builtin segment on top of the output builtin
+type countingOutput struct {
+	builtins.Output
+	writes int
+}
+
+func (b *countingOutput) CheckWrite(segment *memory.Segment, offset uint64, value *memory.MemoryValue) error {
+	b.writes++
+	return b.Output.CheckWrite(segment, offset, value)
+}
+
+// A counting output runner holding its count as state, so it is cloned
+type clonedOutput struct {
+	countingOutput
+}
+
+func (b *clonedOutput) Clone() memory.BuiltinRunner {
+	clone := *b
+	return &clone
+}
+
+// A builtin runner that accepts anything under any builtin name
+type namedBuiltin struct {
+	memory.NoBuiltin
+	name string
+}
+
+func (b *namedBuiltin) String() string {
+	return b.name
+}
+
+func TestWithBuiltin(t *testing.T) {
+	// main writes twice to the output and returns the pointer past them
+	program := createDefaultProgram(`
+        [ap] = 1, ap++;
+        [ap - 1] = [[fp - 3]];
+        [ap] = 2, ap++;
+        [ap - 1] = [[fp - 3] + 1];
+        [ap] = [fp - 3] + 2, ap++;
+        ret;
+    `)
+	program.builtins = []starknetParser.Builtin{starknetParser.Output}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	output := &countingOutput{}
+	require.NoError(t, runner.WithBuiltin("output", output))
+	require.NoError(t, runner.Run())
+	assert.Equal(t, 2, output.writes)
+
+	// the registered runner outlives a reset
+	require.NoError(t, runner.Reset())
+	require.NoError(t, runner.Run())
+	assert.Equal(t, 4, output.writes)
+
+	// a stateful runner is cloned for every run instead
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	cloned := &clonedOutput{}
+	require.NoError(t, runner.WithBuiltin("output", cloned))
+	for i := 0; i < 2; i++ {
+		require.NoError(t, runner.Run())
+		used := runner.segments()[VM.ExecutionSegment+1].BuiltinRunner.(*clonedOutput)
+		assert.NotSame(t, cloned, used)
+		assert.Equal(t, 2, used.writes)
+		require.NoError(t, runner.Reset())
+	}
+	assert.Equal(t, 0, cloned.writes)
+
+	// a builtin the program doesn't use is registered all the same
+	require.NoError(t, runner.WithBuiltin("range_check", &builtins.RangeCheck{}))
+
+	require.EqualError(
+		t, runner.WithBuiltin("sha", &builtins.Output{}),
+		"cannot register builtin runner: unmarshal unknown builtin: sha",
+	)
+	require.EqualError(
+		t, runner.WithBuiltin("range_check", &builtins.Output{}),
+		"cannot register the output builtin runner for builtin range_check",
+	)
+
+	// a builtin without implementation can be supplied
+	program.builtins = []starknetParser.Builtin{starknetParser.Pedersen}
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.WithBuiltin("pedersen", &namedBuiltin{name: "pedersen"}))
+	require.NoError(t, runner.Run())
+}
+
+func TestFixedSegmentIndexes(t *testing.T) {
+	// builtin segments come after the ones the vm addresses by a fixed index
+	program := createDefaultProgram("ret;")
+	program.builtins = []starknetParser.Builtin{starknetParser.Output, starknetParser.RangeCheck}
+	program.Labels = map[string]uint64{"__start__": 0, "__end__": 0}
+	for _, proofmode := range []bool{false, true} {
+		runner, err := NewRunner(program, proofmode, math.MaxUint64)
+		require.NoError(t, err)
+		assert.Equal(t, VM.ProgramSegmentName, runner.segments()[VM.ProgramSegment].Name)
+		assert.Equal(t, VM.ExecutionSegmentName, runner.segments()[VM.ExecutionSegment].Name)
+		require.NoError(t, VM.CheckFixedSegments(runner.memory()))
+
+		require.NoError(t, runner.Reset())
+		require.NoError(t, VM.CheckFixedSegments(runner.memory()))
+	}
+}
+
+func TestBuiltinBasesPushedToMain(t *testing.T) {
+	// main receives the range check pointer and returns it incremented
+	program := createDefaultProgram(`
+        [ap] = [fp - 3] + 1, ap++;
+        ret;
+    `)
+	program.builtins = []starknetParser.Builtin{starknetParser.RangeCheck}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.Run())
+
+	rangeCheckBase := memory.MemoryValueFromSegmentAndOffset(2, 0)
+	assert.Equal(t, rangeCheckBase, runner.segments()[VM.ExecutionSegment].Data[0])
+
+	returnedPtr, err := runner.memory().Read(VM.ExecutionSegment, runner.vm.Context.Ap-1)
+	require.NoError(t, err)
+	assert.Equal(t, memory.MemoryValueFromSegmentAndOffset(2, 1), returnedPtr)
+}
+
+func TestBuiltinSegmentsOrderMismatch(t *testing.T) {
+	program := createDefaultProgram("ret;")
+	program.builtins = []starknetParser.Builtin{starknetParser.Output, starknetParser.RangeCheck}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	segments := runner.segments()
+	segments[2], segments[3] = segments[3], segments[2]
+	require.EqualError(
+		t, runner.Run(),
+		"initializing main entry point: builtin output segment was allocated at index 3 instead of 2",
+	)
+
+	// same name, different runner
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	runner.segments()[2].BuiltinRunner = &builtins.RangeCheck{}
+	require.EqualError(
+		t, runner.Run(),
+		"initializing main entry point: builtin output segment 2 is run by the range_check builtin",
+	)
+
+	program.builtins = []starknetParser.Builtin{starknetParser.Output, starknetParser.Output}
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.EqualError(t, runner.Run(), "initializing main entry point: builtin output is declared twice")
+}
+
+func TestBuiltinBasesProofMode(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = [fp], ap++;
+        jmp rel 0;
+    `)
+	program.Labels = map[string]uint64{"__start__": 0, "__end__": 1}
+	program.builtins = []starknetParser.Builtin{starknetParser.RangeCheck, starknetParser.Keccak}
+
+	runner, err := NewRunner(program, true, math.MaxUint64)
+	require.NoError(t, err)
+	_, err = runner.InitializeMainEntrypoint()
+	require.NoError(t, err)
+
+	// the bases follow the dummy fp and pc values, where ap and fp start
+	assert.Equal(t, uint64(2), runner.vm.Context.Fp)
+	executionSegment := runner.segments()[VM.ExecutionSegment]
+	assert.Equal(t, memory.MemoryValueFromSegmentAndOffset(2, 0), executionSegment.Data[2])
+	assert.Equal(t, memory.MemoryValueFromSegmentAndOffset(3, 0), executionSegment.Data[3])
+}
+
+func TestBuildProofFinalizesSegments(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = [fp], ap++;
+        jmp rel 0;
+    `)
+	program.Labels = map[string]uint64{"__start__": 0, "__end__": 1}
+	program.builtins = []starknetParser.Builtin{starknetParser.Keccak}
+
+	runner, err := NewRunner(program, true, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.Run())
+	zero := memory.MemoryValueFromInt(0)
+	require.NoError(t, runner.memory().Write(2, 3, &zero))
+
+	_, _, err = runner.BuildProof()
+	require.NoError(t, err)
+	for _, segment := range runner.segments() {
+		assert.True(t, segment.Finalized())
+	}
+	// the keccak segment is padded to a whole instance
+	assert.Equal(t, uint64(16), runner.segments()[2].Len())
+	relocated, err := runner.memoryManager.RelocateMemory()
+	require.NoError(t, err)
+	assert.Len(t, relocated, int(runner.memoryManager.SegmentOffsets()[2]+16))
+
+	// the execution segment can't grow after being finalized
+	err = runner.memory().Write(VM.ExecutionSegment, runner.segments()[VM.ExecutionSegment].Len(), &zero)
+	require.ErrorContains(t, err, "outside of the finalized segment")
+}
+
+func TestBuildProofBuiltinSegments(t *testing.T) {
+	program := createDefaultProgram(`
+        ap += 2;
+        [ap] = 7, ap++;
+        [ap - 1] = [[fp]];
+        [ap - 1] = [[fp + 1]];
+        [ap] = [fp] + 1, ap++;
+        [ap] = [fp + 1] + 1, ap++;
+        jmp rel 0;
+    `)
+	program.Labels = map[string]uint64{"__start__": 0, "__end__": 10}
+	// declared out of the layout order on purpose
+	program.builtins = []starknetParser.Builtin{starknetParser.RangeCheck, starknetParser.Output}
+
+	runner, err := NewRunnerWithLayout(program, true, math.MaxUint64, SmallLayout)
+	require.NoError(t, err)
+	require.NoError(t, runner.Run())
+	_, encodedMemory, err := runner.BuildProof()
+	require.NoError(t, err)
+	relocated := DecodeMemory(encodedMemory)
+
+	// builtin segments follow the execution segment in the layout order
+	offsets := runner.memoryManager.SegmentOffsets()
+	executionEnd := offsets[VM.ExecutionSegment] + runner.segments()[VM.ExecutionSegment].Len()
+	outputOffset, rangeCheckOffset := offsets[2], offsets[3]
+	assert.Equal(t, executionEnd, outputOffset)
+	assert.Equal(t, outputOffset+1, rangeCheckOffset)
+
+	seven := new(f.Element).SetUint64(7)
+	assert.Equal(t, seven, relocated[outputOffset])
+	assert.Equal(t, seven, relocated[rangeCheckOffset])
+	// main receives the relocated builtin bases in the order the program declares them
+	executionOffset := offsets[VM.ExecutionSegment]
+	assert.Equal(t, new(f.Element).SetUint64(rangeCheckOffset), relocated[executionOffset+2])
+	assert.Equal(t, new(f.Element).SetUint64(outputOffset), relocated[executionOffset+3])
+}
+
+func TestBuiltinStopPointers(t *testing.T) {
+	program := createDefaultProgram(`
+        ap += 2;
+        jmp rel 0;
+    `)
+	program.Labels = map[string]uint64{"__start__": 0, "__end__": 2}
+	program.builtins = []starknetParser.Builtin{starknetParser.Output}
+
+	// the stop pointer main left unknown is written
+	runner, err := NewRunner(program, true, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.Run())
+	stopPtr := runner.vm.Context.Ap - 1
+	assert.Equal(t, memory.MemoryValueFromSegmentAndOffset(2, 0), runner.segments()[VM.ExecutionSegment].Data[stopPtr])
+	assert.Equal(t, []uint64{0, 1, 2, stopPtr}, runner.executionPublicMemory)
+
+	program = createDefaultProgram(`
+        ap += 1;
+        [ap] = 5, ap++;
+        jmp rel 0;
+    `)
+	program.Labels = map[string]uint64{"__start__": 0, "__end__": 4}
+	program.builtins = []starknetParser.Builtin{starknetParser.Output}
+
+	runner, err = NewRunner(program, true, math.MaxUint64)
+	require.NoError(t, err)
+	require.EqualError(t, runner.Run(), "invalid stop pointer for builtin output at 1:3: expected 2:0, found 5")
+}
+
+func TestStreamTrace(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = 2, ap++;
+        [ap] = 3, ap++;
+        [ap] = [ap - 1] + [ap - 2], ap++;
+        jmp rel 0;
+    `)
+	program.Labels = map[string]uint64{
+		"__start__": 0,
+		"__end__":   uint64(len(program.Bytecode) - 2),
+	}
+
+	runner, err := NewRunner(program, true, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.Run())
+	trace, relocatedMemory, err := runner.BuildProof()
+	require.NoError(t, err)
+
+	// the run is longer than the flush size, so entries are flushed while running
+	var stream bytes.Buffer
+	runner, err = NewRunner(program, true, math.MaxUint64)
+	require.NoError(t, err)
+	runner.StreamTrace(&stream, 3)
+	require.NoError(t, runner.Run())
+	assert.Equal(t, trace[:3*ctxSize], stream.Bytes())
+	assert.Less(t, len(runner.vm.Trace), 3)
+	_, err = runner.vm.ExecutionTrace()
+	require.ErrorContains(t, err, "trace entries were already flushed")
+
+	streamedTrace, streamedMemory, err := runner.BuildProof()
+	require.NoError(t, err)
+	assert.Nil(t, streamedTrace)
+	assert.Equal(t, trace, stream.Bytes())
+	assert.Equal(t, relocatedMemory, streamedMemory)
+
+	// a short run only writes its trace when building the proof
+	stream.Reset()
+	runner, err = NewRunner(program, true, math.MaxUint64)
+	require.NoError(t, err)
+	runner.StreamTrace(&stream, 1<<16)
+	require.NoError(t, runner.Run())
+	assert.Zero(t, stream.Len())
+	_, _, err = runner.BuildProof()
+	require.NoError(t, err)
+	assert.Equal(t, trace, stream.Bytes())
+
+	// reading past the end of the program moves the execution segment after
+	// entries were relocated
+	runner, err = NewRunner(program, true, math.MaxUint64)
+	require.NoError(t, err)
+	runner.StreamTrace(&stream, 3)
+	require.NoError(t, runner.Run())
+	_, err = runner.memory().Read(VM.ProgramSegment, runner.programSize()+1)
+	require.NoError(t, err)
+	_, _, err = runner.BuildProof()
+	require.EqualError(
+		t, err, "the program segment grew to 9 cells while the trace was streamed for a 7 words program",
+	)
+}
+
+func TestPublicMemory(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = [fp], ap++;
+        jmp rel 0;
+    `)
+	program.Labels = map[string]uint64{"__start__": 0, "__end__": 1}
+	program.builtins = []starknetParser.Builtin{starknetParser.Output}
+
+	runner, err := NewRunner(program, true, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.Run())
+	value := memory.MemoryValueFromInt(42)
+	require.NoError(t, runner.memory().Write(2, 0, &value))
+
+	cells, err := runner.PublicMemory()
+	require.NoError(t, err)
+	offsets := runner.memoryManager.SegmentOffsets()
+	executionOffset := offsets[VM.ExecutionSegment]
+	assert.Equal(t, []memory.PublicMemoryCell{
+		// the program
+		{Address: 1, Value: *program.Bytecode[0]},
+		{Address: 2, Value: *program.Bytecode[1]},
+		{Address: 3, Value: *program.Bytecode[2]},
+		// the dummy fp and pc and the output base main starts with
+		{Address: executionOffset, Value: f.NewElement(executionOffset + 2)},
+		{Address: executionOffset + 1, Value: f.NewElement(0)},
+		{Address: executionOffset + 2, Value: f.NewElement(offsets[2])},
+		// the output
+		{Address: offsets[2], Value: f.NewElement(42)},
+	}, cells)
+}
+
+func TestLazyProgramProof(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = 2, ap++;
+        [ap] = 3, ap++;
+        jmp rel 4;
+        [ap] = 5, ap++;
+        [ap - 1] = [ap - 2] + 1;
+        jmp rel 0;
+    `)
+	// properties required by proofmode
+	program.Labels = map[string]uint64{
+		"__start__": 0,
+		"__end__":   uint64(len(program.Bytecode) - 2),
+	}
+	// the skipped instruction is only decoded during relocation
+	lazyProgram := *program
+	lazyProgram.Bytecode = nil
+	for i := range program.Bytecode {
+		lazyProgram.rawBytecode = append(lazyProgram.rawBytecode, "0x"+program.Bytecode[i].Text(16))
+	}
+
+	runner, err := NewRunner(program, true, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.Run())
+	trace, memory, err := runner.BuildProof()
+	require.NoError(t, err)
+
+	lazyRunner, err := NewRunner(&lazyProgram, true, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, lazyRunner.Run())
+	lazyTrace, lazyMemory, err := lazyRunner.BuildProof()
+	require.NoError(t, err)
+
+	assert.Equal(t, trace, lazyTrace)
+	assert.Equal(t, memory, lazyMemory)
+}
+
+func TestProfileStats(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = 2, ap++;
+        [ap] = 3, ap++;
+        [ap] = 4, ap++;
+        [ap] = 4;
+        [ap - 1] = [ap];
+        ret;
+    `)
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	assert.Nil(t, runner.ProfileStats())
+
+	runner.EnableProfiling()
+	require.NoError(t, runner.Run())
+
+	assert.Equal(t, map[string]uint64{
+		"opcode Assert":        5,
+		"opcode Ret":           1,
+		"res Op1":              6,
+		"pc update Next instr": 5,
+		"pc update Jump Abs":   1,
+	}, runner.ProfileStats())
+}
+
+func TestExecutionResources(t *testing.T) {
+	program := createDefaultProgram(`ret;`)
+	program.builtins = []starknetParser.Builtin{starknetParser.RangeCheck, starknetParser.Keccak}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+
+	_, err = runner.ExecutionResources()
+	require.ErrorContains(t, err, "running the program first")
+
+	require.NoError(t, runner.Run())
+
+	value := memory.MemoryValueFromInt(7)
+	// three range checks
+	for i := uint64(0); i < 3; i++ {
+		require.NoError(t, runner.memory().Write(2, i, &value))
+	}
+	// the last keccak instance is only partially used
+	require.NoError(t, runner.memory().Write(3, 17, &value))
+	// leaves two holes after the builtin pointers, return fp and pc
+	require.NoError(t, runner.memory().Write(VM.ExecutionSegment, 6, &value))
+	// a cell only holding a default value is a hole too, as in the relocated memory
+	require.NoError(t, runner.segments()[VM.ExecutionSegment].WriteDefault(7, &value))
+
+	resources, err := runner.ExecutionResources()
+	require.NoError(t, err)
+	assert.Equal(t, ExecutionResources{
+		NSteps:       1,
+		NMemoryHoles: 3,
+		BuiltinInstanceCounter: map[string]uint64{
+			"range_check": 3,
+			"keccak":      2,
+		},
+	}, resources)
+}
+
+func TestOutput(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = [fp - 3] + 2, ap++;
+        ret;
+    `)
+	program.builtins = []starknetParser.Builtin{starknetParser.Output}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.Run())
+
+	// the first output cell is left unknown
+	value := memory.MemoryValueFromInt(5)
+	require.NoError(t, runner.memory().Write(2, 1, &value))
+
+	output, err := runner.Output()
+	require.NoError(t, err)
+	assert.Equal(t, []memory.MemoryValue{{}, value}, output)
+
+	program.builtins = nil
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	_, err = runner.Output()
+	require.EqualError(t, err, "the program doesn't use the output builtin")
+}
+
+func TestMemoryRange(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = [fp - 3] + 2, ap++;
+        ret;
+    `)
+	program.builtins = []starknetParser.Builtin{starknetParser.Output, starknetParser.RangeCheck}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.Run())
+	value := memory.MemoryValueFromInt(5)
+	require.NoError(t, runner.memory().Write(2, 1, &value))
+	runner.finalizeSegments()
+
+	// unknown cells aren't deduced and the segments don't grow
+	values, err := runner.MemoryRange(&memory.MemoryAddress{SegmentIndex: 2, Offset: 0}, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []memory.MemoryValue{{}, value, {}, {}}, values)
+	assert.Equal(t, uint64(2), runner.segments()[2].Len())
+
+	values, err = runner.MemoryRange(&memory.MemoryAddress{SegmentIndex: 3, Offset: 0}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []memory.MemoryValue{{}, {}}, values)
+	assert.Equal(t, uint64(0), runner.segments()[3].Len())
+
+	_, err = runner.MemoryRange(&memory.MemoryAddress{SegmentIndex: 40, Offset: 0}, 1)
+	require.EqualError(t, err, "unallocated segment at index 40")
+}
+
+func TestReset(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = 2, ap++;
+        [ap] = [fp - 3] + 1, ap++;
+        ret;
+    `)
+	program.builtins = []starknetParser.Builtin{starknetParser.SegmentArena}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	runner.EnableProfiling()
+	runner.EnableTracing()
+	require.NoError(t, runner.Run())
+	require.ErrorContains(t, runner.Run(), "cannot re-run using the same runner")
+	require.Len(t, runner.RawTrace(), 3)
+
+	firstMemory := runner.memory().String()
+	firstArena := runner.segments()[2].BuiltinRunner.(*builtins.SegmentArena)
+	firstArena.AllocateDict(runner.memory())
+
+	require.NoError(t, runner.Reset())
+	assert.Equal(t, uint64(0), runner.steps())
+	assert.Empty(t, runner.ProfileStats())
+	assert.Empty(t, runner.RawTrace())
+	assert.NotNil(t, runner.RawTrace())
+
+	require.NoError(t, runner.Run())
+	assert.Equal(t, uint64(3), runner.steps())
+	assert.Equal(t, firstMemory, runner.memory().String())
+	assert.Equal(t, uint64(1), runner.ProfileStats()["res Add"])
+
+	arena := runner.segments()[2].BuiltinRunner.(*builtins.SegmentArena)
+	assert.NotSame(t, firstArena, arena)
+	assert.Equal(t, uint64(0), arena.DictCount())
+}
+
+func TestMainReturnValues(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = [fp - 3] + 1, ap++;
+        [ap] = 5, ap++;
+        [ap] = 6, ap++;
+        ret;
+    `)
+	program.builtins = []starknetParser.Builtin{starknetParser.RangeCheck}
+	program.returnSizes = map[string]uint64{"main": 2}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	_, err = runner.MainReturnValues()
+	require.ErrorContains(t, err, "running main until it returns")
+
+	require.NoError(t, runner.Run())
+	values, err := runner.MainReturnValues()
+	require.NoError(t, err)
+	assert.Equal(t, []f.Element{*new(f.Element).SetUint64(5), *new(f.Element).SetUint64(6)}, values)
+
+	// the implicit range check pointer is not a felt
+	program.returnSizes["main"] = 3
+	_, err = runner.MainReturnValues()
+	require.ErrorContains(t, err, "main return value 0")
+
+	delete(program.returnSizes, "main")
+	_, err = runner.MainReturnValues()
+	require.ErrorContains(t, err, "unknown return size of main")
+}
+
+func TestRunAfterMainReturned(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = 5, ap++;
+        ret;
+    `)
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.Run())
+	require.Equal(t, uint64(2), runner.steps())
+
+	// the empty return segment is never decoded
+	require.NoError(t, runner.RunFor(10))
+	assert.Equal(t, uint64(2), runner.steps())
+	err = runner.RunUntilPc(&memory.MemoryAddress{SegmentIndex: VM.ProgramSegment, Offset: 0})
+	require.ErrorContains(t, err, "main returned before reaching pc 0:0")
+}
+
+func TestMainReturnsInsideItsEnd(t *testing.T) {
+	// jumps one cell past the return pc
+	program := createDefaultProgram(`
+        [ap] = [fp - 1] + 1, ap++;
+        jmp abs [ap - 1];
+    `)
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	err = runner.Run()
+	require.ErrorContains(t, err, "pc 3:1 step 2: main returned past its end 3:0")
+}
+
+func TestRunCompactsSegments(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = 1, ap++;
+        [ap] = 2, ap++;
+        ret;
+    `)
+	program.returnSizes = map[string]uint64{"main": 2}
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.Run())
+	for _, segment := range runner.segments() {
+		assert.Equal(t, segment.Len(), uint64(cap(segment.Data)))
+	}
+	values, err := runner.MainReturnValues()
+	require.NoError(t, err)
+	assert.Equal(t, []f.Element{f.NewElement(1), f.NewElement(2)}, values)
+}
+
+func TestExecutionSegmentCapacity(t *testing.T) {
+	program := createDefaultProgram(`ret;`)
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	assert.Equal(t, memory.DefaultSegmentCapacity, cap(runner.segments()[VM.ExecutionSegment].Data))
+
+	words := func(n int) []*f.Element {
+		bytecode := make([]*f.Element, n)
+		for i := range bytecode {
+			bytecode[i] = new(f.Element)
+		}
+		return bytecode
+	}
+
+	program.Bytecode = words(5000)
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	assert.Equal(t, 5000, cap(runner.segments()[VM.ExecutionSegment].Data))
+
+	// ap cannot go further than two cells per step
+	runner, err = NewRunner(program, false, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 22, cap(runner.segments()[VM.ExecutionSegment].Data))
+
+	program.Bytecode = words(1 << 21)
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	assert.Equal(t, maxExecutionSegmentCapacity, cap(runner.segments()[VM.ExecutionSegment].Data))
+}
+
+func TestRunTimeout(t *testing.T) {
+	// loops forever
+	program := createDefaultProgram(`jmp rel 0;`)
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	runner.SetTimeout(10 * time.Millisecond)
+
+	start := time.Now()
+	err = runner.Run()
+	require.ErrorContains(t, err, "run timed out after 10ms")
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Zero(t, runner.steps()%timeoutCheckInterval)
+
+	// the clock starts again after resetting
+	require.NoError(t, runner.Reset())
+	require.ErrorContains(t, runner.Run(), "run timed out")
+}
+
+func TestRunFromContext(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = 1, ap++;
+        [ap] = [ap - 1] + 2, ap++;
+        [ap] = [ap - 1] * 3, ap++;
+        ret;
+    `)
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	end, err := runner.InitializeMainEntrypoint()
+	require.NoError(t, err)
+	require.NoError(t, runner.RunFor(1))
+	ctx := runner.vm.Context
+	snapshot := runner.memory().Snapshot()
+	require.NoError(t, runner.RunUntilPc(&end))
+
+	// continues from the captured state as if the run never went further
+	require.NoError(t, runner.memory().Restore(&snapshot))
+	require.NoError(t, runner.RunFromContext(ctx, end))
+	result, err := runner.memory().Read(VM.ExecutionSegment, runner.vm.Context.Ap-1)
+	require.NoError(t, err)
+	assert.Equal(t, memory.MemoryValueFromInt(9), result)
+	require.EqualError(t, runner.Run(), "cannot re-run using the same runner, call Reset first")
+
+	ctx.Pc = memory.MemoryAddress{SegmentIndex: 42}
+	require.EqualError(t, runner.RunFromContext(ctx, end), "context pc 42:0 is in an unallocated segment")
+	ctx.Pc = memory.MemoryAddress{SegmentIndex: VM.ProgramSegment, Offset: 7}
+	require.EqualError(
+		t, runner.RunFromContext(ctx, end), "context pc 0:7 is past the end of the 7 words program",
+	)
+}
+
+func TestPcJumpOutOfProgram(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = 1, ap++;
+        jmp rel 10;
+    `)
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.EqualError(t, runner.Run(), "pc 0:2 step 1: pc jumped out of program segment to 0:12")
+
+	// the immediate of the instruction it lands on is cut off
+	program = createDefaultProgram(`
+        jmp rel 2;
+        [ap] = 5, ap++;
+    `)
+	program.Bytecode = program.Bytecode[:3]
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.EqualError(
+		t, runner.Run(), "pc 0:0 step 0: pc jumped to 0:2 whose immediate is out of program segment",
+	)
+}
+
+func TestReadOnlyProgram(t *testing.T) {
+	program := createDefaultProgram(`
+        [ap] = 2, ap++;
+        ret;
+    `)
+	seven := memory.MemoryValueFromInt(7)
+
+	runner, err := NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	require.NoError(t, runner.memory().Write(vm.ProgramSegment, 5, &seven))
+
+	runner, err = NewRunner(program, false, math.MaxUint64)
+	require.NoError(t, err)
+	runner.EnableReadOnlyProgram()
+	err = runner.memory().Write(vm.ProgramSegment, 5, &seven)
+	require.ErrorContains(t, err, "memory 0:5: cannot write to read only segment at offset 5")
+	require.NoError(t, runner.Run())
+
+	// the program segment stays read only after resetting
+	require.NoError(t, runner.Reset())
+	err = runner.memory().Write(vm.ProgramSegment, 5, &seven)
+	require.ErrorContains(t, err, "read only segment")
+}
+
 func TestTraceEncodingDecoding(t *testing.T) {
 	trace := []vm.Trace{
 		{Ap: 1, Fp: 2, Pc: 3},
@@ -255,211 +1130,10 @@ func TestMemoryEncodingDecoding(t *testing.T) {
 }
 
 func BenchmarkRunnerWithFibonacci(b *testing.B) {
-	compiledJson := []byte(`
-        {
-            "compiler_version": "0.11.0.2",
-            "data": [
-                "0x40780017fff7fff",
-                "0x0",
-                "0x1104800180018000",
-                "0x4",
-                "0x10780017fff7fff",
-                "0x0",
-                "0x480680017fff8000",
-                "0x1",
-                "0x480680017fff8000",
-                "0x1",
-                "0x480680017fff8000",
-                "0xf4240",
-                "0x1104800180018000",
-                "0x3",
-                "0x208b7fff7fff7ffe",
-                "0x20780017fff7ffd",
-                "0x4",
-                "0x480a7ffc7fff8000",
-                "0x208b7fff7fff7ffe",
-                "0x482a7ffc7ffb8000",
-                "0x480a7ffc7fff8000",
-                "0x48127ffe7fff8000",
-                "0x482680017ffd8000",
-                "0x800000000000011000000000000000000000000000000000000000000000000",
-                "0x1104800180018000",
-                "0x800000000000010fffffffffffffffffffffffffffffffffffffffffffffff8",
-                "0x208b7fff7fff7ffe"
-            ],
-            "identifiers": {
-                "__main__.__end__": {
-                    "pc": 4,
-                    "type": "label"
-                },
-                "__main__.__start__": {
-                    "pc": 0,
-                    "type": "label"
-                },
-                "__main__.fib": {
-                    "decorators": [],
-                    "pc": 15,
-                    "type": "function"
-                },
-                "__main__.fib.Args": {
-                    "full_name": "__main__.fib.Args",
-                    "members": {
-                        "first_element": {
-                            "cairo_type": "felt",
-                            "offset": 0
-                        },
-                        "n": {
-                            "cairo_type": "felt",
-                            "offset": 2
-                        },
-                        "second_element": {
-                            "cairo_type": "felt",
-                            "offset": 1
-                        }
-                    },
-                    "size": 3,
-                    "type": "struct"
-                },
-                "__main__.fib.ImplicitArgs": {
-                    "full_name": "__main__.fib.ImplicitArgs",
-                    "members": {},
-                    "size": 0,
-                    "type": "struct"
-                },
-                "__main__.fib.Return": {
-                    "cairo_type": "(res: felt)",
-                    "type": "type_definition"
-                },
-                "__main__.fib.SIZEOF_LOCALS": {
-                    "type": "const",
-                    "value": 0
-                },
-                "__main__.fib.first_element": {
-                    "cairo_type": "felt",
-                    "full_name": "__main__.fib.first_element",
-                    "references": [
-                        {
-                            "ap_tracking_data": {
-                                "group": 4,
-                                "offset": 0
-                            },
-                            "pc": 15,
-                            "value": "[cast(fp + (-5), felt*)]"
-                        }
-                    ],
-                    "type": "reference"
-                },
-                "__main__.fib.n": {
-                    "cairo_type": "felt",
-                    "full_name": "__main__.fib.n",
-                    "references": [
-                        {
-                            "ap_tracking_data": {
-                                "group": 4,
-                                "offset": 0
-                            },
-                            "pc": 15,
-                            "value": "[cast(fp + (-3), felt*)]"
-                        }
-                    ],
-                    "type": "reference"
-                },
-                "__main__.fib.second_element": {
-                    "cairo_type": "felt",
-                    "full_name": "__main__.fib.second_element",
-                    "references": [
-                        {
-                            "ap_tracking_data": {
-                                "group": 4,
-                                "offset": 0
-                            },
-                            "pc": 15,
-                            "value": "[cast(fp + (-4), felt*)]"
-                        }
-                    ],
-                    "type": "reference"
-                },
-                "__main__.fib.y": {
-                    "cairo_type": "felt",
-                    "full_name": "__main__.fib.y",
-                    "references": [
-                        {
-                            "ap_tracking_data": {
-                                "group": 4,
-                                "offset": 1
-                            },
-                            "pc": 20,
-                            "value": "[cast(ap + (-1), felt*)]"
-                        }
-                    ],
-                    "type": "reference"
-                },
-                "__main__.main": {
-                    "decorators": [],
-                    "pc": 6,
-                    "type": "function"
-                },
-                "__main__.main.Args": {
-                    "full_name": "__main__.main.Args",
-                    "members": {},
-                    "size": 0,
-                    "type": "struct"
-                },
-                "__main__.main.ImplicitArgs": {
-                    "full_name": "__main__.main.ImplicitArgs",
-                    "members": {},
-                    "size": 0,
-                    "type": "struct"
-                },
-                "__main__.main.Return": {
-                    "cairo_type": "()",
-                    "type": "type_definition"
-                },
-                "__main__.main.SIZEOF_LOCALS": {
-                    "type": "const",
-                    "value": 0
-                }
-            },
-            "main_scope": "__main__",
-            "prime": "0x800000000000011000000000000000000000000000000000000000000000001",
-            "reference_manager": {
-                "references": [
-                    {
-                        "ap_tracking_data": {
-                            "group": 4,
-                            "offset": 0
-                        },
-                        "pc": 15,
-                        "value": "[cast(fp + (-5), felt*)]"
-                    },
-                    {
-                        "ap_tracking_data": {
-                            "group": 4,
-                            "offset": 0
-                        },
-                        "pc": 15,
-                        "value": "[cast(fp + (-4), felt*)]"
-                    },
-                    {
-                        "ap_tracking_data": {
-                            "group": 4,
-                            "offset": 0
-                        },
-                        "pc": 15,
-                        "value": "[cast(fp + (-3), felt*)]"
-                    },
-                    {
-                        "ap_tracking_data": {
-                            "group": 4,
-                            "offset": 1
-                        },
-                        "pc": 20,
-                        "value": "[cast(ap + (-1), felt*)]"
-                    }
-                ]
-            }
-        }
-    `)
+	compiledJson, err := os.ReadFile("testdata/fibonacci.json")
+	if err != nil {
+		b.Fatal(err)
+	}
 	for i := 0; i < b.N; i++ {
 		program, err := LoadCairoZeroProgram(compiledJson)
 		if err != nil {
@@ -478,6 +1152,39 @@ func BenchmarkRunnerWithFibonacci(b *testing.B) {
 	}
 }
 
+// Runs the programs of testdata, already loaded, so only the vm core is
+// measured. Besides allocations it reports the executed steps per second,
+// which is comparable across programs
+func BenchmarkRunProgram(b *testing.B) {
+	for _, name := range []string{"fibonacci"} {
+		b.Run(name, func(b *testing.B) {
+			content, err := os.ReadFile(filepath.Join("testdata", name+".json"))
+			if err != nil {
+				b.Fatal(err)
+			}
+			program, err := LoadCairoZeroProgram(content)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			steps := uint64(0)
+			for i := 0; i < b.N; i++ {
+				runner, err := NewRunner(program, true, math.MaxUint64)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err := runner.Run(); err != nil {
+					b.Fatal(err)
+				}
+				steps += runner.steps()
+			}
+			b.ReportMetric(float64(steps)/b.Elapsed().Seconds(), "steps/s")
+		})
+	}
+}
+
 func createSegment(values ...any) *memory.Segment {
 	data := make([]memory.MemoryValue, len(values))
 	for i := range values {
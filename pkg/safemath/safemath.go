@@ -25,32 +25,52 @@ import (
 // doesn't contain any conditional jump instructions
 // making it easier for a processor to pipeline the function.
 func SafeOffset(x uint64, y int16) (res uint64, isOverflow bool) {
+	// y is sign extended, so for y < 0 the addition below is x - |y| modulo 2**64
 	enlargedY := uint64(y)
-	// I'll leave proving that this is correct as an exercise for the reader :)
 	res = x + enlargedY
-	// Why does this work?
-	// Let's proceed by cases on the most significant bit of (MSB(x)).
-	// If MSB(x) == 1 and y < 0 (MSB(y) == 1) then overflow doesn't happen.
-	// Let's consider the case y >= 0 (MSB(y) == 0).
-	// In that case we can only wrap up by going to the begining of uint64 range making the MSB(res) = 0.
-	// This is the second disjunct of the disjunctive formula.
-	//
-	// In the same fashion the case MSB(x) == 0 and MSB(y) == 1 is reasoned about.
-	//
+	// Why does this work? |y| is at most 2**15, far below 2**63, so the result
+	// wraps around if and only if its most significant bit (MSB) differs from
+	// the one of x in the direction of y:
+	// - y >= 0 (MSB(y) == 0) overflows iff x was in the upper half and res
+	// wrapped to the lower one, MSB(x) == 1 and MSB(res) == 0. This is the
+	// second disjunct of the formula
+	// - y < 0 (MSB(y) == 1) underflows iff x was in the lower half and res
+	// wrapped to the upper one, MSB(x) == 0 and MSB(res) == 1. This is the
+	// first disjunct of the formula
 	// Finally, we boil everything down to MSBs by rotating and anding with ...000001.
 	isOverflow = bits.RotateLeft64((^x&enlargedY&res)|(x & ^enlargedY & ^res), 1)&0x1 != 0
 	return
 }
 
-// Given a number returns its closest power of two bigger than the number
-func NextPowerOfTwo(n uint64) uint64 {
+// Returns the addition of two uint64 and whether it overflowed
+func SafeAdd(x, y uint64) (res uint64, isOverflow bool) {
+	res, carry := bits.Add64(x, y, 0)
+	return res, carry != 0
+}
+
+// Returns the multiplication of two uint64 and whether it overflowed
+func SafeMul(x, y uint64) (res uint64, isOverflow bool) {
+	hi, res := bits.Mul64(x, y)
+	return res, hi != 0
+}
+
+// Given a number returns the smallest power of two greater or equal than it,
+// so powers of two are returned unchanged and zero yields one. Overflows when
+// the number is bigger than 2**63
+func NextPowerOfTwo(n uint64) (res uint64, isOverflow bool) {
+	if n == 0 {
+		return 1, false
+	}
 	// it is already a power of 2
 	if (n & (n - 1)) == 0 {
-		return uint64(n)
+		return n, false
 	}
 
 	higherBit := 64 - bits.LeadingZeros64(n)
-	return 1 << higherBit
+	if higherBit == 64 {
+		return 0, true
+	}
+	return 1 << higherBit, false
 }
 
 func Max[T constraints.Integer](a, b T) T {
@@ -59,3 +79,10 @@ func Max[T constraints.Integer](a, b T) T {
 	}
 	return b
 }
+
+func Min[T constraints.Integer](a, b T) T {
+	if a < b {
+		return a
+	}
+	return b
+}
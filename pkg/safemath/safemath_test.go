@@ -1,9 +1,11 @@
 package safemath
 
 import (
+	"math/big"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestOffsetNeg(t *testing.T) {
@@ -33,3 +35,111 @@ func TestOffsetRightNoOverflow(t *testing.T) {
 	assert.Equal(t, uint64(18446744073709551603), res)
 	assert.False(t, isOverflow)
 }
+
+func TestOffsetBounds(t *testing.T) {
+	const (
+		minOffset int16 = -1 << 15
+		maxOffset int16 = 1<<15 - 1
+	)
+	maxUint := ^uint64(0)
+
+	res, isOverflow := SafeOffset(1<<15, minOffset)
+	assert.Equal(t, uint64(0), res)
+	assert.False(t, isOverflow)
+	_, isOverflow = SafeOffset(1<<15-1, minOffset)
+	assert.True(t, isOverflow)
+	res, isOverflow = SafeOffset(maxUint, minOffset)
+	assert.Equal(t, maxUint-1<<15, res)
+	assert.False(t, isOverflow)
+
+	res, isOverflow = SafeOffset(maxUint-(1<<15-1), maxOffset)
+	assert.Equal(t, maxUint, res)
+	assert.False(t, isOverflow)
+	_, isOverflow = SafeOffset(maxUint-(1<<15-2), maxOffset)
+	assert.True(t, isOverflow)
+	res, isOverflow = SafeOffset(0, maxOffset)
+	assert.Equal(t, uint64(1<<15-1), res)
+	assert.False(t, isOverflow)
+
+	// every offset against registers around the bounds and the middle of the range
+	registers := []uint64{0, 1, 1<<15 - 1, 1 << 15, 1<<15 + 1, 1<<63 - 1, 1 << 63, maxUint - 1<<15, maxUint - 1, maxUint}
+	for _, x := range registers {
+		for y := int(minOffset); y <= int(maxOffset); y++ {
+			expected := new(big.Int).Add(new(big.Int).SetUint64(x), big.NewInt(int64(y)))
+			expectedOverflow := expected.Sign() < 0 || !expected.IsUint64()
+
+			res, isOverflow := SafeOffset(x, int16(y))
+			require.Equal(t, expectedOverflow, isOverflow, "%d + %d", x, y)
+			if !expectedOverflow {
+				require.Equal(t, expected.Uint64(), res, "%d + %d", x, y)
+			}
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	res, isOverflow := SafeAdd(7, 11)
+	assert.Equal(t, uint64(18), res)
+	assert.False(t, isOverflow)
+}
+
+func TestAddUpperBound(t *testing.T) {
+	res, isOverflow := SafeAdd(^uint64(0)-1, 1)
+	assert.Equal(t, ^uint64(0), res)
+	assert.False(t, isOverflow)
+}
+
+func TestAddOverflow(t *testing.T) {
+	res, isOverflow := SafeAdd(^uint64(0), 2)
+	assert.Equal(t, uint64(1), res)
+	assert.True(t, isOverflow)
+}
+
+func TestMul(t *testing.T) {
+	res, isOverflow := SafeMul(7, 11)
+	assert.Equal(t, uint64(77), res)
+	assert.False(t, isOverflow)
+}
+
+func TestMulUpperBound(t *testing.T) {
+	res, isOverflow := SafeMul(1<<32-1, 1<<32+1)
+	assert.Equal(t, ^uint64(0), res)
+	assert.False(t, isOverflow)
+}
+
+func TestMulOverflow(t *testing.T) {
+	_, isOverflow := SafeMul(1<<32, 1<<32)
+	assert.True(t, isOverflow)
+
+	_, isOverflow = SafeMul(^uint64(0), 2)
+	assert.True(t, isOverflow)
+}
+
+func TestNextPowerOfTwo(t *testing.T) {
+	testCases := []struct {
+		n        uint64
+		expected uint64
+	}{
+		{0, 1},
+		{1, 1},
+		{2, 2},
+		{3, 4},
+		{1000, 1024},
+		{1024, 1024},
+		{1<<62 + 1, 1 << 63},
+		{1 << 63, 1 << 63},
+	}
+
+	for _, tc := range testCases {
+		res, isOverflow := NextPowerOfTwo(tc.n)
+		assert.Equal(t, tc.expected, res, "n = %d", tc.n)
+		assert.False(t, isOverflow, "n = %d", tc.n)
+	}
+}
+
+func TestNextPowerOfTwoOverflow(t *testing.T) {
+	for _, n := range []uint64{1<<63 + 1, ^uint64(0)} {
+		_, isOverflow := NextPowerOfTwo(n)
+		assert.True(t, isOverflow, "n = %d", n)
+	}
+}
@@ -0,0 +1,42 @@
+package builtins
+
+import (
+	"fmt"
+
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+)
+
+// Given a builtin returns the runner which enforces its behaviour over
+// the builtin segment
+func Runner(name starknetParser.Builtin) (memory.BuiltinRunner, error) {
+	switch name {
+	case starknetParser.Output:
+		return &Output{}, nil
+	case starknetParser.RangeCheck:
+		return &RangeCheck{}, nil
+	case starknetParser.Keccak:
+		return &Keccak{}, nil
+	case starknetParser.SegmentArena:
+		return &SegmentArena{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported builtin: %s", name)
+	}
+}
+
+// Returns how many instances of cellsPerInstance cells are needed to cover
+// the used cells of a builtin segment. A partially used instance counts as used.
+// Rounds up without adding to the length so it cannot wrap around
+func instancesUsed(segment *memory.Segment, cellsPerInstance uint64) uint64 {
+	instances := segment.Len() / cellsPerInstance
+	if segment.Len()%cellsPerInstance != 0 {
+		instances++
+	}
+	return instances
+}
+
+// Returns the size of a builtin segment padded to a whole number of instances,
+// as the prover expects every instance to be complete
+func finalizedSize(segment *memory.Segment, cellsPerInstance uint64) uint64 {
+	return instancesUsed(segment, cellsPerInstance) * cellsPerInstance
+}
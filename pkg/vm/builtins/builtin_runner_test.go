@@ -0,0 +1,19 @@
+package builtins
+
+import (
+	"testing"
+
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunnerName(t *testing.T) {
+	for _, name := range []starknetParser.Builtin{
+		starknetParser.Output, starknetParser.RangeCheck, starknetParser.Keccak, starknetParser.SegmentArena,
+	} {
+		runner, err := Runner(name)
+		require.NoError(t, err)
+		assert.Equal(t, name.String(), runner.String())
+	}
+}
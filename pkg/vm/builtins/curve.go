@@ -0,0 +1,132 @@
+package builtins
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+)
+
+// A point of the stark curve y^2 = x^3 + alpha * x + beta in affine coordinates.
+// The point at infinity can't be represented, operations reaching it error
+// like the python vm does
+type EcPoint struct {
+	X fp.Element
+	Y fp.Element
+}
+
+var ecAlpha = fp.NewElement(1)
+
+var ecBeta = func() fp.Element {
+	var beta fp.Element
+	if _, err := beta.SetString("0x6f21413efbe40de150e596d72f7a8c5609ad26c15c915c1f4cdfcb99cee9e89"); err != nil {
+		panic(err)
+	}
+	return beta
+}()
+
+func (p *EcPoint) String() string {
+	return fmt.Sprintf("(%s, %s)", &p.X, &p.Y)
+}
+
+// Returns whether the point satisfies the curve equation
+func (p *EcPoint) OnCurve() bool {
+	var lhs, rhs, term fp.Element
+	lhs.Square(&p.Y)
+	rhs.Square(&p.X).Mul(&rhs, &p.X)
+	term.Mul(&ecAlpha, &p.X)
+	rhs.Add(&rhs, &term).Add(&rhs, &ecBeta)
+	return lhs.Equal(&rhs)
+}
+
+// Adds two points with different x coordinates
+func EcAdd(p, q *EcPoint) (EcPoint, error) {
+	if err := checkOnCurve(p, q); err != nil {
+		return EcPoint{}, err
+	}
+	if p.X.Equal(&q.X) {
+		return EcPoint{}, fmt.Errorf("cannot add points %s and %s with the same x coordinate", p, q)
+	}
+
+	// slope = (y2 - y1) / (x2 - x1)
+	var slope, dx fp.Element
+	slope.Sub(&q.Y, &p.Y)
+	dx.Sub(&q.X, &p.X)
+	dx.Inverse(&dx)
+	slope.Mul(&slope, &dx)
+	return ecLine(p, q, &slope), nil
+}
+
+// Doubles a point whose y coordinate is not zero
+func EcDouble(p *EcPoint) (EcPoint, error) {
+	if err := checkOnCurve(p); err != nil {
+		return EcPoint{}, err
+	}
+	if p.Y.IsZero() {
+		return EcPoint{}, fmt.Errorf("cannot double point %s with y = 0", p)
+	}
+
+	// slope = (3 * x^2 + alpha) / (2 * y)
+	var slope, denominator fp.Element
+	slope.Square(&p.X)
+	slope.Mul(&slope, new(fp.Element).SetUint64(3)).Add(&slope, &ecAlpha)
+	denominator.Double(&p.Y).Inverse(&denominator)
+	slope.Mul(&slope, &denominator)
+	return ecLine(p, p, &slope), nil
+}
+
+// Multiplies a point by a positive scalar. The additions happen in the same
+// order as in the python vm, so both fail on the same inputs
+func EcScalarMul(scalar *big.Int, p *EcPoint) (EcPoint, error) {
+	if scalar.Sign() <= 0 {
+		return EcPoint{}, errors.New("scalar must be positive")
+	}
+	if err := checkOnCurve(p); err != nil {
+		return EcPoint{}, err
+	}
+
+	// doublings[i] = 2**i * p
+	doublings := make([]EcPoint, scalar.BitLen())
+	doublings[0] = *p
+	for i := 1; i < len(doublings); i++ {
+		var err error
+		doublings[i], err = EcDouble(&doublings[i-1])
+		if err != nil {
+			return EcPoint{}, err
+		}
+	}
+
+	result := doublings[len(doublings)-1]
+	for i := len(doublings) - 2; i >= 0; i-- {
+		if scalar.Bit(i) == 0 {
+			continue
+		}
+		var err error
+		result, err = EcAdd(&result, &doublings[i])
+		if err != nil {
+			return EcPoint{}, err
+		}
+	}
+	return result, nil
+}
+
+// Returns the third intersection of the line through p and q with the given
+// slope, mirrored over the x axis
+func ecLine(p, q *EcPoint, slope *fp.Element) EcPoint {
+	var r EcPoint
+	// x3 = slope^2 - x1 - x2
+	r.X.Square(slope).Sub(&r.X, &p.X).Sub(&r.X, &q.X)
+	// y3 = slope * (x1 - x3) - y1
+	r.Y.Sub(&p.X, &r.X).Mul(&r.Y, slope).Sub(&r.Y, &p.Y)
+	return r
+}
+
+func checkOnCurve(points ...*EcPoint) error {
+	for _, p := range points {
+		if !p.OnCurve() {
+			return fmt.Errorf("point %s is not on the stark curve", p)
+		}
+	}
+	return nil
+}
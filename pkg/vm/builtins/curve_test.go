@@ -0,0 +1,67 @@
+package builtins
+
+import (
+	"math/big"
+	"testing"
+
+	starkcurve "github.com/consensys/gnark-crypto/ecc/stark-curve"
+	"github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generator() EcPoint {
+	_, g := starkcurve.Generators()
+	return EcPoint{X: g.X, Y: g.Y}
+}
+
+// computes scalar * g with gnark as a reference
+func expectedMul(scalar int64) EcPoint {
+	_, g := starkcurve.Generators()
+	var p starkcurve.G1Affine
+	p.ScalarMultiplication(&g, big.NewInt(scalar))
+	return EcPoint{X: p.X, Y: p.Y}
+}
+
+func TestEcAddAndDouble(t *testing.T) {
+	g := generator()
+	require.True(t, g.OnCurve())
+
+	double, err := EcDouble(&g)
+	require.NoError(t, err)
+	assert.Equal(t, expectedMul(2), double)
+
+	triple, err := EcAdd(&double, &g)
+	require.NoError(t, err)
+	assert.Equal(t, expectedMul(3), triple)
+	swapped, err := EcAdd(&g, &double)
+	require.NoError(t, err)
+	assert.Equal(t, triple, swapped)
+
+	_, err = EcAdd(&g, &g)
+	require.ErrorContains(t, err, "with the same x coordinate")
+
+	offCurve := EcPoint{X: g.X, Y: fp.NewElement(1)}
+	_, err = EcAdd(&g, &offCurve)
+	require.ErrorContains(t, err, "is not on the stark curve")
+	_, err = EcDouble(&offCurve)
+	require.ErrorContains(t, err, "is not on the stark curve")
+}
+
+func TestEcScalarMul(t *testing.T) {
+	g := generator()
+	for _, scalar := range []int64{1, 2, 7, 12, 1 << 40} {
+		p, err := EcScalarMul(big.NewInt(scalar), &g)
+		require.NoError(t, err)
+		assert.Equal(t, expectedMul(scalar), p, "scalar %d", scalar)
+	}
+
+	_, err := EcScalarMul(big.NewInt(0), &g)
+	require.EqualError(t, err, "scalar must be positive")
+
+	// the order of the curve leads to the point at infinity
+	order, ok := new(big.Int).SetString("0x800000000000010ffffffffffffffffb781126dcae7b2321e66a241adc64d2f", 0)
+	require.True(t, ok)
+	_, err = EcScalarMul(order, &g)
+	require.ErrorContains(t, err, "with the same x coordinate")
+}
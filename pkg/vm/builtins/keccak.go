@@ -0,0 +1,164 @@
+package builtins
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	"github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+)
+
+const (
+	// each keccak instance has 8 input cells followed by 8 output cells
+	keccakCellsPerInstance = 16
+	keccakInputCells       = 8
+	// every cell holds 200 bits (25 bytes) of the 1600 bits keccak state
+	keccakBytesPerCell = 25
+)
+
+type Keccak struct{}
+
+func (k *Keccak) CheckWrite(segment *memory.Segment, offset uint64, value *memory.MemoryValue) error {
+	if offset%keccakCellsPerInstance >= keccakInputCells {
+		return nil
+	}
+
+	felt, err := value.ToFieldElement()
+	if err != nil {
+		return fmt.Errorf("keccak builtin input at offset %d: %w", offset, err)
+	}
+
+	// felt >= (2^200)
+	if !fitsInKeccakCell(felt) {
+		return fmt.Errorf("keccak builtin failed for offset: %d value %s is not a 200 bit word", offset, value)
+	}
+	return nil
+}
+
+func (k *Keccak) InferValue(segment *memory.Segment, offset uint64) error {
+	index := offset % keccakCellsPerInstance
+	if index < keccakInputCells {
+		return fmt.Errorf("keccak builtin: cannot infer input cell at offset %d", offset)
+	}
+
+	startOffset := offset - index
+	var state [keccakInputCells * keccakBytesPerCell]byte
+	for i := uint64(0); i < keccakInputCells; i++ {
+		input := segment.Peek(startOffset + i)
+		if !input.Known() {
+			return fmt.Errorf("keccak builtin: input cell at offset %d is unknown", startOffset+i)
+		}
+		felt, err := input.ToFieldElement()
+		if err != nil {
+			return fmt.Errorf("keccak builtin input at offset %d: %w", startOffset+i, err)
+		}
+
+		var feltBytes [32]byte
+		fp.LittleEndian.PutElement(&feltBytes, *felt)
+		copy(state[i*keccakBytesPerCell:], feltBytes[:keccakBytesPerCell])
+	}
+
+	var lanes [25]uint64
+	for i := range lanes {
+		lanes[i] = binary.LittleEndian.Uint64(state[i*8:])
+	}
+	KeccakF1600(&lanes)
+	for i := range lanes {
+		binary.LittleEndian.PutUint64(state[i*8:], lanes[i])
+	}
+
+	outputIndex := index - keccakInputCells
+	var outputBytes [32]byte
+	copy(outputBytes[:], state[outputIndex*keccakBytesPerCell:(outputIndex+1)*keccakBytesPerCell])
+	output, err := fp.LittleEndian.Element(&outputBytes)
+	if err != nil {
+		return fmt.Errorf("keccak builtin output at offset %d: %w", offset, err)
+	}
+
+	value := memory.MemoryValueFromFieldElement(&output)
+	return segment.WriteInferred(offset, &value)
+}
+
+// The output cells are computed from the input ones
+func (k *Keccak) Deduces(offset uint64) bool {
+	return offset%keccakCellsPerInstance >= keccakInputCells
+}
+
+func (k *Keccak) InstancesUsed(segment *memory.Segment) uint64 {
+	return instancesUsed(segment, keccakCellsPerInstance)
+}
+
+func (k *Keccak) FinalizedSize(segment *memory.Segment) uint64 {
+	return finalizedSize(segment, keccakCellsPerInstance)
+}
+
+func (k *Keccak) String() string {
+	return starknetParser.Keccak.String()
+}
+
+func fitsInKeccakCell(felt *fp.Element) bool {
+	var feltBytes [32]byte
+	fp.LittleEndian.PutElement(&feltBytes, *felt)
+	for _, b := range feltBytes[keccakBytesPerCell:] {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808a, 0x8000000080008000,
+	0x000000000000808b, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008a, 0x0000000000000088, 0x0000000080008009, 0x000000008000000a,
+	0x000000008000808b, 0x800000000000008b, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800a, 0x800000008000000a,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// rotation offsets indexed by lane position x + 5y
+var keccakRotations = [25]int{
+	0, 1, 62, 28, 27,
+	36, 44, 6, 55, 20,
+	3, 10, 43, 25, 39,
+	41, 45, 15, 21, 8,
+	18, 2, 61, 56, 14,
+}
+
+// Applies the keccak-f[1600] permutation in place to a state of
+// 25 lanes of 64 bits, where lane (x, y) is stored at index x + 5y
+func KeccakF1600(state *[25]uint64) {
+	var c, d [5]uint64
+	var b [25]uint64
+	for round := 0; round < 24; round++ {
+		// theta
+		for x := 0; x < 5; x++ {
+			c[x] = state[x] ^ state[x+5] ^ state[x+10] ^ state[x+15] ^ state[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d[x] = c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+		}
+		for i := range state {
+			state[i] ^= d[i%5]
+		}
+
+		// rho and pi
+		for x := 0; x < 5; x++ {
+			for y := 0; y < 5; y++ {
+				b[y+5*((2*x+3*y)%5)] = bits.RotateLeft64(state[x+5*y], keccakRotations[x+5*y])
+			}
+		}
+
+		// chi
+		for y := 0; y < 25; y += 5 {
+			for x := 0; x < 5; x++ {
+				state[y+x] = b[y+x] ^ (^b[y+(x+1)%5] & b[y+(x+2)%5])
+			}
+		}
+
+		// iota
+		state[0] ^= keccakRoundConstants[round]
+	}
+}
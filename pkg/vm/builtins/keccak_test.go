@@ -0,0 +1,105 @@
+package builtins
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	"github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeccakF1600ZeroState(t *testing.T) {
+	state := [25]uint64{}
+	KeccakF1600(&state)
+
+	expected := [25]uint64{
+		0xF1258F7940E1DDE7, 0x84D5CCF933C0478A, 0xD598261EA65AA9EE, 0xBD1547306F80494D, 0x8B284E056253D057,
+		0xFF97A42D7F8E6FD4, 0x90FEE5A0A44647C4, 0x8C5BDA0CD6192E76, 0xAD30A6F71B19059C, 0x30935AB7D08FFC64,
+		0xEB5AA93F2317D635, 0xA9A6E6260D712103, 0x81A57C16DBCF555F, 0x43B831CD0347C826, 0x01F22F1A11A5569F,
+		0x05E5635A21D9AE61, 0x64BEFEF28CC970F2, 0x613670957BC46611, 0xB87C5A554FD00ECB, 0x8C3EE88A1CCF32C8,
+		0x940C7922AE3A2614, 0x1841F924A2C509E4, 0x16F53526E70465C2, 0x75F644E97F30A13B, 0xEAF1FF7B5CECA249,
+	}
+	assert.Equal(t, expected, state)
+}
+
+func TestKeccakWriteMemoryAddress(t *testing.T) {
+	builtin := Keccak{}
+	memoryAddress := memory.EmptyMemoryValueAsAddress()
+	assert.Error(t, builtin.CheckWrite(nil, 0, &memoryAddress))
+}
+
+func TestKeccakWriteOutOfRange(t *testing.T) {
+	builtin := Keccak{}
+	// 2^200
+	outOfRangeValueFelt, err := new(fp.Element).SetString("0x100000000000000000000000000000000000000000000000000")
+	require.NoError(t, err)
+	outOfRangeValue := memory.MemoryValueFromFieldElement(outOfRangeValueFelt)
+	assert.Error(t, builtin.CheckWrite(nil, 3, &outOfRangeValue))
+	// output cells are not checked
+	assert.NoError(t, builtin.CheckWrite(nil, 8, &outOfRangeValue))
+}
+
+func TestKeccakWrite(t *testing.T) {
+	builtin := Keccak{}
+	// 2^200 - 1
+	f, err := new(fp.Element).SetString("0xffffffffffffffffffffffffffffffffffffffffffffffffff")
+	require.NoError(t, err)
+	v := memory.MemoryValueFromFieldElement(f)
+	assert.NoError(t, builtin.CheckWrite(nil, 0, &v))
+}
+
+func TestKeccakInfer(t *testing.T) {
+	segment := memory.EmptySegment().WithBuiltinRunner(&Keccak{})
+	for i := uint64(0); i < keccakInputCells; i++ {
+		zero := memory.MemoryValueFromInt(0)
+		require.NoError(t, segment.Write(i, &zero))
+	}
+
+	// the first output word are the first 25 bytes of the permuted zero state
+	// in little endian: lanes 0, 1, 2 and the lowest byte of lane 3
+	expected := new(big.Int).SetUint64(0x4D)
+	for _, lane := range []uint64{0xD598261EA65AA9EE, 0x84D5CCF933C0478A, 0xF1258F7940E1DDE7} {
+		expected.Lsh(expected, 64)
+		expected.Or(expected, new(big.Int).SetUint64(lane))
+	}
+	expectedFelt := new(fp.Element).SetBigInt(expected)
+
+	output, err := segment.Read(keccakInputCells)
+	require.NoError(t, err)
+	assert.Equal(t, memory.MemoryValueFromFieldElement(expectedFelt), output)
+}
+
+func TestKeccakInferMissingInput(t *testing.T) {
+	segment := memory.EmptySegment().WithBuiltinRunner(&Keccak{})
+	zero := memory.MemoryValueFromInt(0)
+	require.NoError(t, segment.Write(0, &zero))
+
+	_, err := segment.Read(keccakInputCells)
+	require.ErrorContains(t, err, "offset 1 is unknown")
+
+	_, err = segment.Read(2)
+	require.ErrorContains(t, err, "cannot infer input cell")
+}
+
+func TestKeccakFinalize(t *testing.T) {
+	segment := memory.EmptySegment().WithBuiltinRunner(&Keccak{})
+	zero := memory.MemoryValueFromInt(0)
+	require.NoError(t, segment.Write(keccakCellsPerInstance+1, &zero))
+
+	// the partially used second instance is padded
+	segment.Finalize()
+	assert.Equal(t, uint64(2*keccakCellsPerInstance), segment.Len())
+	padding := segment.Peek(2*keccakCellsPerInstance - 1)
+	assert.False(t, padding.Known())
+	require.Error(t, segment.Write(2*keccakCellsPerInstance, &zero))
+}
+
+func TestKeccakDeduces(t *testing.T) {
+	keccak := &Keccak{}
+	assert.False(t, keccak.Deduces(keccakInputCells-1))
+	assert.True(t, keccak.Deduces(keccakInputCells))
+	assert.True(t, keccak.Deduces(keccakCellsPerInstance-1))
+	assert.False(t, keccak.Deduces(keccakCellsPerInstance))
+}
@@ -0,0 +1,40 @@
+package builtins
+
+import (
+	"fmt"
+
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+)
+
+// each output instance is a single cell
+const outputCellsPerInstance = 1
+
+// The segment where a program writes its public output. Any value can be
+// written, but nothing can be inferred
+type Output struct{}
+
+func (o *Output) CheckWrite(segment *memory.Segment, offset uint64, value *memory.MemoryValue) error {
+	return nil
+}
+
+func (o *Output) InferValue(segment *memory.Segment, offset uint64) error {
+	return fmt.Errorf("output builtin: cannot infer value at offset %d", offset)
+}
+
+func (o *Output) InstancesUsed(segment *memory.Segment) uint64 {
+	return instancesUsed(segment, outputCellsPerInstance)
+}
+
+// The whole output is public
+func (o *Output) PublicOffsets(segment *memory.Segment) []uint64 {
+	offsets := make([]uint64, segment.Len())
+	for i := range offsets {
+		offsets[i] = uint64(i)
+	}
+	return offsets
+}
+
+func (o *Output) String() string {
+	return starknetParser.Output.String()
+}
@@ -0,0 +1,20 @@
+package builtins
+
+import (
+	"testing"
+
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputWrite(t *testing.T) {
+	segment := memory.EmptySegment().WithBuiltinRunner(&Output{})
+	address := memory.MemoryValueFromSegmentAndOffset(1, 4)
+	require.NoError(t, segment.Write(0, memoryValuePointer(7)))
+	require.NoError(t, segment.Write(2, &address))
+	assert.Equal(t, uint64(3), segment.BuiltinRunner.InstancesUsed(segment))
+
+	_, err := segment.Read(1)
+	require.EqualError(t, err, "output builtin: cannot infer value at offset 1")
+}
@@ -3,10 +3,14 @@ package builtins
 import (
 	"fmt"
 
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
 	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
 	"github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
 )
 
+// each range check instance is a single cell
+const rangeCheckCellsPerInstance = 1
+
 type RangeCheck struct{}
 
 // 1 << 128
@@ -26,6 +30,14 @@ func (r *RangeCheck) CheckWrite(segment *memory.Segment, offset uint64, value *m
 }
 
 func (r *RangeCheck) InferValue(segment *memory.Segment, offset uint64) error {
-	segment.Data[offset] = memory.EmptyMemoryValueAsFelt()
-	return nil
+	zero := memory.EmptyMemoryValueAsFelt()
+	return segment.WriteDefault(offset, &zero)
+}
+
+func (r *RangeCheck) InstancesUsed(segment *memory.Segment) uint64 {
+	return instancesUsed(segment, rangeCheckCellsPerInstance)
+}
+
+func (r *RangeCheck) String() string {
+	return starknetParser.RangeCheck.String()
 }
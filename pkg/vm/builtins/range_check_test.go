@@ -37,3 +37,15 @@ func TestRangeCheckInfer(t *testing.T) {
 	assert.NoError(t, builtin.InferValue(segment, 0))
 	require.Equal(t, memory.EmptyMemoryValueAsFelt(), segment.Data[0])
 }
+
+func TestRangeCheckInferThenAssert(t *testing.T) {
+	segment := memory.EmptySegment().WithBuiltinRunner(&RangeCheck{})
+	value, err := segment.Read(0)
+	require.NoError(t, err)
+	require.Equal(t, memory.EmptyMemoryValueAsFelt(), value)
+
+	// the program can assert the inferred value, but not change it
+	require.NoError(t, segment.Write(0, &value))
+	one := memory.MemoryValueFromInt(1)
+	require.ErrorContains(t, segment.Write(0, &one), "rewriting cell")
+}
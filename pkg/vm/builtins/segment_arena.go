@@ -0,0 +1,188 @@
+package builtins
+
+import (
+	"fmt"
+
+	starknetParser "github.com/NethermindEth/cairo-vm-go/pkg/parsers/starknet"
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+)
+
+// each segment arena instance is formed by the cells
+// | infos pointer | number of dicts | number of destructed dicts |
+const (
+	segmentArenaCellsPerInstance = 3
+	segmentArenaInfosCell        = 0
+	segmentArenaDictsCell        = 1
+	segmentArenaDestructedCell   = 2
+)
+
+// Information tracked for every dictionary segment allocated through the arena
+type DictSegment struct {
+	// index of the memory segment holding the dictionary accesses
+	SegmentIndex uint64
+	// amount of cells currently used by the dictionary accesses
+	Size uint64
+	// set once the dictionary has been squashed
+	Finalized bool
+}
+
+// SegmentArena keeps track of the dictionary segments used by Cairo 1
+// `Felt252Dict`s. Each time a dictionary is created a new segment is
+// allocated and recorded, so it can later be found, resized and finalized
+type SegmentArena struct {
+	dicts []DictSegment
+}
+
+func (arena *SegmentArena) CheckWrite(segment *memory.Segment, offset uint64, value *memory.MemoryValue) error {
+	switch offset % segmentArenaCellsPerInstance {
+	case segmentArenaInfosCell:
+		if !value.IsAddress() {
+			return fmt.Errorf("segment arena builtin: infos at offset %d must be an address, got %s", offset, value)
+		}
+		// every instance must point to the same infos segment
+		if offset >= segmentArenaCellsPerInstance {
+			previous := knownCell(segment, offset-segmentArenaCellsPerInstance)
+			if previous.IsAddress() {
+				previousAddr, _ := previous.ToMemoryAddress()
+				infosAddr, _ := value.ToMemoryAddress()
+				if previousAddr.SegmentIndex != infosAddr.SegmentIndex {
+					return fmt.Errorf(
+						"segment arena builtin: infos at offset %d changed segment from %d to %d",
+						offset, previousAddr.SegmentIndex, infosAddr.SegmentIndex,
+					)
+				}
+			}
+		}
+	case segmentArenaDictsCell:
+		nDicts, err := value.Uint64()
+		if err != nil {
+			return fmt.Errorf("segment arena builtin: number of dicts at offset %d: %w", offset, err)
+		}
+		if nDicts > arena.DictCount() {
+			return fmt.Errorf(
+				"segment arena builtin: number of dicts %d at offset %d exceeds the %d allocated",
+				nDicts, offset, arena.DictCount(),
+			)
+		}
+		destructed := knownCell(segment, offset+1)
+		if destructed.Known() {
+			return checkDestructedDicts(offset+1, &destructed, nDicts)
+		}
+	case segmentArenaDestructedCell:
+		if _, err := value.Uint64(); err != nil {
+			return fmt.Errorf("segment arena builtin: number of destructed dicts at offset %d: %w", offset, err)
+		}
+		dicts := knownCell(segment, offset-1)
+		if dicts.Known() {
+			nDicts, err := dicts.Uint64()
+			if err != nil {
+				return fmt.Errorf("segment arena builtin: number of dicts at offset %d: %w", offset-1, err)
+			}
+			return checkDestructedDicts(offset, value, nDicts)
+		}
+	}
+	return nil
+}
+
+func (arena *SegmentArena) InferValue(segment *memory.Segment, offset uint64) error {
+	return fmt.Errorf("segment arena builtin: cannot infer value at offset %d", offset)
+}
+
+func (arena *SegmentArena) InstancesUsed(segment *memory.Segment) uint64 {
+	return instancesUsed(segment, segmentArenaCellsPerInstance)
+}
+
+func (arena *SegmentArena) FinalizedSize(segment *memory.Segment) uint64 {
+	return finalizedSize(segment, segmentArenaCellsPerInstance)
+}
+
+func (arena *SegmentArena) Clone() memory.BuiltinRunner {
+	return &SegmentArena{dicts: append([]DictSegment(nil), arena.dicts...)}
+}
+
+func (arena *SegmentArena) String() string {
+	return starknetParser.SegmentArena.String()
+}
+
+// Allocates a new memory segment for a dictionary and returns the dictionary
+// index inside the arena together with the segment start address
+func (arena *SegmentArena) AllocateDict(mem *memory.Memory) (uint64, memory.MemoryAddress) {
+	segmentIndex := uint64(mem.AllocateEmptySegment())
+	arena.dicts = append(arena.dicts, DictSegment{SegmentIndex: segmentIndex})
+	return uint64(len(arena.dicts) - 1), memory.MemoryAddress{SegmentIndex: segmentIndex, Offset: 0}
+}
+
+// Returns the amount of dictionary segments allocated so far
+func (arena *SegmentArena) DictCount() uint64 {
+	return uint64(len(arena.dicts))
+}
+
+// Returns the tracked information of a dictionary given its arena index
+func (arena *SegmentArena) Dict(index uint64) (DictSegment, error) {
+	if index >= arena.DictCount() {
+		return DictSegment{}, fmt.Errorf("segment arena: unknown dict index %d", index)
+	}
+	return arena.dicts[index], nil
+}
+
+// Given the memory segment of a dictionary returns its index inside the arena
+func (arena *SegmentArena) DictIndex(segmentIndex uint64) (uint64, error) {
+	for i := range arena.dicts {
+		if arena.dicts[i].SegmentIndex == segmentIndex {
+			return uint64(i), nil
+		}
+	}
+	return 0, fmt.Errorf("segment arena: segment %d is not a dict segment", segmentIndex)
+}
+
+// Updates the amount of cells used by a dictionary. Errors if the dictionary
+// was already finalized or if its size decreases
+func (arena *SegmentArena) UpdateDictSize(index uint64, size uint64) error {
+	if index >= arena.DictCount() {
+		return fmt.Errorf("segment arena: unknown dict index %d", index)
+	}
+	dict := &arena.dicts[index]
+	if dict.Finalized {
+		return fmt.Errorf("segment arena: dict %d is already finalized", index)
+	}
+	if size < dict.Size {
+		return fmt.Errorf("segment arena: dict %d size cannot decrease from %d to %d", index, dict.Size, size)
+	}
+	dict.Size = size
+	return nil
+}
+
+// Marks a dictionary as finalized. Errors if it was already finalized
+func (arena *SegmentArena) FinalizeDict(index uint64) error {
+	if index >= arena.DictCount() {
+		return fmt.Errorf("segment arena: unknown dict index %d", index)
+	}
+	if arena.dicts[index].Finalized {
+		return fmt.Errorf("segment arena: dict %d is already finalized", index)
+	}
+	arena.dicts[index].Finalized = true
+	return nil
+}
+
+// returns the value of a cell without modifying the segment, which
+// is unknown if the cell is out of the segment bounds
+func knownCell(segment *memory.Segment, offset uint64) memory.MemoryValue {
+	if offset >= segment.RealLen() {
+		return memory.MemoryValue{}
+	}
+	return segment.Data[offset]
+}
+
+func checkDestructedDicts(offset uint64, destructed *memory.MemoryValue, nDicts uint64) error {
+	nDestructed, err := destructed.Uint64()
+	if err != nil {
+		return fmt.Errorf("segment arena builtin: number of destructed dicts at offset %d: %w", offset, err)
+	}
+	if nDestructed > nDicts {
+		return fmt.Errorf(
+			"segment arena builtin: destructed dicts %d at offset %d exceed the number of dicts %d",
+			nDestructed, offset, nDicts,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package builtins
+
+import (
+	"testing"
+
+	"github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmentArenaAllocateDicts(t *testing.T) {
+	mem := memory.InitializeEmptyMemory()
+	mem.AllocateEmptySegment()
+	arena := SegmentArena{}
+
+	index, addr := arena.AllocateDict(mem)
+	assert.Equal(t, uint64(0), index)
+	assert.Equal(t, memory.MemoryAddress{SegmentIndex: 1, Offset: 0}, addr)
+
+	index, addr = arena.AllocateDict(mem)
+	assert.Equal(t, uint64(1), index)
+	assert.Equal(t, memory.MemoryAddress{SegmentIndex: 2, Offset: 0}, addr)
+	assert.Equal(t, uint64(2), arena.DictCount())
+
+	dictIndex, err := arena.DictIndex(2)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), dictIndex)
+	_, err = arena.DictIndex(0)
+	require.Error(t, err)
+
+	require.NoError(t, arena.UpdateDictSize(0, 6))
+	require.ErrorContains(t, arena.UpdateDictSize(0, 3), "cannot decrease")
+	require.NoError(t, arena.FinalizeDict(0))
+	require.ErrorContains(t, arena.FinalizeDict(0), "already finalized")
+	require.ErrorContains(t, arena.UpdateDictSize(0, 9), "already finalized")
+
+	dict, err := arena.Dict(0)
+	require.NoError(t, err)
+	assert.Equal(t, DictSegment{SegmentIndex: 1, Size: 6, Finalized: true}, dict)
+
+	_, err = arena.Dict(2)
+	require.Error(t, err)
+}
+
+func TestSegmentArenaCheckWrite(t *testing.T) {
+	mem := memory.InitializeEmptyMemory()
+	arena := &SegmentArena{}
+	segment := memory.EmptySegment().WithBuiltinRunner(arena)
+	infos := memory.MemoryValueFromSegmentAndOffset(2, 0)
+	arena.AllocateDict(mem)
+
+	require.NoError(t, segment.Write(0, &infos))
+	require.NoError(t, segment.Write(1, memoryValuePointer(1)))
+	require.NoError(t, segment.Write(2, memoryValuePointer(0)))
+
+	// same infos segment, new dict allocated
+	require.NoError(t, segment.Write(3, &infos))
+	require.ErrorContains(t, segment.Write(4, memoryValuePointer(2)), "exceeds the 1 allocated")
+}
+
+func TestSegmentArenaCheckWriteInvalidInstance(t *testing.T) {
+	arena := &SegmentArena{}
+	segment := memory.EmptySegment().WithBuiltinRunner(arena)
+
+	require.ErrorContains(t, segment.Write(0, memoryValuePointer(3)), "must be an address")
+
+	infos := memory.MemoryValueFromSegmentAndOffset(2, 0)
+	otherInfos := memory.MemoryValueFromSegmentAndOffset(5, 0)
+	require.NoError(t, segment.Write(3, &infos))
+	require.ErrorContains(t, segment.Write(6, &otherInfos), "changed segment from 2 to 5")
+
+	require.NoError(t, segment.Write(5, memoryValuePointer(1)))
+	require.ErrorContains(t, segment.Write(4, memoryValuePointer(0)), "exceed the number of dicts 0")
+}
+
+func TestSegmentArenaInfer(t *testing.T) {
+	segment := memory.EmptySegment().WithBuiltinRunner(&SegmentArena{})
+	_, err := segment.Read(0)
+	require.Error(t, err)
+}
+
+func memoryValuePointer(v uint64) *memory.MemoryValue {
+	mv := memory.MemoryValueFromUint(v)
+	return &mv
+}
+
+func TestSegmentArenaInstancesUsed(t *testing.T) {
+	arena := &SegmentArena{}
+	assert.Equal(t, uint64(0), arena.InstancesUsed(memory.EmptySegment()))
+	assert.Equal(t, uint64(1), arena.InstancesUsed(memory.EmptySegmentWithLength(3)))
+	assert.Equal(t, uint64(2), arena.InstancesUsed(memory.EmptySegmentWithLength(4)))
+}
+
+func TestSegmentArenaClone(t *testing.T) {
+	mem := memory.InitializeEmptyMemory()
+	arenaIndex := mem.AllocateBuiltinSegment("segment_arena", &SegmentArena{})
+	arena := mem.Segments[arenaIndex].BuiltinRunner.(*SegmentArena)
+	arena.AllocateDict(mem)
+
+	clone := mem.Clone()
+	clonedArena := clone.Segments[arenaIndex].BuiltinRunner.(*SegmentArena)
+	require.NotSame(t, arena, clonedArena)
+	clonedArena.AllocateDict(clone)
+	require.NoError(t, clonedArena.UpdateDictSize(0, 3))
+
+	assert.Equal(t, uint64(1), arena.DictCount())
+	dict, err := arena.Dict(0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), dict.Size)
+	assert.Equal(t, uint64(2), clonedArena.DictCount())
+}
@@ -0,0 +1,102 @@
+package vm
+
+import (
+	"fmt"
+
+	mem "github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
+)
+
+// Error produced while executing a single VM step
+type VMError struct {
+	Pc   mem.MemoryAddress
+	Step uint64
+	// the stage of the step that failed, e.g. "decoding instruction" or "dst cell"
+	Op  string
+	Err error
+}
+
+func (e *VMError) Error() string {
+	return fmt.Sprintf("pc %s step %d: %s: %s", e.Pc, e.Step, e.Op, e.Err)
+}
+
+func (e *VMError) Unwrap() error {
+	return e.Err
+}
+
+// Error produced when an assert_eq instruction computes a value different
+// from the one already stored at dst
+type AssertEqError struct {
+	Dst      mem.MemoryAddress
+	Expected mem.MemoryValue
+	Got      mem.MemoryValue
+}
+
+func (e *AssertEqError) Error() string {
+	return fmt.Sprintf(
+		"assertion failed at %s: expected %s, got %s", e.Dst, e.Expected, e.Got,
+	)
+}
+
+func (e *AssertEqError) Unwrap() error {
+	return nil
+}
+
+// Error produced when a ret instruction doesn't have the shape of a function
+// return, which usually means miscompiled or corrupted bytecode
+type MalformedRetError struct {
+	Reason string
+}
+
+func (e *MalformedRetError) Error() string {
+	return fmt.Sprintf("malformed ret: %s", e.Reason)
+}
+
+func (e *MalformedRetError) Unwrap() error {
+	return nil
+}
+
+// Error produced when the flags of an instruction word form an invalid
+// combination. Group names the flag group at fault, e.g. "pc update"
+type InvalidFlagsError struct {
+	Flags uint16
+	Group string
+	Err   error
+}
+
+func (e *InvalidFlagsError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Group, e.Err)
+}
+
+func (e *InvalidFlagsError) Unwrap() error {
+	return e.Err
+}
+
+// Error produced when the word at pc is not a valid instruction. The words
+// around it are kept since a misaligned pc usually lands on the immediate of
+// the previous instruction, or right before the instruction it skipped.
+// Neighbours outside of the segment or not written are unknown
+type DecodeError struct {
+	Word     f.Element
+	Previous mem.MemoryValue
+	Next     mem.MemoryValue
+	Err      error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf(
+		"word 0x%s (previous %s, next %s): %s",
+		e.Word.Text(16), wordRepr(&e.Previous), wordRepr(&e.Next), e.Err,
+	)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+func wordRepr(word *mem.MemoryValue) string {
+	if !word.Known() {
+		return "unknown"
+	}
+	return word.StringHex()
+}
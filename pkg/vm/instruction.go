@@ -1,6 +1,7 @@
 package vm
 
 import (
+	"errors"
 	"fmt"
 	"math/bits"
 
@@ -163,6 +164,9 @@ type Instruction struct {
 	Opcode Opcode
 }
 
+// Returns the amount of memory cells the instruction takes. An instruction with
+// an immediate operand is followed by the immediate value itself at pc + 1, so
+// it takes two cells and pc must advance by 2 to reach the next instruction
 func (instr Instruction) Size() uint8 {
 	if instr.Op1Source == Imm {
 		return 2
@@ -170,30 +174,92 @@ func (instr Instruction) Size() uint8 {
 	return 1
 }
 
+// Returns the instruction in Cairo assembly, e.g. `[ap] = [fp - 3] + imm, ap++`.
+// Immediates are shown as `imm` since their value is stored in the next word
 func (i Instruction) String() string {
-	return fmt.Sprintf(`
-        Dst Offset: %d
-        Dst Register: %s
-        Op0 Offset: %d
-        Op0 Register: %s
-        Op1 Offset: %d
-        Op1 Source: %s
-        ResLogic: %s
-        Pc Update: %s
-        Ap Update: %s
-        Opcode: %s
-    `,
-		i.OffDest,
-		i.DstRegister,
-		i.OffOp0,
-		i.Op0Register,
-		i.OffOp1,
-		i.Op1Source,
-		i.Res,
-		i.PcUpdate,
-		i.ApUpdate,
-		i.Opcode,
-	)
+	var repr string
+	switch i.Opcode {
+	case AssertEq:
+		repr = fmt.Sprintf("%s = %s", i.dstString(), i.resString())
+	case Call:
+		repr = fmt.Sprintf("call %s %s", jumpKind(i.PcUpdate), i.resString())
+	case Ret:
+		repr = "ret"
+	default:
+		switch {
+		case i.PcUpdate == Jnz:
+			repr = fmt.Sprintf("jmp rel %s if %s != 0", i.op1String(), i.dstString())
+		case i.PcUpdate != NextInstr:
+			repr = fmt.Sprintf("jmp %s %s", jumpKind(i.PcUpdate), i.resString())
+		case i.ApUpdate == AddImm:
+			return fmt.Sprintf("ap += %s", i.resString())
+		default:
+			repr = "nop"
+		}
+	}
+
+	if i.ApUpdate == Add1 {
+		repr += ", ap++"
+	}
+	return repr
+}
+
+func (i Instruction) dstString() string {
+	return cellString(registerString(i.DstRegister), i.OffDest)
+}
+
+func (i Instruction) op0String() string {
+	return cellString(registerString(i.Op0Register), i.OffOp0)
+}
+
+func (i Instruction) op1String() string {
+	switch i.Op1Source {
+	case Imm:
+		return "imm"
+	case FpPlusOffOp1:
+		return cellString("fp", i.OffOp1)
+	case ApPlusOffOp1:
+		return cellString("ap", i.OffOp1)
+	default:
+		return cellString(i.op0String(), i.OffOp1)
+	}
+}
+
+func (i Instruction) resString() string {
+	switch i.Res {
+	case AddOperands:
+		return fmt.Sprintf("%s + %s", i.op0String(), i.op1String())
+	case MulOperands:
+		return fmt.Sprintf("%s * %s", i.op0String(), i.op1String())
+	default:
+		return i.op1String()
+	}
+}
+
+func registerString(reg Register) string {
+	if reg == Ap {
+		return "ap"
+	}
+	return "fp"
+}
+
+// Formats the memory cell at base + offset, e.g. `[fp - 3]`
+func cellString(base string, offset int16) string {
+	switch {
+	case offset > 0:
+		return fmt.Sprintf("[%s + %d]", base, offset)
+	case offset < 0:
+		return fmt.Sprintf("[%s - %d]", base, -int32(offset))
+	default:
+		return fmt.Sprintf("[%s]", base)
+	}
+}
+
+func jumpKind(pcUpdate PcUpdate) string {
+	if pcUpdate == Jump {
+		return "abs"
+	}
+	return "rel"
 }
 
 const (
@@ -212,7 +278,9 @@ const (
 	opcodeCallBit     = 12
 	opcodeRetBit      = 13
 	opcodeAssertEqBit = 14
-	offsetBits        = 16
+	// the last flag bit is reserved and must be zero
+	reservedBit = 15
+	offsetBits  = 16
 )
 
 func DecodeInstruction(rawInstruction *f.Element) (*Instruction, error) {
@@ -242,6 +310,9 @@ func DecodeInstruction(rawInstruction *f.Element) (*Instruction, error) {
 // |         off1            |
 // |         off2            |
 // |         flags           |
+// Offsets are biased by 2**15, so every 16 bits pattern is a valid offset in
+// [-2**15, 2**15). Corrupted bits above them either reach the reserved flag
+// bit or make the word bigger than 64 bits, and both are rejected when decoding
 func decodeInstructionValues(encoding uint64) (
 	off0Enc int16, off1Enc int16, off2Enc int16, flags uint16,
 ) {
@@ -261,19 +332,31 @@ func decodeInstructionValues(encoding uint64) (
 // |-----|-----|---------|-------|--------|--------|----------|----|
 // |  0  |  1  | 2  3  4 |  5 6  | 7  8 9 | 10  11 | 12 13 14 | 15 |
 func decodeInstructionFlags(instruction *Instruction, flags uint16) error {
+	if flags>>reservedBit != 0 {
+		return &InvalidFlagsError{Flags: flags, Group: "reserved bit", Err: errors.New("must be zero")}
+	}
+
 	// Extract instruction flags
 	instruction.DstRegister = Register((flags >> dstRegBit) & 1)
 	instruction.Op0Register = Register((flags >> op0RegBit) & 1)
 
 	op1Addr, err := oneHot(flags&(1<<op1ImmBit|1<<op1FpBit|1<<op1ApBit), op1ImmBit, 3)
 	if err != nil {
-		return fmt.Errorf("op1 source: %w", err)
+		return &InvalidFlagsError{Flags: flags, Group: "op1 source", Err: err}
 	}
 	instruction.Op1Source = Op1Src(op1Addr)
+	// the immediate is always stored right after the instruction
+	if instruction.Op1Source == Imm && instruction.OffOp1 != 1 {
+		return &InvalidFlagsError{
+			Flags: flags,
+			Group: "op1 source",
+			Err:   fmt.Errorf("immediate operand must have offset 1, got %d", instruction.OffOp1),
+		}
+	}
 
 	pcUpdate, err := oneHot(flags&(1<<pcJumpAbsBit|1<<pcJumpRelBit|1<<pcJnzBit), pcJumpAbsBit, 3)
 	if err != nil {
-		return fmt.Errorf("pc update: %w", err)
+		return &InvalidFlagsError{Flags: flags, Group: "pc update", Err: err}
 	}
 	instruction.PcUpdate = PcUpdate(pcUpdate)
 
@@ -289,7 +372,7 @@ func decodeInstructionFlags(instruction *Instruction, flags uint16) error {
 
 	res, err := oneHot(flags&(1<<resAddBit|1<<resMulBit), resAddBit, 2)
 	if err != nil {
-		return fmt.Errorf("res logic: %w", err)
+		return &InvalidFlagsError{Flags: flags, Group: "res logic", Err: err}
 	}
 
 	if res == 2 {
@@ -300,13 +383,13 @@ func decodeInstructionFlags(instruction *Instruction, flags uint16) error {
 
 	apUpdate, err := oneHot(flags&(1<<apAddBit|1<<apAdd1Bit), apAddBit, 2)
 	if err != nil {
-		return fmt.Errorf("ap update: %w", err)
+		return &InvalidFlagsError{Flags: flags, Group: "ap update", Err: err}
 	}
 	instruction.ApUpdate = ApUpdate(apUpdate)
 
 	opcode, err := oneHot(flags&(1<<opcodeCallBit|1<<opcodeRetBit|1<<opcodeAssertEqBit), opcodeCallBit, 3)
 	if err != nil {
-		return fmt.Errorf("opcode: %w", err)
+		return &InvalidFlagsError{Flags: flags, Group: "opcode", Err: err}
 	}
 	instruction.Opcode = Opcode(opcode)
 
@@ -315,9 +398,11 @@ func decodeInstructionFlags(instruction *Instruction, flags uint16) error {
 		(instruction.Res != Unconstrained ||
 			instruction.Opcode != Nop ||
 			instruction.ApUpdate != SameAp) {
-		return fmt.Errorf(
-			"jnz opcode must have unconstrained res logic, no opcode, and no ap change",
-		)
+		return &InvalidFlagsError{
+			Flags: flags,
+			Group: "pc update",
+			Err:   errors.New("jnz opcode must have unconstrained res logic, no opcode, and no ap change"),
+		}
 	}
 
 	if instruction.Opcode == Call {
@@ -325,7 +410,9 @@ func decodeInstructionFlags(instruction *Instruction, flags uint16) error {
 		// behaviour in different opcodes.
 		// Call treats (0, 0) as ADD2 logic
 		if instruction.ApUpdate != SameAp {
-			return fmt.Errorf("CALL must have ap_update = ADD2")
+			return &InvalidFlagsError{
+				Flags: flags, Group: "ap update", Err: errors.New("CALL must have ap_update = ADD2"),
+			}
 		}
 		instruction.ApUpdate = Add2
 	}
@@ -4,6 +4,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/NethermindEth/cairo-vm-go/pkg/assembler"
 	"github.com/stretchr/testify/require"
 
 	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
@@ -20,6 +21,31 @@ func TestDecodeInstructionValues(t *testing.T) {
 	assert.Equal(t, uint16(0x4806), flags)
 }
 
+func TestDecodeInstructionOffsetBounds(t *testing.T) {
+	// [ap + 0] = [fp + 0] + [ap + 0] with every offset biased encoding replaced
+	const flags = 0x4032
+	withOffsets := func(offDest, offOp0, offOp1 uint64) *f.Element {
+		return new(f.Element).SetUint64(flags<<48 | offOp1<<32 | offOp0<<16 | offDest)
+	}
+
+	decoded, err := DecodeInstruction(withOffsets(0x0000, 0xffff, 0x8000))
+	require.NoError(t, err)
+	assert.Equal(t, int16(-1<<15), decoded.OffDest)
+	assert.Equal(t, int16(1<<15-1), decoded.OffOp0)
+	assert.Equal(t, int16(0), decoded.OffOp1)
+
+	// corrupting the bits above the offsets never yields a decodable instruction
+	word := withOffsets(0x8000, 0x8000, 0x8000)
+	corrupted := new(f.Element).Add(word, new(f.Element).SetUint64(1<<63))
+	_, err = DecodeInstruction(corrupted)
+	assert.ErrorContains(t, err, "reserved bit")
+
+	corrupted = new(f.Element).SetBigInt(new(big.Int).Lsh(big.NewInt(1), 64))
+	corrupted.Add(corrupted, word)
+	_, err = DecodeInstruction(corrupted)
+	assert.ErrorContains(t, err, "is bigger than 64 bits")
+}
+
 func TestAssertEq(t *testing.T) {
 	expected := Instruction{
 		OffDest:     0,
@@ -242,3 +268,111 @@ func TestCallInvalidApUpdate(t *testing.T) {
 	require.Error(t, err)
 	assert.ErrorContains(t, err, "CALL must have ap_update = ADD2")
 }
+
+func TestImmediateWithApOrFpSource(t *testing.T) {
+	// [ap] = imm, ap++ with the ap op1 source also set
+	instruction := new(f.Element).SetUint64(0x481680017fff8000)
+
+	_, err := DecodeInstruction(instruction)
+
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "op1 source")
+	assert.ErrorContains(t, err, "wrong sequence of bits")
+}
+
+func TestImmediateInvalidOffset(t *testing.T) {
+	// [ap] = imm, ap++ with the immediate at pc + 2
+	instruction := new(f.Element).SetUint64(0x480680027fff8000)
+
+	_, err := DecodeInstruction(instruction)
+
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "immediate operand must have offset 1, got 2")
+}
+
+func TestImmediateSize(t *testing.T) {
+	instruction, err := DecodeInstruction(new(f.Element).SetUint64(0x480680017fff8000))
+	require.NoError(t, err)
+	assert.Equal(t, Imm, instruction.Op1Source)
+	assert.Equal(t, uint8(2), instruction.Size())
+
+	// [ap] = [ap - 1]
+	instruction, err = DecodeInstruction(new(f.Element).SetUint64(0x48127fff7fff8000))
+	require.NoError(t, err)
+	assert.Equal(t, uint8(1), instruction.Size())
+}
+
+func TestInvalidFlags(t *testing.T) {
+	// every instruction uses offsets dst = 0, op0 = 0 and op1 = 1
+	word := func(flags uint16) *f.Element {
+		return new(f.Element).SetUint64(uint64(flags)<<48 | 0x8001<<32 | 0x8000<<16 | 0x8000)
+	}
+
+	testCases := []struct {
+		name  string
+		flags uint16
+		group string
+		err   string
+	}{
+		{"op1 imm and fp", 1<<op1ImmBit | 1<<op1FpBit, "op1 source", "wrong sequence of bits"},
+		{"op1 imm and ap", 1<<op1ImmBit | 1<<op1ApBit, "op1 source", "wrong sequence of bits"},
+		{"op1 fp and ap", 1<<op1FpBit | 1<<op1ApBit, "op1 source", "wrong sequence of bits"},
+		{"op1 imm, fp and ap", 1<<op1ImmBit | 1<<op1FpBit | 1<<op1ApBit, "op1 source", "wrong sequence of bits"},
+		{"res add and mul", 1<<resAddBit | 1<<resMulBit, "res logic", "wrong sequence of bits"},
+		{"pc jump abs and rel", 1<<pcJumpAbsBit | 1<<pcJumpRelBit, "pc update", "wrong sequence of bits"},
+		{"pc jump abs and jnz", 1<<pcJumpAbsBit | 1<<pcJnzBit, "pc update", "wrong sequence of bits"},
+		{"pc jump rel and jnz", 1<<pcJumpRelBit | 1<<pcJnzBit, "pc update", "wrong sequence of bits"},
+		{"ap add and add1", 1<<apAddBit | 1<<apAdd1Bit, "ap update", "wrong sequence of bits"},
+		{"opcode call and ret", 1<<opcodeCallBit | 1<<opcodeRetBit, "opcode", "wrong sequence of bits"},
+		{"opcode call and assert", 1<<opcodeCallBit | 1<<opcodeAssertEqBit, "opcode", "wrong sequence of bits"},
+		{"opcode ret and assert", 1<<opcodeRetBit | 1<<opcodeAssertEqBit, "opcode", "wrong sequence of bits"},
+		{"jnz with res add", 1<<pcJnzBit | 1<<resAddBit, "pc update", "jnz opcode must have"},
+		{"jnz with opcode", 1<<pcJnzBit | 1<<opcodeAssertEqBit, "pc update", "jnz opcode must have"},
+		{"jnz with ap update", 1<<pcJnzBit | 1<<apAdd1Bit, "pc update", "jnz opcode must have"},
+		{"call with ap update", 1<<opcodeCallBit | 1<<apAdd1Bit, "ap update", "CALL must have ap_update = ADD2"},
+		{"reserved bit", 1 << reservedBit, "reserved bit", "must be zero"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := DecodeInstruction(word(tc.flags))
+
+			var flagsErr *InvalidFlagsError
+			require.ErrorAs(t, err, &flagsErr)
+			assert.Equal(t, tc.flags, flagsErr.Flags)
+			assert.Equal(t, tc.group, flagsErr.Group)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestInstructionString(t *testing.T) {
+	testCases := []struct {
+		code     string
+		expected string
+	}{
+		{"[ap] = 5, ap++;", "[ap] = imm, ap++"},
+		{"[ap + 1] = [fp - 3];", "[ap + 1] = [fp - 3]"},
+		{"[fp] = [ap - 1] + [fp - 4];", "[fp] = [ap - 1] + [fp - 4]"},
+		{"[ap] = [fp - 1] * 3, ap++;", "[ap] = [fp - 1] * imm, ap++"},
+		{"[ap] = [[fp - 3] + 2];", "[ap] = [[fp - 3] + 2]"},
+		{"call rel 4;", "call rel imm"},
+		{"call abs [fp - 2];", "call abs [fp - 2]"},
+		{"ret;", "ret"},
+		{"jmp rel 3;", "jmp rel imm"},
+		{"jmp abs [ap - 1], ap++;", "jmp abs [ap - 1], ap++"},
+		{"jmp rel [fp] if [ap - 2] != 0;", "jmp rel [fp] if [ap - 2] != 0"},
+		{"ap += 4;", "ap += imm"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.code, func(t *testing.T) {
+			bytecode, err := assembler.CasmToBytecode(tc.code)
+			require.NoError(t, err)
+
+			instruction, err := DecodeInstruction(bytecode[0])
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, instruction.String())
+		})
+	}
+}
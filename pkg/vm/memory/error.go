@@ -0,0 +1,18 @@
+package memory
+
+import "fmt"
+
+// Error produced when reading or writing a specific memory cell
+type MemoryError struct {
+	Segment uint64
+	Offset  uint64
+	Err     error
+}
+
+func (e *MemoryError) Error() string {
+	return fmt.Sprintf("memory %d:%d: %s", e.Segment, e.Offset, e.Err)
+}
+
+func (e *MemoryError) Unwrap() error {
+	return e.Err
+}
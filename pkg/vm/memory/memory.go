@@ -2,6 +2,8 @@ package memory
 
 import (
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/NethermindEth/cairo-vm-go/pkg/safemath"
 	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
@@ -9,7 +11,42 @@ import (
 
 type BuiltinRunner interface {
 	CheckWrite(segment *Segment, offset uint64, value *MemoryValue) error
+	// Deduces the value of an unknown cell and writes it with Segment.WriteInferred,
+	// or Segment.WriteDefault when it falls back to a default value
 	InferValue(segment *Segment, offset uint64) error
+	// Returns how many builtin instances are used by the segment
+	InstancesUsed(segment *Segment) uint64
+	// Returns the canonical name of the builtin, e.g. "range_check"
+	String() string
+}
+
+// Implemented by builtin runners whose cells are part of the public memory,
+// e.g. the output of the program
+type BuiltinPublicMemory interface {
+	// Returns the offsets of the segment cells that must be public
+	PublicOffsets(segment *Segment) []uint64
+}
+
+// Implemented by builtin runners whose segment must be padded when finalized,
+// e.g. to a whole number of instances
+type BuiltinFinalizer interface {
+	// Returns the size of the segment once finalized, it is never shrunk
+	FinalizedSize(segment *Segment) uint64
+}
+
+// Implemented by builtin runners deducing some of their cells from the others,
+// like the auto deduction rules of the python vm, e.g. the keccak outputs
+type BuiltinDeducer interface {
+	// Returns whether InferValue computes the cell at offset, instead of
+	// failing or falling back to a default value
+	Deduces(offset uint64) bool
+}
+
+// Implemented by builtin runners holding state that changes while running,
+// so every clone of a memory gets its own copy, see Memory.Clone. Runners
+// without it are stateless and shared between a memory and its clones
+type BuiltinCloner interface {
+	Clone() BuiltinRunner
 }
 
 type NoBuiltin struct{}
@@ -19,8 +56,16 @@ func (b *NoBuiltin) CheckWrite(segment *Segment, offset uint64, value *MemoryVal
 }
 
 func (b *NoBuiltin) InferValue(segment *Segment, offset uint64) error {
-	segment.Data[offset] = EmptyMemoryValueAsFelt()
-	return nil
+	zero := EmptyMemoryValueAsFelt()
+	return segment.WriteDefault(offset, &zero)
+}
+
+func (b *NoBuiltin) InstancesUsed(segment *Segment) uint64 {
+	return 0
+}
+
+func (b *NoBuiltin) String() string {
+	return "none"
 }
 
 type Segment struct {
@@ -28,6 +73,27 @@ type Segment struct {
 	// the max index where a value was written
 	LastIndex     int
 	BuiltinRunner BuiltinRunner
+	// optional name identifying the segment, e.g. "program" or "range_check"
+	Name string
+	// once set, every write to the segment fails
+	ReadOnly bool
+	// offsets of the cells that became known while a snapshot was taken
+	journal    []uint64
+	journaling bool
+	// value of accessClock when each cell was first written or read, only
+	// recorded after calling RecordAccesses
+	accessSteps map[uint64]uint64
+	accessClock *uint64
+	// once set, the segment size can't change anymore
+	finalized bool
+	// offsets of the cells that are part of the public memory, see SetPublicMemory
+	publicOffsets []uint64
+	// offsets of the cells holding a default value because they were read
+	// before being written, see WriteDefault
+	defaulted map[uint64]struct{}
+	// undecoded words backing the first cells, see AllocateLazySegment. Each
+	// is decoded into a felt the first time its cell is accessed
+	lazyWords []string
 }
 
 func (segment *Segment) WithBuiltinRunner(builtinRunner BuiltinRunner) *Segment {
@@ -35,10 +101,122 @@ func (segment *Segment) WithBuiltinRunner(builtinRunner BuiltinRunner) *Segment
 	return segment
 }
 
+func (segment *Segment) WithName(name string) *Segment {
+	segment.Name = name
+	return segment
+}
+
+func (segment *Segment) WithReadOnly() *Segment {
+	segment.ReadOnly = true
+	return segment
+}
+
+// From then on, the first time each cell is written or read the current value
+// of clock is recorded, see FirstAccess. Usually the clock is the vm step
+func (segment *Segment) RecordAccesses(clock *uint64) {
+	segment.accessClock = clock
+	segment.accessSteps = make(map[uint64]uint64)
+}
+
+// Returns the clock value recorded when the cell was first accessed. False if
+// it never was or accesses aren't being recorded
+func (segment *Segment) FirstAccess(offset uint64) (uint64, bool) {
+	step, ok := segment.accessSteps[offset]
+	return step, ok
+}
+
+func (segment *Segment) recordAccess(offset uint64) {
+	if segment.accessClock == nil {
+		return
+	}
+	if _, ok := segment.accessSteps[offset]; !ok {
+		segment.accessSteps[offset] = *segment.accessClock
+	}
+}
+
+// Locks the segment size so no cell past it can be accessed anymore. If the
+// builtin runner implements BuiltinFinalizer, the segment is first padded with
+// unknown cells up to the size it asks for. Finalizing twice does nothing
+func (segment *Segment) Finalize() {
+	if segment.finalized {
+		return
+	}
+	if finalizer, ok := segment.BuiltinRunner.(BuiltinFinalizer); ok {
+		size := finalizer.FinalizedSize(segment)
+		if size > segment.RealLen() {
+			segment.IncreaseSegmentSize(size)
+		}
+		if size > segment.Len() {
+			segment.LastIndex = int(size) - 1
+		}
+	}
+	segment.finalized = true
+}
+
+// Returns a deep copy of the segment, see Memory.Clone
+func (segment *Segment) Clone() *Segment {
+	clone := *segment
+	clone.Data = append([]MemoryValue(nil), segment.Data...)
+	clone.journal = append([]uint64(nil), segment.journal...)
+	clone.publicOffsets = append([]uint64(nil), segment.publicOffsets...)
+	if segment.defaulted != nil {
+		clone.defaulted = make(map[uint64]struct{}, len(segment.defaulted))
+		for offset := range segment.defaulted {
+			clone.defaulted[offset] = struct{}{}
+		}
+	}
+	if segment.accessSteps != nil {
+		clone.accessSteps = make(map[uint64]uint64, len(segment.accessSteps))
+		for offset, step := range segment.accessSteps {
+			clone.accessSteps[offset] = step
+		}
+	}
+	if cloner, ok := segment.BuiltinRunner.(BuiltinCloner); ok {
+		clone.BuiltinRunner = cloner.Clone()
+	}
+	return &clone
+}
+
+// Shrinks the memory held by the segment to its length, releasing the spare
+// capacity left by growing it. Known cells and the length are preserved, the
+// segment grows again on a later write past its end
+func (segment *Segment) Compact() {
+	length := segment.Len()
+	if uint64(cap(segment.Data)) == length {
+		return
+	}
+	data := make([]MemoryValue, length)
+	copy(data, segment.Data)
+	segment.Data = data
+}
+
+// Marks cells of the segment as part of the public memory, on top of the ones
+// its builtin runner makes public. Replaces the previously marked ones
+func (segment *Segment) SetPublicMemory(offsets []uint64) {
+	segment.publicOffsets = offsets
+}
+
+// Returns whether Finalize was called
+func (segment *Segment) Finalized() bool {
+	return segment.finalized
+}
+
+// Errors if the offset is past the size of a finalized segment
+func (segment *Segment) checkFinalized(offset uint64) error {
+	if segment.finalized && offset >= segment.Len() {
+		return fmt.Errorf(
+			"offset %d is outside of the finalized segment of size %d", offset, segment.Len(),
+		)
+	}
+	return nil
+}
+
+// Capacity of segments allocated without a size estimate
+const DefaultSegmentCapacity = 100
+
 func EmptySegment() *Segment {
-	// empty segments have capacity 100 as a default
 	return &Segment{
-		Data:          make([]MemoryValue, 0, 100),
+		Data:          make([]MemoryValue, 0, DefaultSegmentCapacity),
 		LastIndex:     -1,
 		BuiltinRunner: &NoBuiltin{},
 	}
@@ -71,29 +249,130 @@ func (segment *Segment) RealLen() uint64 {
 	return uint64(len(segment.Data))
 }
 
+// How a write treats a cell that is already known
+type WriteMode uint8
+
+const (
+	// Any write to a known cell fails, even if it holds the same value
+	WriteOnce WriteMode = iota
+	// Writing the value a cell already holds is an assertion and succeeds.
+	// Only writing a different value fails
+	WriteAllowEqual
+)
+
 // Writes a new memory value to a specified offset, errors in case of overwriting an existing cell
+// with a different value
 func (segment *Segment) Write(offset uint64, value *MemoryValue) error {
+	return segment.WriteWithMode(offset, value, WriteAllowEqual)
+}
+
+// Writes a new memory value to a specified offset, mode decides whether a known cell can be
+// written again with the value it holds
+func (segment *Segment) WriteWithMode(offset uint64, value *MemoryValue, mode WriteMode) error {
+	if segment.ReadOnly {
+		return fmt.Errorf("cannot write to read only segment at offset %d", offset)
+	}
+	if err := segment.checkFinalized(offset); err != nil {
+		return err
+	}
 	if offset >= segment.RealLen() {
 		segment.IncreaseSegmentSize(offset + 1)
 	}
 	if offset >= segment.Len() {
 		segment.LastIndex = int(offset)
 	}
+	// writes must match the undecoded words
+	if err := segment.decodeLazyWord(offset); err != nil {
+		return err
+	}
 
 	cell := &segment.Data[offset]
-	if cell.Known() && !cell.Equal(value) {
+	if cell.Known() && (mode == WriteOnce || !cell.Equal(value)) {
 		return fmt.Errorf(
 			"rewriting cell: old value: %s, new value: %s",
-			cell.String(),
-			value.String(),
+			cell.StringHex(),
+			value.StringHex(),
 		)
 	}
+	if segment.journaling && !cell.Known() {
+		segment.journal = append(segment.journal, offset)
+	}
+	segment.recordAccess(offset)
+	// asserting a default value writes it for real
+	delete(segment.defaulted, offset)
 	segment.Data[offset] = *value
 	return segment.BuiltinRunner.CheckWrite(segment, offset, value)
 }
 
+// Writes a value deduced by the segment builtin runner, meant to be called from
+// BuiltinRunner.InferValue. Unlike Write it works on read only segments and
+// doesn't check the value with the builtin runner again. Errors only if the cell
+// already holds a different value, so a builtin can infer cells the program
+// already asserted
+func (segment *Segment) WriteInferred(offset uint64, value *MemoryValue) error {
+	if err := segment.checkFinalized(offset); err != nil {
+		return err
+	}
+	if offset >= segment.RealLen() {
+		segment.IncreaseSegmentSize(offset + 1)
+	}
+	if offset >= segment.Len() {
+		segment.LastIndex = int(offset)
+	}
+
+	cell := &segment.Data[offset]
+	if cell.Known() {
+		if !cell.Equal(value) {
+			return fmt.Errorf(
+				"inferring cell: old value: %s, inferred value: %s",
+				cell.StringHex(),
+				value.StringHex(),
+			)
+		}
+		return nil
+	}
+	if segment.journaling {
+		segment.journal = append(segment.journal, offset)
+	}
+	segment.Data[offset] = *value
+	return nil
+}
+
+// Like WriteInferred, but the value is a default the builtin runner falls back
+// to rather than one it deduced, e.g. zero for a cell never written. The cell
+// isn't accessed until the program writes it, see Accessed
+func (segment *Segment) WriteDefault(offset uint64, value *MemoryValue) error {
+	wasKnown := offset < segment.RealLen() && segment.Data[offset].Known()
+	if err := segment.WriteInferred(offset, value); err != nil {
+		return err
+	}
+	if wasKnown {
+		return nil
+	}
+	if segment.defaulted == nil {
+		segment.defaulted = make(map[uint64]struct{})
+	}
+	segment.defaulted[offset] = struct{}{}
+	return nil
+}
+
+// Returns whether the cell was written, either by the program or by a value
+// deduced by the builtin runner. Unknown cells and cells only holding a
+// default value aren't, and are left out of the relocated memory as the
+// python vm does
+func (segment *Segment) Accessed(offset uint64) bool {
+	if offset >= segment.RealLen() || !segment.Data[offset].Known() {
+		return false
+	}
+	_, ok := segment.defaulted[offset]
+	return !ok
+}
+
 // Reads a memory value from a specified offset at the segment
 func (segment *Segment) Read(offset uint64) (MemoryValue, error) {
+	if err := segment.checkFinalized(offset); err != nil {
+		return MemoryValue{}, err
+	}
 	if offset >= segment.RealLen() {
 		segment.IncreaseSegmentSize(offset + 1)
 	}
@@ -103,20 +382,65 @@ func (segment *Segment) Read(offset uint64) (MemoryValue, error) {
 
 	cell := &segment.Data[offset]
 	if !cell.Known() {
-		if err := segment.BuiltinRunner.InferValue(segment, offset); err != nil {
+		if err := segment.inferValue(offset); err != nil {
 			return MemoryValue{}, err
 		}
 	}
+	segment.recordAccess(offset)
 	return *cell, nil
 }
 
+// The cells of a lazy segment hold its words, other segments leave the
+// inference to their builtin runner
+func (segment *Segment) inferValue(offset uint64) error {
+	if segment.lazyWords == nil {
+		return segment.BuiltinRunner.InferValue(segment, offset)
+	}
+	if offset >= uint64(len(segment.lazyWords)) {
+		return fmt.Errorf("offset %d is outside of the %d program words", offset, len(segment.lazyWords))
+	}
+	return segment.decodeLazyWord(offset)
+}
+
+// Decodes the word backing a cell of a lazy segment, unless the cell is
+// already known or isn't backed by any word
+func (segment *Segment) decodeLazyWord(offset uint64) error {
+	if offset >= uint64(len(segment.lazyWords)) || segment.Data[offset].Known() {
+		return nil
+	}
+	felt, err := FeltFromWord(segment.lazyWords[offset])
+	if err != nil {
+		return fmt.Errorf(
+			"cannot decode word %s at offset %d: %w", segment.lazyWords[offset], offset, err,
+		)
+	}
+	word := MemoryValueFromFieldElement(felt)
+	return segment.WriteInferred(offset, &word)
+}
+
+// Decodes every word of a lazy segment whose cell hasn't been accessed yet
+func (segment *Segment) decodeLazyWords() error {
+	for offset := range segment.lazyWords {
+		if err := segment.decodeLazyWord(uint64(offset)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (segment *Segment) Peek(offset uint64) MemoryValue {
+	// a finalized segment doesn't grow, everything past it is unknown
+	if segment.finalized && offset >= segment.Len() {
+		return MemoryValue{}
+	}
 	if offset >= segment.RealLen() {
 		segment.IncreaseSegmentSize(offset + 1)
 	}
 	if offset >= segment.Len() {
 		segment.LastIndex = int(offset)
 	}
+	// an invalid word is left unknown, reading its cell reports why
+	_ = segment.decodeLazyWord(offset)
 	return segment.Data[offset]
 }
 
@@ -135,7 +459,12 @@ func (segment *Segment) IncreaseSegmentSize(newSize uint64) {
 	if cap(segmentData) > int(newSize) {
 		newSegmentData = segmentData[:cap(segmentData)]
 	} else {
-		newSegmentData = make([]MemoryValue, safemath.Max(newSize, uint64(len(segmentData)*2)))
+		// grow by doubling unless doubling the size would wrap around
+		doubledSize, isOverflow := safemath.SafeMul(uint64(len(segmentData)), 2)
+		if isOverflow {
+			doubledSize = newSize
+		}
+		newSegmentData = make([]MemoryValue, safemath.Max(newSize, doubledSize))
 		copy(newSegmentData, segmentData)
 	}
 	segment.Data = newSegmentData
@@ -178,6 +507,18 @@ func (segment *Segment) String() string {
 // Represents the whole VM memory divided into segments
 type Memory struct {
 	Segments []*Segment
+	// snapshots that can still be restored, from oldest to newest
+	snapshots      []uint64
+	nextSnapshotId uint64
+	// accesses made since RecordAccessTrace was called, nil before
+	accessTrace []MemoryAccess
+}
+
+// A read or a write of a memory cell with the value it holds afterwards, see
+// Memory.RecordAccessTrace
+type MemoryAccess struct {
+	Address MemoryAddress
+	Value   MemoryValue
 }
 
 // todo(rodro): can the amount of segments be known before hand?
@@ -202,33 +543,123 @@ func (memory *Memory) AllocateSegment(data []*f.Element) (int, error) {
 	return len(memory.Segments) - 1, nil
 }
 
+// Allocates a new segment backed by undecoded words and returns its index.
+// Words are decoded on first access instead of up front
+func (memory *Memory) AllocateLazySegment(words []string) int {
+	newSegment := EmptySegmentWithLength(len(words))
+	newSegment.lazyWords = words
+	memory.Segments = append(memory.Segments, newSegment)
+	return len(memory.Segments) - 1
+}
+
 // Allocates an empty segment and returns its index
 func (memory *Memory) AllocateEmptySegment() int {
 	memory.Segments = append(memory.Segments, EmptySegment())
 	return len(memory.Segments) - 1
 }
 
+// Allocates an empty segment whose reads and writes are handled by
+// a builtin runner and returns its index. The segment is named after the builtin
+func (memory *Memory) AllocateBuiltinSegment(name string, builtinRunner BuiltinRunner) int {
+	memory.Segments = append(
+		memory.Segments, EmptySegment().WithBuiltinRunner(builtinRunner).WithName(name),
+	)
+	return len(memory.Segments) - 1
+}
+
+// Returns the index of the first segment with the given name
+func (memory *Memory) FindSegmentByName(name string) (int, bool) {
+	for i := range memory.Segments {
+		if memory.Segments[i].Name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
 // Writes to a memory address a new memory value. Errors if writing to an unallocated
 // space or if rewriting a specific cell
 func (memory *Memory) Write(segmentIndex uint64, offset uint64, value *MemoryValue) error {
 	if segmentIndex >= uint64(len(memory.Segments)) {
-		return fmt.Errorf("unallocated segment at index %d", segmentIndex)
+		return &MemoryError{segmentIndex, offset, fmt.Errorf("unallocated segment at index %d", segmentIndex)}
 	}
-	return memory.Segments[segmentIndex].Write(offset, value)
+	if err := memory.Segments[segmentIndex].Write(offset, value); err != nil {
+		return &MemoryError{segmentIndex, offset, err}
+	}
+	memory.recordAccess(segmentIndex, offset, value)
+	return nil
+}
+
+// Same as Write, with the rewrite behaviour of mode
+func (memory *Memory) WriteWithMode(segmentIndex uint64, offset uint64, value *MemoryValue, mode WriteMode) error {
+	if segmentIndex >= uint64(len(memory.Segments)) {
+		return &MemoryError{segmentIndex, offset, fmt.Errorf("unallocated segment at index %d", segmentIndex)}
+	}
+	if err := memory.Segments[segmentIndex].WriteWithMode(offset, value, mode); err != nil {
+		return &MemoryError{segmentIndex, offset, err}
+	}
+	memory.recordAccess(segmentIndex, offset, value)
+	return nil
 }
 
 func (memory *Memory) WriteToAddress(address *MemoryAddress, value *MemoryValue) error {
 	return memory.Write(address.SegmentIndex, address.Offset, value)
 }
 
+// Writes values into consecutive cells starting at an address, growing the
+// segment once for all of them. Errors at the first cell that already holds
+// a different value, leaving the cells before it written
+func (memory *Memory) WriteRange(address *MemoryAddress, values []MemoryValue) error {
+	if address.SegmentIndex >= uint64(len(memory.Segments)) {
+		return &MemoryError{
+			address.SegmentIndex, address.Offset,
+			fmt.Errorf("unallocated segment at index %d", address.SegmentIndex),
+		}
+	}
+	segment := memory.Segments[address.SegmentIndex]
+	end, overflow := safemath.SafeAdd(address.Offset, uint64(len(values)))
+	if overflow {
+		return &MemoryError{
+			address.SegmentIndex, address.Offset,
+			fmt.Errorf("writing %d cells overflows the offset", len(values)),
+		}
+	}
+	if end > segment.RealLen() {
+		segment.IncreaseSegmentSize(end)
+	}
+	for i := range values {
+		offset := address.Offset + uint64(i)
+		if err := segment.Write(offset, &values[i]); err != nil {
+			return &MemoryError{address.SegmentIndex, offset, err}
+		}
+		memory.recordAccess(address.SegmentIndex, offset, &values[i])
+	}
+	return nil
+}
+
 // Reads a memory value given the segment index and offset. Errors if reading from
 // an unallocated space. If reading a cell which hasn't been accesed before, it is
 // initalized with its default zero value
 func (memory *Memory) Read(segmentIndex uint64, offset uint64) (MemoryValue, error) {
 	if segmentIndex >= uint64(len(memory.Segments)) {
-		return MemoryValue{}, fmt.Errorf("unallocated segment at index %d", segmentIndex)
+		return MemoryValue{}, &MemoryError{segmentIndex, offset, fmt.Errorf("unallocated segment at index %d", segmentIndex)}
 	}
-	return memory.Segments[segmentIndex].Read(offset)
+	value, err := memory.Segments[segmentIndex].Read(offset)
+	if err != nil {
+		return MemoryValue{}, &MemoryError{segmentIndex, offset, err}
+	}
+	memory.recordAccess(segmentIndex, offset, &value)
+	return value, nil
+}
+
+// Like Read, but a segment index one past the last segment allocates that
+// segment first, as the python vm does for hints that read a segment they
+// are about to create. Any other unallocated segment is still an error
+func (memory *Memory) ReadOrCreate(segmentIndex uint64, offset uint64) (MemoryValue, error) {
+	if segmentIndex == uint64(len(memory.Segments)) {
+		memory.AllocateEmptySegment()
+	}
+	return memory.Read(segmentIndex, offset)
 }
 
 // Reads a memory value from a memory address. Errors if reading from an unallocated
@@ -238,10 +669,24 @@ func (memory *Memory) ReadFromAddress(address *MemoryAddress) (MemoryValue, erro
 	return memory.Read(address.SegmentIndex, address.Offset)
 }
 
+// Reads size consecutive memory values starting at an address. Errors if any
+// of them cannot be read
+func (memory *Memory) GetRange(address *MemoryAddress, size uint64) ([]MemoryValue, error) {
+	values := make([]MemoryValue, size)
+	for i := range values {
+		value, err := memory.Read(address.SegmentIndex, address.Offset+uint64(i))
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
 // Given a segment index and offset returns a pointer to the Memory Cell
 func (memory *Memory) Peek(segmentIndex uint64, offset uint64) (MemoryValue, error) {
 	if segmentIndex >= uint64(len(memory.Segments)) {
-		return MemoryValue{}, fmt.Errorf("unallocated segment at index %d", segmentIndex)
+		return MemoryValue{}, &MemoryError{segmentIndex, offset, fmt.Errorf("unallocated segment at index %d", segmentIndex)}
 	}
 	return memory.Segments[segmentIndex].Peek(offset), nil
 }
@@ -250,3 +695,173 @@ func (memory *Memory) Peek(segmentIndex uint64, offset uint64) (MemoryValue, err
 func (memory *Memory) PeekFromAddress(address *MemoryAddress) (MemoryValue, error) {
 	return memory.Peek(address.SegmentIndex, address.Offset)
 }
+
+// From then on every Read and Write, in any of their forms, is appended to the
+// access trace in the order they are made, see AccessTrace. Peeking a cell
+// isn't an access. It slows down every access and keeps all of them in memory
+func (memory *Memory) RecordAccessTrace() {
+	if memory.accessTrace == nil {
+		memory.accessTrace = make([]MemoryAccess, 0)
+	}
+}
+
+// Returns the accesses recorded since RecordAccessTrace was called, nil if it
+// wasn't
+func (memory *Memory) AccessTrace() []MemoryAccess {
+	return memory.accessTrace
+}
+
+// Appends an access made without Read or Write to the access trace, e.g. of a
+// cell the vm peeked. Does nothing unless accesses are being recorded
+func (memory *Memory) RecordAccess(address *MemoryAddress, value *MemoryValue) {
+	memory.recordAccess(address.SegmentIndex, address.Offset, value)
+}
+
+func (memory *Memory) recordAccess(segmentIndex uint64, offset uint64, value *MemoryValue) {
+	if memory.accessTrace == nil {
+		return
+	}
+	memory.accessTrace = append(memory.accessTrace, MemoryAccess{
+		Address: MemoryAddress{SegmentIndex: segmentIndex, Offset: offset},
+		Value:   *value,
+	})
+}
+
+// The state of the memory at some point, which can be restored later
+type MemorySnapshot struct {
+	id uint64
+	// length and journal size of every segment allocated when taken
+	lastIndexes []int
+	journalLens []int
+	// length of the access trace when taken
+	accessTraceLen int
+}
+
+// Takes a snapshot of the memory. From then on every segment keeps track of
+// the cells that become known, so restoring only undoes those instead of
+// copying the whole memory. Builtin runners state is not part of it
+func (memory *Memory) Snapshot() MemorySnapshot {
+	snapshot := MemorySnapshot{
+		id:             memory.nextSnapshotId,
+		lastIndexes:    make([]int, len(memory.Segments)),
+		journalLens:    make([]int, len(memory.Segments)),
+		accessTraceLen: len(memory.accessTrace),
+	}
+	for i, segment := range memory.Segments {
+		segment.journaling = true
+		snapshot.lastIndexes[i] = segment.LastIndex
+		snapshot.journalLens[i] = len(segment.journal)
+	}
+	memory.snapshots = append(memory.snapshots, snapshot.id)
+	memory.nextSnapshotId++
+	return snapshot
+}
+
+// Brings the memory back to the state it had when the snapshot was taken,
+// dropping the segments allocated since then. Snapshots taken afterwards
+// can no longer be restored, restoring one of them errors
+func (memory *Memory) Restore(snapshot *MemorySnapshot) error {
+	live := -1
+	for i, id := range memory.snapshots {
+		if id == snapshot.id {
+			live = i
+		}
+	}
+	if live < 0 {
+		return fmt.Errorf("snapshot %d was discarded by restoring an older one", snapshot.id)
+	}
+	memory.snapshots = memory.snapshots[:live+1]
+
+	for i := len(snapshot.lastIndexes); i < len(memory.Segments); i++ {
+		memory.Segments[i] = nil
+	}
+	memory.Segments = memory.Segments[:len(snapshot.lastIndexes)]
+	for i, segment := range memory.Segments {
+		for _, offset := range segment.journal[snapshot.journalLens[i]:] {
+			segment.Data[offset] = MemoryValue{}
+			delete(segment.defaulted, offset)
+		}
+		segment.journal = segment.journal[:snapshot.journalLens[i]]
+		segment.LastIndex = snapshot.lastIndexes[i]
+	}
+	if memory.accessTrace != nil && snapshot.accessTraceLen <= len(memory.accessTrace) {
+		memory.accessTrace = memory.accessTrace[:snapshot.accessTraceLen]
+	}
+	return nil
+}
+
+// Returns a deep copy of the memory, so speculative runs can branch from a
+// common state without affecting each other. Snapshots taken before cloning
+// can be restored in both. Segments recording accesses keep the clock they
+// were given, see Segment.RecordAccesses to give the clone its own
+func (memory *Memory) Clone() *Memory {
+	clone := &Memory{
+		Segments:       make([]*Segment, len(memory.Segments), cap(memory.Segments)),
+		snapshots:      append([]uint64(nil), memory.snapshots...),
+		nextSnapshotId: memory.nextSnapshotId,
+	}
+	if memory.accessTrace != nil {
+		clone.accessTrace = append(make([]MemoryAccess, 0, len(memory.accessTrace)), memory.accessTrace...)
+	}
+	for i, segment := range memory.Segments {
+		clone.Segments[i] = segment.Clone()
+	}
+	return clone
+}
+
+// Writes every segment with its index, its name if any and all of its known cells to w.
+// Unnamed segments backed by a builtin are labeled with the builtin name.
+// Each cell is tagged as either a felt or an address, felts being printed in
+// radix, see FeltString. If maxCells is greater than zero, at most maxCells
+// known cells are printed per segment
+func (memory *Memory) Dump(w io.Writer, maxCells int, radix int) error {
+	for i, segment := range memory.Segments {
+		name := ""
+		if segment.Name != "" {
+			name = " " + segment.Name
+		} else if builtin := segment.BuiltinRunner.String(); builtin != "none" {
+			name = " " + builtin
+		}
+		_, err := fmt.Fprintf(w, "segment %d%s (len %d):\n", i, name, segment.Len())
+		if err != nil {
+			return err
+		}
+
+		printed := 0
+		skipped := 0
+		for offset := uint64(0); offset < segment.Len(); offset++ {
+			cell := &segment.Data[offset]
+			if !cell.Known() {
+				continue
+			}
+			if maxCells > 0 && printed >= maxCells {
+				skipped++
+				continue
+			}
+
+			kind := "felt"
+			if cell.IsAddress() {
+				kind = "addr"
+			}
+			if _, err := fmt.Fprintf(w, "  [%d] %s %s\n", offset, kind, cell.Text(radix)); err != nil {
+				return err
+			}
+			printed++
+		}
+
+		if skipped > 0 {
+			if _, err := fmt.Fprintf(w, "  ... %d more known cells\n", skipped); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the representation of the whole memory with decimal felts, see Dump
+func (memory *Memory) String() string {
+	var builder strings.Builder
+	// writing to a strings.Builder never fails
+	_ = memory.Dump(&builder, 0, 10)
+	return builder.String()
+}
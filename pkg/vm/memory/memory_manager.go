@@ -1,6 +1,9 @@
 package memory
 
 import (
+	"bytes"
+	"fmt"
+
 	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
 )
 
@@ -17,22 +20,46 @@ func CreateMemoryManager() *MemoryManager {
 	}
 }
 
+// Allocates an empty segment with room for the given amount of cells and
+// returns its index. Useful when the segment size can be estimated beforehand,
+// since growing it means copying all of its cells
+func (mm *MemoryManager) AllocateEmptySegmentWithCapacity(capacity int) int {
+	mm.Memory.Segments = append(mm.Memory.Segments, EmptySegmentWithCapacity(capacity))
+	return len(mm.Memory.Segments) - 1
+}
+
+// Returns the relocation base of each segment, that is, the value added to
+// an offset of that segment to obtain its relocated address
+//
+// segmentsOffsets[0] = 1
+// segmentsOffsets[1] = 1 + len(segment[0])
+// segmentsOffsets[N] = 1 + len(segment[n-1]) + sum of segements[n-1-i] for i in [1, n-1]
+func (mm *MemoryManager) SegmentOffsets() []uint64 {
+	segmentsOffsets := make([]uint64, len(mm.Memory.Segments))
+	// the prover expects relocated memory to start at one
+	var offset uint64 = 1
+	for i, segment := range mm.Memory.Segments {
+		segmentsOffsets[i] = offset
+		offset += segment.Len()
+	}
+	return segmentsOffsets
+}
+
 // It returns all segments in memory but relocated as a single segment
 // Each element is a pointer to a field element, if the cell was not accessed,
-// nil is stored instead
-func (mm *MemoryManager) RelocateMemory() []*f.Element {
+// nil is stored instead. Cells only holding a default value are holes too, see
+// Segment.Accessed
+func (mm *MemoryManager) RelocateMemory() ([]*f.Element, error) {
+	// lazy segments must be fully decoded to be relocated
+	if err := mm.decodeLazySegments(); err != nil {
+		return nil, err
+	}
+
+	segmentsOffsets := mm.SegmentOffsets()
 	// this begins at one, because the prover expects for max memory used to
 	var maxMemoryUsed uint64 = 1
-
-	// segmentsOffsets[0] = 1
-	// segmentsOffsets[1] = 1 + len(segment[0])
-	// segmentsOffsets[N] = 1 + len(segment[n-1]) + sum of segements[n-1-i] for i in [1, n-1]
-	segmentsOffsets := make([]uint64, uint64(len(mm.Memory.Segments))+1)
-	segmentsOffsets[0] = 1
-	for i, segment := range mm.Memory.Segments {
-		segmentLength := segment.Len()
-		maxMemoryUsed += segmentLength
-		segmentsOffsets[i+1] = segmentsOffsets[i] + segmentLength
+	for _, segment := range mm.Memory.Segments {
+		maxMemoryUsed += segment.Len()
 	}
 
 	// the prover expect first element of the relocated memory to start at index 1,
@@ -40,12 +67,11 @@ func (mm *MemoryManager) RelocateMemory() []*f.Element {
 	// returned has nil as its first element.
 	relocatedMemory := make([]*f.Element, maxMemoryUsed)
 	for i, segment := range mm.Memory.Segments {
-		// fmt.Printf("s: %s", segment)
 		for j := uint64(0); j < segment.Len(); j++ {
-			cell := segment.Data[j]
-			if !cell.Known() {
+			if !segment.Accessed(j) {
 				continue
 			}
+			cell := segment.Data[j]
 
 			var felt *f.Element
 			if cell.IsAddress() {
@@ -57,5 +83,122 @@ func (mm *MemoryManager) RelocateMemory() []*f.Element {
 			relocatedMemory[segmentsOffsets[i]+j] = felt
 		}
 	}
-	return relocatedMemory
+	return relocatedMemory, nil
+}
+
+// Encodes the memory before relocation as a json object mapping the address
+// of every accessed cell to its value, e.g. {"1:3": "0x2a", "1:4": "2:0"}, as
+// cairo debuggers load it. Felts are in hexadecimal and addresses are written
+// like the keys. Cells are ordered by segment and then by offset. Lazy segments
+// are decoded first, errors if any of their words is invalid
+func EncodeMemoryRelocatableJSON(memory *Memory) ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	first := true
+	for i, segment := range memory.Segments {
+		if err := segment.decodeLazyWords(); err != nil {
+			return nil, err
+		}
+		for offset := uint64(0); offset < segment.Len(); offset++ {
+			if !segment.Accessed(offset) {
+				continue
+			}
+			if !first {
+				buffer.WriteByte(',')
+			}
+			first = false
+			address := MemoryAddress{SegmentIndex: uint64(i), Offset: offset}
+			fmt.Fprintf(&buffer, "%q:%q", address.String(), segment.Data[offset].StringHex())
+		}
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// A cell of the public memory the prover receives along the proof
+type PublicMemoryCell struct {
+	// relocated address of the cell
+	Address uint64
+	// relocated value of the cell
+	Value f.Element
+	// every cell is in the main page 0, additional pages are not supported
+	Page uint64
+}
+
+// Returns the relocated cells that must be public: the ones marked with
+// Segment.SetPublicMemory and the ones made public by builtin runners. They are
+// sorted by segment and then in the order they were marked. Errors if any of
+// them is unknown
+func (mm *MemoryManager) PublicMemory() ([]PublicMemoryCell, error) {
+	if err := mm.decodeLazySegments(); err != nil {
+		return nil, err
+	}
+
+	segmentsOffsets := mm.SegmentOffsets()
+	var cells []PublicMemoryCell
+	for i, segment := range mm.Memory.Segments {
+		offsets := segment.publicOffsets
+		if builtin, ok := segment.BuiltinRunner.(BuiltinPublicMemory); ok {
+			offsets = append(offsets[:len(offsets):len(offsets)], builtin.PublicOffsets(segment)...)
+		}
+
+		for _, offset := range offsets {
+			cell := segment.Peek(offset)
+			if !cell.Known() {
+				return nil, fmt.Errorf("public cell %d:%d is unknown", i, offset)
+			}
+
+			publicCell := PublicMemoryCell{Address: segmentsOffsets[i] + offset}
+			if cell.IsAddress() {
+				publicCell.Value = *cell.addrUnsafe().Relocate(segmentsOffsets)
+			} else {
+				publicCell.Value = cell.felt
+			}
+			cells = append(cells, publicCell)
+		}
+	}
+	return cells, nil
+}
+
+func (mm *MemoryManager) decodeLazySegments() error {
+	for _, segment := range mm.Memory.Segments {
+		if err := segment.decodeLazyWords(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rebuilds the segmented memory from a relocated one given the relocation base
+// of each segment, as returned by SegmentOffsets. It inverts RelocateMemory,
+// except that relocated addresses cannot be told apart from felts so every
+// known cell is rebuilt as a felt. Trailing unknown cells are not encoded in a
+// relocated memory, so cells past its end are rebuilt as unknown
+func BuildMemoryFromRelocated(relocated []*f.Element, segmentOffsets []uint64) (*Memory, error) {
+	memory := InitializeEmptyMemory()
+	for i, start := range segmentOffsets {
+		// the last segment ends with the relocated memory
+		end := uint64(len(relocated))
+		if i+1 < len(segmentOffsets) {
+			end = segmentOffsets[i+1]
+		} else if start > end {
+			end = start
+		}
+		if start == 0 || start > end {
+			return nil, fmt.Errorf("segment %d spans the invalid range [%d, %d)", i, start, end)
+		}
+
+		segment := EmptySegmentWithLength(int(end - start))
+		for offset := range segment.Data {
+			address := start + uint64(offset)
+			if address >= uint64(len(relocated)) {
+				break
+			}
+			if felt := relocated[address]; felt != nil {
+				segment.Data[offset] = MemoryValueFromFieldElement(felt)
+			}
+		}
+		memory.Segments = append(memory.Segments, segment)
+	}
+	return memory, nil
 }
@@ -1,6 +1,8 @@
 package memory
 
 import (
+	"encoding/json"
+	"fmt"
 	"testing"
 
 	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
@@ -27,7 +29,8 @@ func TestMemoryRelocationWithFelt(t *testing.T) {
 		},
 	)
 
-	res := manager.RelocateMemory()
+	res, err := manager.RelocateMemory()
+	require.NoError(t, err)
 
 	expected := []*f.Element{
 		nil,
@@ -88,7 +91,8 @@ func TestMemoryRelocationWithAddress(t *testing.T) {
 		},
 	)
 
-	res := manager.RelocateMemory()
+	res, err := manager.RelocateMemory()
+	require.NoError(t, err)
 
 	expected := []*f.Element{
 		nil,
@@ -148,3 +152,218 @@ func updateMemoryWithValues(memory *Memory, valuesToWrite []memoryWrite) {
 
 	}
 }
+
+func TestMemoryRelocationWithLazySegment(t *testing.T) {
+	manager := CreateMemoryManager()
+	manager.Memory.AllocateLazySegment([]string{"0x2", "0x3"})
+
+	res, err := manager.RelocateMemory()
+	require.NoError(t, err)
+	require.Equal(t, []*f.Element{nil, new(f.Element).SetUint64(2), new(f.Element).SetUint64(3)}, res)
+
+	manager = CreateMemoryManager()
+	manager.Memory.AllocateLazySegment([]string{"0x2", "bad"})
+	_, err = manager.RelocateMemory()
+	require.Error(t, err)
+}
+
+func TestMemoryRelocationWithDefaultCells(t *testing.T) {
+	// segment 0: [2, 0 (read), 3]
+	// relocated: [-, 2, -, 3]
+	manager := CreateMemoryManager()
+	updateMemoryWithValues(manager.Memory, []memoryWrite{{0, 0, uint64(2)}, {0, 2, uint64(3)}})
+	_, err := manager.Memory.Read(0, 1)
+	require.NoError(t, err)
+
+	res, err := manager.RelocateMemory()
+	require.NoError(t, err)
+	require.Equal(t, []*f.Element{nil, new(f.Element).SetUint64(2), nil, new(f.Element).SetUint64(3)}, res)
+}
+
+func TestEncodeMemoryRelocatableJSON(t *testing.T) {
+	memory := InitializeEmptyMemory()
+	memory.AllocateLazySegment([]string{"0x2", "0xdeadbeef"})
+	memory.AllocateEmptySegment()
+	require.NoError(t, memory.Write(1, 3, UseInTestOnlyMemoryValuePointerFromInt(42)))
+	address := MemoryValueFromSegmentAndOffset(0, 1)
+	require.NoError(t, memory.Write(1, 12, &address))
+	// a cell only read isn't accessed
+	_, err := memory.Read(1, 5)
+	require.NoError(t, err)
+
+	encoded, err := EncodeMemoryRelocatableJSON(memory)
+	require.NoError(t, err)
+	require.Equal(t, `{"0:0":"0x2","0:1":"0xdeadbeef","1:3":"0x2a","1:12":"0:1"}`, string(encoded))
+	var decoded map[string]string
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	require.Len(t, decoded, 4)
+
+	encoded, err = EncodeMemoryRelocatableJSON(InitializeEmptyMemory())
+	require.NoError(t, err)
+	require.Equal(t, "{}", string(encoded))
+
+	memory = InitializeEmptyMemory()
+	memory.AllocateLazySegment([]string{"bad"})
+	_, err = EncodeMemoryRelocatableJSON(memory)
+	require.Error(t, err)
+}
+
+type publicBuiltin struct {
+	NoBuiltin
+}
+
+func (b *publicBuiltin) PublicOffsets(segment *Segment) []uint64 {
+	return []uint64{0}
+}
+
+func TestPublicMemory(t *testing.T) {
+	manager := CreateMemoryManager()
+	manager.Memory.AllocateLazySegment([]string{"0x2", "0x3"})
+	manager.Memory.AllocateEmptySegment()
+	for i, value := range []MemoryValue{
+		MemoryValueFromInt(5), MemoryValueFromSegmentAndOffset(0, 1), MemoryValueFromInt(7),
+	} {
+		require.NoError(t, manager.Memory.Write(1, uint64(i), &value))
+	}
+	manager.Memory.Segments = append(manager.Memory.Segments, EmptySegment().WithBuiltinRunner(&publicBuiltin{}))
+	nine := MemoryValueFromInt(9)
+	require.NoError(t, manager.Memory.Write(2, 0, &nine))
+	require.NoError(t, manager.Memory.Write(2, 1, &nine))
+
+	manager.Memory.Segments[0].SetPublicMemory([]uint64{0, 1})
+	manager.Memory.Segments[1].SetPublicMemory([]uint64{1})
+	cells, err := manager.PublicMemory()
+	require.NoError(t, err)
+	require.Equal(t, []PublicMemoryCell{
+		{Address: 1, Value: f.NewElement(2)},
+		{Address: 2, Value: f.NewElement(3)},
+		// the address 0:1 relocated
+		{Address: 4, Value: f.NewElement(2)},
+		{Address: 6, Value: f.NewElement(9)},
+	}, cells)
+
+	manager.Memory.Segments[1].SetPublicMemory([]uint64{1, 3})
+	_, err = manager.PublicMemory()
+	require.EqualError(t, err, "public cell 1:3 is unknown")
+}
+
+func TestSegmentOffsets(t *testing.T) {
+	manager := CreateMemoryManager()
+	require.Empty(t, manager.SegmentOffsets())
+
+	updateMemoryWithValues(
+		manager.Memory,
+		[]memoryWrite{
+			{0, 3, uint64(2)},
+			{1, 0, uint64(5)},
+			{3, 1, uint64(7)},
+		},
+	)
+
+	// segment 2 is empty so segment 3 shares its relocation base
+	require.Equal(t, []uint64{1, 5, 6, 6}, manager.SegmentOffsets())
+}
+
+func TestAllocateEmptySegmentWithCapacity(t *testing.T) {
+	manager := CreateMemoryManager()
+	manager.Memory.AllocateEmptySegment()
+
+	index := manager.AllocateEmptySegmentWithCapacity(1000)
+	require.Equal(t, 1, index)
+
+	segment := manager.Memory.Segments[index]
+	require.Equal(t, 1000, cap(segment.Data))
+	require.Equal(t, uint64(0), segment.Len())
+	require.IsType(t, &NoBuiltin{}, segment.BuiltinRunner)
+}
+
+// Writes a segment sequentially, as the vm does with the execution segment,
+// reporting how many times the segment had to grow
+func BenchmarkSegmentGrowth(b *testing.B) {
+	const cells = 100_000
+
+	for _, capacity := range []int{DefaultSegmentCapacity, cells} {
+		b.Run(fmt.Sprintf("capacity %d", capacity), func(b *testing.B) {
+			b.ReportAllocs()
+			reallocations := 0
+			value := MemoryValueFromInt(1)
+			for i := 0; i < b.N; i++ {
+				manager := CreateMemoryManager()
+				segment := manager.Memory.Segments[manager.AllocateEmptySegmentWithCapacity(capacity)]
+				for offset := uint64(0); offset < cells; offset++ {
+					previousCap := cap(segment.Data)
+					if err := segment.Write(offset, &value); err != nil {
+						b.Fatal(err)
+					}
+					if cap(segment.Data) != previousCap {
+						reallocations++
+					}
+				}
+			}
+			b.ReportMetric(float64(reallocations)/float64(b.N), "reallocs/op")
+		})
+	}
+}
+
+func TestBuildMemoryFromRelocated(t *testing.T) {
+	manager := CreateMemoryManager()
+	updateMemoryWithValues(
+		manager.Memory,
+		[]memoryWrite{
+			{0, 0, uint64(2)},
+			{0, 3, uint64(3)},
+			{1, 1, &MemoryAddress{SegmentIndex: 3, Offset: 2}},
+			{3, 0, uint64(5)},
+			{3, 2, uint64(7)},
+		},
+	)
+
+	relocated, err := manager.RelocateMemory()
+	require.NoError(t, err)
+	rebuilt, err := BuildMemoryFromRelocated(relocated, manager.SegmentOffsets())
+	require.NoError(t, err)
+
+	// addresses come back as their relocated value
+	addr := MemoryAddress{SegmentIndex: 3, Offset: 2}
+	relocatedAddr := MemoryValueFromFieldElement(addr.Relocate(manager.SegmentOffsets()))
+	manager.Memory.Segments[1].Data[1] = relocatedAddr
+	requireSameMemory(t, manager.Memory, rebuilt)
+
+	// the missing trailing cells are unknown
+	require.Equal(t, []uint64{1, 5, 7, 7}, manager.SegmentOffsets())
+	rebuilt, err = BuildMemoryFromRelocated(relocated[:6], manager.SegmentOffsets())
+	require.NoError(t, err)
+	require.Equal(t, []uint64{4, 2, 0, 0}, segmentLengths(rebuilt))
+	require.Equal(t, MemoryValueFromInt(3), rebuilt.Segments[0].Data[3])
+	require.False(t, rebuilt.Segments[1].Data[1].Known())
+
+	_, err = BuildMemoryFromRelocated(relocated, []uint64{1, 8, 5})
+	require.EqualError(t, err, "segment 1 spans the invalid range [8, 5)")
+	_, err = BuildMemoryFromRelocated(relocated, []uint64{0, 5})
+	require.EqualError(t, err, "segment 0 spans the invalid range [0, 5)")
+}
+
+func segmentLengths(memory *Memory) []uint64 {
+	lengths := make([]uint64, len(memory.Segments))
+	for i, segment := range memory.Segments {
+		lengths[i] = segment.Len()
+	}
+	return lengths
+}
+
+// reports the first segment and offset where both memories differ
+func requireSameMemory(t *testing.T, expected, actual *Memory) {
+	t.Helper()
+	require.Equal(t, len(expected.Segments), len(actual.Segments), "amount of segments")
+	for i := range expected.Segments {
+		require.Equal(t, expected.Segments[i].Len(), actual.Segments[i].Len(), "segment %d length", i)
+		for offset := uint64(0); offset < expected.Segments[i].Len(); offset++ {
+			expectedCell := expected.Segments[i].Data[offset]
+			actualCell := actual.Segments[i].Data[offset]
+			require.True(
+				t, expectedCell.Known() == actualCell.Known() && (!expectedCell.Known() || expectedCell.Equal(&actualCell)),
+				"segment %d offset %d: expected %s, got %s", i, offset, &expectedCell, &actualCell,
+			)
+		}
+	}
+}
@@ -2,8 +2,11 @@ package memory
 
 import (
 	"fmt"
+	"math"
+	"strings"
 	"testing"
 
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -172,6 +175,28 @@ func TestMemoryReadOutOfRange(t *testing.T) {
 	memory.AllocateEmptySegment()
 	_, err := memory.Read(2, 2)
 	assert.Error(t, err)
+
+	var memoryErr *MemoryError
+	require.ErrorAs(t, err, &memoryErr)
+	assert.Equal(t, uint64(2), memoryErr.Segment)
+	assert.Equal(t, uint64(2), memoryErr.Offset)
+}
+
+func TestMemoryReadOrCreate(t *testing.T) {
+	mem := InitializeEmptyMemory()
+	mem.AllocateEmptySegment()
+
+	_, err := mem.ReadOrCreate(1, 3)
+	require.NoError(t, err)
+	assert.Len(t, mem.Segments, 2)
+	assert.Equal(t, uint64(4), mem.Segments[1].Len())
+
+	// strict reads are unchanged
+	_, err = mem.Read(2, 0)
+	assert.ErrorContains(t, err, "unallocated segment at index 2")
+	_, err = mem.ReadOrCreate(3, 0)
+	assert.ErrorContains(t, err, "unallocated segment at index 3")
+	assert.Len(t, mem.Segments, 2)
 }
 
 func TestMemoryPeek(t *testing.T) {
@@ -202,8 +227,16 @@ func (b *testBuiltin) InferValue(segment *Segment, offset uint64) error {
 	if offset%2 == 1 {
 		return fmt.Errorf("deduce error")
 	}
-	segment.Data[offset] = MemoryValueFromInt(offset)
-	return nil
+	value := MemoryValueFromUint(offset)
+	return segment.WriteInferred(offset, &value)
+}
+
+func (b *testBuiltin) InstancesUsed(segment *Segment) uint64 {
+	return segment.Len()
+}
+
+func (b *testBuiltin) String() string {
+	return "test"
 }
 
 func TestSegmentBuiltin(t *testing.T) {
@@ -235,3 +268,386 @@ func TestSegmentBuiltin(t *testing.T) {
 		assert.Equal(t, empty, v)
 	})
 }
+
+func TestSegmentWriteModes(t *testing.T) {
+	segment := EmptySegment()
+	one := MemoryValueFromInt(1)
+	two := MemoryValueFromInt(2)
+
+	require.NoError(t, segment.WriteWithMode(0, &one, WriteOnce))
+	require.EqualError(t, segment.WriteWithMode(0, &one, WriteOnce), "rewriting cell: old value: 0x1, new value: 0x1")
+
+	require.NoError(t, segment.WriteWithMode(0, &one, WriteAllowEqual))
+	require.EqualError(t, segment.WriteWithMode(0, &two, WriteAllowEqual), "rewriting cell: old value: 0x1, new value: 0x2")
+
+	mem := InitializeEmptyMemory()
+	mem.AllocateEmptySegment()
+	require.NoError(t, mem.WriteWithMode(0, 3, &one, WriteOnce))
+	require.EqualError(t, mem.WriteWithMode(0, 3, &one, WriteOnce), "memory 0:3: rewriting cell: old value: 0x1, new value: 0x1")
+}
+
+func TestSegmentInferredThenAsserted(t *testing.T) {
+	segment := EmptySegment().WithBuiltinRunner(&testBuiltin{})
+	inferred, err := segment.Read(2)
+	require.NoError(t, err)
+	assert.Equal(t, MemoryValueFromInt(2), inferred)
+
+	// asserting the inferred value is a write of the same value
+	require.NoError(t, segment.Write(2, &inferred))
+	other := MemoryValueFromInt(3)
+	require.EqualError(t, segment.Write(2, &other), "rewriting cell: old value: 0x2, new value: 0x3")
+	require.Error(t, segment.WriteWithMode(2, &inferred, WriteOnce))
+
+	// inferring a cell the program already asserted only checks the value
+	asserted := MemoryValueFromInt(4)
+	require.NoError(t, segment.Write(4, &asserted))
+	require.NoError(t, segment.WriteInferred(4, &asserted))
+	require.EqualError(t, segment.WriteInferred(4, &other), "inferring cell: old value: 0x4, inferred value: 0x3")
+
+	// inferred values are not checked by the builtin and can fill read only segments
+	segment.WithReadOnly()
+	require.NoError(t, segment.WriteInferred(5, &other))
+	assert.Equal(t, other, segment.Peek(5))
+}
+
+func TestSegmentInferredRestore(t *testing.T) {
+	mem := InitializeEmptyMemory()
+	mem.Segments = append(mem.Segments, EmptySegment().WithBuiltinRunner(&testBuiltin{}))
+	snapshot := mem.Snapshot()
+
+	_, err := mem.Read(0, 2)
+	require.NoError(t, err)
+	require.NoError(t, mem.Restore(&snapshot))
+	value := mem.Segments[0].Peek(2)
+	assert.False(t, value.Known())
+}
+
+func TestSegmentWriteDefault(t *testing.T) {
+	mem := InitializeEmptyMemory()
+	mem.AllocateEmptySegment()
+	segment := mem.Segments[0]
+	require.NoError(t, segment.Write(0, UseInTestOnlyMemoryValuePointerFromInt(1)))
+	// the cells read before being written hold a zero but aren't accessed
+	for _, offset := range []uint64{1, 2} {
+		value, err := segment.Read(offset)
+		require.NoError(t, err)
+		assert.Equal(t, EmptyMemoryValueAsFelt(), value)
+	}
+	assert.True(t, segment.Accessed(0))
+	assert.False(t, segment.Accessed(1))
+	assert.False(t, segment.Accessed(3))
+
+	// writing the default value makes it accessed
+	zero := EmptyMemoryValueAsFelt()
+	require.NoError(t, segment.Write(2, &zero))
+	assert.True(t, segment.Accessed(2))
+	// defaulting a known cell keeps it accessed
+	require.NoError(t, segment.WriteDefault(0, UseInTestOnlyMemoryValuePointerFromInt(1)))
+	assert.True(t, segment.Accessed(0))
+
+	// a restored default cell is unknown again, and a clone keeps its own
+	snapshot := mem.Snapshot()
+	_, err := segment.Read(4)
+	require.NoError(t, err)
+	clone := mem.Clone()
+	require.NoError(t, mem.Restore(&snapshot))
+	require.NoError(t, segment.Write(4, UseInTestOnlyMemoryValuePointerFromInt(5)))
+	assert.True(t, segment.Accessed(4))
+	assert.False(t, clone.Segments[0].Accessed(4))
+}
+
+func TestSegmentRecordAccesses(t *testing.T) {
+	segment := EmptySegment()
+	require.NoError(t, segment.Write(0, UseInTestOnlyMemoryValuePointerFromInt(1)))
+	_, ok := segment.FirstAccess(0)
+	assert.False(t, ok)
+
+	var step uint64 = 3
+	segment.RecordAccesses(&step)
+	require.NoError(t, segment.Write(2, UseInTestOnlyMemoryValuePointerFromInt(5)))
+	step = 7
+	_, err := segment.Read(1)
+	require.NoError(t, err)
+	_, err = segment.Read(2)
+	require.NoError(t, err)
+
+	for offset, expected := range map[uint64]uint64{1: 7, 2: 3} {
+		accessStep, ok := segment.FirstAccess(offset)
+		require.True(t, ok)
+		assert.Equal(t, expected, accessStep, "offset %d", offset)
+	}
+	_, ok = segment.FirstAccess(0)
+	assert.False(t, ok)
+}
+
+// pads its segment to a fixed size when finalized
+type paddedBuiltin struct {
+	NoBuiltin
+	size uint64
+}
+
+func (b *paddedBuiltin) FinalizedSize(segment *Segment) uint64 {
+	return b.size
+}
+
+func TestSegmentFinalize(t *testing.T) {
+	segment := EmptySegment()
+	require.NoError(t, segment.Write(2, UseInTestOnlyMemoryValuePointerFromInt(1)))
+	segment.Finalize()
+	assert.True(t, segment.Finalized())
+
+	// cells within the segment can still be accessed
+	require.NoError(t, segment.Write(0, UseInTestOnlyMemoryValuePointerFromInt(2)))
+	_, err := segment.Read(1)
+	require.NoError(t, err)
+
+	// but it doesn't grow anymore
+	err = segment.Write(3, UseInTestOnlyMemoryValuePointerFromInt(3))
+	require.EqualError(t, err, "offset 3 is outside of the finalized segment of size 3")
+	_, err = segment.Read(5)
+	require.EqualError(t, err, "offset 5 is outside of the finalized segment of size 3")
+	past := segment.Peek(7)
+	assert.False(t, past.Known())
+	assert.Equal(t, uint64(3), segment.Len())
+}
+
+func TestSegmentFinalizePadding(t *testing.T) {
+	segment := EmptySegment().WithBuiltinRunner(&paddedBuiltin{size: 6})
+	require.NoError(t, segment.Write(1, UseInTestOnlyMemoryValuePointerFromInt(1)))
+	segment.Finalize()
+	assert.Equal(t, uint64(6), segment.Len())
+	require.NoError(t, segment.Write(5, UseInTestOnlyMemoryValuePointerFromInt(1)))
+
+	// segments are never shrunk
+	segment = EmptySegment().WithBuiltinRunner(&paddedBuiltin{size: 1})
+	require.NoError(t, segment.Write(3, UseInTestOnlyMemoryValuePointerFromInt(1)))
+	segment.Finalize()
+	assert.Equal(t, uint64(4), segment.Len())
+}
+
+func TestSegmentCompact(t *testing.T) {
+	segment := EmptySegmentWithCapacity(64)
+	one, two := MemoryValueFromInt(1), MemoryValueFromInt(2)
+	require.NoError(t, segment.Write(0, &one))
+	require.NoError(t, segment.Write(2, &two))
+
+	segment.Compact()
+	assert.Equal(t, uint64(3), segment.Len())
+	assert.Equal(t, 3, cap(segment.Data))
+	assertNoErrorAndEqual(t, segment, 0, one)
+	assert.False(t, segment.Data[1].Known())
+	assertNoErrorAndEqual(t, segment, 2, two)
+
+	// it grows again when written past its end
+	require.NoError(t, segment.Write(5, &one))
+	assert.Equal(t, uint64(6), segment.Len())
+
+	empty := EmptySegmentWithCapacity(8)
+	empty.Compact()
+	assert.Zero(t, empty.Len())
+	assert.Zero(t, cap(empty.Data))
+}
+
+func TestMemoryDump(t *testing.T) {
+	memory := InitializeEmptyMemory()
+	memory.AllocateEmptySegment()
+	memory.AllocateEmptySegment()
+	require.NoError(t, memory.Write(0, 0, UseInTestOnlyMemoryValuePointerFromInt(7)))
+	require.NoError(t, memory.Write(0, 2, UseInTestOnlyMemoryValuePointerFromInt(9)))
+	address := MemoryValueFromSegmentAndOffset(1, 4)
+	require.NoError(t, memory.Write(1, 1, &address))
+	// an unnamed segment is labeled by its builtin
+	memory.Segments = append(memory.Segments, EmptySegment().WithBuiltinRunner(&testBuiltin{}))
+	require.NoError(t, memory.Write(2, 0, UseInTestOnlyMemoryValuePointerFromInt(3)))
+
+	assert.Equal(
+		t,
+		"segment 0 (len 3):\n"+
+			"  [0] felt 7\n"+
+			"  [2] felt 9\n"+
+			"segment 1 (len 2):\n"+
+			"  [1] addr 1:4\n"+
+			"segment 2 test (len 1):\n"+
+			"  [0] felt 3\n",
+		memory.String(),
+	)
+
+	var builder strings.Builder
+	require.NoError(t, memory.Dump(&builder, 1, 16))
+	assert.Equal(
+		t,
+		"segment 0 (len 3):\n"+
+			"  [0] felt 0x7\n"+
+			"  ... 1 more known cells\n"+
+			"segment 1 (len 2):\n"+
+			"  [1] addr 1:4\n"+
+			"segment 2 test (len 1):\n"+
+			"  [0] felt 0x3\n",
+		builder.String(),
+	)
+}
+
+func TestFindSegmentByName(t *testing.T) {
+	memory := InitializeEmptyMemory()
+	memory.AllocateEmptySegment()
+	builtinIndex := memory.AllocateBuiltinSegment("range_check", &testBuiltin{})
+
+	index, ok := memory.FindSegmentByName("range_check")
+	require.True(t, ok)
+	assert.Equal(t, builtinIndex, index)
+	assert.Equal(t, &testBuiltin{}, memory.Segments[index].BuiltinRunner)
+
+	_, ok = memory.FindSegmentByName("keccak")
+	assert.False(t, ok)
+
+	value := MemoryValueFromInt(2)
+	require.NoError(t, memory.Write(uint64(builtinIndex), 0, &value))
+	assert.Equal(t, "segment 0 (len 0):\nsegment 1 range_check (len 1):\n  [0] felt 2\n", memory.String())
+}
+
+func TestMemoryLazySegment(t *testing.T) {
+	memory := InitializeEmptyMemory()
+	index := memory.AllocateLazySegment([]string{"0x1", "0x2", "0x3", "bad"})
+	segment := memory.Segments[index]
+	assert.Equal(t, uint64(4), segment.Len())
+	// the words aren't a builtin
+	assert.Equal(t, "none", segment.BuiltinRunner.String())
+
+	// words are decoded on first access only
+	assert.False(t, segment.Data[0].Known())
+	val, err := memory.Read(uint64(index), 0)
+	require.NoError(t, err)
+	assert.Equal(t, MemoryValueFromInt(1), val)
+
+	// writes must match the undecoded words
+	require.NoError(t, memory.Write(uint64(index), 1, UseInTestOnlyMemoryValuePointerFromInt(2)))
+	require.ErrorContains(t, memory.Write(uint64(index), 2, UseInTestOnlyMemoryValuePointerFromInt(4)), "rewriting cell")
+
+	_, err = memory.Read(uint64(index), 3)
+	require.ErrorContains(t, err, "cannot decode word bad at offset 3")
+	_, err = memory.Read(uint64(index), 4)
+	require.ErrorContains(t, err, "outside of the 4 program words")
+}
+
+func TestReadOnlySegment(t *testing.T) {
+	mem := InitializeEmptyMemory()
+	_, err := mem.AllocateSegment([]*f.Element{new(f.Element).SetUint64(3)})
+	require.NoError(t, err)
+	mem.Segments[0].WithReadOnly()
+
+	value, err := mem.Read(0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, MemoryValueFromInt(3), value)
+
+	// even writing the value the cell already holds fails
+	err = mem.Write(0, 0, &value)
+	assert.ErrorContains(t, err, "memory 0:0: cannot write to read only segment at offset 0")
+	newValue := MemoryValueFromInt(5)
+	err = mem.Write(0, 1, &newValue)
+	assert.ErrorContains(t, err, "cannot write to read only segment at offset 1")
+}
+
+func TestGetRange(t *testing.T) {
+	mem := InitializeEmptyMemory()
+	_, err := mem.AllocateSegment([]*f.Element{
+		new(f.Element).SetUint64(1), new(f.Element).SetUint64(2), new(f.Element).SetUint64(3),
+	})
+	require.NoError(t, err)
+
+	values, err := mem.GetRange(&MemoryAddress{SegmentIndex: 0, Offset: 1}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []MemoryValue{MemoryValueFromInt(2), MemoryValueFromInt(3)}, values)
+
+	_, err = mem.GetRange(&MemoryAddress{SegmentIndex: 1, Offset: 0}, 1)
+	assert.ErrorContains(t, err, "unallocated segment at index 1")
+}
+
+func TestWriteRange(t *testing.T) {
+	mem := InitializeEmptyMemory()
+	mem.AllocateEmptySegment()
+	values := []MemoryValue{MemoryValueFromInt(1), MemoryValueFromInt(2), MemoryValueFromInt(3)}
+
+	require.NoError(t, mem.WriteRange(&MemoryAddress{SegmentIndex: 0, Offset: 2}, values))
+	assert.Equal(t, uint64(5), mem.Segments[0].Len())
+	written, err := mem.GetRange(&MemoryAddress{SegmentIndex: 0, Offset: 2}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, values, written)
+
+	// cells holding the same value are asserted
+	require.NoError(t, mem.WriteRange(&MemoryAddress{SegmentIndex: 0, Offset: 3}, values[1:]))
+
+	err = mem.WriteRange(&MemoryAddress{SegmentIndex: 0, Offset: 3}, values)
+	var memoryErr *MemoryError
+	require.ErrorAs(t, err, &memoryErr)
+	assert.Equal(t, uint64(3), memoryErr.Offset)
+	assert.ErrorContains(t, err, "rewriting cell")
+
+	err = mem.WriteRange(&MemoryAddress{SegmentIndex: 1, Offset: 0}, values)
+	assert.ErrorContains(t, err, "unallocated segment at index 1")
+	err = mem.WriteRange(&MemoryAddress{SegmentIndex: 0, Offset: math.MaxUint64}, values)
+	assert.ErrorContains(t, err, "writing 3 cells overflows the offset")
+}
+
+func TestMemorySnapshotRestore(t *testing.T) {
+	mem := InitializeEmptyMemory()
+	mem.AllocateEmptySegment()
+	one, two := MemoryValueFromInt(1), MemoryValueFromInt(2)
+	require.NoError(t, mem.Write(0, 0, &one))
+
+	first := mem.Snapshot()
+	require.NoError(t, mem.Write(0, 0, &one))
+	require.NoError(t, mem.Write(0, 3, &two))
+
+	second := mem.Snapshot()
+	mem.AllocateEmptySegment()
+	require.NoError(t, mem.Write(1, 0, &two))
+	require.NoError(t, mem.Write(0, 5, &two))
+
+	require.NoError(t, mem.Restore(&second))
+	assert.Len(t, mem.Segments, 1)
+	assert.Equal(t, uint64(4), mem.Segments[0].Len())
+	assert.False(t, mem.Segments[0].Data[5].Known())
+	assertNoErrorAndEqual(t, mem.Segments[0], 3, two)
+
+	require.NoError(t, mem.Restore(&first))
+	assert.Equal(t, uint64(1), mem.Segments[0].Len())
+	assert.False(t, mem.Segments[0].Data[3].Known())
+	assertNoErrorAndEqual(t, mem.Segments[0], 0, one)
+
+	// the cells written after the first snapshot can be written again
+	require.NoError(t, mem.Write(0, 3, &one))
+
+	// the second snapshot was taken after state that no longer exists
+	err := mem.Restore(&second)
+	assert.ErrorContains(t, err, "snapshot 1 was discarded by restoring an older one")
+}
+
+func TestMemoryClone(t *testing.T) {
+	mem := InitializeEmptyMemory()
+	mem.AllocateEmptySegment()
+	mem.AllocateBuiltinSegment("test", &testBuiltin{})
+	one, two := MemoryValueFromInt(1), MemoryValueFromInt(2)
+	require.NoError(t, mem.Write(0, 0, &one))
+	snapshot := mem.Snapshot()
+
+	clone := mem.Clone()
+	require.NoError(t, clone.Write(0, 2, &two))
+	clone.AllocateEmptySegment()
+	assert.Len(t, mem.Segments, 2)
+	assert.Equal(t, uint64(1), mem.Segments[0].Len())
+	assert.False(t, mem.Segments[0].Data[2].Known())
+
+	// writes to the original don't show in the clone either
+	require.NoError(t, mem.Write(0, 1, &two))
+	assert.False(t, clone.Segments[0].Data[1].Known())
+	assertNoErrorAndEqual(t, clone.Segments[0], 2, two)
+
+	// stateless builtin runners are shared
+	assert.Same(t, mem.Segments[1].BuiltinRunner, clone.Segments[1].BuiltinRunner)
+	assert.Equal(t, "test", clone.Segments[1].Name)
+
+	require.NoError(t, clone.Restore(&snapshot))
+	assert.Len(t, clone.Segments, 2)
+	assert.Equal(t, uint64(1), clone.Segments[0].Len())
+	assert.Equal(t, uint64(2), mem.Segments[0].Len())
+}
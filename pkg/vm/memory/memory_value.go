@@ -3,6 +3,7 @@ package memory
 import (
 	"errors"
 	"fmt"
+	"math/big"
 	"unsafe"
 
 	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
@@ -23,6 +24,14 @@ func (address *MemoryAddress) Equal(other *MemoryAddress) bool {
 	return address.SegmentIndex == other.SegmentIndex && address.Offset == other.Offset
 }
 
+// Orders addresses by segment and then by offset
+func (address *MemoryAddress) Less(other *MemoryAddress) bool {
+	if address.SegmentIndex != other.SegmentIndex {
+		return address.SegmentIndex < other.SegmentIndex
+	}
+	return address.Offset < other.Offset
+}
+
 // Adds a memory address and a field element
 func (address *MemoryAddress) Add(lhs *MemoryAddress, rhs *f.Element) error {
 	lhsOffset := new(f.Element).SetUint64(lhs.Offset)
@@ -49,30 +58,47 @@ func (address *MemoryAddress) Sub(lhs *MemoryAddress, rhs any) error {
 		address.Offset = lhs.Offset - rhs
 		return nil
 	case *f.Element:
-		if !rhs.IsUint64() {
-			return fmt.Errorf("rhs field element does not fit in uint64: %s", rhs)
-		}
-		feltRhs64 := rhs.Uint64()
-		if feltRhs64 > lhs.Offset {
-			return fmt.Errorf("rhs %d is greater than lhs offset %d", feltRhs64, lhs.Offset)
-		}
-		address.Offset = lhs.Offset - feltRhs64
-		return nil
+		return address.SubFelt(lhs, rhs)
 	case *MemoryAddress:
-		if lhs.SegmentIndex != rhs.SegmentIndex {
-			return fmt.Errorf("addresses are in different segments: rhs is in %d, lhs is in %d",
-				rhs.SegmentIndex, lhs.SegmentIndex)
-		}
-		if rhs.Offset > lhs.Offset {
-			return fmt.Errorf("rhs offset %d is greater than lhs offset %d", rhs.Offset, lhs.Offset)
+		distance, err := lhs.Distance(rhs)
+		if err != nil {
+			return err
 		}
-		address.Offset = lhs.Offset - rhs.Offset
+		address.Offset = distance
 		return nil
 	default:
 		return fmt.Errorf("unknown rhs type: %T", rhs)
 	}
 }
 
+// Subtracts a field element from a memory address. Errors if the resulting
+// offset would be negative
+func (address *MemoryAddress) SubFelt(lhs *MemoryAddress, rhs *f.Element) error {
+	lhsOffset := new(f.Element).SetUint64(lhs.Offset)
+	newOffset := new(f.Element).Sub(lhsOffset, rhs)
+	if !newOffset.IsUint64() || newOffset.Uint64() > lhs.Offset {
+		return fmt.Errorf("offset %d underflows when subtracting %s", lhs.Offset, rhs.Text(10))
+	}
+	address.SegmentIndex = lhs.SegmentIndex
+	address.Offset = newOffset.Uint64()
+	return nil
+}
+
+// Returns how many cells the address is ahead of another address of the same
+// segment. Errors if they belong to different segments or the other address is ahead
+func (address *MemoryAddress) Distance(other *MemoryAddress) (uint64, error) {
+	if address.SegmentIndex != other.SegmentIndex {
+		return 0, fmt.Errorf(
+			"addresses are in different segments: %s is in %d, %s is in %d",
+			address, address.SegmentIndex, other, other.SegmentIndex,
+		)
+	}
+	if other.Offset > address.Offset {
+		return 0, fmt.Errorf("address %s is ahead of %s", other, address)
+	}
+	return address.Offset - other.Offset, nil
+}
+
 func (address *MemoryAddress) Relocate(segmentsOffset []uint64) *f.Element {
 	// no risk overflow because this sizes exists in actual Memory
 	// so if by chance the uint64 addition overflowed, then we have
@@ -114,18 +140,50 @@ func MemoryValueFromFieldElement(felt *f.Element) MemoryValue {
 	}
 }
 
-func MemoryValueFromInt[T constraints.Integer](v T) MemoryValue {
+// Parses a decimal or 0x prefixed hexadecimal word into a felt. Unlike
+// f.Element.SetString, words that are negative or not below the field
+// modulus are rejected instead of being silently reduced
+func FeltFromWord(word string) (*f.Element, error) {
+	value, ok := new(big.Int).SetString(word, 0)
+	if !ok {
+		return nil, fmt.Errorf("word %s is not a number", word)
+	}
+	if value.Sign() < 0 || value.Cmp(f.Modulus()) >= 0 {
+		return nil, fmt.Errorf("word %s is outside of the field [0, P)", word)
+	}
+	return new(f.Element).SetBigInt(value), nil
+}
+
+// Negative values are reduced modulo the field, so -1 becomes P - 1
+func MemoryValueFromInt[T constraints.Signed](v T) MemoryValue {
 	if v >= 0 {
 		return MemoryValueFromUint(uint64(v))
 	}
 
 	value := MemoryValue{isFelt: true}
-	rhs := f.NewElement(uint64(-v))
+	// widened first so the negation of the minimum of smaller types fits,
+	// the minimum int64 negates to itself which is still 2**63 as a uint64
+	rhs := f.NewElement(uint64(-int64(v)))
 	value.felt.Sub(&value.felt, &rhs)
 	return value
 }
 
+// Most cells hold small integers, keeping them precomputed avoids converting
+// them into montgomery form every time
+const smallFeltsCount = 256
+
+var smallFelts = func() [smallFeltsCount]MemoryValue {
+	var values [smallFeltsCount]MemoryValue
+	for i := range values {
+		values[i] = MemoryValue{felt: f.NewElement(uint64(i)), isFelt: true}
+	}
+	return values
+}()
+
 func MemoryValueFromUint[T constraints.Unsigned](v T) MemoryValue {
+	if uint64(v) < smallFeltsCount {
+		return smallFelts[v]
+	}
 	return MemoryValue{
 		felt:   f.NewElement(uint64(v)),
 		isFelt: true,
@@ -207,6 +265,22 @@ func (mv *MemoryValue) Known() bool {
 	return mv.isAddress || mv.isFelt
 }
 
+// Returns whether the value is the zero felt. Errors if it is not a felt
+func (mv *MemoryValue) IsZero() (bool, error) {
+	if !mv.isFelt {
+		return false, fmt.Errorf("cannot compare a non felt memory value with zero: %s", mv)
+	}
+	return mv.felt.IsZero(), nil
+}
+
+// Returns whether the value is the one felt. Errors if it is not a felt
+func (mv *MemoryValue) IsOne() (bool, error) {
+	if !mv.isFelt {
+		return false, fmt.Errorf("cannot compare a non felt memory value with one: %s", mv)
+	}
+	return mv.felt.IsOne(), nil
+}
+
 func (mv *MemoryValue) Equal(other *MemoryValue) bool {
 	if mv.IsAddress() && other.IsAddress() {
 		return mv.addrUnsafe().Equal(other.addrUnsafe())
@@ -217,6 +291,16 @@ func (mv *MemoryValue) Equal(other *MemoryValue) bool {
 	return false
 }
 
+// Compares two felts as the integers in [0, P) they represent, returning -1,
+// 0 or 1 if the value is lower, equal or greater than the other one. Errors if
+// either is an address or unknown
+func (mv *MemoryValue) Cmp(other *MemoryValue) (int, error) {
+	if !mv.IsFelt() || !other.IsFelt() {
+		return 0, fmt.Errorf("cannot compare %s and %s: only felts are ordered", mv, other)
+	}
+	return mv.felt.Cmp(&other.felt), nil
+}
+
 // Adds two memory values is the second one is a Felt
 func (mv *MemoryValue) Add(lhs, rhs *MemoryValue) error {
 	if lhs.IsAddress() {
@@ -234,7 +318,25 @@ func (mv *MemoryValue) Add(lhs, rhs *MemoryValue) error {
 }
 
 // Subs two memory values if they're in the same segment or the rhs is a Felt.
+// Subtracting two addresses of the same segment yields their felt distance
 func (mv *MemoryValue) Sub(lhs, rhs *MemoryValue) error {
+	if lhs.IsAddress() && rhs.IsAddress() {
+		lhsAddr, rhsAddr := lhs.addrUnsafe(), rhs.addrUnsafe()
+		if lhsAddr.SegmentIndex != rhsAddr.SegmentIndex {
+			return fmt.Errorf(
+				"addresses are in different segments: lhs is in %d, rhs is in %d",
+				lhsAddr.SegmentIndex, rhsAddr.SegmentIndex,
+			)
+		}
+		// the distance can be negative, it is computed in the field
+		lhsOffset := new(f.Element).SetUint64(lhsAddr.Offset)
+		rhsOffset := new(f.Element).SetUint64(rhsAddr.Offset)
+		mv.felt.Sub(lhsOffset, rhsOffset)
+		mv.isFelt = true
+		mv.isAddress = false
+		return nil
+	}
+
 	if lhs.IsAddress() {
 		return mv.addrUnsafe().Sub(lhs.addrUnsafe(), rhs.ToAny())
 	}
@@ -259,15 +361,48 @@ func (mv *MemoryValue) Div(lhs, rhs *MemoryValue) error {
 	if lhs.IsAddress() || rhs.IsAddress() {
 		return errors.New("cannot divide memory addresses")
 	}
+	// the field library would silently use zero as the inverse of zero
+	if rhs.felt.IsZero() {
+		return errors.New("division by zero")
+	}
 	mv.felt.Div(&lhs.felt, &rhs.felt)
 	return nil
 }
 
-func (mv MemoryValue) String() string {
+// Checks felts can be printed in radix, either 10 or 16
+func CheckFeltRadix(radix int) error {
+	if radix != 10 && radix != 16 {
+		return fmt.Errorf("unsupported felt radix %d, expected 10 or 16", radix)
+	}
+	return nil
+}
+
+// Formats a felt in hexadecimal, prefixed by 0x, if radix is 16 and in
+// decimal otherwise, like the memory text of the python vm
+func FeltString(felt *f.Element, radix int) string {
+	if radix == 16 {
+		return "0x" + felt.Text(16)
+	}
+	return felt.Text(10)
+}
+
+// Formats addresses as `segment:offset` and felts in radix, see FeltString
+func (mv MemoryValue) Text(radix int) string {
 	if mv.IsAddress() {
 		return mv.addrUnsafe().String()
 	}
-	return mv.felt.String()
+	return FeltString(&mv.felt, radix)
+}
+
+// Formats addresses as `segment:offset` and felts in decimal
+func (mv MemoryValue) String() string {
+	return mv.Text(10)
+}
+
+// Like String but felts are formatted in hexadecimal, which keeps big felts
+// readable and matches the output of other Cairo tooling
+func (mv MemoryValue) StringHex() string {
+	return mv.Text(16)
 }
 
 // Retuns a MemoryValue holding a felt as uint if it fits
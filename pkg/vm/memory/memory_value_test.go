@@ -1,6 +1,8 @@
 package memory
 
 import (
+	"math"
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -10,7 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func UseInTestOnlyMemoryValuePointerFromInt[T constraints.Integer](v T) *MemoryValue {
+func UseInTestOnlyMemoryValuePointerFromInt[T constraints.Signed](v T) *MemoryValue {
 	mv := MemoryValueFromInt(v)
 	return &mv
 }
@@ -129,14 +131,23 @@ func TestMemoryAddressSubMemoryAddressSameSegment(t *testing.T) {
 		SegmentIndex: 2,
 		Offset:       2,
 	})
-	expected := MemoryValueFromMemoryAddress(&MemoryAddress{
-		SegmentIndex: 2,
-		Offset:       8,
-	})
+	expected := MemoryValueFromInt(8)
 
 	err := memVal.Sub(&lhs, &rhs)
 	require.NoError(t, err)
 	assert.Equal(t, expected, memVal)
+	assert.True(t, memVal.IsFelt())
+}
+
+func TestMemoryAddressSubMemoryAddressNegativeDistance(t *testing.T) {
+	memVal := EmptyMemoryValueAsFelt()
+	// two addresses in the execution segment
+	lhs := MemoryValueFromSegmentAndOffset(1, 3)
+	rhs := MemoryValueFromSegmentAndOffset(1, 7)
+
+	err := memVal.Sub(&lhs, &rhs)
+	require.NoError(t, err)
+	assert.Equal(t, MemoryValueFromInt(-4), memVal)
 }
 
 func TestMemoryAddressSubMemoryAddressDiffSegment(t *testing.T) {
@@ -151,7 +162,216 @@ func TestMemoryAddressSubMemoryAddressDiffSegment(t *testing.T) {
 	})
 
 	err := memVal.Sub(&lhs, &rhs)
+	assert.ErrorContains(t, err, "different segments: lhs is in 2, rhs is in 5")
+}
+
+func TestMemoryAddressSubFeltUnderflow(t *testing.T) {
+	address := MemoryAddress{}
+	lhs := MemoryAddress{SegmentIndex: 3, Offset: 5}
+
+	require.NoError(t, address.SubFelt(&lhs, new(f.Element).SetUint64(5)))
+	assert.Equal(t, MemoryAddress{SegmentIndex: 3, Offset: 0}, address)
+
+	err := address.SubFelt(&lhs, new(f.Element).SetUint64(6))
+	assert.ErrorContains(t, err, "offset 5 underflows when subtracting 6")
+
+	// subtracting a negative felt would move the address forward
+	err = address.SubFelt(&lhs, new(f.Element).SetInt64(-1))
+	assert.ErrorContains(t, err, "underflows")
+}
+
+func TestMemoryAddressDistance(t *testing.T) {
+	lhs := MemoryAddress{SegmentIndex: 2, Offset: 10}
+
+	distance, err := lhs.Distance(&MemoryAddress{SegmentIndex: 2, Offset: 4})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), distance)
+
+	_, err = lhs.Distance(&MemoryAddress{SegmentIndex: 2, Offset: 11})
+	assert.ErrorContains(t, err, "address 2:11 is ahead of 2:10")
+
+	_, err = lhs.Distance(&MemoryAddress{SegmentIndex: 4, Offset: 1})
+	assert.ErrorContains(t, err, "different segments")
+}
+
+func TestMemoryAddressLess(t *testing.T) {
+	addresses := []MemoryAddress{
+		{SegmentIndex: 2, Offset: 1},
+		{SegmentIndex: 0, Offset: 7},
+		{SegmentIndex: 2, Offset: 0},
+		{SegmentIndex: 1, Offset: 9},
+		{SegmentIndex: 0, Offset: 3},
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i].Less(&addresses[j]) })
+	assert.Equal(t, []MemoryAddress{
+		{SegmentIndex: 0, Offset: 3},
+		{SegmentIndex: 0, Offset: 7},
+		{SegmentIndex: 1, Offset: 9},
+		{SegmentIndex: 2, Offset: 0},
+		{SegmentIndex: 2, Offset: 1},
+	}, addresses)
+
+	address := MemoryAddress{SegmentIndex: 1, Offset: 2}
+	assert.False(t, address.Less(&address))
+}
+
+func TestMemoryValueCmp(t *testing.T) {
+	zero := MemoryValueFromInt(0)
+	one := MemoryValueFromInt(1)
+	// P - 1 is the biggest felt, it is not a negative number
+	minusOne := MemoryValueFromInt(-1)
+	minusTwo := MemoryValueFromInt(-2)
+
+	for _, tc := range []struct {
+		lhs, rhs MemoryValue
+		expected int
+	}{
+		{zero, one, -1},
+		{one, one, 0},
+		{one, zero, 1},
+		{one, minusOne, -1},
+		{minusOne, zero, 1},
+		{minusTwo, minusOne, -1},
+		{minusOne, minusOne, 0},
+	} {
+		cmp, err := tc.lhs.Cmp(&tc.rhs)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected, cmp, "%s vs %s", tc.lhs, tc.rhs)
+	}
+
+	// P wraps around to 0
+	modulus := MemoryValueFromFieldElement(new(f.Element).SetBigInt(f.Modulus()))
+	cmp, err := modulus.Cmp(&zero)
+	require.NoError(t, err)
+	assert.Equal(t, 0, cmp)
+
+	address := MemoryValueFromSegmentAndOffset(1, 2)
+	_, err = address.Cmp(&one)
+	assert.EqualError(t, err, "cannot compare 1:2 and 1: only felts are ordered")
+	_, err = one.Cmp(&address)
 	assert.Error(t, err)
+	unknown := MemoryValue{}
+	_, err = one.Cmp(&unknown)
+	assert.Error(t, err)
+}
+
+func TestFeltDivZero(t *testing.T) {
+	memVal := EmptyMemoryValueAsFelt()
+	lhs := MemoryValueFromInt(6)
+	rhs := MemoryValueFromInt(0)
+
+	err := memVal.Div(&lhs, &rhs)
+	assert.EqualError(t, err, "division by zero")
+
+	rhs = MemoryValueFromInt(3)
+	require.NoError(t, memVal.Div(&lhs, &rhs))
+	assert.Equal(t, MemoryValueFromInt(2), memVal)
+}
+
+func TestMemoryValueStringHex(t *testing.T) {
+	address := MemoryValueFromSegmentAndOffset(2, 15)
+	assert.Equal(t, "2:15", address.String())
+	assert.Equal(t, "2:15", address.StringHex())
+
+	felt := MemoryValueFromInt(255)
+	assert.Equal(t, "255", felt.String())
+	assert.Equal(t, "0xff", felt.StringHex())
+
+	minusOne := MemoryValueFromInt(-1)
+	assert.Equal(t, "0x800000000000011000000000000000000000000000000000000000000000000", minusOne.StringHex())
+}
+
+func TestFeltRadix(t *testing.T) {
+	felt := MemoryValueFromInt(255)
+	assert.Equal(t, "0xff", felt.Text(16))
+	assert.Equal(t, "255", felt.Text(10))
+	address := MemoryValueFromSegmentAndOffset(2, 15)
+	assert.Equal(t, "2:15", address.Text(16))
+
+	require.NoError(t, CheckFeltRadix(16))
+	require.EqualError(t, CheckFeltRadix(2), "unsupported felt radix 2, expected 10 or 16")
+}
+
+func TestFeltFromWord(t *testing.T) {
+	maxFelt, err := FeltFromWord("0x800000000000011000000000000000000000000000000000000000000000000")
+	require.NoError(t, err)
+	assert.Equal(t, MemoryValueFromInt(-1), MemoryValueFromFieldElement(maxFelt))
+
+	felt, err := FeltFromWord("42")
+	require.NoError(t, err)
+	assert.Equal(t, f.NewElement(42), *felt)
+
+	_, err = FeltFromWord("0x800000000000011000000000000000000000000000000000000000000000001")
+	assert.EqualError(t, err, "word 0x800000000000011000000000000000000000000000000000000000000000001 is outside of the field [0, P)")
+	_, err = FeltFromWord("-1")
+	assert.EqualError(t, err, "word -1 is outside of the field [0, P)")
+	_, err = FeltFromWord("0xzz")
+	assert.EqualError(t, err, "word 0xzz is not a number")
+}
+
+func TestMemoryValueIsZero(t *testing.T) {
+	zero := MemoryValueFromInt(0)
+	isZero, err := zero.IsZero()
+	require.NoError(t, err)
+	assert.True(t, isZero)
+
+	nonZero := MemoryValueFromInt(5)
+	isZero, err = nonZero.IsZero()
+	require.NoError(t, err)
+	assert.False(t, isZero)
+
+	address := MemoryValueFromSegmentAndOffset(1, 0)
+	_, err = address.IsZero()
+	assert.ErrorContains(t, err, "cannot compare a non felt memory value with zero: 1:0")
+}
+
+func TestMemoryValueIsOne(t *testing.T) {
+	one := MemoryValueFromInt(1)
+	isOne, err := one.IsOne()
+	require.NoError(t, err)
+	assert.True(t, isOne)
+
+	nonOne := MemoryValueFromInt(0)
+	isOne, err = nonOne.IsOne()
+	require.NoError(t, err)
+	assert.False(t, isOne)
+
+	address := MemoryValueFromSegmentAndOffset(1, 1)
+	_, err = address.IsOne()
+	assert.ErrorContains(t, err, "cannot compare a non felt memory value with one: 1:1")
+}
+
+func TestMemoryValueFromUintSmallValues(t *testing.T) {
+	for _, v := range []uint64{0, 1, 255, 256, 1 << 40} {
+		felt := f.NewElement(v)
+		assert.Equal(t, MemoryValueFromFieldElement(&felt), MemoryValueFromUint(v))
+	}
+
+	// cached values are copies, operating on them doesn't change the cache
+	three := MemoryValueFromUint[uint64](3)
+	one := MemoryValueFromInt(1)
+	require.NoError(t, three.Add(&three, &one))
+	assert.Equal(t, MemoryValueFromInt(4), three)
+	fresh := MemoryValueFromUint[uint64](3)
+	assert.True(t, fresh.Equal(&MemoryValue{felt: f.NewElement(3), isFelt: true}))
+
+	segment := EmptySegment()
+	require.NoError(t, segment.Write(0, &fresh))
+	require.ErrorContains(t, segment.Write(0, &three), "rewriting cell")
+}
+
+func TestMemoryValueFromNegativeInt(t *testing.T) {
+	pMinusOne := new(f.Element).SetInt64(-1)
+	assert.Equal(t, MemoryValueFromFieldElement(pMinusOne), MemoryValueFromInt(-1))
+	assert.Equal(t, MemoryValueFromFieldElement(pMinusOne), MemoryValueFromInt(int8(-1)))
+
+	// the minimum of each type is negated without overflowing
+	for _, v := range []int64{math.MinInt8, math.MinInt32, math.MinInt64} {
+		expected := new(f.Element).SetInt64(v)
+		assert.Equal(t, MemoryValueFromFieldElement(expected), MemoryValueFromInt(v))
+	}
+	expected := new(f.Element).SetInt64(math.MinInt8)
+	assert.Equal(t, MemoryValueFromFieldElement(expected), MemoryValueFromInt(int8(math.MinInt8)))
 }
 
 // Note: Leaving relocation logic for later
@@ -5,6 +5,7 @@ import (
 
 	safemath "github.com/NethermindEth/cairo-vm-go/pkg/safemath"
 	mem "github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
+	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
 )
 
 const (
@@ -12,6 +13,36 @@ const (
 	ExecutionSegment
 )
 
+const (
+	ProgramSegmentName   = "program"
+	ExecutionSegmentName = "execution"
+)
+
+// Checks the program and execution segments are at the fixed indexes the vm
+// addresses them by, see ProgramSegment and ExecutionSegment. They are found
+// by their name, so every other segment must be allocated after them
+func CheckFixedSegments(memory *mem.Memory) error {
+	fixed := []struct {
+		name  string
+		index int
+	}{
+		{ProgramSegmentName, ProgramSegment},
+		{ExecutionSegmentName, ExecutionSegment},
+	}
+	for _, segment := range fixed {
+		index, ok := memory.FindSegmentByName(segment.name)
+		if !ok {
+			return fmt.Errorf("no %s segment is allocated", segment.name)
+		}
+		if index != segment.index {
+			return fmt.Errorf(
+				"%s segment is allocated at index %d instead of %d", segment.name, index, segment.index,
+			)
+		}
+	}
+	return nil
+}
+
 // Required by the VM to run hints.
 //
 // HintRunner is defined as an external component of the VM so any user
@@ -50,14 +81,23 @@ func (ctx *Context) AddressPc() mem.MemoryAddress {
 }
 
 // relocates pc, ap and fp to be their real address value
-// that is, pc + 1, ap + programSegmentOffset, fp + programSegmentOffset
-func (ctx *Context) Relocate(executionSegmentOffset uint64) Trace {
-	return Trace{
-		// todo(rodro): this should be improved upon
-		Pc: ctx.Pc.Offset + 1,
-		Ap: ctx.Ap + executionSegmentOffset,
-		Fp: ctx.Fp + executionSegmentOffset,
+// that is, pc + 1, ap + programSegmentOffset, fp + programSegmentOffset.
+// Errors instead of wrapping around if any of them overflows
+func (ctx *Context) Relocate(executionSegmentOffset uint64) (Trace, error) {
+	// todo(rodro): this should be improved upon
+	pc, isOverflow := safemath.SafeAdd(ctx.Pc.Offset, 1)
+	if isOverflow {
+		return Trace{}, fmt.Errorf("relocating pc %s overflows", ctx.Pc)
 	}
+	ap, isOverflow := safemath.SafeAdd(ctx.Ap, executionSegmentOffset)
+	if isOverflow {
+		return Trace{}, fmt.Errorf("relocating ap %d to %d overflows", ctx.Ap, executionSegmentOffset)
+	}
+	fp, isOverflow := safemath.SafeAdd(ctx.Fp, executionSegmentOffset)
+	if isOverflow {
+		return Trace{}, fmt.Errorf("relocating fp %d to %d overflows", ctx.Fp, executionSegmentOffset)
+	}
+	return Trace{Pc: pc, Ap: ap, Fp: fp}, nil
 }
 
 type Trace struct {
@@ -70,6 +110,16 @@ type Trace struct {
 type VirtualMachineConfig struct {
 	// If true, the vm outputs the trace and the relocated memory at the end of execution
 	ProofMode bool
+	// If true, the vm counts how many times each opcode, res logic and pc update is executed
+	CollectProfile bool
+	// If true, the vm records the context of every step even outside of proof mode
+	CollectTrace bool
+	// If true, decoded instructions are also cached by their bytecode word, so
+	// identical words found at different pcs are decoded only once
+	CacheByBytecode bool
+	// If true, every memory access is recorded in order, see MemoryAccessTrace.
+	// It slows down execution and keeps every access in memory
+	CollectMemoryAccesses bool
 }
 
 type VirtualMachine struct {
@@ -78,125 +128,450 @@ type VirtualMachine struct {
 	Step    uint64
 	Trace   []Context
 	config  VirtualMachineConfig
-	// instructions cache
-	instructions map[uint64]*Instruction
+	// instructions cache of the program segment indexed by offset, nil means undecoded
+	programInstructions []*Instruction
+	// instructions cache of any other segment
+	instructions map[mem.MemoryAddress]*Instruction
+	// instructions cache indexed by bytecode word, nil unless CacheByBytecode is set
+	bytecodeInstructions map[f.Element]*Instruction
+	// execution counters, only used when collecting a profile
+	profile map[string]uint64
+	// called with the recorded trace once it holds traceFlushSize entries, see SetTraceFlusher
+	traceFlusher   func([]Context) error
+	traceFlushSize int
+	// amount of trace entries already handed to traceFlusher and dropped
+	flushedTraceLen int
 }
 
 // NewVirtualMachine creates a VM from the program bytecode using a specified config.
 func NewVirtualMachine(initialContext Context, memory *mem.Memory, config VirtualMachineConfig) (*VirtualMachine, error) {
 	// Initialize the trace if necesary
 	var trace []Context
-	if config.ProofMode {
+	if config.ProofMode || config.CollectTrace {
 		trace = make([]Context, 0)
 	}
 
+	var profile map[string]uint64
+	if config.CollectProfile {
+		profile = make(map[string]uint64)
+	}
+
+	var bytecodeInstructions map[f.Element]*Instruction
+	if config.CacheByBytecode {
+		bytecodeInstructions = make(map[f.Element]*Instruction)
+	}
+
+	if config.CollectMemoryAccesses {
+		memory.RecordAccessTrace()
+	}
+
+	var programInstructions []*Instruction
+	if len(memory.Segments) > ProgramSegment {
+		programInstructions = make([]*Instruction, memory.Segments[ProgramSegment].Len())
+	}
+
 	return &VirtualMachine{
-		Context:      initialContext,
-		Memory:       memory,
-		Trace:        trace,
-		config:       config,
-		instructions: make(map[uint64]*Instruction),
+		Context:              initialContext,
+		Memory:               memory,
+		Trace:                trace,
+		config:               config,
+		programInstructions:  programInstructions,
+		instructions:         make(map[mem.MemoryAddress]*Instruction),
+		bytecodeInstructions: bytecodeInstructions,
+		profile:              profile,
 	}, nil
 }
 
-// todo(rodro): add a cache mechanism for not decoding the same instruction twice
-
 func (vm *VirtualMachine) RunStep(hintRunner HintRunner) error {
-	// if instruction is not in cache, redecode and store it
-	instruction, ok := vm.instructions[vm.Context.Pc.Offset]
-	if !ok {
-		memoryValue, err := vm.Memory.ReadFromAddress(&vm.Context.Pc)
-		if err != nil {
-			return fmt.Errorf("reading instruction: %w", err)
-		}
+	_, _, err := vm.runStep()
+	return err
+}
 
-		bytecodeInstruction, err := memoryValue.ToFieldElement()
-		if err != nil {
-			return fmt.Errorf("reading instruction: %w", err)
-		}
+// What a single step executed, see RunStepDetailed
+type StepDetails struct {
+	// the context right before the step
+	Context     Context
+	Instruction *Instruction
+	// the res of the instruction, unknown when it is unconstrained
+	Res mem.MemoryValue
+}
 
-		instruction, err = DecodeInstruction(bytecodeInstruction)
-		if err != nil {
-			return fmt.Errorf("decoding instruction: %w", err)
-		}
-		vm.instructions[vm.Context.Pc.Offset] = instruction
+// Runs a step like RunStep and also returns the instruction executed and
+// the res it computed, so a debugger can show them without decoding again
+func (vm *VirtualMachine) RunStepDetailed(hintRunner HintRunner) (StepDetails, error) {
+	context := vm.Context
+	instruction, res, err := vm.runStep()
+	if err != nil {
+		return StepDetails{}, err
+	}
+	return StepDetails{Context: context, Instruction: instruction, Res: res}, nil
+}
+
+func (vm *VirtualMachine) runStep() (*Instruction, mem.MemoryValue, error) {
+	instruction, err := vm.fetchInstruction()
+	if err != nil {
+		return nil, mem.MemoryValue{}, err
 	}
 
 	// store the trace before state change
-	if vm.config.ProofMode {
+	if vm.config.ProofMode || vm.config.CollectTrace {
 		vm.Trace = append(vm.Trace, vm.Context)
+		if vm.traceFlusher != nil && len(vm.Trace) >= vm.traceFlushSize {
+			if err := vm.FlushTrace(); err != nil {
+				return nil, mem.MemoryValue{}, err
+			}
+		}
 	}
 
-	err := vm.RunInstruction(instruction)
+	// errors are already wrapped as VMError
+	res, err := vm.runInstruction(instruction)
 	if err != nil {
-		return fmt.Errorf("running instruction: %w", err)
+		return nil, mem.MemoryValue{}, err
 	}
 
 	vm.Step++
-	return nil
+	return instruction, res, nil
 }
 
 func (vm *VirtualMachine) RunInstruction(instruction *Instruction) error {
+	_, err := vm.runInstruction(instruction)
+	return err
+}
+
+// Runs the instruction and returns its res
+func (vm *VirtualMachine) runInstruction(instruction *Instruction) (mem.MemoryValue, error) {
 	dstAddr, err := vm.getDstAddr(instruction)
 	if err != nil {
-		return fmt.Errorf("dst cell: %w", err)
+		return mem.MemoryValue{}, vm.newError("dst cell", err)
+	}
+
+	if instruction.Opcode == Ret {
+		if err := vm.validateRet(instruction, &dstAddr); err != nil {
+			return mem.MemoryValue{}, vm.newError("ret", err)
+		}
 	}
 
 	op0Addr, err := vm.getOp0Addr(instruction)
 	if err != nil {
-		return fmt.Errorf("op0 cell: %w", err)
+		return mem.MemoryValue{}, vm.newError("op0 cell", err)
 	}
 
 	op1Addr, err := vm.getOp1Addr(instruction, &op0Addr)
 	if err != nil {
-		return fmt.Errorf("op1 cell: %w", err)
+		return mem.MemoryValue{}, vm.newError("op1 cell", err)
 	}
 
 	res, err := vm.inferOperand(instruction, &dstAddr, &op0Addr, &op1Addr)
 	if err != nil {
-		return fmt.Errorf("res infer: %w", err)
+		return mem.MemoryValue{}, vm.newError("res infer", err)
 	}
 	if !res.Known() {
 		res, err = vm.computeRes(instruction, &op0Addr, &op1Addr)
 		if err != nil {
-			return fmt.Errorf("compute res: %w", err)
+			return mem.MemoryValue{}, vm.newError("compute res", err)
 		}
 	}
 
 	err = vm.opcodeAssertions(instruction, &dstAddr, &op0Addr, &res)
 	if err != nil {
-		return fmt.Errorf("opcode assertions: %w", err)
+		return mem.MemoryValue{}, vm.newError("opcode assertions", err)
 	}
 
-	nextPc, err := vm.updatePc(instruction, &dstAddr, &op1Addr, &res)
-	if err != nil {
-		return fmt.Errorf("pc update: %w", err)
+	// most instructions just move to the next one, which doesn't need the
+	// operands so it skips going through every pc update
+	var nextPc mem.MemoryAddress
+	if instruction.PcUpdate == NextInstr {
+		nextPc = mem.MemoryAddress{
+			SegmentIndex: vm.Context.Pc.SegmentIndex,
+			Offset:       vm.Context.Pc.Offset + uint64(instruction.Size()),
+		}
+	} else {
+		nextPc, err = vm.updatePc(instruction, &dstAddr, &op1Addr, &res)
+		if err != nil {
+			return mem.MemoryValue{}, vm.newError("pc update", err)
+		}
 	}
 
 	nextAp, err := vm.updateAp(instruction, &res)
 	if err != nil {
-		return fmt.Errorf("ap update: %w", err)
+		return mem.MemoryValue{}, vm.newError("ap update", err)
 	}
 
 	nextFp, err := vm.updateFp(instruction, &dstAddr)
 	if err != nil {
-		return fmt.Errorf("fp update: %w", err)
+		return mem.MemoryValue{}, vm.newError("fp update", err)
+	}
+
+	if vm.config.CollectMemoryAccesses {
+		// operands are mostly peeked, which isn't recorded by the memory
+		for _, address := range []*mem.MemoryAddress{&vm.Context.Pc, &dstAddr, &op0Addr, &op1Addr} {
+			value, err := vm.Memory.PeekFromAddress(address)
+			if err != nil {
+				return mem.MemoryValue{}, vm.newError("memory access", err)
+			}
+			vm.Memory.RecordAccess(address, &value)
+		}
 	}
 
 	vm.Context.Pc = nextPc
 	vm.Context.Ap = nextAp
 	vm.Context.Fp = nextFp
 
+	if vm.config.CollectProfile {
+		vm.profile["opcode "+instruction.Opcode.String()]++
+		vm.profile["res "+instruction.Res.String()]++
+		vm.profile["pc update "+instruction.PcUpdate.String()]++
+	}
+
+	return res, nil
+}
+
+// Starts counting the executed opcodes, res logics and pc updates
+func (vm *VirtualMachine) EnableProfiling() {
+	if vm.config.CollectProfile {
+		return
+	}
+	vm.config.CollectProfile = true
+	vm.profile = make(map[string]uint64)
+}
+
+// Returns how many times each opcode, res logic and pc update was executed,
+// or nil when the profile is not being collected
+func (vm *VirtualMachine) ProfileStats() map[string]uint64 {
+	if !vm.config.CollectProfile {
+		return nil
+	}
+	stats := make(map[string]uint64, len(vm.profile))
+	for key, count := range vm.profile {
+		stats[key] = count
+	}
+	return stats
+}
+
+// Starts caching decoded instructions by their bytecode word as well, see
+// VirtualMachineConfig.CacheByBytecode
+func (vm *VirtualMachine) EnableBytecodeCache() {
+	vm.config.CacheByBytecode = true
+	if vm.bytecodeInstructions == nil {
+		vm.bytecodeInstructions = make(map[f.Element]*Instruction)
+	}
+}
+
+// Starts recording the context of every step, which is otherwise only done in proof mode
+func (vm *VirtualMachine) EnableTracing() {
+	vm.config.CollectTrace = true
+	if vm.Trace == nil {
+		vm.Trace = make([]Context, 0)
+	}
+}
+
+// Hands the recorded trace to flush every time it holds size entries and drops
+// them afterwards, so long runs don't keep their whole trace in memory. Runs
+// shorter than size keep their trace in memory until FlushTrace is called
+func (vm *VirtualMachine) SetTraceFlusher(size int, flush func([]Context) error) {
+	vm.traceFlusher = flush
+	vm.traceFlushSize = size
+}
+
+// Hands the trace recorded since the last flush to the flusher set with
+// SetTraceFlusher. Does nothing if there is none
+func (vm *VirtualMachine) FlushTrace() error {
+	if vm.traceFlusher == nil || len(vm.Trace) == 0 {
+		return nil
+	}
+	if err := vm.traceFlusher(vm.Trace); err != nil {
+		return fmt.Errorf("flushing trace: %w", err)
+	}
+	vm.flushedTraceLen += len(vm.Trace)
+	vm.Trace = vm.Trace[:0]
 	return nil
 }
 
-// It returns the current trace entry, the public memory, and the occurrence of an error
+// Returns the context of every step executed so far as recorded, i.e. pc is
+// an address in its own segment while ap and fp are offsets of the execution
+// segment. Nothing is relocated, which is what tools working with segments
+// such as debuggers need. Nil if the trace is not being recorded. Entries
+// already flushed are not part of it
+func (vm *VirtualMachine) RawTrace() []Context {
+	return vm.Trace
+}
+
+// Returns the trace relocated into the format the prover expects: pc, ap and
+// fp become addresses of the relocated memory, which starts at 1 and places
+// the execution segment right after the program. Only available in proof mode
+// and as long as no entry was flushed
 func (vm *VirtualMachine) ExecutionTrace() ([]Trace, error) {
 	if !vm.config.ProofMode {
 		return nil, fmt.Errorf("proof mode is off")
 	}
+	if vm.flushedTraceLen > 0 {
+		return nil, fmt.Errorf("%d trace entries were already flushed", vm.flushedTraceLen)
+	}
+
+	return vm.relocateTrace()
+}
+
+// Returns every memory access made so far in order: the reads and writes made
+// through the memory, e.g. by hints, and after every step its pc, dst, op0 and
+// op1 cells as they are once the instruction ran. A cell can be accessed more
+// than once. Nil unless CollectMemoryAccesses is set
+func (vm *VirtualMachine) MemoryAccessTrace() []mem.MemoryAccess {
+	if !vm.config.CollectMemoryAccesses {
+		return nil
+	}
+	return vm.Memory.AccessTrace()
+}
 
-	return vm.relocateTrace(), nil
+// The state of the vm at some step, see Snapshot
+type VMSnapshot struct {
+	Context Context
+	Step    uint64
+	// amount of trace entries recorded when taken, flushed ones included
+	traceLen int
+	memory   mem.MemorySnapshot
+}
+
+// Captures the current context, step and memory so the vm can speculatively
+// run and later roll back with Restore. Profiling counters, hint state and
+// builtin runners state are not captured
+func (vm *VirtualMachine) Snapshot() VMSnapshot {
+	return VMSnapshot{
+		Context:  vm.Context,
+		Step:     vm.Step,
+		traceLen: vm.flushedTraceLen + len(vm.Trace),
+		memory:   vm.Memory.Snapshot(),
+	}
+}
+
+// Rolls the vm back to a snapshot, undoing the memory writes made since it
+// was taken. Errors if the snapshot is ahead of the current state
+func (vm *VirtualMachine) Restore(snapshot VMSnapshot) error {
+	if snapshot.Step > vm.Step || snapshot.traceLen > vm.flushedTraceLen+len(vm.Trace) {
+		return fmt.Errorf("cannot restore step %d snapshot at step %d", snapshot.Step, vm.Step)
+	}
+	if snapshot.traceLen < vm.flushedTraceLen {
+		return fmt.Errorf(
+			"cannot restore step %d snapshot, %d trace entries were already flushed",
+			snapshot.Step, vm.flushedTraceLen,
+		)
+	}
+	if err := vm.Memory.Restore(&snapshot.memory); err != nil {
+		return fmt.Errorf("restoring memory: %w", err)
+	}
+
+	vm.Context = snapshot.Context
+	vm.Step = snapshot.Step
+	if vm.Trace != nil {
+		vm.Trace = vm.Trace[:snapshot.traceLen-vm.flushedTraceLen]
+	}
+	// instructions outside of the program segment may have been undone
+	vm.instructions = make(map[mem.MemoryAddress]*Instruction)
+	return nil
+}
+
+// Returns the instruction at pc. If it is not in cache, it is decoded and stored
+func (vm *VirtualMachine) fetchInstruction() (*Instruction, error) {
+	pc := vm.Context.Pc
+	inProgram := pc.SegmentIndex == ProgramSegment && pc.Offset < uint64(len(vm.programInstructions))
+	if inProgram {
+		if instruction := vm.programInstructions[pc.Offset]; instruction != nil {
+			return instruction, nil
+		}
+	} else if instruction, ok := vm.instructions[pc]; ok {
+		return instruction, nil
+	}
+
+	memoryValue, err := vm.Memory.ReadFromAddress(&pc)
+	if err != nil {
+		return nil, vm.newError("reading instruction", err)
+	}
+
+	bytecodeInstruction, err := memoryValue.ToFieldElement()
+	if err != nil {
+		return nil, vm.newError("reading instruction", err)
+	}
+
+	instruction, err := vm.decodeInstruction(bytecodeInstruction)
+	if err != nil {
+		return nil, vm.newError("decoding instruction", &DecodeError{
+			Word:     *bytecodeInstruction,
+			Previous: vm.neighbourWord(&pc, -1),
+			Next:     vm.neighbourWord(&pc, 1),
+			Err:      err,
+		})
+	}
+
+	if inProgram {
+		vm.programInstructions[pc.Offset] = instruction
+	} else {
+		vm.instructions[pc] = instruction
+	}
+	return instruction, nil
+}
+
+// Decodes a bytecode word, reusing the instruction of an identical word
+// decoded before when caching by bytecode
+func (vm *VirtualMachine) decodeInstruction(bytecode *f.Element) (*Instruction, error) {
+	if vm.bytecodeInstructions == nil {
+		return DecodeInstruction(bytecode)
+	}
+	if instruction, ok := vm.bytecodeInstructions[*bytecode]; ok {
+		return instruction, nil
+	}
+	instruction, err := DecodeInstruction(bytecode)
+	if err != nil {
+		return nil, err
+	}
+	vm.bytecodeInstructions[*bytecode] = instruction
+	return instruction, nil
+}
+
+// Returns the word at delta cells from pc for decoding errors, unknown if
+// it is outside of the segment or was never written. Unlike a read, it
+// doesn't infer any value or grow the segment
+func (vm *VirtualMachine) neighbourWord(pc *mem.MemoryAddress, delta int) mem.MemoryValue {
+	if pc.SegmentIndex >= uint64(len(vm.Memory.Segments)) || (delta < 0 && pc.Offset == 0) {
+		return mem.MemoryValue{}
+	}
+	segment := vm.Memory.Segments[pc.SegmentIndex]
+	offset := uint64(int64(pc.Offset) + int64(delta))
+	if offset >= segment.Len() {
+		return mem.MemoryValue{}
+	}
+	// the words of a lazy program are decoded on demand
+	return segment.Peek(offset)
+}
+
+// wraps an error produced during the current step
+func (vm *VirtualMachine) newError(op string, err error) error {
+	return &VMError{Pc: vm.Context.Pc, Step: vm.Step, Op: op, Err: err}
+}
+
+// A ret must jump to an absolute address leaving ap untouched, and its dst
+// must be [fp - 2] holding the caller fp address
+func (vm *VirtualMachine) validateRet(instruction *Instruction, dstAddr *mem.MemoryAddress) error {
+	if instruction.PcUpdate != Jump {
+		return &MalformedRetError{fmt.Sprintf("pc update must be %s, got %s", Jump, instruction.PcUpdate)}
+	}
+	if instruction.ApUpdate != SameAp {
+		return &MalformedRetError{fmt.Sprintf("ap update must be %s, got %s", SameAp, instruction.ApUpdate)}
+	}
+	if instruction.DstRegister != Fp || instruction.OffDest != -2 {
+		return &MalformedRetError{fmt.Sprintf(
+			"dst must be [Fp - 2], got [%s + %d]", instruction.DstRegister, instruction.OffDest,
+		)}
+	}
+
+	dstValue, err := vm.Memory.ReadFromAddress(dstAddr)
+	if err != nil {
+		return err
+	}
+	// the caller fp is not required to be in the execution segment since the
+	// runner may return into a sentinel segment at the end of execution
+	if !dstValue.IsAddress() {
+		return &MalformedRetError{fmt.Sprintf("[fp - 2] must be an address, got %s", dstValue)}
+	}
+	return nil
 }
 
 func (vm *VirtualMachine) getDstAddr(instruction *Instruction) (mem.MemoryAddress, error) {
@@ -233,15 +608,26 @@ func (vm *VirtualMachine) getOp1Addr(instruction *Instruction, op0Addr *mem.Memo
 	var op1Address mem.MemoryAddress
 	switch instruction.Op1Source {
 	case Op0:
-		// in this case Op0 is being used as an address, and must be of unwrapped as it
-		op0Value, err := vm.Memory.ReadFromAddress(op0Addr)
+		// in this case Op0 is being used as an address, and must be of unwrapped as it.
+		// It is peeked so an unknown op0 isn't inferred as a felt
+		op0Value, err := vm.Memory.PeekFromAddress(op0Addr)
 		if err != nil {
 			return mem.UnknownValue, fmt.Errorf("cannot read op0: %w", err)
 		}
+		if !op0Value.IsAddress() {
+			held := "unknown"
+			if op0Value.Known() {
+				held = op0Value.String()
+			}
+			return mem.UnknownValue, fmt.Errorf(
+				"op1 via op0 requires op0 to hold a known address: op0 at %s (off_op0 %d) is %s, off_op1 %d",
+				op0Addr, instruction.OffOp0, held, instruction.OffOp1,
+			)
+		}
 
 		op0Address, err := op0Value.ToMemoryAddress()
 		if err != nil {
-			return mem.UnknownValue, fmt.Errorf("op0 is not an address: %w", err)
+			return mem.UnknownValue, err
 		}
 		op1Address = mem.MemoryAddress{SegmentIndex: op0Address.SegmentIndex, Offset: op0Address.Offset}
 	case Imm:
@@ -267,6 +653,9 @@ func (vm *VirtualMachine) getOp1Addr(instruction *Instruction, op0Addr *mem.Memo
 func (vm *VirtualMachine) inferOperand(
 	instruction *Instruction, dstAddr *mem.MemoryAddress, op0Addr *mem.MemoryAddress, op1Addr *mem.MemoryAddress,
 ) (mem.MemoryValue, error) {
+	if instruction.Opcode == AssertEq && instruction.Res == Op1 {
+		return vm.inferOp1(dstAddr, op1Addr)
+	}
 	if instruction.Opcode != AssertEq ||
 		(instruction.Res != AddOperands && instruction.Res != MulOperands) {
 		return mem.MemoryValue{}, nil
@@ -322,6 +711,38 @@ func (vm *VirtualMachine) inferOperand(
 	return dstValue, nil
 }
 
+// An assertion such as `[ap] = [[fp - 3]]` writes dst into an unknown op1,
+// which is how programs write into builtin and array segments. Like the
+// deduce_op1 of the python vm, it only happens once the builtin of the op1
+// segment, if any, couldn't deduce the cell, and an unknown dst can't be
+// deduced from an unknown op1
+func (vm *VirtualMachine) inferOp1(dstAddr *mem.MemoryAddress, op1Addr *mem.MemoryAddress) (mem.MemoryValue, error) {
+	op1Value, err := vm.Memory.PeekFromAddress(op1Addr)
+	if err != nil {
+		return mem.MemoryValue{}, fmt.Errorf("cannot read op1: %w", err)
+	}
+	if op1Value.Known() {
+		return mem.MemoryValue{}, nil
+	}
+	// the deduced cell is read with res and then asserted equal to dst
+	builtinRunner := vm.Memory.Segments[op1Addr.SegmentIndex].BuiltinRunner
+	if deducer, ok := builtinRunner.(mem.BuiltinDeducer); ok && deducer.Deduces(op1Addr.Offset) {
+		return mem.MemoryValue{}, nil
+	}
+
+	dstValue, err := vm.Memory.PeekFromAddress(dstAddr)
+	if err != nil {
+		return mem.MemoryValue{}, fmt.Errorf("cannot read dst: %w", err)
+	}
+	if !dstValue.Known() {
+		return mem.MemoryValue{}, fmt.Errorf("op1 and dst cells are unknown")
+	}
+	if err := vm.Memory.WriteToAddress(op1Addr, &dstValue); err != nil {
+		return mem.MemoryValue{}, err
+	}
+	return dstValue, nil
+}
+
 func (vm *VirtualMachine) computeRes(
 	instruction *Instruction, op0Addr *mem.MemoryAddress, op1Addr *mem.MemoryAddress,
 ) (mem.MemoryValue, error) {
@@ -377,6 +798,13 @@ func (vm *VirtualMachine) opcodeAssertions(
 			return err
 		}
 	case AssertEq:
+		dstValue, err := vm.Memory.PeekFromAddress(dstAddr)
+		if err != nil {
+			return err
+		}
+		if dstValue.Known() && !dstValue.Equal(res) {
+			return &AssertEqError{Dst: *dstAddr, Expected: *res, Got: dstValue}
+		}
 		// assert that the calculated res is stored in dst
 		if err := vm.Memory.WriteToAddress(dstAddr, res); err != nil {
 			return err
@@ -417,12 +845,17 @@ func (vm *VirtualMachine) updatePc(
 			return mem.UnknownValue, err
 		}
 
-		dest, err := destMv.ToFieldElement()
+		if destMv.IsAddress() {
+			return mem.UnknownValue, fmt.Errorf(
+				"jnz condition must be a felt, got address at dst %s: %s", dstAddr, &destMv,
+			)
+		}
+		isZero, err := destMv.IsZero()
 		if err != nil {
 			return mem.UnknownValue, err
 		}
 
-		if dest.IsZero() {
+		if isZero {
 			return mem.MemoryAddress{
 				SegmentIndex: vm.Context.Pc.SegmentIndex,
 				Offset:       vm.Context.Pc.Offset + uint64(instruction.Size()),
@@ -452,11 +885,22 @@ func (vm *VirtualMachine) updateAp(instruction *Instruction, res *mem.MemoryValu
 	case SameAp:
 		return vm.Context.Ap, nil
 	case AddImm:
+		if !res.IsFelt() {
+			return 0, fmt.Errorf(
+				"ap update AddImm requires a small felt result, got address %s at ap %d", res, vm.Context.Ap,
+			)
+		}
 		res64, err := res.Uint64()
 		if err != nil {
-			return 0, err
+			return 0, fmt.Errorf(
+				"ap update AddImm requires a small felt result, got %s at ap %d", res.StringHex(), vm.Context.Ap,
+			)
+		}
+		nextAp, isOverflow := safemath.SafeAdd(vm.Context.Ap, res64)
+		if isOverflow {
+			return 0, fmt.Errorf("ap update AddImm overflows: %d + %d", vm.Context.Ap, res64)
 		}
-		return vm.Context.Ap + res64, nil
+		return nextAp, nil
 	case Add1:
 		return vm.Context.Ap + 1, nil
 	case Add2:
@@ -487,13 +931,17 @@ func (vm *VirtualMachine) updateFp(instruction *Instruction, dstAddr *mem.Memory
 	}
 }
 
-func (vm *VirtualMachine) relocateTrace() []Trace {
+func (vm *VirtualMachine) relocateTrace() ([]Trace, error) {
 	// one is added, because prover expect that the first element to be on
 	// indexed on 1 instead of 0
 	relocatedTrace := make([]Trace, len(vm.Trace))
 	totalBytecode := vm.Memory.Segments[ProgramSegment].Len() + 1
 	for i := range vm.Trace {
-		relocatedTrace[i] = vm.Trace[i].Relocate(totalBytecode)
+		var err error
+		relocatedTrace[i], err = vm.Trace[i].Relocate(totalBytecode)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i, err)
+		}
 	}
-	return relocatedTrace
+	return relocatedTrace, nil
 }
@@ -1,12 +1,14 @@
 package vm
 
 import (
+	"math/big"
 	"testing"
 
 	f "github.com/consensys/gnark-crypto/ecc/stark-curve/fp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/NethermindEth/cairo-vm-go/pkg/assembler"
 	mem "github.com/NethermindEth/cairo-vm-go/pkg/vm/memory"
 )
 
@@ -18,6 +20,26 @@ import (
 // - update AP: verify all posible cases, and when Res is a negative value
 // - update FP: verify all posible cases, and when Res is a negative value
 
+func TestCheckFixedSegments(t *testing.T) {
+	memory := mem.InitializeEmptyMemory()
+	require.EqualError(t, CheckFixedSegments(memory), "no program segment is allocated")
+
+	memory.AllocateEmptySegment()
+	memory.AllocateEmptySegment()
+	memory.Segments[0].Name = ExecutionSegmentName
+	memory.Segments[1].Name = ProgramSegmentName
+	require.EqualError(t, CheckFixedSegments(memory), "program segment is allocated at index 1 instead of 0")
+
+	memory.Segments[0].Name = ProgramSegmentName
+	memory.Segments[1].Name = ""
+	memory.AllocateEmptySegment()
+	memory.Segments[2].Name = ExecutionSegmentName
+	require.EqualError(t, CheckFixedSegments(memory), "execution segment is allocated at index 2 instead of 1")
+
+	memory.Segments[1].Name = ExecutionSegmentName
+	require.NoError(t, CheckFixedSegments(memory))
+}
+
 func TestGetCellApDst(t *testing.T) {
 	vm, _ := defaultVirtualMachine()
 
@@ -118,6 +140,41 @@ func TestGetCellDstFpNegativeOffset(t *testing.T) {
 	assert.Equal(t, mem.MemoryValueFromInt(100), mv)
 }
 
+func TestGetCellOffsetBounds(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 1 << 15
+	instruction := Instruction{OffDest: -1 << 15, DstRegister: Ap}
+
+	addr, err := vm.getDstAddr(&instruction)
+	require.NoError(t, err)
+	assert.Equal(t, mem.MemoryAddress{SegmentIndex: ExecutionSegment, Offset: 0}, addr)
+
+	vm.Context.Ap = 1<<15 - 1
+	_, err = vm.getDstAddr(&instruction)
+	require.EqualError(t, err, "offset overflow: 32767 + -32768")
+
+	vm.Context.Ap = ^uint64(0) - (1<<15 - 1)
+	instruction.OffDest = 1<<15 - 1
+	addr, err = vm.getDstAddr(&instruction)
+	require.NoError(t, err)
+	assert.Equal(t, ^uint64(0), addr.Offset)
+
+	vm.Context.Ap++
+	_, err = vm.getDstAddr(&instruction)
+	require.EqualError(t, err, "offset overflow: 18446744073709518849 + 32767")
+
+	// op1 relative to fp
+	vm.Context.Fp = 1 << 15
+	instruction = Instruction{OffOp1: -1 << 15, Op1Source: FpPlusOffOp1}
+	addr, err = vm.getOp1Addr(&instruction, nil)
+	require.NoError(t, err)
+	assert.Equal(t, mem.MemoryAddress{SegmentIndex: ExecutionSegment, Offset: 0}, addr)
+
+	vm.Context.Fp--
+	_, err = vm.getOp1Addr(&instruction, nil)
+	require.EqualError(t, err, "offset overflow: 32767 + -32768")
+}
+
 func TestGetApCellOp0(t *testing.T) {
 	vm, _ := defaultVirtualMachine()
 
@@ -170,6 +227,77 @@ func TestGetImmCellOp1(t *testing.T) {
 	assert.Equal(t, mem.MemoryValueFromInt(1234), mv)
 }
 
+func TestRunStepImmediate(t *testing.T) {
+	// [ap] = 1234, ap++
+	vm, _ := defaultVirtualMachineWithBytecode([]*f.Element{
+		newElementPtr(0x480680017fff8000),
+		newElementPtr(1234),
+	})
+	vm.Context.Fp = 1
+
+	require.NoError(t, vm.RunStep(nil))
+
+	// the immediate is read from pc + 1 and pc skips over it
+	assert.Equal(t, mem.MemoryAddress{SegmentIndex: ProgramSegment, Offset: 2}, vm.Context.Pc)
+	assert.Equal(t, uint64(1), vm.Context.Ap)
+	value, err := vm.Memory.Read(ExecutionSegment, 0)
+	require.NoError(t, err)
+	assert.Equal(t, mem.MemoryValueFromInt(1234), value)
+}
+
+func TestRunStepDetailed(t *testing.T) {
+	bytecode, err := assembler.CasmToBytecode(`
+        [ap] = 1234, ap++;
+        [ap] = [ap - 1] * [ap - 1], ap++;
+    `)
+	require.NoError(t, err)
+	vm, _ := defaultVirtualMachineWithBytecode(bytecode)
+	vm.Context.Fp = 1
+
+	details, err := vm.RunStepDetailed(nil)
+	require.NoError(t, err)
+	assert.Equal(t, Context{Pc: mem.MemoryAddress{SegmentIndex: ProgramSegment, Offset: 0}, Fp: 1}, details.Context)
+	assert.Equal(t, AssertEq, details.Instruction.Opcode)
+	assert.Equal(t, mem.MemoryValueFromInt(1234), details.Res)
+
+	details, err = vm.RunStepDetailed(nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), details.Context.Ap)
+	assert.Equal(t, MulOperands, details.Instruction.Res)
+	assert.Equal(t, mem.MemoryValueFromInt(1234*1234), details.Res)
+	assert.Equal(t, uint64(2), vm.Step)
+}
+
+func TestRunStepBytecodeCache(t *testing.T) {
+	// the same instruction word at pcs 0, 2 and 4
+	bytecode, err := assembler.CasmToBytecode(`
+        [ap] = 1, ap++;
+        [ap] = 2, ap++;
+        [ap] = 3, ap++;
+    `)
+	require.NoError(t, err)
+	vm, _ := defaultVirtualMachineWithBytecode(bytecode)
+	vm.Context.Fp = 1
+	vm.EnableBytecodeCache()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, vm.RunStep(nil))
+	}
+	require.NotNil(t, vm.programInstructions[0])
+	assert.Same(t, vm.programInstructions[0], vm.programInstructions[2])
+	assert.Same(t, vm.programInstructions[0], vm.programInstructions[4])
+	assert.Len(t, vm.bytecodeInstructions, 1)
+
+	// without the cache each pc decodes its own instruction
+	vm, _ = defaultVirtualMachineWithBytecode(bytecode)
+	vm.Context.Fp = 1
+	for i := 0; i < 3; i++ {
+		require.NoError(t, vm.RunStep(nil))
+	}
+	assert.NotSame(t, vm.programInstructions[0], vm.programInstructions[2])
+	assert.Equal(t, *vm.programInstructions[0], *vm.programInstructions[2])
+}
+
 func TestGetOp0PosCellOp1(t *testing.T) {
 	vm, _ := defaultVirtualMachineWithBytecode(
 		[]*f.Element{
@@ -226,6 +354,45 @@ func TestGetOp0NegCellOp1(t *testing.T) {
 	assert.Equal(t, mem.MemoryValueFromInt(444), mv)
 }
 
+func TestGetOp0UnknownCellOp1(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	op0Addr := mem.MemoryAddress{SegmentIndex: ExecutionSegment, Offset: 3}
+	instruction := Instruction{
+		OffOp0:    -2,
+		OffOp1:    1,
+		Op1Source: Op0,
+	}
+
+	_, err := vm.getOp1Addr(&instruction, &op0Addr)
+	require.EqualError(
+		t,
+		err,
+		"op1 via op0 requires op0 to hold a known address: op0 at 1:3 (off_op0 -2) is unknown, off_op1 1",
+	)
+	// op0 is left unknown instead of being inferred
+	op0, err := vm.Memory.PeekFromAddress(&op0Addr)
+	require.NoError(t, err)
+	assert.False(t, op0.Known())
+}
+
+func TestGetOp0FeltCellOp1(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	writeToDataSegment(vm, 0, mem.MemoryValueFromInt(7))
+	op0Addr := mem.MemoryAddress{SegmentIndex: ExecutionSegment, Offset: 0}
+	instruction := Instruction{
+		OffOp0:    -1,
+		OffOp1:    2,
+		Op1Source: Op0,
+	}
+
+	_, err := vm.getOp1Addr(&instruction, &op0Addr)
+	require.EqualError(
+		t,
+		err,
+		"op1 via op0 requires op0 to hold a known address: op0 at 1:0 (off_op0 -1) is 7, off_op1 2",
+	)
+}
+
 func TestGetFpPosCellOp1(t *testing.T) {
 	vm, _ := defaultVirtualMachine()
 
@@ -324,7 +491,8 @@ func TestInferOperandSub(t *testing.T) {
 	op1Addr := mem.MemoryAddress{SegmentIndex: ExecutionSegment, Offset: 1}
 	op0Addr := mem.MemoryAddress{SegmentIndex: ExecutionSegment, Offset: 2}
 
-	expectedOp0Vaue := mem.MemoryValueFromSegmentAndOffset(3, 8)
+	// op0 + 3:7 = 3:15 means op0 is the felt distance between both addresses
+	expectedOp0Vaue := mem.MemoryValueFromInt(8)
 	inferedRes, err := vm.inferOperand(&instruction, &dstAddr, &op0Addr, &op1Addr)
 	require.NoError(t, err)
 	assert.Equal(t, mem.MemoryValueFromSegmentAndOffset(3, 15), inferedRes)
@@ -334,6 +502,105 @@ func TestInferOperandSub(t *testing.T) {
 	assert.Equal(t, expectedOp0Vaue, op0Value)
 }
 
+// Runs `[ap] = [[fp]]` with dst at 1:0 and op1 at 2:3, each written only if
+// given, the op1 segment being backed by builtinRunner
+func runAssertOp1(
+	t *testing.T, builtinRunner mem.BuiltinRunner, dst, op1 *mem.MemoryValue,
+) (*VirtualMachine, error) {
+	t.Helper()
+	bytecode, err := assembler.CasmToBytecode("[ap] = [[fp]];")
+	require.NoError(t, err)
+	vm, _ := defaultVirtualMachineWithBytecode(bytecode)
+	vm.Context.Fp = 1
+	vm.Memory.AllocateBuiltinSegment("test", builtinRunner)
+	writeToDataSegment(vm, 1, mem.MemoryValueFromSegmentAndOffset(2, 3))
+	if dst != nil {
+		writeToDataSegment(vm, 0, *dst)
+	}
+	if op1 != nil {
+		require.NoError(t, vm.Memory.Write(2, 3, op1))
+	}
+	return vm, vm.RunStep(nil)
+}
+
+// A builtin runner deducing every cell as 9
+type deducingBuiltin struct {
+	mem.NoBuiltin
+}
+
+func (b *deducingBuiltin) InferValue(segment *mem.Segment, offset uint64) error {
+	nine := mem.MemoryValueFromInt(9)
+	return segment.WriteInferred(offset, &nine)
+}
+
+func (b *deducingBuiltin) Deduces(offset uint64) bool {
+	return true
+}
+
+// Follows the assert_eq deduction of the python vm: op1 is deduced by its
+// builtin first, then as dst, and dst as res
+func TestRunStepInferOp1(t *testing.T) {
+	five, seven, nine := mem.MemoryValueFromInt(5), mem.MemoryValueFromInt(7), mem.MemoryValueFromInt(9)
+	for _, tc := range []struct {
+		name          string
+		builtinRunner mem.BuiltinRunner
+		dst, op1      *mem.MemoryValue
+		// value of both cells after the step, unless it fails
+		expected mem.MemoryValue
+		err      string
+	}{
+		{name: "unknown op1", builtinRunner: &mem.NoBuiltin{}, dst: &five, expected: five},
+		{name: "unknown dst", builtinRunner: &mem.NoBuiltin{}, op1: &seven, expected: seven},
+		{name: "both known", builtinRunner: &mem.NoBuiltin{}, dst: &five, op1: &five, expected: five},
+		{
+			name: "both known and different", builtinRunner: &mem.NoBuiltin{}, dst: &five, op1: &seven,
+			err: "assertion failed at 1:0: expected 7, got 5",
+		},
+		{
+			name: "both unknown", builtinRunner: &mem.NoBuiltin{},
+			err: "op1 and dst cells are unknown",
+		},
+		{name: "deduced op1", builtinRunner: &deducingBuiltin{}, expected: nine},
+		{name: "deduced op1 and dst", builtinRunner: &deducingBuiltin{}, dst: &nine, expected: nine},
+		{
+			name: "deduced op1 and different dst", builtinRunner: &deducingBuiltin{}, dst: &five,
+			err: "assertion failed at 1:0: expected 9, got 5",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			vm, err := runAssertOp1(t, tc.builtinRunner, tc.dst, tc.op1)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			dstValue, err := vm.Memory.Peek(ExecutionSegment, 0)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, dstValue)
+			op1Value, err := vm.Memory.Peek(2, 3)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, op1Value)
+		})
+	}
+}
+
+func TestRunStepInferOperandDivByZero(t *testing.T) {
+	// only dst and op0 are known
+	bytecode, err := assembler.CasmToBytecode("[ap] = [fp] * [ap + 1];")
+	require.NoError(t, err)
+	vm, _ := defaultVirtualMachineWithBytecode(bytecode)
+	vm.Context.Fp = 2
+	writeToDataSegment(vm, 0, mem.MemoryValueFromInt(6))
+	writeToDataSegment(vm, 2, mem.MemoryValueFromInt(0))
+
+	err = vm.RunStep(nil)
+
+	var vmErr *VMError
+	require.ErrorAs(t, err, &vmErr)
+	assert.Equal(t, "res infer", vmErr.Op)
+	assert.ErrorContains(t, err, "division by zero")
+}
+
 func TestComputeResUnconstrained(t *testing.T) {
 	vm, _ := defaultVirtualMachine()
 	instruction := Instruction{Res: Unconstrained}
@@ -514,6 +781,320 @@ func TestOpcodeAssertionAssertEq(t *testing.T) {
 	assert.Equal(t, res, op0Value)
 }
 
+func TestOpcodeAssertionAssertEqFails(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	dstAddr := mem.MemoryAddress{SegmentIndex: ExecutionSegment, Offset: 0}
+	dstValue := mem.MemoryValueFromInt(5)
+	require.NoError(t, vm.Memory.WriteToAddress(&dstAddr, &dstValue))
+
+	instruction := Instruction{
+		Opcode: AssertEq,
+	}
+
+	res := mem.MemoryValueFromInt(7)
+	err := vm.opcodeAssertions(&instruction, &dstAddr, nil, &res)
+
+	var assertErr *AssertEqError
+	require.ErrorAs(t, err, &assertErr)
+	assert.Equal(t, AssertEqError{Dst: dstAddr, Expected: res, Got: dstValue}, *assertErr)
+}
+
+func TestRunStepDecodeError(t *testing.T) {
+	// a felt bigger than 64 bits is not a valid instruction
+	invalid := new(f.Element).SetBigInt(new(big.Int).Lsh(big.NewInt(1), 64))
+	vm, _ := defaultVirtualMachineWithBytecode([]*f.Element{invalid})
+	vm.Step = 3
+
+	err := vm.RunStep(nil)
+
+	var vmErr *VMError
+	require.ErrorAs(t, err, &vmErr)
+	assert.Equal(t, "decoding instruction", vmErr.Op)
+	assert.Equal(t, uint64(3), vmErr.Step)
+	assert.Equal(t, mem.MemoryAddress{SegmentIndex: ProgramSegment, Offset: 0}, vmErr.Pc)
+	assert.ErrorContains(t, err, "pc 0:0 step 3: decoding instruction")
+
+	var decodeErr *DecodeError
+	require.ErrorAs(t, err, &decodeErr)
+	assert.Equal(t, *invalid, decodeErr.Word)
+	assert.EqualError(
+		t, err,
+		"pc 0:0 step 3: decoding instruction: word 0x10000000000000000 "+
+			"(previous unknown, next unknown): 18446744073709551616 is bigger than 64 bits",
+	)
+
+	// a pc landing on an immediate shows the instruction it belongs to
+	vm, _ = defaultVirtualMachineWithBytecode([]*f.Element{
+		new(f.Element).SetUint64(0x480680017fff8000),
+		invalid,
+		new(f.Element).SetUint64(0x208b7fff7fff7ffe),
+	})
+	vm.Context.Pc = mem.MemoryAddress{SegmentIndex: ProgramSegment, Offset: 1}
+	err = vm.RunStep(nil)
+	assert.ErrorContains(
+		t, err, "word 0x10000000000000000 (previous 0x480680017fff8000, next 0x208b7fff7fff7ffe)",
+	)
+}
+
+func TestRunStepAssertEqError(t *testing.T) {
+	// [ap + 0] = 7
+	vm, _ := defaultVirtualMachineWithBytecode([]*f.Element{
+		new(f.Element).SetUint64(0x480680017fff8000),
+		new(f.Element).SetUint64(7),
+	})
+	vm.Context.Fp = 1
+	dstValue := mem.MemoryValueFromInt(5)
+	require.NoError(t, vm.Memory.Write(ExecutionSegment, 0, &dstValue))
+
+	err := vm.RunStep(nil)
+
+	var vmErr *VMError
+	require.ErrorAs(t, err, &vmErr)
+	assert.Equal(t, "opcode assertions", vmErr.Op)
+
+	var assertErr *AssertEqError
+	require.ErrorAs(t, err, &assertErr)
+	assert.Equal(t, mem.MemoryValueFromInt(7), assertErr.Expected)
+}
+
+func TestInstructionCache(t *testing.T) {
+	// [ap + 0] = 7, ap++
+	assertEq := new(f.Element).SetUint64(0x480680017fff8000)
+	vm, _ := defaultVirtualMachineWithBytecode([]*f.Element{assertEq, new(f.Element).SetUint64(7)})
+	require.Len(t, vm.programInstructions, 2)
+
+	vm.Context.Fp = 1
+	require.NoError(t, vm.RunStep(nil))
+	assert.NotNil(t, vm.programInstructions[0])
+	assert.Nil(t, vm.programInstructions[1])
+	assert.Empty(t, vm.instructions)
+
+	// instructions outside of the program segment are cached by address
+	otherSegment := vm.Memory.AllocateEmptySegment()
+	bytecode := mem.MemoryValueFromFieldElement(assertEq)
+	imm := mem.MemoryValueFromInt(7)
+	require.NoError(t, vm.Memory.Write(uint64(otherSegment), 1, &bytecode))
+	require.NoError(t, vm.Memory.Write(uint64(otherSegment), 2, &imm))
+
+	pc := mem.MemoryAddress{SegmentIndex: uint64(otherSegment), Offset: 1}
+	vm.Context.Pc = pc
+	vm.Context.Ap = 1
+	require.NoError(t, vm.RunStep(nil))
+	assert.Equal(t, vm.programInstructions[0], vm.instructions[pc])
+}
+
+func TestRawTrace(t *testing.T) {
+	// [ap + 0] = 7, ap++
+	vm, _ := defaultVirtualMachineWithBytecode([]*f.Element{
+		new(f.Element).SetUint64(0x480680017fff8000),
+		new(f.Element).SetUint64(7),
+	})
+	vm.Context.Fp = 1
+	assert.Nil(t, vm.RawTrace())
+	_, err := vm.ExecutionTrace()
+	assert.ErrorContains(t, err, "proof mode is off")
+
+	vm.EnableTracing()
+	assert.Empty(t, vm.RawTrace())
+	require.NoError(t, vm.RunStep(nil))
+
+	// the context is recorded before executing the step and it isn't relocated
+	assert.Equal(t, []Context{{
+		Pc: mem.MemoryAddress{SegmentIndex: ProgramSegment, Offset: 0},
+		Ap: 0,
+		Fp: 1,
+	}}, vm.RawTrace())
+}
+
+func TestTraceFlusher(t *testing.T) {
+	bytecode, err := assembler.CasmToBytecode(`
+        [ap] = 1, ap++;
+        [ap] = 2, ap++;
+        [ap] = 3, ap++;
+    `)
+	require.NoError(t, err)
+	vm, _ := defaultVirtualMachineWithBytecode(bytecode)
+	vm.Context.Fp = 1
+	vm.EnableTracing()
+
+	var flushed []Context
+	vm.SetTraceFlusher(2, func(trace []Context) error {
+		flushed = append(flushed, trace...)
+		return nil
+	})
+
+	snapshot := vm.Snapshot()
+	require.NoError(t, vm.RunStep(nil))
+	require.NoError(t, vm.RunStep(nil))
+	assert.Len(t, flushed, 2)
+	assert.Empty(t, vm.RawTrace())
+
+	// the flushed entries can't be taken back
+	err = vm.Restore(snapshot)
+	require.EqualError(t, err, "cannot restore step 0 snapshot, 2 trace entries were already flushed")
+
+	snapshot = vm.Snapshot()
+	require.NoError(t, vm.RunStep(nil))
+	assert.Len(t, vm.RawTrace(), 1)
+	require.NoError(t, vm.Restore(snapshot))
+	assert.Empty(t, vm.RawTrace())
+
+	require.NoError(t, vm.RunStep(nil))
+	require.NoError(t, vm.FlushTrace())
+	assert.Equal(t, uint64(4), flushed[2].Pc.Offset)
+	assert.Len(t, flushed, 3)
+}
+
+func TestRelocateOverflow(t *testing.T) {
+	ctx := Context{Pc: mem.MemoryAddress{SegmentIndex: ProgramSegment, Offset: 4}, Ap: 10, Fp: 7}
+	trace, err := ctx.Relocate(^uint64(0) - 10)
+	require.NoError(t, err)
+	assert.Equal(t, Trace{Pc: 5, Ap: ^uint64(0), Fp: ^uint64(0) - 3}, trace)
+
+	_, err = ctx.Relocate(^uint64(0) - 9)
+	require.EqualError(t, err, "relocating ap 10 to 18446744073709551606 overflows")
+
+	ctx.Pc.Offset = ^uint64(0)
+	_, err = ctx.Relocate(0)
+	require.EqualError(t, err, "relocating pc 0:18446744073709551615 overflows")
+}
+
+func TestExecutionTraceOverflow(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.config.ProofMode = true
+	vm.Trace = []Context{{Ap: 0, Fp: 0}, {Ap: 1, Fp: ^uint64(0)}}
+
+	_, err := vm.ExecutionTrace()
+	require.EqualError(t, err, "step 1: relocating fp 18446744073709551615 to 1 overflows")
+}
+
+func TestMemoryAccessTrace(t *testing.T) {
+	bytecode, err := assembler.CasmToBytecode(`
+        [ap] = 7, ap++;
+    `)
+	require.NoError(t, err)
+	manager := mem.CreateMemoryManager()
+	_, err = manager.Memory.AllocateSegment(bytecode)
+	require.NoError(t, err)
+	manager.Memory.AllocateEmptySegment()
+
+	vm, err := NewVirtualMachine(Context{Fp: 1}, manager.Memory, VirtualMachineConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, vm.MemoryAccessTrace())
+
+	vm, err = NewVirtualMachine(
+		Context{Fp: 1}, manager.Memory, VirtualMachineConfig{CollectMemoryAccesses: true},
+	)
+	require.NoError(t, err)
+	hintWrite := mem.MemoryValueFromInt(3)
+	require.NoError(t, vm.Memory.Write(ExecutionSegment, 4, &hintWrite))
+	require.NoError(t, vm.RunStep(nil))
+
+	seven := mem.MemoryValueFromInt(7)
+	accesses := vm.MemoryAccessTrace()
+	assert.Equal(t, mem.MemoryAccess{
+		Address: mem.MemoryAddress{SegmentIndex: ExecutionSegment, Offset: 4}, Value: hintWrite,
+	}, accesses[0])
+	// the pc, dst, op0 and op1 cells of the step come last
+	assert.Equal(t, []mem.MemoryAccess{
+		{Address: mem.MemoryAddress{SegmentIndex: ProgramSegment, Offset: 0}, Value: mem.MemoryValueFromFieldElement(bytecode[0])},
+		{Address: mem.MemoryAddress{SegmentIndex: ExecutionSegment, Offset: 0}, Value: seven},
+		{Address: mem.MemoryAddress{SegmentIndex: ExecutionSegment, Offset: 0}, Value: seven},
+		{Address: mem.MemoryAddress{SegmentIndex: ProgramSegment, Offset: 1}, Value: seven},
+	}, accesses[len(accesses)-4:])
+
+	// restoring a snapshot drops the accesses made since
+	snapshot := vm.Snapshot()
+	_, err = vm.Memory.Read(ExecutionSegment, 4)
+	require.NoError(t, err)
+	require.NoError(t, vm.Restore(snapshot))
+	assert.Equal(t, accesses, vm.MemoryAccessTrace())
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	bytecode, err := assembler.CasmToBytecode(`
+        [ap] = 7, ap++;
+        [ap] = 8, ap++;
+    `)
+	require.NoError(t, err)
+	vm, _ := defaultVirtualMachineWithBytecode(bytecode)
+	vm.Context.Fp = 1
+	vm.EnableTracing()
+
+	require.NoError(t, vm.RunStep(nil))
+	snapshot := vm.Snapshot()
+	require.NoError(t, vm.RunStep(nil))
+	vm.Memory.AllocateEmptySegment()
+
+	require.NoError(t, vm.Restore(snapshot))
+	assert.Equal(t, uint64(1), vm.Step)
+	assert.Equal(t, Context{Pc: mem.MemoryAddress{SegmentIndex: ProgramSegment, Offset: 2}, Ap: 1, Fp: 1}, vm.Context)
+	assert.Len(t, vm.RawTrace(), 1)
+	assert.Len(t, vm.Memory.Segments, 2)
+	assert.Equal(t, uint64(1), vm.Memory.Segments[ExecutionSegment].Len())
+
+	// running again from the snapshot yields the same state
+	require.NoError(t, vm.RunStep(nil))
+	eight, err := vm.Memory.Read(ExecutionSegment, 1)
+	require.NoError(t, err)
+	assert.Equal(t, mem.MemoryValueFromInt(8), eight)
+
+	err = vm.Restore(VMSnapshot{Step: 5})
+	assert.ErrorContains(t, err, "cannot restore step 5 snapshot at step 2")
+}
+
+func TestValidateRet(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Fp = 2
+	dstAddr := mem.MemoryAddress{SegmentIndex: ExecutionSegment, Offset: 0}
+
+	ret := Instruction{
+		OffDest:     -2,
+		DstRegister: Fp,
+		PcUpdate:    Jump,
+		ApUpdate:    SameAp,
+		Opcode:      Ret,
+	}
+
+	var retErr *MalformedRetError
+
+	// [fp - 2] holds a felt instead of the caller fp
+	writeToDataSegment(vm, 0, mem.MemoryValueFromInt(3))
+	err := vm.validateRet(&ret, &dstAddr)
+	require.ErrorAs(t, err, &retErr)
+	assert.ErrorContains(t, err, "must be an address")
+
+	vm, _ = defaultVirtualMachine()
+	vm.Context.Fp = 2
+	writeToDataSegment(vm, 0, mem.MemoryValueFromSegmentAndOffset(ExecutionSegment, 0))
+	require.NoError(t, vm.validateRet(&ret, &dstAddr))
+
+	invalidPc := ret
+	invalidPc.PcUpdate = NextInstr
+	require.ErrorAs(t, vm.validateRet(&invalidPc, &dstAddr), &retErr)
+
+	invalidAp := ret
+	invalidAp.ApUpdate = Add1
+	require.ErrorAs(t, vm.validateRet(&invalidAp, &dstAddr), &retErr)
+
+	invalidDst := ret
+	invalidDst.DstRegister = Ap
+	err = vm.validateRet(&invalidDst, &dstAddr)
+	require.ErrorAs(t, err, &retErr)
+	assert.ErrorContains(t, err, "dst must be [Fp - 2], got [Ap + -2]")
+}
+
+func TestRunStepMalformedRet(t *testing.T) {
+	// ret
+	vm, _ := defaultVirtualMachineWithBytecode([]*f.Element{new(f.Element).SetUint64(0x208b7fff7fff7ffe)})
+	vm.Context.Fp = 2
+	writeToDataSegment(vm, 0, mem.MemoryValueFromInt(3))
+	writeToDataSegment(vm, 1, mem.MemoryValueFromInt(0))
+
+	var retErr *MalformedRetError
+	require.ErrorAs(t, vm.RunStep(nil), &retErr)
+}
+
 func TestUpdatePcNextInstr(t *testing.T) {
 	vm, _ := defaultVirtualMachine()
 
@@ -563,7 +1144,7 @@ func TestUpdatePcJumpRel(t *testing.T) {
 
 	vm.Context.Pc = mem.MemoryAddress{SegmentIndex: 0, Offset: 3}
 	relAddr := uint64(10)
-	res := mem.MemoryValueFromInt(relAddr)
+	res := mem.MemoryValueFromUint(relAddr)
 
 	instruction := Instruction{
 		PcUpdate: JumpRel,
@@ -577,8 +1158,8 @@ func TestUpdatePcJumpRel(t *testing.T) {
 func TestUpdatePcJnz(t *testing.T) {
 	vm, _ := defaultVirtualMachine()
 	relAddr := uint64(10)
-	writeToDataSegment(vm, 0, mem.MemoryValueFromInt(10))      //dstCell
-	writeToDataSegment(vm, 1, mem.MemoryValueFromInt(relAddr)) //op1Cell
+	writeToDataSegment(vm, 0, mem.MemoryValueFromInt(10))       //dstCell
+	writeToDataSegment(vm, 1, mem.MemoryValueFromUint(relAddr)) //op1Cell
 	dstAddr := mem.MemoryAddress{SegmentIndex: ExecutionSegment, Offset: 0}
 	op1Addr := mem.MemoryAddress{SegmentIndex: ExecutionSegment, Offset: 1}
 
@@ -628,6 +1209,31 @@ func TestUpdatePcJnzDstZeroImm(t *testing.T) {
 	assert.Equal(t, mem.MemoryAddress{SegmentIndex: 0, Offset: 9 + 2}, nextPc)
 }
 
+func TestUpdatePcJnzDstAddress(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	writeToDataSegment(vm, 0, mem.MemoryValueFromSegmentAndOffset(ExecutionSegment, 4)) //dstCell
+	dstAddr := mem.MemoryAddress{SegmentIndex: ExecutionSegment, Offset: 0}
+
+	instruction := Instruction{
+		PcUpdate:  Jnz,
+		Op1Source: Imm,
+	}
+	_, err := vm.updatePc(&instruction, &dstAddr, nil, nil)
+	require.EqualError(t, err, "jnz condition must be a felt, got address at dst 1:0: 1:4")
+}
+
+func TestRunStepJnzDstAddress(t *testing.T) {
+	// jmp rel 2 if [ap] != 0
+	vm, _ := defaultVirtualMachineWithBytecode([]*f.Element{
+		new(f.Element).SetUint64(0x020680017fff8000), new(f.Element).SetUint64(2),
+	})
+	vm.Context.Fp = 1
+	writeToDataSegment(vm, 0, mem.MemoryValueFromSegmentAndOffset(ExecutionSegment, 4))
+
+	err := vm.RunStep(nil)
+	require.ErrorContains(t, err, "pc update: jnz condition must be a felt, got address at dst 1:0")
+}
+
 func TestUpdateApAddOne(t *testing.T) {
 	vm, _ := defaultVirtualMachine()
 
@@ -642,6 +1248,34 @@ func TestUpdateApAddOne(t *testing.T) {
 	assert.Equal(t, vm.Context.Ap+1, nextAp)
 }
 
+func TestUpdateApAddImm(t *testing.T) {
+	vm, _ := defaultVirtualMachine()
+	vm.Context.Ap = 5
+	instruction := Instruction{
+		Opcode:   Nop,
+		ApUpdate: AddImm,
+	}
+
+	res := mem.MemoryValueFromInt(3)
+	nextAp, err := vm.updateAp(&instruction, &res)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(8), nextAp)
+
+	res = mem.MemoryValueFromSegmentAndOffset(ExecutionSegment, 4)
+	_, err = vm.updateAp(&instruction, &res)
+	require.EqualError(t, err, "ap update AddImm requires a small felt result, got address 1:4 at ap 5")
+
+	res = mem.MemoryValueFromFieldElement(new(f.Element).SetBigInt(new(big.Int).Lsh(big.NewInt(1), 64)))
+	_, err = vm.updateAp(&instruction, &res)
+	require.EqualError(
+		t, err, "ap update AddImm requires a small felt result, got 0x10000000000000000 at ap 5",
+	)
+
+	res = mem.MemoryValueFromUint(^uint64(0))
+	_, err = vm.updateAp(&instruction, &res)
+	require.EqualError(t, err, "ap update AddImm overflows: 5 + 18446744073709551615")
+}
+
 func TestUpdateFp(t *testing.T) {
 	vm, _ := defaultVirtualMachine()
 